@@ -0,0 +1,431 @@
+// Package client envoie des logs à logger-server depuis un service Go : les entrées sont
+// mises en file, regroupées en lots compressés sur POST /log/batch et renvoyées avec un
+// backoff en cas d'échec. Quand le serveur reste injoignable, les lots sont écrits sur
+// disque (si SpoolDir est défini) et renvoyés plus tard.
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Valeurs par défaut de Config
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+	DefaultQueueSize     = 10000
+	DefaultMaxRetries    = 5
+	DefaultMinBackoff    = 200 * time.Millisecond
+	DefaultMaxBackoff    = 30 * time.Second
+	DefaultMaxSpoolSize  = 64 << 20
+
+	// MaxBatchSize est le nombre maximal d'entrées acceptées par POST /log/batch
+	MaxBatchSize = 1000
+)
+
+var (
+	ErrQueueFull = errors.New("log queue is full")
+	ErrClosed    = errors.New("client is closed")
+)
+
+// Niveaux reconnus par le serveur (log_levels.LogLevel)
+const (
+	LevelTrace = "TRACE"
+	LevelDebug = "DEBUG"
+	LevelInfo  = "INFO"
+	LevelWarn  = "WARN"
+	LevelError = "ERROR"
+	LevelFatal = "FATAL"
+)
+
+// Entry est une entrée de log, au format JSON de l'API
+type Entry struct {
+	ID        string                 `json:"id,omitempty"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Service   string                 `json:"service,omitempty"`
+	Host      string                 `json:"host,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Context   map[string]interface{} `json:"context,omitempty"`
+}
+
+type Config struct {
+	URL    string // adresse du serveur, ex. http://localhost:8080
+	APIKey string // envoyée dans X-API-Key
+	// SignRequests signe les lots avec APIKey (SignRequest) au lieu de l'envoyer
+	SignRequests bool
+
+	// Service et Host complètent les entrées qui ne les renseignent pas
+	Service string
+	Host    string
+
+	BatchSize     int           // entrées par lot (au plus MaxBatchSize)
+	FlushInterval time.Duration // délai maximal avant l'envoi d'un lot incomplet
+	QueueSize     int           // entrées en attente au-delà desquelles Log retourne ErrQueueFull
+
+	MaxRetries int // renvois d'un lot avant de l'écrire sur disque ou de l'abandonner (< 0 : aucun)
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	DisableCompression bool
+
+	// SpoolDir active le tampon disque des lots non envoyés, borné à MaxSpoolSize octets
+	SpoolDir     string
+	MaxSpoolSize int64
+
+	HTTPClient *http.Client
+	// OnError reçoit les erreurs d'envoi (lots abandonnés, entrées refusées, ...)
+	OnError func(error)
+}
+
+// Stats compte les entrées depuis la création du client
+type Stats struct {
+	Sent     uint64 // acceptées par le serveur
+	Rejected uint64 // refusées par le serveur (entrée invalide; les entrées limitées sont renvoyées)
+	Dropped  uint64 // abandonnées (file pleine, lot refusé, tampon disque plein)
+	Spooled  uint64 // écrites sur disque en attendant le serveur
+	Retries  uint64 // renvois de lots
+}
+
+type batch struct {
+	key     string // Idempotency-Key, conservée entre les renvois d'un même lot
+	entries []Entry
+}
+
+type flushRequest struct {
+	done chan struct{}
+}
+
+type Client struct {
+	cfg      Config
+	endpoint string
+	http     *http.Client
+	spool    *spool
+
+	queue   chan Entry
+	flushCh chan flushRequest
+	closing chan struct{}
+	done    chan struct{}
+
+	// ctx annule les envois en cours quand le délai de Close expire
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	closed bool
+
+	sent, rejected, dropped, spooled, retries atomic.Uint64
+}
+
+// New crée un client et démarre sa goroutine d'envoi; Close doit être appelé à l'arrêt
+func New(cfg Config) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("client: URL is required")
+	}
+	if cfg.SignRequests && !signable(cfg.APIKey) {
+		return nil, errUnsignableKey
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.BatchSize > MaxBatchSize {
+		cfg.BatchSize = MaxBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.MaxSpoolSize <= 0 {
+		cfg.MaxSpoolSize = DefaultMaxSpoolSize
+	}
+
+	c := &Client{
+		cfg:      cfg,
+		endpoint: strings.TrimRight(cfg.URL, "/") + "/log/batch",
+		http:     cfg.HTTPClient,
+		queue:    make(chan Entry, cfg.QueueSize),
+		flushCh:  make(chan flushRequest),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.SpoolDir != "" {
+		s, err := openSpool(cfg.SpoolDir, cfg.MaxSpoolSize)
+		if err != nil {
+			return nil, err
+		}
+		c.spool = s
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	go c.run()
+	return c, nil
+}
+
+// Log met une entrée en file sans bloquer. Quand la file est pleine (serveur lent ou
+// injoignable), l'entrée est abandonnée et ErrQueueFull est retourné.
+func (c *Client) Log(entry Entry) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return ErrClosed
+	}
+
+	select {
+	case c.queue <- c.prepare(entry):
+		return nil
+	default:
+		c.dropped.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// LogWait met une entrée en file en attendant qu'une place se libère ou que ctx expire
+func (c *Client) LogWait(ctx context.Context, entry Entry) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return ErrClosed
+	}
+
+	select {
+	case c.queue <- c.prepare(entry):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush envoie les entrées en file et les lots en attente sur disque, et attend la fin
+// des envois ou l'expiration de ctx
+func (c *Client) Flush(ctx context.Context) error {
+	req := flushRequest{done: make(chan struct{})}
+	select {
+	case c.flushCh <- req:
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-req.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close refuse les nouvelles entrées et envoie celles en file. Si ctx expire avant la fin,
+// les envois en cours sont interrompus et les lots restants écrits sur disque (si SpoolDir
+// est défini) ou abandonnés.
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		<-c.done
+		return nil
+	}
+	c.closed = true
+	close(c.closing)
+	c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		c.cancel()
+		return nil
+	case <-ctx.Done():
+		c.cancel()
+		<-c.done
+		return ctx.Err()
+	}
+}
+
+// Stats retourne les compteurs du client
+func (c *Client) Stats() Stats {
+	return Stats{
+		Sent:     c.sent.Load(),
+		Rejected: c.rejected.Load(),
+		Dropped:  c.dropped.Load(),
+		Spooled:  c.spooled.Load(),
+		Retries:  c.retries.Load(),
+	}
+}
+
+func (c *Client) prepare(entry Entry) Entry {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	if entry.Service == "" {
+		entry.Service = c.cfg.Service
+	}
+	if entry.Host == "" {
+		entry.Host = c.cfg.Host
+	}
+	return entry
+}
+
+// run regroupe les entrées en lots et les envoie. Les envois sont faits dans cette
+// goroutine : pendant les renvois, la file se remplit et Log finit par refuser les entrées.
+func (c *Client) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var pending []Entry
+	flush := func() {
+		if len(pending) > 0 {
+			c.deliver(&batch{key: newBatchKey(), entries: pending})
+			pending = nil
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case entry := <-c.queue:
+				pending = append(pending, entry)
+				if len(pending) >= c.cfg.BatchSize {
+					flush()
+				}
+			default:
+				flush()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case entry := <-c.queue:
+			pending = append(pending, entry)
+			if len(pending) >= c.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			c.replaySpool()
+		case req := <-c.flushCh:
+			drain()
+			c.replaySpool()
+			close(req.done)
+		case <-c.closing:
+			drain()
+			c.replaySpool()
+			return
+		}
+	}
+}
+
+// deliver envoie un lot avec renvois; en cas d'échec persistant, le lot est écrit sur
+// disque ou abandonné
+func (c *Client) deliver(b *batch) {
+	err := c.sendWithRetry(b)
+	if err == nil {
+		return
+	}
+
+	var permanent *StatusError
+	if errors.As(err, &permanent) && !permanent.Retryable() {
+		c.dropped.Add(uint64(len(b.entries)))
+		c.reportError(fmt.Errorf("batch of %d entries dropped: %w", len(b.entries), err))
+		return
+	}
+
+	if c.spool != nil {
+		spoolErr := c.spool.write(b)
+		if spoolErr == nil {
+			c.spooled.Add(uint64(len(b.entries)))
+			return
+		}
+		err = fmt.Errorf("%v; spool: %w", err, spoolErr)
+	}
+	c.dropped.Add(uint64(len(b.entries)))
+	c.reportError(fmt.Errorf("batch of %d entries dropped: %w", len(b.entries), err))
+}
+
+// replaySpool renvoie les lots écrits sur disque, du plus ancien au plus récent, et
+// s'arrête au premier échec (le serveur est probablement encore indisponible)
+func (c *Client) replaySpool() {
+	if c.spool == nil {
+		return
+	}
+
+	for {
+		if c.ctx.Err() != nil {
+			return
+		}
+		name, b, err := c.spool.oldest()
+		if err != nil {
+			c.reportError(fmt.Errorf("spool: %w", err))
+			if name != "" {
+				c.spool.remove(name)
+				continue
+			}
+			return
+		}
+		if b == nil {
+			return
+		}
+
+		err = c.send(b)
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			// Seules les entrées refusées par une limite restent à renvoyer
+			c.spool.remove(name)
+			if spoolErr := c.spool.write(b); spoolErr != nil {
+				c.dropped.Add(uint64(len(b.entries)))
+				c.reportError(fmt.Errorf("spooled batch of %d entries dropped: %v; spool: %w", len(b.entries), err, spoolErr))
+			}
+			return
+		}
+		var permanent *StatusError
+		if err != nil && !(errors.As(err, &permanent) && !permanent.Retryable()) {
+			return
+		}
+		if err != nil {
+			c.dropped.Add(uint64(len(b.entries)))
+			c.reportError(fmt.Errorf("spooled batch of %d entries dropped: %w", len(b.entries), err))
+		}
+		c.spool.remove(name)
+	}
+}
+
+func (c *Client) reportError(err error) {
+	if c.cfg.OnError != nil {
+		c.cfg.OnError(err)
+	}
+}
+
+// newBatchKey génère la clé Idempotency-Key d'un lot : le serveur en dérive l'ID de chaque
+// entrée, ce qui rend les renvois sans effet quand la déduplication est active
+func newBatchKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,463 @@
+package client_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/client"
+	"github.com/rypi-dev/logger-server/internal/handler"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+	"github.com/rypi-dev/logger-server/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+type receivedBatch struct {
+	key      string
+	encoding string
+	apiKey   string
+	entries  []client.Entry
+}
+
+// fakeServer imite POST /log/batch; reply choisit la réponse de chaque requête (n à partir de 0)
+type fakeServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests int
+	batches  []receivedBatch
+	reply    func(w http.ResponseWriter, r *http.Request, n int) bool
+}
+
+func newFakeServer(t *testing.T, reply func(w http.ResponseWriter, r *http.Request, n int) bool) *fakeServer {
+	t.Helper()
+	s := &fakeServer{reply: reply}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/log/batch" || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	n := s.requests
+	s.requests++
+	s.mu.Unlock()
+
+	if s.reply != nil && s.reply(w, r, n) {
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	var entries []client.Entry
+	if err := json.NewDecoder(body).Decode(&entries); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.batches = append(s.batches, receivedBatch{
+		key:      r.Header.Get("Idempotency-Key"),
+		encoding: r.Header.Get("Content-Encoding"),
+		apiKey:   r.Header.Get("X-API-Key"),
+		entries:  entries,
+	})
+	s.mu.Unlock()
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"accepted": len(entries), "rejected": []interface{}{}})
+}
+
+func (s *fakeServer) received() []receivedBatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedBatch(nil), s.batches...)
+}
+
+func (s *fakeServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *fakeServer) entryCount() int {
+	n := 0
+	for _, b := range s.received() {
+		n += len(b.entries)
+	}
+	return n
+}
+
+// handlerServer sert le handler du serveur et garde en mémoire les entrées qu'il stocke
+type handlerServer struct {
+	*httptest.Server
+	*handler.Handler
+
+	mu     sync.Mutex
+	stored []handler.LogEntry
+}
+
+func newHandlerServer(t *testing.T) *handlerServer {
+	t.Helper()
+	s := &handlerServer{}
+	s.Handler = handler.NewHandler(s, zap.NewNop())
+	s.Server = httptest.NewServer(s.Router())
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *handlerServer) Write(entry handler.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stored = append(s.stored, entry)
+	return nil
+}
+
+func (s *handlerServer) QueryLogs(tenant, level string, page, limit int) ([]handler.LogEntry, error) {
+	return nil, nil
+}
+
+func (s *handlerServer) entries() []handler.LogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]handler.LogEntry(nil), s.stored...)
+}
+
+func closeClient(t *testing.T, c *client.Client) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestClient_BatchesAndCompresses(t *testing.T) {
+	srv := newFakeServer(t, nil)
+	c, err := client.New(client.Config{
+		URL:           srv.URL,
+		APIKey:        "secret",
+		Service:       "billing",
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 7; i++ {
+		if err := c.Log(client.Entry{Level: "INFO", Message: "hello"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	closeClient(t, c)
+
+	batches := srv.received()
+	if len(batches) != 3 || len(batches[0].entries) != 3 || len(batches[2].entries) != 1 {
+		t.Fatalf("expected batches of 3, 3 and 1 entries, got %+v", batches)
+	}
+	keys := map[string]bool{}
+	for _, b := range batches {
+		if b.encoding != "gzip" || b.apiKey != "secret" || b.key == "" {
+			t.Errorf("unexpected request headers: %+v", b)
+		}
+		keys[b.key] = true
+	}
+	if len(keys) != 3 {
+		t.Errorf("expected a distinct Idempotency-Key per batch, got %v", keys)
+	}
+	if e := batches[0].entries[0]; e.Service != "billing" || e.Timestamp.IsZero() {
+		t.Errorf("expected service and timestamp to be filled in, got %+v", e)
+	}
+	if st := c.Stats(); st.Sent != 7 {
+		t.Errorf("expected 7 sent entries, got %+v", st)
+	}
+	if err := c.Log(client.Entry{Level: "INFO", Message: "late"}); !errors.Is(err, client.ErrClosed) {
+		t.Errorf("expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestClient_FlushInterval(t *testing.T) {
+	srv := newFakeServer(t, nil)
+	c, err := client.New(client.Config{URL: srv.URL, FlushInterval: 20 * time.Millisecond, DisableCompression: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeClient(t, c)
+
+	c.Log(client.Entry{Level: "INFO", Message: "lonely"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.entryCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("incomplete batch was not sent after the flush interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if b := srv.received()[0]; b.encoding != "" {
+		t.Errorf("expected uncompressed body, got Content-Encoding %q", b.encoding)
+	}
+}
+
+func TestClient_RetryHonorsRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	srv := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int) bool {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		mu.Unlock()
+		if n == 0 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return true
+		}
+		return false
+	})
+	c, err := client.New(client.Config{URL: srv.URL, FlushInterval: time.Hour, MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Log(client.Entry{Level: "INFO", Message: "limited"})
+	start := time.Now()
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	closeClient(t, c)
+
+	if elapsed < time.Second {
+		t.Errorf("expected retry to wait for Retry-After (1s), waited %v", elapsed)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 2 || keys[0] != keys[1] {
+		t.Errorf("expected the retry to reuse the Idempotency-Key, got %v", keys)
+	}
+	if st := c.Stats(); st.Retries != 1 || st.Sent != 1 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestClient_PermanentErrorDropsBatch(t *testing.T) {
+	srv := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int) bool {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid JSON"}`))
+		return true
+	})
+	var reported []error
+	c, err := client.New(client.Config{
+		URL:           srv.URL,
+		FlushInterval: time.Hour,
+		MinBackoff:    time.Millisecond,
+		SpoolDir:      t.TempDir(),
+		OnError:       func(err error) { reported = append(reported, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Log(client.Entry{Level: "INFO", Message: "bad"})
+	c.Flush(context.Background())
+	closeClient(t, c)
+
+	if n := srv.requestCount(); n != 1 {
+		t.Errorf("expected no retry on 400, got %d requests", n)
+	}
+	var statusErr *client.StatusError
+	if len(reported) != 1 || !errors.As(reported[0], &statusErr) || statusErr.Message != "invalid JSON" {
+		t.Errorf("expected the 400 to be reported, got %v", reported)
+	}
+	if st := c.Stats(); st.Dropped != 1 || st.Spooled != 0 {
+		t.Errorf("expected the batch to be dropped, not spooled: %+v", st)
+	}
+}
+
+func TestClient_QueueFull(t *testing.T) {
+	release := make(chan struct{})
+	srv := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int) bool {
+		<-release
+		return false
+	})
+	c, err := client.New(client.Config{URL: srv.URL, BatchSize: 1, QueueSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var queueErr error
+	for i := 0; i < 10 && queueErr == nil; i++ {
+		queueErr = c.Log(client.Entry{Level: "INFO", Message: "flood"})
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	closeClient(t, c)
+
+	if !errors.Is(queueErr, client.ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull while the server is stalled, got %v", queueErr)
+	}
+	if st := c.Stats(); st.Dropped != 1 || st.Sent != 3 {
+		t.Errorf("expected 3 sent and 1 dropped entries, got %+v", st)
+	}
+}
+
+func TestClient_SpoolsWhileServerIsDown(t *testing.T) {
+	var mu sync.Mutex
+	down := true
+	srv := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+	dir := t.TempDir()
+	cfg := client.Config{URL: srv.URL, FlushInterval: time.Hour, MaxRetries: -1, SpoolDir: dir}
+
+	first, err := client.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Log(client.Entry{Level: "ERROR", Message: "one"})
+	first.Log(client.Entry{Level: "ERROR", Message: "two"})
+	closeClient(t, first)
+
+	if st := first.Stats(); st.Spooled != 2 || st.Dropped != 0 {
+		t.Fatalf("expected 2 spooled entries, got %+v", st)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected 1 spooled batch on disk, got %d files", len(files))
+	}
+
+	// Un nouveau client (redémarrage du service) renvoie le lot une fois le serveur revenu
+	mu.Lock()
+	down = false
+	mu.Unlock()
+
+	second, err := client.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	closeClient(t, second)
+
+	batches := srv.received()
+	if len(batches) != 1 || len(batches[0].entries) != 2 || batches[0].entries[1].Message != "two" {
+		t.Fatalf("expected the spooled batch to be replayed, got %+v", batches)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("expected spool to be empty after replay, got %d files", len(files))
+	}
+}
+
+func TestClient_CloseTimeoutSpoolsPendingBatch(t *testing.T) {
+	stalled := make(chan struct{})
+	srv := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int) bool {
+		<-stalled
+		return true
+	})
+	t.Cleanup(func() { close(stalled) })
+
+	c, err := client.New(client.Config{URL: srv.URL, FlushInterval: time.Hour, SpoolDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Log(client.Entry{Level: "WARN", Message: "stuck"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if st := c.Stats(); st.Spooled != 1 {
+		t.Errorf("expected the interrupted batch to be spooled, got %+v", st)
+	}
+}
+
+func TestClient_ResendsRateLimitedEntries(t *testing.T) {
+	rl, err := ratelimit.NewRateLimiter(ratelimit.Config{
+		MaxRequests: 100,
+		Window:      time.Second,
+		EntryLimits: map[log_levels.LogLevel]int{log_levels.LogLevelError: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Stop()
+	srv := newHandlerServer(t)
+	srv.SetEntryLimiter(rl)
+
+	var mu sync.Mutex
+	var reported []error
+	c, err := client.New(client.Config{
+		URL:           srv.URL,
+		FlushInterval: time.Hour,
+		MinBackoff:    time.Millisecond,
+		MaxBackoff:    5 * time.Second,
+		OnError: func(err error) {
+			mu.Lock()
+			reported = append(reported, err)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		c.Log(client.Entry{Level: "ERROR", Message: fmt.Sprintf("payment %d failed", i)})
+	}
+	start := time.Now()
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	closeClient(t, c)
+
+	// Les 2 entrées au-delà de la limite sont renvoyées après retry_after, sans doublon
+	stored := srv.entries()
+	messages := map[string]bool{}
+	ids := map[string]bool{}
+	for _, e := range stored {
+		messages[e.Message] = true
+		ids[e.ID] = true
+	}
+	if len(stored) != 4 || len(messages) != 4 || len(ids) != 4 {
+		t.Fatalf("expected the 4 entries to be stored once, got %+v", stored)
+	}
+	if elapsed < 500*time.Millisecond {
+		t.Errorf("expected the limited entries to wait for retry_after, waited %v", elapsed)
+	}
+	if st := c.Stats(); st.Sent != 4 || st.Rejected != 0 || st.Dropped != 0 || st.Retries == 0 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 0 {
+		t.Errorf("expected no reported error, got %v", reported)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"encoding/json"
+	"sort"
+	"unicode/utf8"
+)
+
+// Limites d'une entrée imposées par le serveur (LogEntry.Validate), au-delà desquelles
+// l'entrée est refusée
+const (
+	MaxMessageLength = 1024
+	MaxContextKeys   = 10
+	MaxContextSize   = 2048 // octets du contexte sérialisé en JSON
+)
+
+// ContextKeyDropped compte les clés de contexte écartées par les adaptateurs (ZapCore,
+// SlogHandler) pour tenir dans MaxContextKeys et MaxContextSize
+const ContextKeyDropped = "dropped_keys"
+
+// Clés ajoutées par les adaptateurs, gardées en priorité
+var reservedContextKeys = map[string]bool{
+	ContextKeyTraceID: true,
+	"logger":          true,
+	"caller":          true,
+	"source":          true,
+}
+
+// fitEntry ramène une entrée produite par un adaptateur dans les limites du serveur : le
+// message est tronqué à MaxMessageLength, et le contexte garde ses clés réservées puis les
+// plus petites, les autres étant comptées dans ContextKeyDropped
+func fitEntry(entry *Entry) {
+	entry.Message = truncateUTF8(entry.Message, MaxMessageLength)
+	if len(entry.Context) == 0 {
+		return
+	}
+	if len(entry.Context) <= MaxContextKeys {
+		if data, err := json.Marshal(entry.Context); err == nil && len(data) <= MaxContextSize {
+			return
+		}
+	}
+
+	type field struct {
+		key  string
+		size int // octets de `"key":value,`
+	}
+	fields := make([]field, 0, len(entry.Context))
+	for k, v := range entry.Context {
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		fields = append(fields, field{key: k, size: len(key) + len(value) + 2})
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if ri, rj := reservedContextKeys[fields[i].key], reservedContextKeys[fields[j].key]; ri != rj {
+			return ri
+		}
+		if fields[i].size != fields[j].size {
+			return fields[i].size < fields[j].size
+		}
+		return fields[i].key < fields[j].key
+	})
+
+	// Place réservée au compteur des clés écartées : `"dropped_keys":NNNN` et les accolades
+	size := len(ContextKeyDropped) + 3 + 4 + 2
+	kept := make(map[string]interface{}, MaxContextKeys)
+	for _, f := range fields {
+		if len(kept) < MaxContextKeys-1 && size+f.size <= MaxContextSize {
+			kept[f.key] = entry.Context[f.key]
+			size += f.size
+		}
+	}
+	if dropped := len(entry.Context) - len(kept); dropped > 0 {
+		kept[ContextKeyDropped] = dropped
+	}
+	entry.Context = kept
+}
+
+// truncateUTF8 coupe s à n octets au plus, sans couper de caractère
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Préfixe des clés générées par le serveur et longueur de la partie affichée (Key.Prefix),
+// qui identifie la clé d'une requête signée
+const (
+	signingKeyPrefix    = "lsk_"
+	signingKeyPrefixLen = len(signingKeyPrefix) + 8
+	signingLabel        = "logger-server request signing v1"
+)
+
+var errUnsignableKey = errors.New("client: only keys generated by the server (lsk_...) can sign requests")
+
+// SignRequest signe req avec la clé API au lieu de l'envoyer : le serveur vérifie une
+// signature HMAC-SHA256 de la méthode, du chemin, de l'horodatage, d'un nonce et de
+// l'empreinte de body, qui doit être le corps exact de la requête (compressé le cas échéant).
+// Une requête signée ne peut être rejouée qu'une fois; un renvoi doit être signé de nouveau.
+func SignRequest(req *http.Request, apiKey string, body []byte) error {
+	if !signable(apiKey) {
+		return errUnsignableKey
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	n := hex.EncodeToString(nonce)
+
+	// Secret partagé : HMAC de la clé, conservé chiffré par le serveur
+	secret := hmac.New(sha256.New, []byte(apiKey))
+	secret.Write([]byte(signingLabel))
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join([]string{
+		strings.ToUpper(req.Method),
+		req.URL.RequestURI(),
+		ts,
+		n,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")))
+
+	req.Header.Set("X-Signature-Key", apiKey[:signingKeyPrefixLen])
+	req.Header.Set("X-Signature-Timestamp", ts)
+	req.Header.Set("X-Signature-Nonce", n)
+	req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+func signable(apiKey string) bool {
+	return strings.HasPrefix(apiKey, signingKeyPrefix) && len(apiKey) > signingKeyPrefixLen
+}
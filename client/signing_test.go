@@ -0,0 +1,93 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/client"
+	"github.com/rypi-dev/logger-server/internal/apikey"
+)
+
+const signingKey = "lsk_3f9a1c0bQ2xhdWRlIHdhcyBoZXJlLCBzaWduaW5nIGtleQ"
+
+// verifySigned vérifie la requête comme le serveur et retourne son nonce
+func verifySigned(t *testing.T, r *http.Request, body []byte) string {
+	t.Helper()
+	if r.Header.Get("X-API-Key") != "" {
+		t.Error("expected the API key not to be sent")
+	}
+	if got := r.Header.Get(apikey.HeaderSignatureKey); got != "lsk_3f9a1c0b" {
+		t.Errorf("expected key prefix lsk_3f9a1c0b, got %q", got)
+	}
+	sts := apikey.StringToSign(r.Method, r.URL.RequestURI(), r.Header.Get(apikey.HeaderSignatureTimestamp),
+		r.Header.Get(apikey.HeaderSignatureNonce), body)
+	if !apikey.VerifySignature(apikey.SigningSecret(signingKey), sts, r.Header.Get(apikey.HeaderSignature)) {
+		t.Error("expected a signature valid for the server")
+	}
+	return r.Header.Get(apikey.HeaderSignatureNonce)
+}
+
+func TestSignRequest(t *testing.T) {
+	body := []byte(`[{"message":"hello"}]`)
+	req, _ := http.NewRequest(http.MethodPost, "http://logs.example.com/log/batch?dry_run=1", bytes.NewReader(body))
+	if err := client.SignRequest(req, signingKey, body); err != nil {
+		t.Fatal(err)
+	}
+	verifySigned(t, req, body)
+
+	if err := client.SignRequest(req, "bootstrap-key", body); err == nil {
+		t.Error("expected keys not generated by the server to be refused")
+	}
+}
+
+func TestClient_SignRequests(t *testing.T) {
+	var mu sync.Mutex
+	var nonces []string
+	srv := newFakeServer(t, func(w http.ResponseWriter, r *http.Request, n int) bool {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		nonces = append(nonces, verifySigned(t, r, body))
+		mu.Unlock()
+		if n == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		return false
+	})
+
+	c, err := client.New(client.Config{
+		URL:          srv.URL,
+		APIKey:       signingKey,
+		SignRequests: true,
+		MinBackoff:   time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Log(client.Entry{Level: "INFO", Message: "signed"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	closeClient(t, c)
+
+	// Le renvoi après l'erreur 503 est signé avec un nouveau nonce
+	if len(nonces) != 2 || nonces[0] == nonces[1] {
+		t.Errorf("expected two requests with distinct nonces, got %v", nonces)
+	}
+	if srv.entryCount() != 1 {
+		t.Errorf("expected the entry to be delivered, got %d", srv.entryCount())
+	}
+
+	if _, err := client.New(client.Config{URL: srv.URL, APIKey: "bootstrap-key", SignRequests: true}); err == nil || !strings.Contains(err.Error(), "lsk_") {
+		t.Errorf("expected an error for an unsignable key, got %v", err)
+	}
+}
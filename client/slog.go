@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// SlogOptions configure SlogHandler
+type SlogOptions struct {
+	// Level est le niveau minimal transmis (slog.LevelInfo par défaut)
+	Level slog.Leveler
+	// AddSource ajoute "source" (fichier:ligne) au contexte
+	AddSource bool
+	// TraceID extrait le trace ID du contexte; TraceIDFromContext par défaut
+	TraceID func(ctx context.Context) string
+}
+
+// SlogHandler est un slog.Handler qui envoie les enregistrements au serveur via un Client.
+// Les attributs (et groupes, sous forme d'objets imbriqués) vont dans le contexte de l'entrée;
+// ceux au-delà des limites du contexte sont écartés (voir ContextKeyDropped).
+type SlogHandler struct {
+	client *Client
+	opts   SlogOptions
+	attrs  map[string]interface{}
+	groups []string
+}
+
+func NewSlogHandler(c *Client, opts *SlogOptions) *SlogHandler {
+	h := &SlogHandler{client: c}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelInfo
+	}
+	if h.opts.TraceID == nil {
+		h.opts.TraceID = TraceIDFromContext
+	}
+	return h
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+// Handle met l'entrée en file sans bloquer; ErrQueueFull est retourné si la file est pleine
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := cloneFields(h.attrs)
+	if r.NumAttrs() > 0 {
+		target := groupFields(fields, h.groups)
+		r.Attrs(func(a slog.Attr) bool {
+			addSlogAttr(target, a)
+			return true
+		})
+	}
+
+	if traceID := h.opts.TraceID(ctx); traceID != "" {
+		fields[ContextKeyTraceID] = traceID
+	}
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		fields["source"] = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+	}
+
+	entry := Entry{
+		Level:     SlogLevel(r.Level),
+		Message:   r.Message,
+		Timestamp: r.Time,
+	}
+	if len(fields) > 0 {
+		entry.Context = fields
+	}
+	fitEntry(&entry)
+	return h.client.Log(entry)
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = cloneFields(h.attrs)
+	target := groupFields(h2.attrs, h.groups)
+	for _, a := range attrs {
+		addSlogAttr(target, a)
+	}
+	return &h2
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(append([]string(nil), h.groups...), name)
+	return &h2
+}
+
+// SlogLevel convertit un niveau slog : les niveaux intermédiaires (ex. LevelInfo+2) prennent
+// le niveau inférieur le plus proche, et au-delà de LevelError+4 l'entrée est FATAL
+func SlogLevel(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return LevelTrace
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarn
+	case level < slog.LevelError+4:
+		return LevelError
+	default:
+		return LevelFatal
+	}
+}
+
+func addSlogAttr(fields map[string]interface{}, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return
+		}
+		// Groupe sans nom : ses attributs sont mis à plat
+		target := fields
+		if a.Key != "" {
+			target = groupFields(fields, []string{a.Key})
+		}
+		for _, ga := range attrs {
+			addSlogAttr(target, ga)
+		}
+		return
+	}
+
+	fields[a.Key] = slogValue(a.Value)
+}
+
+func slogValue(v slog.Value) interface{} {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	default:
+		return jsonValue(v.Any())
+	}
+}
+
+// jsonValue garde les valeurs sérialisables en JSON; les erreurs et autres valeurs
+// deviennent leur représentation texte
+func jsonValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case error:
+		return x.Error()
+	case fmt.Stringer:
+		return x.String()
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+// groupFields retourne la map imbriquée correspondant au chemin de groupes, en la créant
+func groupFields(fields map[string]interface{}, groups []string) map[string]interface{} {
+	for _, g := range groups {
+		sub, ok := fields[g].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			fields[g] = sub
+		}
+		fields = sub
+	}
+	return fields
+}
+
+// cloneFields copie les maps imbriquées, que les handlers dérivés complètent sans se
+// partager leur état
+func cloneFields(fields map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if sub, ok := v.(map[string]interface{}); ok {
+			v = cloneFields(sub)
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/client"
+)
+
+func TestSlogHandler(t *testing.T) {
+	srv := newFakeServer(t, nil)
+	c, err := client.New(client.Config{URL: srv.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(client.NewSlogHandler(c, &client.SlogOptions{Level: slog.LevelDebug})).
+		With("component", "billing").
+		WithGroup("req").
+		With("method", "POST")
+
+	ctx := client.WithTraceID(context.Background(), "trace-42")
+	logger.ErrorContext(ctx, "payment failed",
+		"status", 502,
+		"latency", 1500*time.Millisecond,
+		"err", errors.New("gateway timeout"),
+		slog.Group("user", "id", 7),
+	)
+	logger.Debug("filtered group", slog.Group("empty"))
+
+	closeClient(t, c)
+
+	batches := srv.received()
+	if len(batches) != 1 || len(batches[0].entries) != 2 {
+		t.Fatalf("expected 2 entries in one batch, got %+v", batches)
+	}
+
+	entry := batches[0].entries[0]
+	if entry.Level != client.LevelError || entry.Message != "payment failed" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.Context["component"] != "billing" || entry.Context["trace_id"] != "trace-42" {
+		t.Errorf("expected top-level attrs and trace ID, got %v", entry.Context)
+	}
+	req, ok := entry.Context["req"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected group \"req\" in context, got %v", entry.Context)
+	}
+	if req["method"] != "POST" || req["status"] != float64(502) || req["latency"] != "1.5s" || req["err"] != "gateway timeout" {
+		t.Errorf("unexpected group attrs: %v", req)
+	}
+	if user, ok := req["user"].(map[string]interface{}); !ok || user["id"] != float64(7) {
+		t.Errorf("expected nested group \"user\", got %v", req["user"])
+	}
+
+	// Groupe vide : seul le contexte hérité reste
+	debug := batches[0].entries[1]
+	if req, _ := debug.Context["req"].(map[string]interface{}); debug.Level != client.LevelDebug || len(req) != 1 {
+		t.Errorf("expected empty groups to be dropped, got %+v", debug)
+	}
+}
+
+func TestSlogHandler_FitsServerLimits(t *testing.T) {
+	srv := newHandlerServer(t)
+	c, err := client.New(client.Config{URL: srv.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(client.NewSlogHandler(c, &client.SlogOptions{AddSource: true}))
+	args := []any{"body", strings.Repeat("x", 3000)}
+	for i := 0; i < 12; i++ {
+		args = append(args, fmt.Sprintf("attr_%02d", i), i)
+	}
+	logger.ErrorContext(client.WithTraceID(context.Background(), "trace-42"), strings.Repeat("é", 600), args...)
+	closeClient(t, c)
+
+	stored := srv.entries()
+	if len(stored) != 1 {
+		t.Fatalf("expected the entry to be accepted by the server, got %+v (stats %+v)", stored, c.Stats())
+	}
+	entry := stored[0]
+	if len(entry.Message) != client.MaxMessageLength || len(entry.Context) != client.MaxContextKeys {
+		t.Errorf("expected the entry cut to the server limits, got %d bytes and %d keys", len(entry.Message), len(entry.Context))
+	}
+	if entry.Context["trace_id"] != "trace-42" || entry.Context["source"] == nil || entry.Context["body"] != nil {
+		t.Errorf("expected trace_id and source kept and the largest attribute dropped, got %v", entry.Context)
+	}
+}
+
+func TestSlogHandler_Enabled(t *testing.T) {
+	h := client.NewSlogHandler(nil, nil)
+	if h.Enabled(context.Background(), slog.LevelDebug) || !h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("expected default minimum level to be Info")
+	}
+}
+
+func TestSlogLevel(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{slog.LevelDebug - 4, client.LevelTrace},
+		{slog.LevelDebug, client.LevelDebug},
+		{slog.LevelInfo, client.LevelInfo},
+		{slog.LevelInfo + 2, client.LevelInfo},
+		{slog.LevelWarn, client.LevelWarn},
+		{slog.LevelError, client.LevelError},
+		{slog.LevelError + 4, client.LevelFatal},
+	}
+	for _, tt := range tests {
+		if got := client.SlogLevel(tt.level); got != tt.want {
+			t.Errorf("SlogLevel(%v) = %s, want %s", tt.level, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrSpoolFull = errors.New("spool directory is full")
+
+const spoolExt = ".batch.json"
+
+// spoolFile est le contenu d'un lot écrit sur disque; la clé est conservée pour que le
+// serveur déduplique un lot déjà reçu avant la coupure
+type spoolFile struct {
+	Key     string  `json:"key"`
+	Entries []Entry `json:"entries"`
+}
+
+// spool conserve sur disque les lots que le serveur n'a pas acceptés, un fichier par lot.
+// Il n'est utilisé que par la goroutine d'envoi du client.
+type spool struct {
+	dir     string
+	maxSize int64
+	size    int64
+}
+
+func openSpool(dir string, maxSize int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("client: spool directory: %w", err)
+	}
+
+	s := &spool{dir: dir, maxSize: maxSize}
+	names, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			s.size += info.Size()
+		}
+	}
+	return s, nil
+}
+
+// write écrit un lot; le nom (horodatage puis clé) donne l'ordre de renvoi
+func (s *spool) write(b *batch) error {
+	data, err := json.Marshal(spoolFile{Key: b.key, Entries: b.entries})
+	if err != nil {
+		return err
+	}
+	if s.size+int64(len(data)) > s.maxSize {
+		return ErrSpoolFull
+	}
+
+	name := fmt.Sprintf("%020d-%s%s", time.Now().UnixNano(), b.key, spoolExt)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.size += int64(len(data))
+	return nil
+}
+
+// oldest retourne le lot le plus ancien, ou un lot nil si le tampon est vide. Un fichier
+// illisible est retourné avec une erreur pour être supprimé.
+func (s *spool) oldest() (string, *batch, error) {
+	names, err := s.list()
+	if err != nil || len(names) == 0 {
+		return "", nil, err
+	}
+
+	name := names[0]
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return name, nil, err
+	}
+	var f spoolFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return name, nil, fmt.Errorf("corrupted batch %s: %w", name, err)
+	}
+	return name, &batch{key: f.Key, entries: f.Entries}, nil
+}
+
+func (s *spool) remove(name string) {
+	path := filepath.Join(s.dir, name)
+	if info, err := os.Stat(path); err == nil {
+		s.size -= info.Size()
+	}
+	os.Remove(path)
+	if s.size < 0 {
+		s.size = 0
+	}
+}
+
+func (s *spool) list() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range dirEntries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), spoolExt) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
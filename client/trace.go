@@ -0,0 +1,27 @@
+package client
+
+import "context"
+
+type ctxKey string
+
+const ctxKeyTraceID ctxKey = "traceID"
+
+// ContextKeyTraceID est la clé de contexte "trace_id", la même que celle des entrées
+// enrichies par le serveur (middleware.EnrichLogContext)
+const ContextKeyTraceID = "trace_id"
+
+// WithTraceID retourne un contexte portant traceID, repris par SlogHandler et ZapTraceID
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, ctxKeyTraceID, traceID)
+}
+
+// TraceIDFromContext retourne le trace ID posé par WithTraceID, ou ""
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if v, ok := ctx.Value(ctxKeyTraceID).(string); ok {
+		return v
+	}
+	return ""
+}
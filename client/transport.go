@@ -0,0 +1,243 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StatusError est une réponse HTTP en erreur du serveur
+type StatusError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // valeur de l'en-tête Retry-After, 0 si absent
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Retryable indique si le lot peut être renvoyé tel quel : limite de débit dépassée
+// ou erreur côté serveur. Les autres réponses 4xx ne changeront pas au renvoi.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout || e.StatusCode >= 500
+}
+
+// RejectedError signale les entrées d'un lot refusées par une limite de débit ou un quota
+// du serveur : le lot ne contient plus qu'elles, sous une nouvelle Idempotency-Key, et peut
+// être renvoyé après RetryAfter (0 si le serveur ne l'a pas indiqué)
+type RejectedError struct {
+	Count      int
+	RetryAfter time.Duration
+	Message    string // erreur de la première entrée refusée
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%d entries rate-limited by server: %s", e.Count, e.Message)
+}
+
+type batchResponse struct {
+	Accepted int `json:"accepted"`
+	Rejected []struct {
+		Index      int    `json:"index"`
+		Error      string `json:"error"`
+		Retryable  bool   `json:"retryable"`
+		RetryAfter int    `json:"retry_after"` // secondes
+	} `json:"rejected"`
+}
+
+// sendWithRetry envoie un lot, puis le renvoie jusqu'à MaxRetries fois tant que l'erreur
+// est transitoire. Le délai entre deux essais suit un backoff exponentiel avec jitter,
+// allongé jusqu'au Retry-After du serveur quand il est fourni. Des entrées refusées par une
+// limite (RejectedError) sont renvoyées de même, sauf si leur délai dépasse MaxBackoff : le
+// lot est alors écrit sur disque ou abandonné sans attendre.
+func (c *Client) sendWithRetry(b *batch) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = c.send(b)
+		if err == nil {
+			return nil
+		}
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.Retryable() {
+			return err
+		}
+		var rejectedErr *RejectedError
+		if errors.As(err, &rejectedErr) && rejectedErr.RetryAfter > c.cfg.MaxBackoff {
+			return err
+		}
+		if attempt >= c.cfg.MaxRetries || c.ctx.Err() != nil {
+			return err
+		}
+
+		wait := backoff(attempt, c.cfg.MinBackoff, c.cfg.MaxBackoff)
+		if statusErr != nil && statusErr.RetryAfter > wait {
+			wait = statusErr.RetryAfter
+		}
+		if rejectedErr != nil && rejectedErr.RetryAfter > wait {
+			wait = rejectedErr.RetryAfter
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-c.ctx.Done():
+			timer.Stop()
+			return err
+		}
+		c.retries.Add(1)
+	}
+}
+
+// send fait un seul POST /log/batch. Les entrées refusées par une limite de débit ou un
+// quota restent dans b (RejectedError); les autres refus sont définitifs.
+func (c *Client) send(b *batch) error {
+	body, err := c.encode(b.entries)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", b.key)
+	if !c.cfg.DisableCompression {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if c.cfg.SignRequests {
+		// Signé à chaque envoi : un renvoi a son propre nonce
+		if err := SignRequest(req, c.cfg.APIKey, body); err != nil {
+			return err
+		}
+	} else if c.cfg.APIKey != "" {
+		req.Header.Set("X-API-Key", c.cfg.APIKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		statusErr := &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil {
+			statusErr.Message = apiErr.Error
+		}
+		return statusErr
+	}
+
+	var result batchResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		// Lot accepté, réponse illisible : on ne renvoie pas
+		c.sent.Add(uint64(len(b.entries)))
+		return nil
+	}
+	c.sent.Add(uint64(result.Accepted))
+
+	var retry []Entry
+	var retryErr *RejectedError
+	rejected := result.Rejected[:0]
+	for _, rej := range result.Rejected {
+		if !rej.Retryable || rej.Index < 0 || rej.Index >= len(b.entries) {
+			rejected = append(rejected, rej)
+			continue
+		}
+		retry = append(retry, b.entries[rej.Index])
+		if retryErr == nil {
+			retryErr = &RejectedError{Message: rej.Error}
+		}
+		if wait := time.Duration(rej.RetryAfter) * time.Second; wait > retryErr.RetryAfter {
+			retryErr.RetryAfter = wait
+		}
+	}
+	if n := len(rejected); n > 0 {
+		c.rejected.Add(uint64(n))
+		first := rejected[0]
+		c.reportError(fmt.Errorf("%d entries rejected by server (entry %d: %s)", n, first.Index, first.Error))
+	}
+	if retryErr == nil {
+		return nil
+	}
+
+	// Le serveur dérive l'ID des entrées de la clé et de leur rang : le sous-lot renvoyé
+	// prend une nouvelle clé pour ne pas être confondu avec les entrées déjà stockées
+	retryErr.Count = len(retry)
+	b.key, b.entries = newBatchKey(), retry
+	return retryErr
+}
+
+func (c *Client) encode(entries []Entry) ([]byte, error) {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.DisableCompression {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// backoff retourne le délai avant le renvoi n° attempt (à partir de 0) : min*2^attempt
+// borné par max, tiré au hasard dans [d/2, d) pour étaler les renvois des clients
+func backoff(attempt int, min, max time.Duration) time.Duration {
+	d := max
+	if attempt < 32 {
+		if exp := min << uint(attempt); exp > 0 && exp < max {
+			d = exp
+		}
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// parseRetryAfter lit un Retry-After en secondes ou en date HTTP
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	min, max := 100*time.Millisecond, time.Second
+	for attempt := 0; attempt < 40; attempt++ {
+		want := max
+		if attempt < 4 {
+			want = min << uint(attempt)
+		}
+		for i := 0; i < 20; i++ {
+			d := backoff(attempt, min, max)
+			if d < want/2 || d >= want {
+				t.Fatalf("attempt %d: backoff %v outside [%v, %v)", attempt, d, want/2, want)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 8, 7, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-1", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestStatusErrorRetryable(t *testing.T) {
+	for code, want := range map[int]bool{
+		http.StatusTooManyRequests:       true,
+		http.StatusServiceUnavailable:    true,
+		http.StatusInternalServerError:   true,
+		http.StatusBadRequest:            false,
+		http.StatusUnauthorized:          false,
+		http.StatusRequestEntityTooLarge: false,
+	} {
+		if got := (&StatusError{StatusCode: code}).Retryable(); got != want {
+			t.Errorf("Retryable(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
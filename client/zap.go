@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Délai maximal de Sync, appelé notamment par zap avant de quitter sur Fatal
+const zapSyncTimeout = 5 * time.Second
+
+// ZapCore est un zapcore.Core qui envoie les entrées au serveur via un Client. Les champs
+// vont dans le contexte de l'entrée, avec "logger" et "caller" s'ils sont renseignés; la
+// stacktrace suit le message, tronquée avec lui à MaxMessageLength. Les champs au-delà des
+// limites du contexte sont écartés (voir ContextKeyDropped). À combiner avec
+// zapcore.NewTee pour garder une sortie locale.
+type ZapCore struct {
+	zapcore.LevelEnabler
+	client *Client
+	fields []zapcore.Field
+}
+
+func NewZapCore(c *Client, enab zapcore.LevelEnabler) *ZapCore {
+	return &ZapCore{LevelEnabler: enab, client: c}
+}
+
+func (c *ZapCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field(nil), c.fields...), fields...)
+	return &clone
+}
+
+func (c *ZapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ZapCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	ctx := enc.Fields
+	if ent.LoggerName != "" {
+		ctx["logger"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		ctx["caller"] = ent.Caller.TrimmedPath()
+	}
+
+	entry := Entry{
+		Level:     ZapLevel(ent.Level),
+		Message:   ent.Message,
+		Timestamp: ent.Time,
+	}
+	if ent.Stack != "" {
+		entry.Message += "\n" + ent.Stack
+	}
+	if len(ctx) > 0 {
+		entry.Context = ctx
+	}
+	fitEntry(&entry)
+	return c.client.Log(entry)
+}
+
+// Sync envoie les entrées en file, dans la limite de zapSyncTimeout. Après Close, il n'y a
+// plus rien à envoyer.
+func (c *ZapCore) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), zapSyncTimeout)
+	defer cancel()
+	if err := c.client.Flush(ctx); err != nil && !errors.Is(err, ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// ZapLevel convertit un niveau zap; DPanic, Panic et Fatal deviennent FATAL
+func ZapLevel(level zapcore.Level) string {
+	switch {
+	case level < zapcore.InfoLevel:
+		return LevelDebug
+	case level == zapcore.InfoLevel:
+		return LevelInfo
+	case level == zapcore.WarnLevel:
+		return LevelWarn
+	case level == zapcore.ErrorLevel:
+		return LevelError
+	default:
+		return LevelFatal
+	}
+}
+
+// ZapTraceID retourne le champ trace_id du contexte (voir WithTraceID), zap n'ayant pas
+// de contexte propre; zap.Skip() s'il n'y en a pas
+func ZapTraceID(ctx context.Context) zap.Field {
+	if traceID := TraceIDFromContext(ctx); traceID != "" {
+		return zap.String(ContextKeyTraceID, traceID)
+	}
+	return zap.Skip()
+}
@@ -0,0 +1,112 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/rypi-dev/logger-server/client"
+)
+
+func TestZapCore(t *testing.T) {
+	srv := newFakeServer(t, nil)
+	c, err := client.New(client.Config{URL: srv.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeClient(t, c)
+
+	logger := zap.New(client.NewZapCore(c, zapcore.InfoLevel)).Named("api").With(zap.String("component", "billing"))
+
+	ctx := client.WithTraceID(context.Background(), "trace-42")
+	logger.Warn("slow query", zap.Int("rows", 12), zap.Duration("took", 2*time.Second), client.ZapTraceID(ctx))
+	logger.Debug("filtered")
+	logger.Info("no trace", client.ZapTraceID(context.Background()))
+
+	// Sync passe par Flush : les entrées sont envoyées avant le retour
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	batches := srv.received()
+	if len(batches) != 1 || len(batches[0].entries) != 2 {
+		t.Fatalf("expected 2 entries after Sync, got %+v", batches)
+	}
+
+	entry := batches[0].entries[0]
+	if entry.Level != client.LevelWarn || entry.Message != "slow query" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	want := map[string]interface{}{
+		"component": "billing",
+		"rows":      float64(12),
+		"took":      float64(2 * time.Second),
+		"trace_id":  "trace-42",
+		"logger":    "api",
+	}
+	for k, v := range want {
+		if entry.Context[k] != v {
+			t.Errorf("context[%q] = %v, want %v", k, entry.Context[k], v)
+		}
+	}
+	if _, ok := batches[0].entries[1].Context["trace_id"]; ok {
+		t.Error("expected no trace_id without one in the context")
+	}
+}
+
+func TestZapCore_ErrorWithStackIsAccepted(t *testing.T) {
+	srv := newHandlerServer(t)
+	c, err := client.New(client.Config{URL: srv.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeClient(t, c)
+
+	// Configuration de production : caller et stacktrace sur les erreurs, plus de champs que
+	// le serveur n'accepte de clés de contexte
+	logger := zap.New(client.NewZapCore(c, zapcore.InfoLevel), zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)).Named("api")
+	fields := []zap.Field{zap.String("payload", strings.Repeat("x", 3000))}
+	for i := 0; i < 12; i++ {
+		fields = append(fields, zap.Int(fmt.Sprintf("field_%02d", i), i))
+	}
+	logger.Error("payment failed", fields...)
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := srv.entries()
+	if len(stored) != 1 {
+		t.Fatalf("expected the entry to be accepted by the server, got %+v (stats %+v)", stored, c.Stats())
+	}
+	entry := stored[0]
+	if !strings.HasPrefix(entry.Message, "payment failed\n") || !strings.Contains(entry.Message, "TestZapCore_ErrorWithStackIsAccepted") {
+		t.Errorf("expected the stacktrace after the message, got %q", entry.Message)
+	}
+	if len(entry.Message) > client.MaxMessageLength || len(entry.Context) > client.MaxContextKeys {
+		t.Errorf("expected the entry within the server limits, got %d bytes and %d keys", len(entry.Message), len(entry.Context))
+	}
+	if entry.Context["logger"] != "api" || entry.Context["caller"] == nil || entry.Context[client.ContextKeyDropped] != float64(6) {
+		t.Errorf("expected logger and caller kept and the largest fields dropped, got %v", entry.Context)
+	}
+}
+
+func TestZapLevel(t *testing.T) {
+	tests := map[zapcore.Level]string{
+		zapcore.DebugLevel:  client.LevelDebug,
+		zapcore.InfoLevel:   client.LevelInfo,
+		zapcore.WarnLevel:   client.LevelWarn,
+		zapcore.ErrorLevel:  client.LevelError,
+		zapcore.DPanicLevel: client.LevelFatal,
+		zapcore.FatalLevel:  client.LevelFatal,
+	}
+	for level, want := range tests {
+		if got := client.ZapLevel(level); got != want {
+			t.Errorf("ZapLevel(%v) = %s, want %s", level, got, want)
+		}
+	}
+}
@@ -1,16 +1,41 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
+	"github.com/rypi-dev/logger-server/client"
 	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/anomaly/anomaly"
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/elastic/elastic"
+	"github.com/rypi-dev/logger-server/internal/forward/forward"
+	"github.com/rypi-dev/logger-server/internal/gelf/gelf"
+	"github.com/rypi-dev/logger-server/internal/grpcapi/grpcapi"
+	"github.com/rypi-dev/logger-server/internal/hec/hec"
+	"github.com/rypi-dev/logger-server/internal/idempotency/idempotency"
+	"github.com/rypi-dev/logger-server/internal/jwtauth/jwtauth"
+	"github.com/rypi-dev/logger-server/internal/loki/loki"
+	"github.com/rypi-dev/logger-server/internal/openapi/openapi"
+	"github.com/rypi-dev/logger-server/internal/otlp/otlp"
+	"github.com/rypi-dev/logger-server/internal/quota/quota"
+	"github.com/rypi-dev/logger-server/internal/ratelimit/ratelimit"
+	"github.com/rypi-dev/logger-server/internal/syslog/syslog"
+	"github.com/rypi-dev/logger-server/internal/tenant/tenant"
+	"github.com/rypi-dev/logger-server/internal/tlsauth/tlsauth"
+	"github.com/rypi-dev/logger-server/internal/webui/webui"
 )
 
 func main() {
@@ -34,20 +59,441 @@ func main() {
 	}
 	defer sqlLogger.Close()
 
-	// Initialiser rate limiter : 100 requêtes / minute / IP
-	rateLimiter := internal.NewRateLimiter(100, time.Minute)
+	// Clés API nommées : LOGGER_API_KEY reste valide comme clé admin de démarrage, les
+	// autres clés sont créées par l'API /admin/keys
+	keyStore, err := apikey.NewSQLiteStore(dbPath)
+	if err != nil {
+		log.Fatalf("failed to initialize API key store: %v", err)
+	}
+	defer keyStore.Close()
+	keyring := apikey.NewKeyring(apiKey, keyStore)
+
+	// Tenants : une clé (ou un jeton) d'un tenant n'écrit et ne lit que les entrées de ce
+	// tenant; les tenants sont gérés par l'API /admin/tenants
+	tenantStore, err := tenant.NewSQLiteStore(dbPath)
+	if err != nil {
+		log.Fatalf("failed to initialize tenant store: %v", err)
+	}
+	defer tenantStore.Close()
+
+	retentionInterval := tenant.DefaultRetentionInterval
+	if raw := os.Getenv("LOGGER_TENANT_RETENTION_INTERVAL"); raw != "" {
+		if retentionInterval, err = time.ParseDuration(raw); err != nil || retentionInterval <= 0 {
+			log.Fatalf("invalid LOGGER_TENANT_RETENTION_INTERVAL: %q", raw)
+		}
+	}
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	defer stopRetention()
+	go tenant.NewRetention(tenantStore, sqlLogger, retentionInterval).Run(retentionCtx)
+
+	// Requêtes signées (HMAC) avec les clés générées, qui ne sont alors plus envoyées. Les
+	// secrets de signature sont conservés chiffrés avec LOGGER_SIGNATURE_KEY : sans elle, les
+	// requêtes signées sont refusées.
+	if signingKey := os.Getenv("LOGGER_SIGNATURE_KEY"); signingKey != "" {
+		if err := keyStore.SetSigningKey(signingKey); err != nil {
+			log.Fatalf("invalid LOGGER_SIGNATURE_KEY: %v", err)
+		}
+	}
+	signatureSkew := internal.DefaultSignatureSkew
+	if raw := os.Getenv("LOGGER_SIGNATURE_MAX_SKEW"); raw != "" {
+		if signatureSkew, err = time.ParseDuration(raw); err != nil || signatureSkew <= 0 {
+			log.Fatalf("invalid LOGGER_SIGNATURE_MAX_SKEW: %q", raw)
+		}
+	}
+	signatures := internal.NewSignatureVerifier(keyring, signatureSkew)
+
+	// Jetons SSO (optionnels) : JWT "Authorization: Bearer" validés avec le JWKS du
+	// fournisseur OIDC, scopes déduits des groupes
+	var tokens apikey.Authenticator
+	if jwksSource := os.Getenv("LOGGER_JWT_JWKS"); jwksSource != "" {
+		refresh := jwtauth.DefaultRefreshInterval
+		if raw := os.Getenv("LOGGER_JWT_JWKS_REFRESH"); raw != "" {
+			if refresh, err = time.ParseDuration(raw); err != nil {
+				log.Fatalf("invalid LOGGER_JWT_JWKS_REFRESH: %v", err)
+			}
+		}
+		groupScopes, err := apikey.ParseScopeMap(os.Getenv("LOGGER_JWT_GROUP_SCOPES"))
+		if err != nil {
+			log.Fatalf("invalid LOGGER_JWT_GROUP_SCOPES: %v", err)
+		}
+
+		jwks := jwtauth.NewKeySet(jwksSource, refresh)
+		if err := jwks.Load(); err != nil {
+			log.Fatalf("failed to load JWKS: %v", err)
+		}
+		verifier, err := jwtauth.NewVerifier(jwtauth.Config{
+			Issuer:        os.Getenv("LOGGER_JWT_ISSUER"),
+			Audience:      os.Getenv("LOGGER_JWT_AUDIENCE"),
+			GroupsClaim:   os.Getenv("LOGGER_JWT_GROUPS_CLAIM"),
+			GroupScopes:   groupScopes,
+			DefaultScopes: envList("LOGGER_JWT_DEFAULT_SCOPES", nil),
+			TenantClaim:   os.Getenv("LOGGER_JWT_TENANT_CLAIM"),
+			ServiceClaim:  os.Getenv("LOGGER_JWT_SERVICE_CLAIM"),
+		}, jwks)
+		if err != nil {
+			log.Fatalf("invalid JWT configuration: %v", err)
+		}
+		tokens = verifier
+	}
+
+	// HTTPS (optionnel) : certificat rechargé à chaud; avec LOGGER_TLS_CLIENT_CA, les
+	// certificats clients vérifiés authentifient les agents selon LOGGER_TLS_CLIENT_SCOPES
+	var tlsConfig *tls.Config
+	var certs internal.CertAuthenticator
+	if certFile := os.Getenv("LOGGER_TLS_CERT"); certFile != "" {
+		tlsConfig, err = tlsauth.ServerConfig(tlsauth.Config{
+			CertFile:     certFile,
+			KeyFile:      os.Getenv("LOGGER_TLS_KEY"),
+			ClientCAFile: os.Getenv("LOGGER_TLS_CLIENT_CA"),
+			ClientAuth:   os.Getenv("LOGGER_TLS_CLIENT_AUTH"),
+		})
+		if err != nil {
+			log.Fatalf("invalid TLS configuration: %v", err)
+		}
+		if tlsConfig.ClientCAs != nil {
+			certScopes, err := apikey.ParseScopeMap(os.Getenv("LOGGER_TLS_CLIENT_SCOPES"))
+			if err != nil {
+				log.Fatalf("invalid LOGGER_TLS_CLIENT_SCOPES: %v", err)
+			}
+			mapper, err := tlsauth.NewMapper(certScopes, envList("LOGGER_TLS_CLIENT_DEFAULT_SCOPES", nil))
+			if err != nil {
+				log.Fatalf("invalid client certificate scopes: %v", err)
+			}
+			certs = mapper
+		}
+	}
+
+	// Rate limiter : LOGGER_RATE_LIMIT requêtes (100 par défaut) par LOGGER_RATE_LIMIT_WINDOW
+	// (1m) et par client (LOGGER_RATE_LIMIT_KEY : ip, key, tenant ou service), selon
+	// LOGGER_RATE_LIMIT_ALGORITHM (fenêtre glissante par défaut). LOGGER_RATE_LIMIT_ROUTES
+	// donne à certaines routes leur propre limite, LOGGER_RATE_LIMIT_ENTRIES limite les
+	// entrées de /log et /log/batch par niveau déclaré dans le corps.
+	rateConfig := ratelimit.Config{MaxRequests: 100, Window: time.Minute, Algorithm: ratelimit.SlidingWindow}
+	rateKey := os.Getenv("LOGGER_RATE_LIMIT_KEY")
+	if rateConfig.Key, err = ratelimit.ParseKey(rateKey); err != nil {
+		log.Fatalf("invalid LOGGER_RATE_LIMIT_KEY: %v", err)
+	}
+	if raw := os.Getenv("LOGGER_RATE_LIMIT"); raw != "" {
+		if rateConfig.MaxRequests, err = strconv.Atoi(raw); err != nil {
+			log.Fatalf("invalid LOGGER_RATE_LIMIT: %q", raw)
+		}
+	}
+	if raw := os.Getenv("LOGGER_RATE_LIMIT_WINDOW"); raw != "" {
+		if rateConfig.Window, err = time.ParseDuration(raw); err != nil {
+			log.Fatalf("invalid LOGGER_RATE_LIMIT_WINDOW: %v", err)
+		}
+	}
+	if raw := os.Getenv("LOGGER_RATE_LIMIT_ALGORITHM"); raw != "" {
+		rateConfig.Algorithm = raw
+	}
+	if raw := os.Getenv("LOGGER_RATE_LIMIT_BURST"); raw != "" {
+		if rateConfig.Burst, err = strconv.Atoi(raw); err != nil {
+			log.Fatalf("invalid LOGGER_RATE_LIMIT_BURST: %q", raw)
+		}
+	}
+	if rateConfig.Routes, err = ratelimit.ParseRules(os.Getenv("LOGGER_RATE_LIMIT_ROUTES")); err != nil {
+		log.Fatalf("invalid LOGGER_RATE_LIMIT_ROUTES: %v", err)
+	}
+	if rateConfig.EntryLimits, err = ratelimit.ParseLevelLimits(os.Getenv("LOGGER_RATE_LIMIT_ENTRIES")); err != nil {
+		log.Fatalf("invalid LOGGER_RATE_LIMIT_ENTRIES: %v", err)
+	}
+	// Limites partagées entre les instances (optionnel) : état dans le serveur Redis
+	// LOGGER_RATE_LIMIT_REDIS_ADDR. S'il est injoignable, chaque instance limite localement.
+	if addr := os.Getenv("LOGGER_RATE_LIMIT_REDIS_ADDR"); addr != "" {
+		redisConfig := ratelimit.RedisConfig{
+			Addr:     addr,
+			Password: os.Getenv("LOGGER_RATE_LIMIT_REDIS_PASSWORD"),
+			Prefix:   os.Getenv("LOGGER_RATE_LIMIT_REDIS_PREFIX"),
+		}
+		if raw := os.Getenv("LOGGER_RATE_LIMIT_REDIS_DB"); raw != "" {
+			if redisConfig.DB, err = strconv.Atoi(raw); err != nil {
+				log.Fatalf("invalid LOGGER_RATE_LIMIT_REDIS_DB: %q", raw)
+			}
+		}
+		if raw := os.Getenv("LOGGER_RATE_LIMIT_REDIS_TIMEOUT"); raw != "" {
+			if redisConfig.Timeout, err = time.ParseDuration(raw); err != nil {
+				log.Fatalf("invalid LOGGER_RATE_LIMIT_REDIS_TIMEOUT: %v", err)
+			}
+		}
+		redisStore, err := ratelimit.NewRedisStore(redisConfig)
+		if err != nil {
+			log.Fatalf("invalid rate limit redis configuration: %v", err)
+		}
+		defer redisStore.Close()
+		if err := redisStore.Ping(context.Background()); err != nil {
+			log.Printf("rate limit redis %s unreachable, limiting locally until it is: %v", addr, err)
+		}
+		rateConfig.Shared = redisStore
+	}
+	rateLimiter, err := ratelimit.NewRateLimiter(rateConfig)
+	if err != nil {
+		log.Fatalf("invalid rate limit configuration: %v", err)
+	}
 	defer rateLimiter.Stop()
 
+	// Logger du serveur (requêtes, réceptions)
+	serverLogger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatalf("failed to initialize server logger: %v", err)
+	}
+	defer serverLogger.Sync()
+
+	// Auto-journalisation (optionnelle) : les entrées du logger serveur à partir de
+	// LOGGER_SELF_LOG_LEVEL (WARN par défaut) sont aussi envoyées au serveur lui-même via le
+	// SDK client. Un seuil sous WARN renverrait aussi les logs générés par ces envois.
+	if os.Getenv("LOGGER_SELF_LOG") == "true" {
+		selfLevel := zapcore.WarnLevel
+		if lvl := os.Getenv("LOGGER_SELF_LOG_LEVEL"); lvl != "" {
+			if selfLevel, err = zapcore.ParseLevel(lvl); err != nil {
+				log.Fatalf("invalid LOGGER_SELF_LOG_LEVEL: %v", err)
+			}
+		}
+
+		// En HTTPS, le certificat du serveur doit être valide pour l'adresse
+		// LOGGER_SELF_LOG_URL (https://localhost:8080 par défaut)
+		selfURL := os.Getenv("LOGGER_SELF_LOG_URL")
+		if selfURL == "" {
+			selfURL = "http://127.0.0.1:8080"
+			if tlsConfig != nil {
+				selfURL = "https://localhost:8080"
+			}
+		}
+		if tlsConfig != nil && tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert {
+			log.Fatal("LOGGER_SELF_LOG cannot be used with LOGGER_TLS_CLIENT_AUTH=require")
+		}
+
+		hostname, _ := os.Hostname()
+		selfClient, err := client.New(client.Config{
+			URL:     selfURL,
+			APIKey:  apiKey,
+			Service: "logger-server",
+			Host:    hostname,
+		})
+		if err != nil {
+			log.Fatalf("failed to initialize self-logging client: %v", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			selfClient.Close(ctx)
+		}()
+
+		serverLogger = serverLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, client.NewZapCore(selfClient, selfLevel))
+		}))
+	}
+
 	// Créer le handler principal
-	handler := internal.NewHandler(sqlLogger)
+	handler := internal.NewHandler(sqlLogger, serverLogger)
+
+	// Déduplication (optionnelle) des entrées portant un id ou une clé Idempotency-Key
+	if window := os.Getenv("LOGGER_IDEMPOTENCY_WINDOW"); window != "" {
+		dedupWindow, err := time.ParseDuration(window)
+		if err != nil {
+			log.Fatalf("invalid LOGGER_IDEMPOTENCY_WINDOW: %v", err)
+		}
+		idempotencyStore, err := idempotency.NewSQLiteStore(dbPath, dedupWindow)
+		if err != nil {
+			log.Fatalf("failed to initialize idempotency store: %v", err)
+		}
+		defer idempotencyStore.Close()
+
+		purgeCtx, stopPurge := context.WithCancel(context.Background())
+		defer stopPurge()
+		go idempotencyStore.Run(purgeCtx)
+
+		handler.SetDeduplicator(idempotencyStore)
+	}
+
+	// Quotas d'ingestion par tenant et par clé (/admin/quotas) : la consommation du jour et du
+	// mois est tenue en mémoire et écrite en base toutes les LOGGER_QUOTA_FLUSH_INTERVAL
+	quotaStore, err := quota.NewSQLiteStore(dbPath)
+	if err != nil {
+		log.Fatalf("failed to initialize quota store: %v", err)
+	}
+	defer quotaStore.Close()
+
+	quotaFlush := quota.DefaultFlushInterval
+	if raw := os.Getenv("LOGGER_QUOTA_FLUSH_INTERVAL"); raw != "" {
+		if quotaFlush, err = time.ParseDuration(raw); err != nil || quotaFlush <= 0 {
+			log.Fatalf("invalid LOGGER_QUOTA_FLUSH_INTERVAL: %q", raw)
+		}
+	}
+	quotaCtx, stopQuota := context.WithCancel(context.Background())
+	defer stopQuota()
+	go quotaStore.Run(quotaCtx, quotaFlush)
+
+	handler.SetQuota(quotaStore)
+	if rateConfig.EntryLimits != nil {
+		handler.SetEntryLimiter(rateLimiter)
+	}
 
 	r := handler.Router()
 	r.Handle("/metrics", promhttp.Handler())
 
-	// Chaîne des middlewares : RateLimit → APIKey → Handler
-	mux := rateLimiter.Middleware(
-		internal.ApiKeyMiddleware(apiKey, r),
-	)
+	// Réception OpenTelemetry OTLP/HTTP : POST /v1/logs (protobuf ou JSON)
+	otlp.NewAPI(handler.Ingest).Register(r)
+
+	// Compatibilité Loki pour promtail / Grafana Agent : POST /loki/api/v1/push
+	loki.NewAPI(handler.Ingest).Register(r)
+
+	// Splunk HEC : POST /services/collector/{event,raw}, jeton "Authorization: Splunk <clé API>"
+	hec.NewAPI(hec.Config{
+		EnableAck: os.Getenv("LOGGER_HEC_ACK") == "true",
+	}, handler.Ingest).Register(r)
+
+	// Surface compatible Elasticsearch (optionnelle) pour Beats, Logstash et Vector
+	if os.Getenv("LOGGER_ELASTIC_COMPAT") == "true" {
+		mapping := elastic.DefaultMapping()
+		mapping.LevelFields = envList("LOGGER_ELASTIC_LEVEL_FIELDS", mapping.LevelFields)
+		mapping.MessageFields = envList("LOGGER_ELASTIC_MESSAGE_FIELDS", mapping.MessageFields)
+		mapping.TimestampFields = envList("LOGGER_ELASTIC_TIMESTAMP_FIELDS", mapping.TimestampFields)
+		mapping.ServiceFields = envList("LOGGER_ELASTIC_SERVICE_FIELDS", mapping.ServiceFields)
+		mapping.HostFields = envList("LOGGER_ELASTIC_HOST_FIELDS", mapping.HostFields)
+		mapping.ContextFields = envList("LOGGER_ELASTIC_CONTEXT_FIELDS", nil)
+
+		elastic.NewAPI(elastic.Config{
+			Mapping: mapping,
+			Version: os.Getenv("LOGGER_ELASTIC_VERSION"),
+		}, handler.Ingest).Register(r)
+	}
+
+	// Interface web de recherche et de live tail : /ui/ (clé API en mot de passe Basic)
+	webStore, err := webui.NewSQLiteStore(dbPath)
+	if err != nil {
+		log.Fatalf("failed to initialize web UI store: %v", err)
+	}
+	defer webStore.Close()
+	webui.NewAPI(webStore).Register(r)
+
+	// Spécification OpenAPI (GET /openapi.json) et Swagger UI (GET /docs)
+	openapiAPI, err := openapi.NewAPI(openapi.Spec())
+	if err != nil {
+		log.Fatalf("failed to build OpenAPI spec: %v", err)
+	}
+	openapiAPI.Register(r)
+
+	// Gestion des clés API, des tenants et des quotas (scope admin) : /admin/keys,
+	// /admin/tenants, /admin/quotas. La suppression d'un tenant révoque ses clés, efface ses
+	// entrées puis ses quotas.
+	keyAPI := apikey.NewAPI(keyStore)
+	keyAPI.SetTenants(tenantStore)
+	keyAPI.Register(r)
+	tenant.NewAPI(tenantStore, keyStore, sqlLogger, quotaStore).Register(r)
+	quota.NewAPI(quotaStore).Register(r)
+	ratelimit.NewAPI(rateLimiter).Register(r)
+
+	// Détection d'anomalies (optionnelle) sur le volume par service/niveau
+	if os.Getenv("LOGGER_ANOMALY_DETECTION") == "true" {
+		anomalyStore, err := anomaly.NewSQLiteStore(dbPath)
+		if err != nil {
+			log.Fatalf("failed to initialize anomaly store: %v", err)
+		}
+		defer anomalyStore.Close()
+
+		var notifier anomaly.Notifier
+		if webhookURL := os.Getenv("LOGGER_ANOMALY_WEBHOOK_URL"); webhookURL != "" {
+			notifier = anomaly.NewWebhookNotifier(webhookURL)
+		}
+
+		detector := anomaly.NewDetector(anomaly.DefaultConfig(), anomalyStore, notifier)
+		defer detector.Close()
+		// Les anomalies sont globales (/anomalies est fermé aux tenants) : seules les entrées
+		// du tenant par défaut sont observées
+		handler.AddIngestHook(func(entry internal.LogEntry) {
+			if entry.Tenant == "" {
+				detector.Observe(entry.Service, entry.Level)
+			}
+		})
+
+		detectorCtx, stopDetector := context.WithCancel(context.Background())
+		defer stopDetector()
+		go detector.Run(detectorCtx)
+
+		anomaly.NewAPI(anomalyStore).Register(r)
+	}
+
+	// Listeners syslog (optionnels) : mêmes validation et stockage que POST /log
+	syslogCfg := syslog.Config{
+		UDPAddr:     os.Getenv("LOGGER_SYSLOG_UDP_ADDR"),
+		TCPAddr:     os.Getenv("LOGGER_SYSLOG_TCP_ADDR"),
+		TLSAddr:     os.Getenv("LOGGER_SYSLOG_TLS_ADDR"),
+		TLSCertFile: os.Getenv("LOGGER_SYSLOG_TLS_CERT"),
+		TLSKeyFile:  os.Getenv("LOGGER_SYSLOG_TLS_KEY"),
+	}
+	if syslogCfg.UDPAddr != "" || syslogCfg.TCPAddr != "" || syslogCfg.TLSAddr != "" {
+		syslogServer := syslog.NewServer(syslogCfg, handler.Ingest)
+		if err := syslogServer.Start(); err != nil {
+			log.Fatalf("failed to start syslog listeners: %v", err)
+		}
+		defer syslogServer.Close()
+		log.Printf("Syslog listeners started: %v", syslogServer.Addrs())
+	}
+
+	// Entrée Fluent forward (optionnelle) pour Fluent Bit / Fluentd avec acks
+	if forwardAddr := os.Getenv("LOGGER_FORWARD_ADDR"); forwardAddr != "" {
+		forwardServer := forward.NewServer(forward.Config{
+			Addr:        forwardAddr,
+			SharedKey:   os.Getenv("LOGGER_FORWARD_SHARED_KEY"),
+			TLSCertFile: os.Getenv("LOGGER_FORWARD_TLS_CERT"),
+			TLSKeyFile:  os.Getenv("LOGGER_FORWARD_TLS_KEY"),
+		}, handler.Ingest)
+		if err := forwardServer.Start(); err != nil {
+			log.Fatalf("failed to start forward listener: %v", err)
+		}
+		defer forwardServer.Close()
+		log.Printf("Forward listener started on %v", forwardServer.Addr())
+	}
+
+	// Listeners GELF (optionnels) pour le driver de logs Docker et Graylog
+	gelfCfg := gelf.Config{
+		UDPAddr: os.Getenv("LOGGER_GELF_UDP_ADDR"),
+		TCPAddr: os.Getenv("LOGGER_GELF_TCP_ADDR"),
+	}
+	if gelfCfg.UDPAddr != "" || gelfCfg.TCPAddr != "" {
+		gelfServer := gelf.NewServer(gelfCfg, handler.Ingest)
+		if err := gelfServer.Start(); err != nil {
+			log.Fatalf("failed to start GELF listeners: %v", err)
+		}
+		defer gelfServer.Close()
+		log.Printf("GELF listeners started: %v", gelfServer.Addrs())
+	}
+
+	// Service gRPC (optionnel) : Ingest par lots, Query et Tail, avec les mêmes clés API et
+	// le même rate limiter que l'API HTTP
+	if grpcAddr := os.Getenv("LOGGER_GRPC_ADDR"); grpcAddr != "" {
+		tailHub := grpcapi.NewHub()
+		handler.AddIngestHook(tailHub.Publish)
+
+		grpcServer := grpcapi.NewServer(grpcapi.Config{
+			Addr:    grpcAddr,
+			Keys:    keyring,
+			Tenants: tenantStore,
+			Limiter: rateLimiter,
+		}, handler.Ingest, sqlLogger, tailHub)
+		if err := grpcServer.Start(); err != nil {
+			log.Fatalf("failed to start gRPC server: %v", err)
+		}
+		defer grpcServer.Close()
+		log.Printf("gRPC server started on %v", grpcServer.Addr())
+	}
+
+	// Chaîne des middlewares : RateLimit → APIKey / signature / Bearer / certificat (scopes)
+	// → Quotas → Handler. Limité par clé, tenant ou service, le rate limit suit
+	// l'authentification qui fournit la clé.
+	auth := internal.ScopedAuthMiddleware(internal.Authenticators{
+		Keys:       keyring,
+		Tokens:     tokens,
+		Certs:      certs,
+		Signatures: signatures,
+		Tenants:    tenantStore,
+	}, sqlLogger)
+	var mux http.Handler
+	if rateKey == "" || rateKey == ratelimit.KeyIP {
+		mux = rateLimiter.Middleware(auth(quota.Middleware(quotaStore)(r)))
+	} else {
+		mux = auth(rateLimiter.Middleware(quota.Middleware(quotaStore)(r)))
+	}
 
 	// Configuration serveur HTTP
 	srv := &http.Server{
@@ -56,6 +502,7 @@ func main() {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		TLSConfig:    tlsConfig,
 	}
 
 	// Gestion arrêt propre
@@ -63,8 +510,16 @@ func main() {
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		log.Println("Logger server is running on :8080")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsConfig != nil {
+			log.Println("Logger server is running on :8080 (HTTPS)")
+			// Le certificat vient de TLSConfig.GetCertificate
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			log.Println("Logger server is running on :8080")
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("server error: %v", err)
 		}
 	}()
@@ -88,4 +543,19 @@ func main() {
 	case <-time.After(shutdownTimeout):
 		log.Println("Shutdown timed out.")
 	}
-}
\ No newline at end of file
+}
+
+// envList lit une liste séparée par des virgules, ou retourne def si la variable est vide
+func envList(key string, def []string) []string {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
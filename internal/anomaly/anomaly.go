@@ -0,0 +1,479 @@
+package anomaly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+)
+
+const (
+	MetricVolume    = "volume"
+	MetricErrorRate = "error_rate"
+
+	DirectionSpike = "spike"
+	DirectionDrop  = "drop"
+
+	// Nombre de créneaux horaires dans une semaine (baseline saisonnière)
+	hoursPerWeek = 7 * 24
+
+	// Service utilisé quand l'entrée n'en déclare aucun
+	unknownService = "unknown"
+
+	// Anomalies en attente de notification; au-delà elles sont seulement stockées
+	notifyQueueSize = 100
+)
+
+// Event représente une anomalie détectée sur une série (service, niveau, métrique).
+type Event struct {
+	ID          int64     `json:"id,omitempty"`
+	Service     string    `json:"service"`
+	Level       string    `json:"level,omitempty"`
+	Metric      string    `json:"metric"`
+	Direction   string    `json:"direction"`
+	Value       float64   `json:"value"`
+	Expected    float64   `json:"expected"`
+	Score       float64   `json:"score"`
+	WindowStart time.Time `json:"window_start"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// Store persiste les anomalies détectées
+type Store interface {
+	SaveAnomaly(event Event) error
+	QueryAnomalies(filter Filter, page, limit int) ([]Event, error)
+}
+
+// Notifier route une anomalie vers un système d'alerte externe
+type Notifier interface {
+	Notify(event Event) error
+}
+
+// Config regroupe les paramètres du détecteur
+type Config struct {
+	Interval       time.Duration // taille d'un bucket d'agrégation
+	Alpha          float64       // facteur de lissage EWMA
+	SeasonalAlpha  float64       // facteur de lissage de la baseline heure-de-semaine (d'une semaine à l'autre)
+	Threshold      float64       // score (z-score) à partir duquel on lève une anomalie
+	MinSamples     int           // nombre de buckets avant de juger une série
+	MinSeasonal    int           // nombre de semaines observées d'un créneau avant d'utiliser la baseline saisonnière
+	MinDelta       float64       // écart absolu minimal pour les volumes (évite le bruit sur petites séries)
+	MinErrorVolume int           // volume minimal d'un bucket pour calculer un taux d'erreur
+	MaxSeries      int           // borne la cardinalité (services déclarés par les clients)
+}
+
+// DefaultConfig retourne une configuration raisonnable pour des buckets d'une minute
+func DefaultConfig() Config {
+	return Config{
+		Interval:       time.Minute,
+		Alpha:          0.1,
+		SeasonalAlpha:  0.3,
+		Threshold:      4,
+		MinSamples:     30,
+		MinSeasonal:    2,
+		MinDelta:       10,
+		MinErrorVolume: 20,
+		MaxSeries:      1000,
+	}
+}
+
+type seriesKey struct {
+	service string
+	level   string
+	metric  string
+}
+
+// seasonalSlot est la baseline d'un créneau heure-de-semaine : moyenne et variance des
+// buckets de cette heure, lissées d'une semaine à l'autre
+type seasonalSlot struct {
+	mean     float64
+	variance float64
+	weeks    int
+}
+
+// hourTotals cumule les buckets de l'heure en cours d'une série
+type hourTotals struct {
+	start   time.Time
+	sum     float64
+	sumSq   float64
+	buckets int
+}
+
+// baseline maintient moyenne/variance EWMA et moyennes saisonnières d'une série. Un créneau
+// saisonnier n'est mis à jour qu'à la fin de son heure, avec la moyenne de ses buckets : il
+// reflète les semaines passées, pas les minutes qui viennent de s'écouler.
+type baseline struct {
+	mean     float64
+	variance float64
+	samples  int
+	hour     hourTotals
+	seasonal [hoursPerWeek]seasonalSlot
+}
+
+type Detector struct {
+	mu          sync.Mutex
+	cfg         Config
+	counts      map[seriesKey]float64
+	newSeries   int
+	baselines   map[seriesKey]*baseline
+	windowStart time.Time
+	store       Store
+	notifier    Notifier
+	onError     func(error)
+
+	// Les notifications partent d'une goroutine : un webhook lent ne bloque pas les buckets
+	notifyMu     sync.Mutex
+	notifyQueue  chan Event
+	notifyClosed bool
+	notifyDone   chan struct{}
+}
+
+var (
+	anomaliesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "anomaly_events_total",
+		Help: "Total number of anomalies detected",
+	}, []string{"metric", "direction"})
+	notificationsDroppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "anomaly_notifications_dropped_total",
+		Help: "Total number of anomalies not notified because the notification queue was full",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(anomaliesTotal, notificationsDroppedTotal)
+}
+
+// NewDetector crée un détecteur; store et notifier sont optionnels (nil)
+func NewDetector(cfg Config, store Store, notifier Notifier) *Detector {
+	def := DefaultConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = def.Interval
+	}
+	if cfg.Alpha <= 0 || cfg.Alpha >= 1 {
+		cfg.Alpha = def.Alpha
+	}
+	if cfg.SeasonalAlpha <= 0 || cfg.SeasonalAlpha >= 1 {
+		cfg.SeasonalAlpha = def.SeasonalAlpha
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = def.Threshold
+	}
+	if cfg.MaxSeries <= 0 {
+		cfg.MaxSeries = def.MaxSeries
+	}
+
+	d := &Detector{
+		cfg:       cfg,
+		counts:    make(map[seriesKey]float64),
+		baselines: make(map[seriesKey]*baseline),
+		store:     store,
+		notifier:  notifier,
+		onError: func(err error) {
+			log.Printf("anomaly detector error: %v", err)
+		},
+	}
+	if notifier != nil {
+		d.notifyQueue = make(chan Event, notifyQueueSize)
+		d.notifyDone = make(chan struct{})
+		go d.notifyLoop()
+	}
+	return d
+}
+
+// Close envoie les notifications en attente puis arrête leur goroutine
+func (d *Detector) Close() {
+	d.notifyMu.Lock()
+	if d.notifyQueue == nil || d.notifyClosed {
+		d.notifyMu.Unlock()
+		return
+	}
+	d.notifyClosed = true
+	close(d.notifyQueue)
+	d.notifyMu.Unlock()
+	<-d.notifyDone
+}
+
+// notifyLoop transmet les anomalies au Notifier, une à la fois. Le Notifier borne la durée
+// de chaque envoi (WebhookNotifier : timeout du client HTTP).
+func (d *Detector) notifyLoop() {
+	defer close(d.notifyDone)
+	for ev := range d.notifyQueue {
+		if err := d.notifier.Notify(ev); err != nil {
+			d.onError(err)
+		}
+	}
+}
+
+// enqueueNotification met une anomalie en file sans attendre; file pleine, elle n'est pas
+// notifiée (elle reste stockée)
+func (d *Detector) enqueueNotification(ev Event) {
+	d.notifyMu.Lock()
+	defer d.notifyMu.Unlock()
+	if d.notifyClosed {
+		return
+	}
+	select {
+	case d.notifyQueue <- ev:
+	default:
+		notificationsDroppedTotal.Inc()
+		d.onError(fmt.Errorf("notification queue full, anomaly on %s not notified", ev.Service))
+	}
+}
+
+// Observe comptabilise une entrée ingérée dans le bucket courant
+func (d *Detector) Observe(service, level string) {
+	service = strings.TrimSpace(service)
+	if service == "" {
+		service = unknownService
+	}
+	lvl := log_levels.NormalizeLogLevel(level)
+	if !log_levels.IsValidLogLevel(string(lvl)) {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := seriesKey{service: service, level: string(lvl), metric: MetricVolume}
+	_, known := d.baselines[key]
+	_, counted := d.counts[key]
+	if !known && !counted {
+		if len(d.baselines)+d.newSeries >= d.cfg.MaxSeries {
+			// Trop de séries : on ignore les nouvelles plutôt que d'exploser en mémoire
+			return
+		}
+		d.newSeries++
+	}
+	d.counts[key]++
+}
+
+// Run agrège et évalue les buckets à intervalle régulier jusqu'à l'annulation du contexte
+func (d *Detector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	d.mu.Lock()
+	d.windowStart = time.Now()
+	d.mu.Unlock()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			d.Flush(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Flush clôt le bucket courant, met à jour les baselines et retourne les anomalies levées
+func (d *Detector) Flush(now time.Time) []Event {
+	d.mu.Lock()
+	windowStart := d.windowStart
+	if windowStart.IsZero() {
+		windowStart = now.Add(-d.cfg.Interval)
+	}
+	values := d.bucketValues()
+	d.counts = make(map[seriesKey]float64)
+	d.newSeries = 0
+	d.windowStart = now
+
+	hour := windowStart.UTC().Truncate(time.Hour)
+	slot := hourOfWeek(hour)
+	var events []Event
+	for key, value := range values {
+		b, ok := d.baselines[key]
+		if !ok {
+			b = &baseline{}
+			d.baselines[key] = b
+		}
+		if !b.hour.start.IsZero() && !b.hour.start.Equal(hour) {
+			b.closeHour(d.cfg.SeasonalAlpha)
+		}
+		if ev, flagged := d.evaluate(key, b, value, slot); flagged {
+			ev.WindowStart = windowStart
+			ev.DetectedAt = now
+			events = append(events, ev)
+		}
+		b.update(value, hour, d.cfg.Alpha)
+	}
+	d.mu.Unlock()
+
+	for _, ev := range events {
+		anomaliesTotal.WithLabelValues(ev.Metric, ev.Direction).Inc()
+		if d.store != nil {
+			if err := d.store.SaveAnomaly(ev); err != nil {
+				d.onError(err)
+			}
+		}
+		if d.notifier != nil {
+			d.enqueueNotification(ev)
+		}
+	}
+	return events
+}
+
+// bucketValues calcule la valeur de chaque série pour le bucket courant (appelé avec lock).
+// Les séries connues sans activité valent 0, ce qui permet de détecter les chutes de volume.
+func (d *Detector) bucketValues() map[seriesKey]float64 {
+	values := make(map[seriesKey]float64, len(d.baselines)+len(d.counts))
+	for key := range d.baselines {
+		if key.metric == MetricVolume {
+			values[key] = 0
+		}
+	}
+
+	totals := make(map[string]float64)
+	failures := make(map[string]float64)
+	for key, count := range d.counts {
+		values[key] = count
+		totals[key.service] += count
+		lvl := log_levels.LogLevel(key.level)
+		if lvl == log_levels.LogLevelError || lvl == log_levels.LogLevelFatal {
+			failures[key.service] += count
+		}
+	}
+
+	for service, total := range totals {
+		if total < float64(d.cfg.MinErrorVolume) {
+			continue
+		}
+		values[seriesKey{service: service, metric: MetricErrorRate}] = failures[service] / total
+	}
+	return values
+}
+
+// evaluate compare la valeur observée à la baseline (appelé avec lock)
+func (d *Detector) evaluate(key seriesKey, b *baseline, value float64, slot int) (Event, bool) {
+	if b.samples < d.cfg.MinSamples {
+		return Event{}, false
+	}
+
+	// Baseline du créneau heure-de-semaine dès qu'il a été vu MinSeasonal semaines, EWMA
+	// globale sinon; moyenne et écart-type viennent toujours de la même baseline
+	expected, variance := b.mean, b.variance
+	if s := b.seasonal[slot]; d.cfg.MinSeasonal > 0 && s.weeks >= d.cfg.MinSeasonal {
+		expected, variance = s.mean, s.variance
+	}
+
+	// Plancher d'écart-type : une série parfaitement stable ne doit pas alerter au moindre écart
+	floor := 1.0
+	if key.metric == MetricErrorRate {
+		floor = 0.01
+	}
+	stddev := math.Max(math.Sqrt(variance), floor)
+
+	delta := value - expected
+	score := delta / stddev
+	if math.Abs(score) < d.cfg.Threshold {
+		return Event{}, false
+	}
+	if key.metric == MetricVolume && math.Abs(delta) < d.cfg.MinDelta {
+		return Event{}, false
+	}
+
+	direction := DirectionSpike
+	if delta < 0 {
+		direction = DirectionDrop
+	}
+
+	return Event{
+		Service:   key.service,
+		Level:     key.level,
+		Metric:    key.metric,
+		Direction: direction,
+		Value:     value,
+		Expected:  expected,
+		Score:     score,
+	}, true
+}
+
+// update intègre une nouvelle valeur dans la baseline EWMA et les totaux de l'heure hour
+func (b *baseline) update(value float64, hour time.Time, alpha float64) {
+	if b.samples == 0 {
+		b.mean = value
+	} else {
+		diff := value - b.mean
+		incr := alpha * diff
+		b.mean += incr
+		b.variance = (1 - alpha) * (b.variance + diff*incr)
+	}
+	b.samples++
+
+	b.hour.start = hour
+	b.hour.sum += value
+	b.hour.sumSq += value * value
+	b.hour.buckets++
+}
+
+// closeHour intègre l'heure écoulée dans son créneau saisonnier : une observation par
+// semaine, la moyenne et la variance de ses buckets
+func (b *baseline) closeHour(alpha float64) {
+	h := b.hour
+	b.hour = hourTotals{}
+	if h.buckets == 0 {
+		return
+	}
+	n := float64(h.buckets)
+	mean := h.sum / n
+	variance := math.Max(h.sumSq/n-mean*mean, 0)
+
+	s := &b.seasonal[hourOfWeek(h.start)]
+	if s.weeks == 0 {
+		s.mean, s.variance = mean, variance
+	} else {
+		// Variance des buckets autour de la baseline : dispersion dans l'heure et écart
+		// de la moyenne de l'heure à celle des semaines précédentes
+		diff := mean - s.mean
+		s.mean += alpha * diff
+		s.variance = (1-alpha)*s.variance + alpha*(variance+(1-alpha)*diff*diff)
+	}
+	s.weeks++
+}
+
+// hourOfWeek retourne l'index 0..167 du créneau horaire (UTC) dans la semaine
+func hourOfWeek(t time.Time) int {
+	t = t.UTC()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// WebhookNotifier poste chaque anomalie en JSON vers une URL (Alertmanager, Slack relay, ...)
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (n *WebhookNotifier) Notify(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.Client.Post(n.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package anomaly_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/anomaly"
+)
+
+// memoryStore enregistre les anomalies en mémoire pour les tests
+type memoryStore struct {
+	mu     sync.Mutex
+	events []anomaly.Event
+	err    error
+}
+
+func (m *memoryStore) SaveAnomaly(event anomaly.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memoryStore) QueryAnomalies(filter anomaly.Filter, page, limit int) ([]anomaly.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	var out []anomaly.Event
+	for _, ev := range m.events {
+		if filter.Service != "" && ev.Service != filter.Service {
+			continue
+		}
+		if filter.Metric != "" && ev.Metric != filter.Metric {
+			continue
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+type recordingNotifier struct {
+	mu      sync.Mutex
+	events  []anomaly.Event
+	release chan struct{} // si non nil, chaque Notify attend un signal
+}
+
+func (n *recordingNotifier) Notify(event anomaly.Event) error {
+	if n.release != nil {
+		<-n.release
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+func (n *recordingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.events)
+}
+
+func testConfig() anomaly.Config {
+	cfg := anomaly.DefaultConfig()
+	cfg.MinSamples = 10
+	cfg.MinDelta = 5
+	cfg.MinErrorVolume = 10
+	return cfg
+}
+
+// warmUp alimente le détecteur avec un volume stable pendant n buckets
+func warmUp(d *anomaly.Detector, start time.Time, buckets int, perBucket map[string]int) time.Time {
+	now := start
+	for i := 0; i < buckets; i++ {
+		for level, n := range perBucket {
+			for j := 0; j < n; j++ {
+				d.Observe("billing", level)
+			}
+		}
+		now = now.Add(time.Minute)
+		d.Flush(now)
+	}
+	return now
+}
+
+func TestDetector_NoAnomalyOnStableTraffic(t *testing.T) {
+	store := &memoryStore{}
+	d := anomaly.NewDetector(testConfig(), store, nil)
+
+	warmUp(d, time.Date(2025, 8, 4, 10, 0, 0, 0, time.UTC), 40, map[string]int{"INFO": 50})
+
+	if len(store.events) != 0 {
+		t.Errorf("expected no anomaly on stable traffic, got %d", len(store.events))
+	}
+}
+
+func TestDetector_VolumeSpike(t *testing.T) {
+	store := &memoryStore{}
+	notifier := &recordingNotifier{}
+	d := anomaly.NewDetector(testConfig(), store, notifier)
+
+	now := warmUp(d, time.Date(2025, 8, 4, 10, 0, 0, 0, time.UTC), 20, map[string]int{"INFO": 50})
+
+	for i := 0; i < 500; i++ {
+		d.Observe("billing", "info")
+	}
+	events := d.Flush(now.Add(time.Minute))
+
+	var spike *anomaly.Event
+	for i := range events {
+		if events[i].Metric == anomaly.MetricVolume && events[i].Level == "INFO" {
+			spike = &events[i]
+		}
+	}
+	if spike == nil {
+		t.Fatalf("expected a volume anomaly, got %+v", events)
+	}
+	if spike.Direction != anomaly.DirectionSpike {
+		t.Errorf("expected direction %q, got %q", anomaly.DirectionSpike, spike.Direction)
+	}
+	if spike.Service != "billing" {
+		t.Errorf("expected service billing, got %q", spike.Service)
+	}
+	if spike.Value != 500 {
+		t.Errorf("expected value 500, got %v", spike.Value)
+	}
+	if len(store.events) == 0 {
+		t.Error("expected anomaly to be stored")
+	}
+	d.Close()
+	if notifier.count() == 0 {
+		t.Error("expected anomaly to be notified")
+	}
+}
+
+func TestDetector_SlowNotifierDoesNotBlockFlush(t *testing.T) {
+	store := &memoryStore{}
+	notifier := &recordingNotifier{release: make(chan struct{})}
+	d := anomaly.NewDetector(testConfig(), store, notifier)
+
+	now := warmUp(d, time.Date(2025, 8, 4, 10, 0, 0, 0, time.UTC), 20, map[string]int{"INFO": 50})
+
+	// Le webhook ne répond pas : les buckets suivants sont évalués quand même
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 500; j++ {
+				d.Observe("billing", "INFO")
+			}
+			now = now.Add(time.Minute)
+			d.Flush(now)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Flush not to wait for the notifier")
+	}
+	if len(store.events) == 0 {
+		t.Error("expected anomalies to be stored while the notifier is stuck")
+	}
+
+	close(notifier.release)
+	d.Close()
+	if notifier.count() != len(store.events) {
+		t.Errorf("expected every stored anomaly to be notified on Close, got %d/%d", notifier.count(), len(store.events))
+	}
+}
+
+func TestDetector_VolumeDrop(t *testing.T) {
+	d := anomaly.NewDetector(testConfig(), nil, nil)
+
+	now := warmUp(d, time.Date(2025, 8, 4, 10, 0, 0, 0, time.UTC), 20, map[string]int{"INFO": 100})
+
+	// Aucun log pendant un bucket : la série connue vaut 0
+	events := d.Flush(now.Add(time.Minute))
+
+	if len(events) != 1 {
+		t.Fatalf("expected 1 anomaly, got %d", len(events))
+	}
+	if events[0].Direction != anomaly.DirectionDrop {
+		t.Errorf("expected direction %q, got %q", anomaly.DirectionDrop, events[0].Direction)
+	}
+}
+
+func TestDetector_ErrorRate(t *testing.T) {
+	d := anomaly.NewDetector(testConfig(), nil, nil)
+
+	now := warmUp(d, time.Date(2025, 8, 4, 10, 0, 0, 0, time.UTC), 20, map[string]int{"INFO": 98, "ERROR": 2})
+
+	// Volume total identique mais 50% d'erreurs
+	for i := 0; i < 50; i++ {
+		d.Observe("billing", "INFO")
+		d.Observe("billing", "ERROR")
+	}
+	events := d.Flush(now.Add(time.Minute))
+
+	found := false
+	for _, ev := range events {
+		if ev.Metric == anomaly.MetricErrorRate {
+			found = true
+			if ev.Value != 0.5 {
+				t.Errorf("expected error rate 0.5, got %v", ev.Value)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected an error_rate anomaly, got %+v", events)
+	}
+}
+
+// Trois semaines de buckets de 5 minutes : un lot hebdomadaire (lundi 10h) est appris par
+// son créneau et n'alerte plus, un pic au même niveau un autre jour reste une anomalie
+func TestDetector_SeasonalWeeklyPattern(t *testing.T) {
+	cfg := testConfig()
+	cfg.MinSeasonal = 2
+	d := anomaly.NewDetector(cfg, nil, nil)
+
+	start := time.Date(2025, 8, 4, 0, 0, 0, 0, time.UTC) // lundi
+	weeklyBatch := func(at time.Time) bool {
+		return at.Weekday() == time.Monday && at.Hour() == 10
+	}
+	const bucket = 5 * time.Minute
+
+	var lastWeek []anomaly.Event
+	for now := start; now.Before(start.Add(3 * 7 * 24 * time.Hour)); {
+		n := 50 + int(now.Unix()/int64(bucket.Seconds()))%5
+		if weeklyBatch(now) {
+			n = 500
+		}
+		for i := 0; i < n; i++ {
+			d.Observe("billing", "INFO")
+		}
+		windowStart := now
+		now = now.Add(bucket)
+		events := d.Flush(now)
+		if windowStart.After(start.Add(2 * 7 * 24 * time.Hour)) {
+			lastWeek = append(lastWeek, events...)
+		}
+	}
+	if len(lastWeek) != 0 {
+		t.Errorf("expected the weekly batch to match its hour-of-week baseline, got %+v", lastWeek)
+	}
+
+	// Mardi de la quatrième semaine, 10h : le volume monte progressivement vers celui du lot,
+	// hors du motif hebdomadaire. Le créneau ne suit pas la dérive pendant l'heure.
+	now := start.Add(3 * 7 * 24 * time.Hour)
+	for ; now.Before(start.Add(3*7*24*time.Hour + 34*time.Hour)); now = now.Add(bucket) {
+		for i := 0; i < 50; i++ {
+			d.Observe("billing", "INFO")
+		}
+		d.Flush(now.Add(bucket))
+	}
+	var spikes []anomaly.Event
+	for i := 1; i <= 12; i++ {
+		for j := 0; j < 50+35*i; j++ {
+			d.Observe("billing", "INFO")
+		}
+		now = now.Add(bucket)
+		for _, ev := range d.Flush(now) {
+			if ev.Direction == anomaly.DirectionSpike {
+				spikes = append(spikes, ev)
+			}
+		}
+	}
+	if len(spikes) < 6 || spikes[len(spikes)-1].Expected > 60 {
+		t.Errorf("expected the drift to be flagged against the Tuesday baseline, got %+v", spikes)
+	}
+}
+
+func TestDetector_NotJudgedBeforeMinSamples(t *testing.T) {
+	d := anomaly.NewDetector(testConfig(), nil, nil)
+
+	now := warmUp(d, time.Date(2025, 8, 4, 10, 0, 0, 0, time.UTC), 3, map[string]int{"INFO": 10})
+
+	for i := 0; i < 1000; i++ {
+		d.Observe("billing", "INFO")
+	}
+	if events := d.Flush(now.Add(time.Minute)); len(events) != 0 {
+		t.Errorf("expected no anomaly before MinSamples, got %d", len(events))
+	}
+}
+
+func TestDetector_IgnoresInvalidLevelAndCapsSeries(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxSeries = 2
+	cfg.MinSamples = 1
+	d := anomaly.NewDetector(cfg, nil, nil)
+
+	d.Observe("a", "NOPE")
+	d.Observe("a", "INFO")
+	d.Observe("b", "INFO")
+	d.Observe("c", "INFO") // au-delà de MaxSeries
+	start := time.Date(2025, 8, 4, 10, 0, 0, 0, time.UTC)
+	d.Flush(start)
+
+	// Au bucket suivant, seules les deux séries connues passent à 0 ; "c" n'existe pas
+	for i := 0; i < 100; i++ {
+		d.Observe("c", "INFO")
+	}
+	for _, ev := range d.Flush(start.Add(time.Minute)) {
+		if ev.Service == "c" {
+			t.Errorf("expected series over MaxSeries to be ignored, got %+v", ev)
+		}
+	}
+}
+
+func TestDetector_StoreErrorDoesNotPanic(t *testing.T) {
+	store := &memoryStore{err: errors.New("db down")}
+	d := anomaly.NewDetector(testConfig(), store, nil)
+
+	now := warmUp(d, time.Date(2025, 8, 4, 10, 0, 0, 0, time.UTC), 20, map[string]int{"INFO": 50})
+	for i := 0; i < 500; i++ {
+		d.Observe("billing", "INFO")
+	}
+
+	if events := d.Flush(now.Add(time.Minute)); len(events) == 0 {
+		t.Error("expected anomaly to be returned even when store fails")
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected Content-Type application/json, got %s", ct)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	n := anomaly.NewWebhookNotifier(srv.URL)
+	if err := n.Notify(anomaly.Event{Service: "billing", Metric: anomaly.MetricVolume}); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+	if !called {
+		t.Error("expected webhook to be called")
+	}
+}
@@ -0,0 +1,66 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// API expose les anomalies détectées en lecture
+type API struct {
+	store Store
+}
+
+func NewAPI(store Store) *API {
+	return &API{store: store}
+}
+
+// Register ajoute les routes de l'API anomalies au routeur
+func (a *API) Register(r *mux.Router) {
+	r.HandleFunc("/anomalies", a.handleGetAnomalies).Methods("GET")
+}
+
+func (a *API) handleGetAnomalies(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	page, limit, err := utils.ParseAndValidatePageLimit(q.Get("page"), q.Get("limit"))
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := Filter{
+		Service: q.Get("service"),
+		Metric:  q.Get("metric"),
+	}
+
+	if filter.Metric != "" && filter.Metric != MetricVolume && filter.Metric != MetricErrorRate {
+		utils.WriteJSONError(w, http.StatusBadRequest, "invalid 'metric' parameter")
+		return
+	}
+
+	if since := q.Get("since"); since != "" {
+		ts, err := time.Parse(utils.TimestampLayout, since)
+		if err != nil {
+			utils.WriteJSONError(w, http.StatusBadRequest, "invalid 'since' parameter")
+			return
+		}
+		filter.Since = ts
+	}
+
+	events, err := a.store.QueryAnomalies(filter, page, limit)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "failed to query anomalies")
+		return
+	}
+	if events == nil {
+		events = []Event{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(events)
+}
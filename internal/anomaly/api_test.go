@@ -0,0 +1,77 @@
+package anomaly_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/anomaly"
+)
+
+func newTestRouter(store anomaly.Store) *mux.Router {
+	r := mux.NewRouter()
+	anomaly.NewAPI(store).Register(r)
+	return r
+}
+
+func TestAPI_GetAnomalies(t *testing.T) {
+	store := &memoryStore{events: []anomaly.Event{
+		{Service: "billing", Metric: anomaly.MetricVolume, DetectedAt: time.Now()},
+		{Service: "auth", Metric: anomaly.MetricErrorRate, DetectedAt: time.Now()},
+	}}
+	r := newTestRouter(store)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantCount  int
+	}{
+		{"all", "", http.StatusOK, 2},
+		{"by service", "?service=billing", http.StatusOK, 1},
+		{"by metric", "?metric=error_rate", http.StatusOK, 1},
+		{"invalid metric", "?metric=latency", http.StatusBadRequest, 0},
+		{"invalid since", "?since=yesterday", http.StatusBadRequest, 0},
+		{"invalid page", "?page=abc", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/anomalies"+tt.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var events []anomaly.Event
+			if err := json.NewDecoder(w.Body).Decode(&events); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(events) != tt.wantCount {
+				t.Errorf("expected %d anomalies, got %d", tt.wantCount, len(events))
+			}
+		})
+	}
+}
+
+func TestAPI_GetAnomalies_StoreError(t *testing.T) {
+	r := newTestRouter(&memoryStore{err: errors.New("db down")})
+
+	req := httptest.NewRequest("GET", "/anomalies", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,161 @@
+package anomaly
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// Filter restreint la recherche d'anomalies (champs vides = pas de filtre)
+type Filter struct {
+	Service string
+	Metric  string
+	Since   time.Time
+}
+
+type SQLiteStore struct {
+	db         *sql.DB
+	insertStmt *sql.Stmt
+	mu         sync.RWMutex
+}
+
+// NewSQLiteStore ouvre (ou crée) la table des anomalies dans la base SQLite donnée.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS anomalies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service TEXT NOT NULL,
+		level TEXT,
+		metric TEXT NOT NULL,
+		direction TEXT NOT NULL,
+		value REAL NOT NULL,
+		expected REAL NOT NULL,
+		score REAL NOT NULL,
+		window_start TEXT NOT NULL,
+		detected_at TEXT NOT NULL
+	);
+	`); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_anomalies_detected_at ON anomalies(detected_at DESC);`); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	stmt, err := db.Prepare(`
+	INSERT INTO anomalies(service, level, metric, direction, value, expected, score, window_start, detected_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{
+		db:         db,
+		insertStmt: stmt,
+	}, nil
+}
+
+func (s *SQLiteStore) SaveAnomaly(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.insertStmt.Exec(
+		event.Service,
+		event.Level,
+		event.Metric,
+		event.Direction,
+		event.Value,
+		event.Expected,
+		event.Score,
+		event.WindowStart.UTC().Format(utils.TimestampLayout),
+		event.DetectedAt.UTC().Format(utils.TimestampLayout),
+	)
+	return err
+}
+
+func (s *SQLiteStore) QueryAnomalies(filter Filter, page, limit int) ([]Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	page, limit, err := utils.ValidatePageLimit(page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, service, level, metric, direction, value, expected, score, window_start, detected_at FROM anomalies WHERE 1=1`
+	args := []interface{}{}
+
+	if filter.Service != "" {
+		query += " AND service = ?"
+		args = append(args, filter.Service)
+	}
+	if filter.Metric != "" {
+		query += " AND metric = ?"
+		args = append(args, filter.Metric)
+	}
+	if !filter.Since.IsZero() {
+		query += " AND detected_at >= ?"
+		args = append(args, filter.Since.UTC().Format(utils.TimestampLayout))
+	}
+
+	query += " ORDER BY detected_at DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, (page-1)*limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var ev Event
+		var level sql.NullString
+		var windowStart, detectedAt string
+		if err := rows.Scan(&ev.ID, &ev.Service, &level, &ev.Metric, &ev.Direction, &ev.Value, &ev.Expected, &ev.Score, &windowStart, &detectedAt); err != nil {
+			return nil, err
+		}
+		ev.Level = level.String
+		ev.WindowStart = utils.SafeParseTimestamp(windowStart)
+		ev.DetectedAt = utils.SafeParseTimestamp(detectedAt)
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	if s.insertStmt != nil {
+		if err := s.insertStmt.Close(); err != nil {
+			firstErr = err
+		}
+	}
+	if s.db != nil {
+		if err := s.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,83 @@
+package anomaly_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/anomaly"
+)
+
+func sampleEvent(service, metric string, detectedAt time.Time) anomaly.Event {
+	return anomaly.Event{
+		Service:     service,
+		Level:       "ERROR",
+		Metric:      metric,
+		Direction:   anomaly.DirectionSpike,
+		Value:       120,
+		Expected:    10,
+		Score:       8.5,
+		WindowStart: detectedAt.Add(-time.Minute),
+		DetectedAt:  detectedAt,
+	}
+}
+
+func TestSQLiteStore_SaveAndQuery(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "anomalies.db")
+	s, err := anomaly.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer s.Close()
+
+	base := time.Date(2025, 8, 4, 10, 0, 0, 0, time.UTC)
+	events := []anomaly.Event{
+		sampleEvent("billing", anomaly.MetricVolume, base),
+		sampleEvent("billing", anomaly.MetricErrorRate, base.Add(time.Minute)),
+		sampleEvent("auth", anomaly.MetricVolume, base.Add(2*time.Minute)),
+	}
+	for _, ev := range events {
+		if err := s.SaveAnomaly(ev); err != nil {
+			t.Fatalf("SaveAnomaly failed: %v", err)
+		}
+	}
+
+	all, err := s.QueryAnomalies(anomaly.Filter{}, 1, 10)
+	if err != nil {
+		t.Fatalf("QueryAnomalies failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 anomalies, got %d", len(all))
+	}
+	if all[0].Service != "auth" {
+		t.Errorf("expected most recent anomaly first, got %q", all[0].Service)
+	}
+
+	billing, err := s.QueryAnomalies(anomaly.Filter{Service: "billing", Metric: anomaly.MetricVolume}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(billing) != 1 || billing[0].Score != 8.5 {
+		t.Errorf("unexpected filtered result: %+v", billing)
+	}
+
+	recent, err := s.QueryAnomalies(anomaly.Filter{Since: base.Add(90 * time.Second)}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 {
+		t.Errorf("expected 1 anomaly since filter, got %d", len(recent))
+	}
+}
+
+func TestSQLiteStore_InvalidPagination(t *testing.T) {
+	s, err := anomaly.NewSQLiteStore(filepath.Join(t.TempDir(), "anomalies.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.QueryAnomalies(anomaly.Filter{}, 1, 5000); err == nil {
+		t.Error("expected error for limit too large")
+	}
+}
@@ -0,0 +1,167 @@
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// DefaultRotationGrace est la durée pendant laquelle l'ancienne clé reste valide après
+// une rotation
+const DefaultRotationGrace = 24 * time.Hour
+
+// Store est implémenté par SQLiteStore
+type Store interface {
+	Create(nk NewKey) (*Key, string, error)
+	List() ([]Key, error)
+	Revoke(id int64) (*Key, error)
+	Rotate(id int64, grace time.Duration) (*Key, string, error)
+}
+
+// CreateRequest est le corps de POST /admin/keys
+type CreateRequest struct {
+	Name      string     `json:"name" example:"fluent-bit-web"`
+	Scopes    []string   `json:"scopes" example:"[\"ingest\"]"`
+	Service   string     `json:"service,omitempty" example:"billing"`
+	Tenant    string     `json:"tenant,omitempty" example:"payments"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// RotateRequest est le corps (optionnel) de POST /admin/keys/{id}/rotate
+type RotateRequest struct {
+	Grace string `json:"grace,omitempty" example:"24h"`
+}
+
+// CreatedKey est retournée à la création et à la rotation : Key n'est affichée qu'une fois
+type CreatedKey struct {
+	Key
+	Secret string `json:"key" example:"lsk_3f9a1c0b..."`
+}
+
+// API expose la gestion des clés; les routes /admin/ exigent le scope admin
+type API struct {
+	store   Store
+	tenants TenantChecker
+}
+
+func NewAPI(store Store) *API {
+	return &API{store: store}
+}
+
+// SetTenants active l'attribution des clés à un tenant; sans lui, seules les clés du
+// tenant par défaut peuvent être créées
+func (a *API) SetTenants(tenants TenantChecker) {
+	a.tenants = tenants
+}
+
+// Register ajoute les routes /admin/keys au routeur
+func (a *API) Register(r *mux.Router) {
+	r.HandleFunc("/admin/keys", a.handleList).Methods("GET")
+	r.HandleFunc("/admin/keys", a.handleCreate).Methods("POST")
+	r.HandleFunc("/admin/keys/{id:[0-9]+}", a.handleRevoke).Methods("DELETE")
+	r.HandleFunc("/admin/keys/{id:[0-9]+}/rotate", a.handleRotate).Methods("POST")
+}
+
+func (a *API) handleList(w http.ResponseWriter, r *http.Request) {
+	keys, err := a.store.List()
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "failed to list API keys")
+		return
+	}
+	if keys == nil {
+		keys = []Key{}
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (a *API) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	nk := NewKey{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		Service:   req.Service,
+		Tenant:    req.Tenant,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := nk.Validate(time.Now()); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if nk.Tenant != "" && (a.tenants == nil || !a.tenants.Exists(nk.Tenant)) {
+		utils.WriteJSONError(w, http.StatusBadRequest, ErrUnknownTenant.Error()+": "+nk.Tenant)
+		return
+	}
+
+	key, secret, err := a.store.Create(nk)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "failed to create API key")
+		return
+	}
+	writeJSON(w, http.StatusCreated, CreatedKey{Key: *key, Secret: secret})
+}
+
+func (a *API) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	key, err := a.store.Revoke(id)
+	if err != nil {
+		writeStoreError(w, err, "failed to revoke API key")
+		return
+	}
+	writeJSON(w, http.StatusOK, key)
+}
+
+func (a *API) handleRotate(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+
+	grace := DefaultRotationGrace
+	var req RotateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+			utils.WriteJSONError(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+	}
+	if req.Grace != "" {
+		d, err := time.ParseDuration(req.Grace)
+		if err != nil || d < 0 {
+			utils.WriteJSONError(w, http.StatusBadRequest, "invalid 'grace' parameter")
+			return
+		}
+		grace = d
+	}
+
+	key, secret, err := a.store.Rotate(id, grace)
+	if err != nil {
+		writeStoreError(w, err, "failed to rotate API key")
+		return
+	}
+	writeJSON(w, http.StatusCreated, CreatedKey{Key: *key, Secret: secret})
+}
+
+func writeStoreError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		utils.WriteJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInactive):
+		utils.WriteJSONError(w, http.StatusConflict, err.Error())
+	default:
+		utils.WriteJSONError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,163 @@
+package apikey_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/apikey"
+)
+
+func newTestRouter(t *testing.T) (*mux.Router, *apikey.SQLiteStore) {
+	t.Helper()
+	store, err := apikey.NewSQLiteStore(filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	r := mux.NewRouter()
+	apikey.NewAPI(store).Register(r)
+	return r, store
+}
+
+func do(r http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+	return w
+}
+
+func TestAPI_CreateListRevoke(t *testing.T) {
+	r, store := newTestRouter(t)
+
+	w := do(r, "POST", "/admin/keys", `{"name":"fluent-bit","scopes":["ingest"],"service":"billing"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created apikey.CreatedKey
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Secret == "" || created.Name != "fluent-bit" || created.Service != "billing" {
+		t.Fatalf("unexpected created key: %+v", created)
+	}
+	if _, err := store.Authenticate(created.Secret); err != nil {
+		t.Errorf("expected the returned secret to authenticate, got %v", err)
+	}
+
+	w = do(r, "GET", "/admin/keys", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte(created.Secret)) {
+		t.Error("expected the list not to expose secrets")
+	}
+	var listed []apikey.Key
+	json.NewDecoder(w.Body).Decode(&listed)
+	if len(listed) != 1 || listed[0].ID != created.ID {
+		t.Errorf("expected the created key in the list, got %+v", listed)
+	}
+
+	w = do(r, "DELETE", "/admin/keys/1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if _, err := store.Authenticate(created.Secret); err == nil {
+		t.Error("expected the revoked key to be rejected")
+	}
+
+	if w := do(r, "DELETE", "/admin/keys/42", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown key, got %d", w.Code)
+	}
+}
+
+func TestAPI_CreateValidation(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	for _, body := range []string{
+		`not json`,
+		`{"scopes":["read"]}`,
+		`{"name":"x","scopes":[]}`,
+		`{"name":"x","scopes":["write"]}`,
+		`{"name":"x","scopes":["read"],"expires_at":"2000-01-01T00:00:00Z"}`,
+	} {
+		if w := do(r, "POST", "/admin/keys", body); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+type tenants map[string]bool
+
+func (t tenants) Exists(name string) bool { return t[name] }
+
+func TestAPI_CreateForTenant(t *testing.T) {
+	r, _ := newTestRouter(t)
+
+	body := `{"name":"acme-agent","scopes":["ingest"],"tenant":"acme"}`
+	if w := do(r, "POST", "/admin/keys", body); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 when tenants are not enabled, got %d", w.Code)
+	}
+
+	store, err := apikey.NewSQLiteStore(filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	api := apikey.NewAPI(store)
+	api.SetTenants(tenants{"acme": true})
+	r = mux.NewRouter()
+	api.Register(r)
+
+	w := do(r, "POST", "/admin/keys", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created apikey.CreatedKey
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Tenant != "acme" {
+		t.Errorf("expected a key of tenant acme, got %+v", created)
+	}
+	if w := do(r, "POST", "/admin/keys", `{"name":"x","scopes":["read"],"tenant":"globex"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown tenant, got %d", w.Code)
+	}
+}
+
+func TestAPI_Rotate(t *testing.T) {
+	r, store := newTestRouter(t)
+
+	old, oldSecret, err := store.Create(apikey.NewKey{Name: "web", Scopes: []string{apikey.ScopeRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := do(r, "POST", "/admin/keys/1/rotate", `{"grace":"1h"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var rotated apikey.CreatedKey
+	json.NewDecoder(w.Body).Decode(&rotated)
+	if rotated.ID == old.ID || rotated.Name != "web" || rotated.Secret == "" {
+		t.Errorf("unexpected rotated key: %+v", rotated)
+	}
+	if _, err := store.Authenticate(oldSecret); err != nil {
+		t.Errorf("expected the old key to stay valid during the grace period, got %v", err)
+	}
+
+	if w := do(r, "POST", "/admin/keys/1/rotate", `{"grace":"soon"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid grace, got %d", w.Code)
+	}
+
+	// Sans délai de grâce l'ancienne clé expire aussitôt; une nouvelle rotation est refusée
+	if w := do(r, "POST", "/admin/keys/2/rotate", `{"grace":"0s"}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+	if w := do(r, "POST", "/admin/keys/2/rotate", ""); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for an expired key, got %d", w.Code)
+	}
+}
@@ -0,0 +1,262 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+)
+
+// Scopes d'une clé. admin donne aussi ingest et read.
+const (
+	ScopeIngest = "ingest"
+	ScopeRead   = "read"
+	ScopeAdmin  = "admin"
+)
+
+// Préfixe des clés générées, pour les reconnaître dans une configuration ou un scanner de secrets
+const keyPrefix = "lsk_"
+
+// Nombre de caractères de la clé conservés en clair pour l'identifier dans les listes
+const displayPrefixLen = len(keyPrefix) + 8
+
+// Nom de la clé LOGGER_API_KEY dans les audits
+const BootstrapName = "bootstrap"
+
+var (
+	ErrInvalidKey        = errors.New("invalid API key")
+	ErrNotFound          = errors.New("API key not found")
+	ErrInvalidScope      = errors.New("invalid scope")
+	ErrInactive          = errors.New("API key is revoked or expired")
+	ErrServiceNotAllowed = errors.New("service not allowed for this API key")
+	ErrUnknownTenant     = errors.New("unknown tenant")
+)
+
+// Key décrit une clé API; la valeur de la clé n'est jamais conservée, seulement son empreinte
+type Key struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name" example:"fluent-bit-web"`
+	Prefix     string     `json:"prefix" example:"lsk_3f9a1c0b"`
+	Scopes     []string   `json:"scopes" example:"[\"ingest\"]"`
+	Service    string     `json:"service,omitempty" example:"billing"`
+	Tenant     string     `json:"tenant,omitempty" example:"payments"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Allows indique si la clé donne accès au scope (scope vide : toute clé valide)
+func (k *Key) Allows(scope string) bool {
+	if scope == "" {
+		return true
+	}
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Active indique si la clé est utilisable à l'instant donné
+func (k *Key) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// ValidateScopes vérifie une liste de scopes et la retourne sans doublons
+func ValidateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != ScopeIngest && s != ScopeRead && s != ScopeAdmin {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// ParseScopeMap lit une correspondance "nom=scope,nom=scope" (groupes SSO, identités de
+// certificats); un nom peut être répété pour recevoir plusieurs scopes
+func ParseScopeMap(raw string) (map[string][]string, error) {
+	out := make(map[string][]string)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, scope, ok := strings.Cut(item, "=")
+		name, scope = strings.TrimSpace(name), strings.TrimSpace(scope)
+		if !ok || name == "" || scope == "" {
+			return nil, fmt.Errorf("invalid scope mapping %q (expected name=scope)", item)
+		}
+		out[name] = append(out[name], scope)
+	}
+	return out, nil
+}
+
+// Generate retourne une nouvelle clé en clair et son empreinte
+func Generate() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, Hash(key), nil
+}
+
+// Hash retourne l'empreinte SHA-256 d'une clé. Les clés générées ont 256 bits d'entropie :
+// un hash lent (bcrypt, ...) n'apporterait rien et coûterait à chaque requête.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func displayPrefix(key string) string {
+	if len(key) <= displayPrefixLen {
+		return key
+	}
+	return key[:displayPrefixLen]
+}
+
+// Authenticator retrouve la clé correspondant à une valeur en clair
+type Authenticator interface {
+	Authenticate(key string) (*Key, error)
+}
+
+// Keyring accepte la clé de démarrage (LOGGER_API_KEY, scope admin) puis les clés du store
+type Keyring struct {
+	bootstrap string
+	store     Authenticator
+}
+
+func NewKeyring(bootstrap string, store Authenticator) *Keyring {
+	return &Keyring{bootstrap: bootstrap, store: store}
+}
+
+func (k *Keyring) Authenticate(key string) (*Key, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+	if k.bootstrap != "" && subtle.ConstantTimeCompare([]byte(key), []byte(k.bootstrap)) == 1 {
+		return &Key{Name: BootstrapName, Scopes: []string{ScopeAdmin}}, nil
+	}
+	if k.store == nil {
+		return nil, ErrInvalidKey
+	}
+	return k.store.Authenticate(key)
+}
+
+// Sondes envoyées par les agents d'ingestion (Beats, Splunk) avant leurs envois
+var ingestProbes = map[string]bool{
+	"/":                              true,
+	"/_license":                      true,
+	"/_xpack":                        true,
+	"/_cluster/health":               true,
+	"/services/collector/health":     true,
+	"/services/collector/health/1.0": true,
+}
+
+// ScopeFor retourne le scope requis par une route : admin sous /admin/, ingest pour les
+// écritures et les sondes des agents, read pour les autres lectures. /health est ouvert
+// à toute clé valide.
+func ScopeFor(method, path string) string {
+	switch {
+	case strings.HasPrefix(path, "/admin/"):
+		return ScopeAdmin
+	case path == "/health":
+		return ""
+	case method != http.MethodGet && method != http.MethodHead:
+		return ScopeIngest
+	case ingestProbes[path]:
+		return ScopeIngest
+	}
+	return ScopeRead
+}
+
+// Routes globales au serveur, fermées aux clés d'un tenant quels que soient leurs scopes :
+// elles exposent des données ou des réglages de tous les tenants
+var globalPrefixes = []string{"/admin/", "/anomalies", "/metrics"}
+
+// TenantAllowed indique si une clé du tenant donné peut accéder à la route. Les clés du
+// tenant par défaut ("") accèdent à tout ce que leurs scopes permettent.
+func TenantAllowed(tenant, path string) bool {
+	if tenant == "" {
+		return true
+	}
+	for _, prefix := range globalPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// TenantChecker vérifie qu'un tenant existe (tenant.SQLiteStore)
+type TenantChecker interface {
+	Exists(name string) bool
+}
+
+type ctxKey struct{}
+
+// WithKey attache la clé authentifiée au contexte de la requête
+func WithKey(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, ctxKey{}, key)
+}
+
+// FromContext retourne la clé authentifiée, ou nil
+func FromContext(ctx context.Context) *Key {
+	key, _ := ctx.Value(ctxKey{}).(*Key)
+	return key
+}
+
+// TenantFromContext retourne le tenant de la clé authentifiée ("" : tenant par défaut)
+func TenantFromContext(ctx context.Context) string {
+	if key := FromContext(ctx); key != nil {
+		return key.Tenant
+	}
+	return ""
+}
+
+// Bind applique les liaisons de la clé du contexte : l'entrée est rangée dans le tenant de
+// la clé et attribuée à son nom (quotas), et une entrée sans service reçoit celui de la
+// clé, une entrée d'un autre service étant refusée (erreur de validation).
+func Bind(ctx context.Context, ingest internal.IngestFunc) internal.IngestFunc {
+	key := FromContext(ctx)
+	if key == nil {
+		return ingest
+	}
+	return func(entry *internal.LogEntry) error {
+		entry.Tenant = key.Tenant
+		entry.KeyName = key.Name
+		if key.Service == "" {
+			return ingest(entry)
+		}
+		if entry.Service == "" {
+			entry.Service = key.Service
+		} else if entry.Service != key.Service {
+			return fmt.Errorf("%w: %s", ErrServiceNotAllowed, entry.Service)
+		}
+		return ingest(entry)
+	}
+}
@@ -0,0 +1,211 @@
+package apikey_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/apikey"
+)
+
+func TestScopeFor(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"POST", "/log", apikey.ScopeIngest},
+		{"POST", "/log/batch", apikey.ScopeIngest},
+		{"PUT", "/_bulk", apikey.ScopeIngest},
+		{"GET", "/log", apikey.ScopeRead},
+		{"GET", "/ui/api/search", apikey.ScopeRead},
+		{"GET", "/anomalies", apikey.ScopeRead},
+		{"HEAD", "/", apikey.ScopeIngest},
+		{"GET", "/_license", apikey.ScopeIngest},
+		{"GET", "/services/collector/health", apikey.ScopeIngest},
+		{"GET", "/admin/keys", apikey.ScopeAdmin},
+		{"DELETE", "/admin/keys/3", apikey.ScopeAdmin},
+		{"GET", "/health", ""},
+	}
+	for _, tt := range tests {
+		if got := apikey.ScopeFor(tt.method, tt.path); got != tt.want {
+			t.Errorf("ScopeFor(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestKey_Allows(t *testing.T) {
+	ingest := &apikey.Key{Scopes: []string{apikey.ScopeIngest}}
+	admin := &apikey.Key{Scopes: []string{apikey.ScopeAdmin}}
+
+	if !ingest.Allows(apikey.ScopeIngest) || ingest.Allows(apikey.ScopeRead) || ingest.Allows(apikey.ScopeAdmin) {
+		t.Error("expected an ingest key to allow ingest only")
+	}
+	if !ingest.Allows("") {
+		t.Error("expected any key to allow routes without scope")
+	}
+	for _, scope := range []string{apikey.ScopeIngest, apikey.ScopeRead, apikey.ScopeAdmin} {
+		if !admin.Allows(scope) {
+			t.Errorf("expected admin to imply %s", scope)
+		}
+	}
+}
+
+func TestKey_Active(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	if !(&apikey.Key{}).Active(now) || !(&apikey.Key{ExpiresAt: &future}).Active(now) {
+		t.Error("expected keys without expiry or expiring later to be active")
+	}
+	if (&apikey.Key{ExpiresAt: &past}).Active(now) || (&apikey.Key{RevokedAt: &past}).Active(now) {
+		t.Error("expected expired and revoked keys to be inactive")
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	got, err := apikey.ValidateScopes([]string{" Ingest", "read", "ingest"})
+	if err != nil {
+		t.Fatalf("ValidateScopes failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != "ingest" || got[1] != "read" {
+		t.Errorf("expected normalized scopes without duplicates, got %v", got)
+	}
+
+	for _, scopes := range [][]string{nil, {"write"}} {
+		if _, err := apikey.ValidateScopes(scopes); !errors.Is(err, apikey.ErrInvalidScope) {
+			t.Errorf("expected ErrInvalidScope for %v, got %v", scopes, err)
+		}
+	}
+}
+
+func TestParseScopeMap(t *testing.T) {
+	got, err := apikey.ParseScopeMap(" sre=admin, dev=read ,dev=ingest,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["sre"][0] != "admin" || strings.Join(got["dev"], ",") != "read,ingest" {
+		t.Errorf("unexpected mapping: %v", got)
+	}
+	if _, err := apikey.ParseScopeMap("sre"); err == nil {
+		t.Error("expected an error for a mapping without scope")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	a, hashA, err := apikey.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _, _ := apikey.Generate()
+	if a == b || len(a) < 40 || a[:4] != "lsk_" {
+		t.Errorf("expected distinct prefixed keys, got %q and %q", a, b)
+	}
+	if hashA != apikey.Hash(a) || hashA == a {
+		t.Error("expected the returned hash to match Hash(key)")
+	}
+}
+
+// keys associe des clés en clair à leur description
+type keys map[string]*apikey.Key
+
+func (k keys) Authenticate(key string) (*apikey.Key, error) {
+	if found, ok := k[key]; ok {
+		return found, nil
+	}
+	return nil, apikey.ErrInvalidKey
+}
+
+func TestKeyring(t *testing.T) {
+	ring := apikey.NewKeyring("bootstrap-secret", keys{"lsk_stored": {Name: "stored", Scopes: []string{apikey.ScopeRead}}})
+
+	key, err := ring.Authenticate("bootstrap-secret")
+	if err != nil || key.Name != apikey.BootstrapName || !key.Allows(apikey.ScopeAdmin) {
+		t.Errorf("expected the bootstrap key to be an admin key, got %+v, %v", key, err)
+	}
+	if key, err := ring.Authenticate("lsk_stored"); err != nil || key.Name != "stored" {
+		t.Errorf("expected the stored key, got %+v, %v", key, err)
+	}
+	for _, k := range []string{"", "wrong"} {
+		if _, err := ring.Authenticate(k); !errors.Is(err, apikey.ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey for %q, got %v", k, err)
+		}
+	}
+
+	if _, err := apikey.NewKeyring("", nil).Authenticate(""); !errors.Is(err, apikey.ErrInvalidKey) {
+		t.Errorf("expected an empty bootstrap key to match nothing, got %v", err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	var stored []*internal.LogEntry
+	ingest := func(entry *internal.LogEntry) error {
+		stored = append(stored, entry)
+		return nil
+	}
+
+	unbound := apikey.WithKey(context.Background(), &apikey.Key{Name: "any"})
+	if err := apikey.Bind(unbound, ingest)(&internal.LogEntry{Service: "payments"}); err != nil {
+		t.Errorf("expected unbound keys to ingest any service, got %v", err)
+	}
+
+	bound := apikey.WithKey(context.Background(), &apikey.Key{Name: "billing", Service: "billing"})
+	bind := apikey.Bind(bound, ingest)
+	if err := bind(&internal.LogEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bind(&internal.LogEntry{Service: "billing"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bind(&internal.LogEntry{Service: "payments"}); !errors.Is(err, apikey.ErrServiceNotAllowed) {
+		t.Errorf("expected ErrServiceNotAllowed, got %v", err)
+	}
+
+	if len(stored) != 3 || stored[1].Service != "billing" || stored[2].Service != "billing" {
+		t.Errorf("expected the key service to fill empty entries, got %+v", stored)
+	}
+}
+
+func TestBind_Tenant(t *testing.T) {
+	var stored *internal.LogEntry
+	ingest := func(entry *internal.LogEntry) error {
+		stored = entry
+		return nil
+	}
+
+	ctx := apikey.WithKey(context.Background(), &apikey.Key{Name: "acme-agent", Tenant: "acme"})
+	if err := apikey.Bind(ctx, ingest)(&internal.LogEntry{Tenant: "globex", Service: "web"}); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Tenant != "acme" || stored.Service != "web" || stored.KeyName != "acme-agent" {
+		t.Errorf("expected the entry to be stored in the key tenant, got %+v", stored)
+	}
+	if got := apikey.TenantFromContext(ctx); got != "acme" {
+		t.Errorf("expected tenant acme from context, got %q", got)
+	}
+	if got := apikey.TenantFromContext(context.Background()); got != "" {
+		t.Errorf("expected the default tenant without a key, got %q", got)
+	}
+}
+
+func TestTenantAllowed(t *testing.T) {
+	tests := []struct {
+		tenant, path string
+		want         bool
+	}{
+		{"", "/admin/keys", true},
+		{"", "/anomalies", true},
+		{"acme", "/log", true},
+		{"acme", "/ui/api/search", true},
+		{"acme", "/admin/keys", false},
+		{"acme", "/admin/tenants", false},
+		{"acme", "/anomalies", false},
+		{"acme", "/metrics", false},
+	}
+	for _, tt := range tests {
+		if got := apikey.TenantAllowed(tt.tenant, tt.path); got != tt.want {
+			t.Errorf("TenantAllowed(%q, %s) = %v, want %v", tt.tenant, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package apikey
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// En-têtes d'une requête signée : la clé n'est jamais envoyée, seulement son préfixe
+// affiché (Key.Prefix) et une signature HMAC-SHA256 de la requête
+const (
+	HeaderSignatureKey       = "X-Signature-Key"
+	HeaderSignatureTimestamp = "X-Signature-Timestamp" // secondes Unix
+	HeaderSignatureNonce     = "X-Signature-Nonce"
+	HeaderSignature          = "X-Signature" // HMAC-SHA256 en hexadécimal
+)
+
+// MinSigningKeyLen est la longueur minimale de la clé serveur qui chiffre les secrets de
+// signature (SQLiteStore.SetSigningKey)
+const MinSigningKeyLen = 32
+
+// SignatureAuthenticator retrouve la clé dont le secret de signature valide une requête
+type SignatureAuthenticator interface {
+	AuthenticateSignature(prefix, stringToSign, signature string) (*Key, error)
+}
+
+// signingLabel distingue le secret de signature de l'empreinte Hash
+const signingLabel = "logger-server request signing v1"
+
+// SigningSecret retourne le secret HMAC d'une clé, que le client calcule à partir de la
+// clé : HMAC-SHA256(clé, signingLabel). Il ne se déduit pas de l'empreinte Hash (key_hash);
+// le serveur le conserve chiffré (SQLiteStore.SetSigningKey).
+func SigningSecret(key string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(signingLabel))
+	return mac.Sum(nil)
+}
+
+// newSecretCipher retourne le chiffrement (AES-256-GCM) des secrets de signature conservés,
+// dont la clé est dérivée de serverKey
+func newSecretCipher(serverKey string) (cipher.AEAD, error) {
+	if len(serverKey) < MinSigningKeyLen {
+		return nil, fmt.Errorf("signing key must be at least %d characters", MinSigningKeyLen)
+	}
+	sum := sha256.Sum256([]byte(serverKey))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealSecret chiffre le secret de signature d'une clé, lié à son empreinte : un secret
+// recopié sur une autre ligne ne se déchiffre pas
+func sealSecret(aead cipher.AEAD, secret []byte, keyHash string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(aead.Seal(nonce, nonce, secret, []byte(keyHash))), nil
+}
+
+func openSecret(aead cipher.AEAD, sealed, keyHash string) ([]byte, error) {
+	raw, err := hex.DecodeString(sealed)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return nil, errors.New("invalid signing secret")
+	}
+	return aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(keyHash))
+}
+
+// StringToSign construit le texte signé : méthode, chemin avec la query, horodatage, nonce
+// et empreinte SHA-256 du corps tel qu'envoyé (compressé le cas échéant), un par ligne
+func StringToSign(method, requestURI, timestamp, nonce string, body []byte) string {
+	digest := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		requestURI,
+		timestamp,
+		nonce,
+		hex.EncodeToString(digest[:]),
+	}, "\n")
+}
+
+// Sign retourne la signature hexadécimale de stringToSign
+func Sign(secret []byte, stringToSign string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature compare la signature en temps constant
+func VerifySignature(secret []byte, stringToSign, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(stringToSign))
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// AuthenticateSignature transmet au store : la clé de démarrage, dont le préfixe pourrait
+// révéler une partie, ne signe pas
+func (k *Keyring) AuthenticateSignature(prefix, stringToSign, signature string) (*Key, error) {
+	store, ok := k.store.(SignatureAuthenticator)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return store.AuthenticateSignature(prefix, stringToSign, signature)
+}
@@ -0,0 +1,63 @@
+package apikey_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rypi-dev/logger-server/internal/apikey"
+)
+
+// Vecteur de référence pour les implémentations clientes
+const (
+	vectorKey       = "lsk_test"
+	vectorSignature = "906e5b111664365353f73183df27e8b02804646add950cffc73e2d907f1ac865"
+)
+
+func TestSign_Vector(t *testing.T) {
+	sts := apikey.StringToSign("post", "/log/batch", "1756720800", "0123456789abcdef", []byte(`[{"message":"hello"}]`))
+	if lines := strings.Split(sts, "\n"); len(lines) != 5 || lines[0] != "POST" {
+		t.Fatalf("unexpected string to sign %q", sts)
+	}
+	if got := apikey.Sign(apikey.SigningSecret(vectorKey), sts); got != vectorSignature {
+		t.Errorf("expected %s, got %s", vectorSignature, got)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := apikey.SigningSecret(vectorKey)
+	sts := apikey.StringToSign("POST", "/log", "1756720800", "n1", []byte(`{}`))
+	sig := apikey.Sign(secret, sts)
+
+	if !apikey.VerifySignature(secret, sts, sig) {
+		t.Error("expected valid signature")
+	}
+	if !apikey.VerifySignature(secret, sts, strings.ToUpper(sig)) {
+		t.Error("expected hex case not to matter")
+	}
+	tampered := apikey.StringToSign("POST", "/log", "1756720800", "n1", []byte(`{"a":1}`))
+	if apikey.VerifySignature(secret, tampered, sig) {
+		t.Error("expected signature to cover the body")
+	}
+	if apikey.VerifySignature(secret, sts, "not-hex") {
+		t.Error("expected malformed signature to be rejected")
+	}
+}
+
+type signingStore struct{ key *apikey.Key }
+
+func (s signingStore) Authenticate(string) (*apikey.Key, error) { return nil, apikey.ErrInvalidKey }
+
+func (s signingStore) AuthenticateSignature(prefix, sts, sig string) (*apikey.Key, error) {
+	return s.key, nil
+}
+
+func TestKeyring_AuthenticateSignature(t *testing.T) {
+	agent := &apikey.Key{Name: "agent"}
+	if key, err := apikey.NewKeyring("boot", signingStore{agent}).AuthenticateSignature("lsk_1", "", ""); err != nil || key != agent {
+		t.Errorf("expected store key, got %v, %v", key, err)
+	}
+	if _, err := apikey.NewKeyring("boot", nil).AuthenticateSignature("boot", "", ""); !errors.Is(err, apikey.ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey without a signing store, got %v", err)
+	}
+}
@@ -0,0 +1,348 @@
+package apikey
+
+import (
+	"crypto/cipher"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// Fréquence maximale de mise à jour de last_used_at par clé (une écriture par requête
+// sinon)
+const lastUsedResolution = time.Minute
+
+// NewKey décrit une clé à créer
+type NewKey struct {
+	Name      string
+	Scopes    []string
+	Service   string
+	Tenant    string
+	ExpiresAt *time.Time
+}
+
+// Validate vérifie une demande de création et normalise son nom et ses scopes
+func (nk *NewKey) Validate(now time.Time) error {
+	nk.Name = strings.TrimSpace(nk.Name)
+	if nk.Name == "" {
+		return errors.New("name is required")
+	}
+	scopes, err := ValidateScopes(nk.Scopes)
+	if err != nil {
+		return err
+	}
+	nk.Scopes = scopes
+	if nk.ExpiresAt != nil && !nk.ExpiresAt.After(now) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
+
+// SQLiteStore conserve les clés API (empreintes uniquement) dans la table api_keys, avec
+// leur secret de signature chiffré
+type SQLiteStore struct {
+	db     *sql.DB
+	now    func() time.Time
+	secret cipher.AEAD // nil : pas de signature
+
+	mu       sync.Mutex
+	lastUsed map[int64]time.Time
+}
+
+// NewSQLiteStore ouvre (ou crée) la table des clés API dans la base SQLite donnée.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		service TEXT NOT NULL DEFAULT '',
+		tenant TEXT NOT NULL DEFAULT '',
+		signing_secret TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL,
+		expires_at TEXT,
+		revoked_at TEXT,
+		last_used_at TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys(prefix);
+	`); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Migration des tables créées avant les tenants (les clés existantes appartiennent au
+	// tenant par défaut) et avant les secrets de signature (les clés existantes ne signent
+	// pas avant leur rotation)
+	for _, column := range []string{"tenant", "signing_secret"} {
+		if err := addColumn(db, column); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return &SQLiteStore{
+		db:       db,
+		now:      time.Now,
+		lastUsed: make(map[int64]time.Time),
+	}, nil
+}
+
+// addColumn ajoute à api_keys une colonne texte vide par défaut si elle manque
+func addColumn(db *sql.DB, column string) error {
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('api_keys') WHERE name = ?`, column).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return nil
+	}
+	_, err := db.Exec(`ALTER TABLE api_keys ADD COLUMN ` + column + ` TEXT NOT NULL DEFAULT ''`)
+	return err
+}
+
+// SetSigningKey active les requêtes signées : le secret de signature de chaque clé créée
+// ensuite est conservé chiffré avec serverKey (MinSigningKeyLen caractères au moins), qui
+// ne doit pas être stockée avec la base
+func (s *SQLiteStore) SetSigningKey(serverKey string) error {
+	aead, err := newSecretCipher(serverKey)
+	if err != nil {
+		return err
+	}
+	s.secret = aead
+	return nil
+}
+
+const selectKey = `SELECT id, name, prefix, scopes, service, tenant, created_at, expires_at, revoked_at, last_used_at FROM api_keys`
+
+// Create génère une clé et retourne sa description et sa valeur en clair, qui n'est
+// plus récupérable ensuite
+func (s *SQLiteStore) Create(nk NewKey) (*Key, string, error) {
+	now := s.now().UTC()
+	if err := nk.Validate(now); err != nil {
+		return nil, "", err
+	}
+
+	plaintext, hash, err := Generate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	sealed := ""
+	if s.secret != nil {
+		if sealed, err = sealSecret(s.secret, SigningSecret(plaintext), hash); err != nil {
+			return nil, "", err
+		}
+	}
+
+	res, err := s.db.Exec(`INSERT INTO api_keys(name, prefix, key_hash, scopes, service, tenant, signing_secret, created_at, expires_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		nk.Name, displayPrefix(plaintext), hash, strings.Join(nk.Scopes, ","), nk.Service, nk.Tenant, sealed,
+		now.Format(utils.TimestampLayout), formatTime(nk.ExpiresAt))
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := s.Get(id)
+	return key, plaintext, err
+}
+
+// Authenticate retrouve une clé active par sa valeur en clair
+func (s *SQLiteStore) Authenticate(plaintext string) (*Key, error) {
+	key, err := scanKey(s.db.QueryRow(selectKey+` WHERE key_hash = ?`, Hash(plaintext)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if !key.Active(now) {
+		return nil, ErrInactive
+	}
+	s.touch(key.ID, now)
+	return key, nil
+}
+
+// AuthenticateSignature retrouve une clé active par son préfixe et la signature d'une
+// requête (voir StringToSign). Deux clés peuvent partager un préfixe : chacune est essayée.
+// Seules les clés créées avec SetSigningKey ont un secret de signature.
+func (s *SQLiteStore) AuthenticateSignature(prefix, stringToSign, signature string) (*Key, error) {
+	if s.secret == nil {
+		return nil, ErrInvalidKey
+	}
+	rows, err := s.db.Query(`SELECT id, key_hash, signing_secret FROM api_keys WHERE prefix = ? AND signing_secret != ''`, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var id int64
+	found := false
+	for rows.Next() && !found {
+		var hash, sealed string
+		if err := rows.Scan(&id, &hash, &sealed); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// Un secret chiffré avec une autre clé serveur ne valide rien
+		if secret, err := openSecret(s.secret, sealed, hash); err == nil {
+			found = VerifySignature(secret, stringToSign, signature)
+		}
+	}
+	rows.Close()
+	if !found {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if !key.Active(now) {
+		return nil, ErrInactive
+	}
+	s.touch(key.ID, now)
+	return key, nil
+}
+
+// touch met à jour last_used_at au plus une fois par lastUsedResolution
+func (s *SQLiteStore) touch(id int64, now time.Time) {
+	s.mu.Lock()
+	if last, ok := s.lastUsed[id]; ok && now.Sub(last) < lastUsedResolution {
+		s.mu.Unlock()
+		return
+	}
+	s.lastUsed[id] = now
+	s.mu.Unlock()
+
+	// Information indicative : une erreur ici ne doit pas refuser la requête
+	s.db.Exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`, now.UTC().Format(utils.TimestampLayout), id)
+}
+
+func (s *SQLiteStore) Get(id int64) (*Key, error) {
+	key, err := scanKey(s.db.QueryRow(selectKey+` WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return key, err
+}
+
+// List retourne toutes les clés, révoquées et expirées comprises, des plus récentes aux
+// plus anciennes
+func (s *SQLiteStore) List() ([]Key, error) {
+	rows, err := s.db.Query(selectKey + ` ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []Key
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke désactive immédiatement une clé; révoquer une clé déjà révoquée est sans effet
+func (s *SQLiteStore) Revoke(id int64) (*Key, error) {
+	if _, err := s.db.Exec(`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`,
+		s.now().UTC().Format(utils.TimestampLayout), id); err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// PurgeTenant révoque toutes les clés encore actives d'un tenant supprimé
+func (s *SQLiteStore) PurgeTenant(tenant string) error {
+	if tenant == "" {
+		return errors.New("the default tenant cannot be purged")
+	}
+	_, err := s.db.Exec(`UPDATE api_keys SET revoked_at = ? WHERE tenant = ? AND revoked_at IS NULL`,
+		s.now().UTC().Format(utils.TimestampLayout), tenant)
+	return err
+}
+
+// Rotate crée une clé de mêmes nom, scopes, service et tenant, et fait expirer l'ancienne après
+// grace pour laisser le temps de déployer la nouvelle. Une expiration plus proche de
+// l'ancienne clé est conservée.
+func (s *SQLiteStore) Rotate(id int64, grace time.Duration) (*Key, string, error) {
+	old, err := s.Get(id)
+	if err != nil {
+		return nil, "", err
+	}
+	now := s.now()
+	if !old.Active(now) {
+		return nil, "", ErrInactive
+	}
+
+	expiresAt := now.Add(grace).UTC()
+	if old.ExpiresAt == nil || expiresAt.Before(*old.ExpiresAt) {
+		if _, err := s.db.Exec(`UPDATE api_keys SET expires_at = ? WHERE id = ?`,
+			expiresAt.Format(utils.TimestampLayout), id); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// La nouvelle clé ne reprend pas l'expiration de l'ancienne : une rotation prolonge l'accès
+	return s.Create(NewKey{Name: old.Name, Scopes: old.Scopes, Service: old.Service, Tenant: old.Tenant})
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanKey(row scanner) (*Key, error) {
+	var key Key
+	var scopes, createdAt string
+	var expiresAt, revokedAt, lastUsedAt sql.NullString
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.Service, &key.Tenant, &createdAt, &expiresAt, &revokedAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	key.Scopes = strings.Split(scopes, ",")
+	key.CreatedAt = utils.SafeParseTimestamp(createdAt)
+	key.ExpiresAt = parseTime(expiresAt)
+	key.RevokedAt = parseTime(revokedAt)
+	key.LastUsedAt = parseTime(lastUsedAt)
+	return &key, nil
+}
+
+func formatTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(utils.TimestampLayout)
+}
+
+func parseTime(s sql.NullString) *time.Time {
+	if !s.Valid || s.String == "" {
+		return nil
+	}
+	t := utils.SafeParseTimestamp(s.String)
+	return &t
+}
@@ -0,0 +1,363 @@
+package apikey
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+func newTestStore(t *testing.T) (*SQLiteStore, *time.Time) {
+	t.Helper()
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.SetSigningKey(testSigningKey); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestSQLiteStore_CreateAndAuthenticate(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	key, secret, err := s.Create(NewKey{Name: " web ", Scopes: []string{"Ingest"}, Service: "billing"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if key.Name != "web" || key.Scopes[0] != ScopeIngest || key.Service != "billing" {
+		t.Errorf("unexpected key: %+v", key)
+	}
+	if key.Prefix != secret[:displayPrefixLen] {
+		t.Errorf("expected prefix %q, got %q", secret[:displayPrefixLen], key.Prefix)
+	}
+
+	var stored string
+	if err := s.db.QueryRow(`SELECT key_hash FROM api_keys WHERE id = ?`, key.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != Hash(secret) {
+		t.Error("expected only the hash of the key to be stored")
+	}
+
+	got, err := s.Authenticate(secret)
+	if err != nil || got.ID != key.ID {
+		t.Fatalf("expected to authenticate the created key, got %+v, %v", got, err)
+	}
+	if _, err := s.Authenticate(secret + "x"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+
+	if _, _, err := s.Create(NewKey{Name: "bad", Scopes: []string{"write"}}); !errors.Is(err, ErrInvalidScope) {
+		t.Errorf("expected ErrInvalidScope, got %v", err)
+	}
+}
+
+func TestSQLiteStore_Expiry(t *testing.T) {
+	s, now := newTestStore(t)
+
+	expiresAt := now.Add(time.Hour)
+	_, secret, err := s.Create(NewKey{Name: "temp", Scopes: []string{ScopeRead}, ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(secret); err != nil {
+		t.Fatalf("expected the key to be valid before expiry, got %v", err)
+	}
+
+	*now = now.Add(time.Hour)
+	if _, err := s.Authenticate(secret); !errors.Is(err, ErrInactive) {
+		t.Errorf("expected ErrInactive after expiry, got %v", err)
+	}
+
+	past := now.Add(-time.Second)
+	if _, _, err := s.Create(NewKey{Name: "late", Scopes: []string{ScopeRead}, ExpiresAt: &past}); err == nil {
+		t.Error("expected an expiry in the past to be rejected")
+	}
+}
+
+func TestSQLiteStore_Revoke(t *testing.T) {
+	s, now := newTestStore(t)
+
+	key, secret, err := s.Create(NewKey{Name: "web", Scopes: []string{ScopeIngest}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := s.Revoke(key.ID)
+	if err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if revoked.RevokedAt == nil || !revoked.RevokedAt.Equal(*now) {
+		t.Errorf("expected revoked_at to be set, got %v", revoked.RevokedAt)
+	}
+	if _, err := s.Authenticate(secret); !errors.Is(err, ErrInactive) {
+		t.Errorf("expected ErrInactive after revocation, got %v", err)
+	}
+
+	*now = now.Add(time.Hour)
+	again, err := s.Revoke(key.ID)
+	if err != nil || !again.RevokedAt.Equal(*revoked.RevokedAt) {
+		t.Errorf("expected a second revocation to keep the first date, got %v, %v", again.RevokedAt, err)
+	}
+	if _, err := s.Revoke(999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSQLiteStore_RotateOverlap(t *testing.T) {
+	s, now := newTestStore(t)
+
+	old, oldSecret, err := s.Create(NewKey{Name: "web", Scopes: []string{ScopeIngest, ScopeRead}, Service: "billing", Tenant: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, newSecret, err := s.Rotate(old.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if rotated.ID == old.ID || rotated.Name != "web" || len(rotated.Scopes) != 2 || rotated.Service != "billing" || rotated.Tenant != "acme" {
+		t.Errorf("expected a new key with the same attributes, got %+v", rotated)
+	}
+
+	// Les deux clés sont acceptées pendant la période de grâce
+	for _, secret := range []string{oldSecret, newSecret} {
+		if _, err := s.Authenticate(secret); err != nil {
+			t.Errorf("expected both keys to be valid during the grace period, got %v", err)
+		}
+	}
+
+	*now = now.Add(time.Hour)
+	if _, err := s.Authenticate(oldSecret); !errors.Is(err, ErrInactive) {
+		t.Errorf("expected the old key to expire after the grace period, got %v", err)
+	}
+	if _, err := s.Authenticate(newSecret); err != nil {
+		t.Errorf("expected the new key to stay valid, got %v", err)
+	}
+
+	if _, _, err := s.Rotate(old.ID, time.Hour); !errors.Is(err, ErrInactive) {
+		t.Errorf("expected ErrInactive when rotating an expired key, got %v", err)
+	}
+}
+
+func TestSQLiteStore_RotateKeepsEarlierExpiry(t *testing.T) {
+	s, now := newTestStore(t)
+
+	expiresAt := now.Add(time.Minute)
+	old, _, err := s.Create(NewKey{Name: "web", Scopes: []string{ScopeIngest}, ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Rotate(old.ID, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.Get(old.ID)
+	if !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expected the earlier expiry to be kept, got %v", got.ExpiresAt)
+	}
+}
+
+func TestSQLiteStore_LastUsedIsThrottled(t *testing.T) {
+	s, now := newTestStore(t)
+
+	key, secret, err := s.Create(NewKey{Name: "web", Scopes: []string{ScopeIngest}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := *now
+	s.Authenticate(secret)
+
+	*now = now.Add(lastUsedResolution / 2)
+	s.Authenticate(secret)
+	got, _ := s.Get(key.ID)
+	if got.LastUsedAt == nil || !got.LastUsedAt.Equal(first) {
+		t.Errorf("expected last_used_at to be written once per resolution, got %v", got.LastUsedAt)
+	}
+
+	*now = now.Add(lastUsedResolution)
+	s.Authenticate(secret)
+	got, _ = s.Get(key.ID)
+	if !got.LastUsedAt.Equal(*now) {
+		t.Errorf("expected last_used_at %v, got %v", *now, got.LastUsedAt)
+	}
+}
+
+func TestSQLiteStore_List(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	for _, name := range []string{"a", "b"} {
+		if _, _, err := s.Create(NewKey{Name: name, Scopes: []string{ScopeRead}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].Name != "b" {
+		t.Errorf("expected newest key first, got %+v", keys)
+	}
+}
+
+func TestSQLiteStore_AuthenticateSignature(t *testing.T) {
+	s, now := newTestStore(t)
+
+	key, secret, err := s.Create(NewKey{Name: "agent", Scopes: []string{ScopeIngest}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Clé de même préfixe : la signature désigne la bonne
+	if _, err := s.db.Exec(`INSERT INTO api_keys(name, prefix, key_hash, scopes, created_at) VALUES ('other', ?, ?, 'read', ?)`,
+		key.Prefix, Hash("another key"), now.Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	sts := StringToSign("POST", "/log/batch", "1756720800", "n1", []byte(`[]`))
+	got, err := s.AuthenticateSignature(key.Prefix, sts, Sign(SigningSecret(secret), sts))
+	if err != nil || got.ID != key.ID {
+		t.Fatalf("expected key %d, got %+v, %v", key.ID, got, err)
+	}
+	if k, _ := s.Get(key.ID); k.LastUsedAt == nil {
+		t.Error("expected last_used_at to be set")
+	}
+
+	if _, err := s.AuthenticateSignature(key.Prefix, sts, Sign([]byte("wrong"), sts)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for a bad signature, got %v", err)
+	}
+	if _, err := s.AuthenticateSignature("lsk_unknown", sts, Sign(SigningSecret(secret), sts)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for an unknown prefix, got %v", err)
+	}
+
+	s.Revoke(key.ID)
+	if _, err := s.AuthenticateSignature(key.Prefix, sts, Sign(SigningSecret(secret), sts)); !errors.Is(err, ErrInactive) {
+		t.Errorf("expected ErrInactive for a revoked key, got %v", err)
+	}
+}
+
+// Le secret de signature ne se déduit ni de key_hash ni de la colonne signing_secret :
+// lire la base ne permet pas de signer
+func TestSQLiteStore_SigningSecretNotInDatabase(t *testing.T) {
+	s, _ := newTestStore(t)
+	key, secret, err := s.Create(NewKey{Name: "agent", Scopes: []string{ScopeIngest}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hash, sealed string
+	if err := s.db.QueryRow(`SELECT key_hash, signing_secret FROM api_keys WHERE id = ?`, key.ID).Scan(&hash, &sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	sts := StringToSign("POST", "/log/batch", "1756720800", "n1", []byte(`[]`))
+	for name, forged := range map[string][]byte{
+		"key_hash":                   []byte(hash),
+		"signing secret of key_hash": SigningSecret(hash),
+		"signing_secret column":      []byte(sealed),
+	} {
+		if _, err := s.AuthenticateSignature(key.Prefix, sts, Sign(forged, sts)); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected a signature with the %s to be rejected, got %v", name, err)
+		}
+	}
+	if strings.Contains(sealed, hex.EncodeToString(SigningSecret(secret))) {
+		t.Error("expected the signing secret to be stored encrypted")
+	}
+
+	// Une autre clé serveur ne déchiffre pas les secrets conservés
+	if err := s.SetSigningKey("another server signing key, 32 chars"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AuthenticateSignature(key.Prefix, sts, Sign(SigningSecret(secret), sts)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey with another server key, got %v", err)
+	}
+}
+
+func TestSQLiteStore_SigningDisabled(t *testing.T) {
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	key, secret, err := s.Create(NewKey{Name: "agent", Scopes: []string{ScopeIngest}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sts := StringToSign("POST", "/log", "1756720800", "n1", nil)
+	if _, err := s.AuthenticateSignature(key.Prefix, sts, Sign(SigningSecret(secret), sts)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected signatures to be refused without a signing key, got %v", err)
+	}
+	// La clé créée sans secret ne signe pas une fois la signature activée
+	if err := s.SetSigningKey(testSigningKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AuthenticateSignature(key.Prefix, sts, Sign(SigningSecret(secret), sts)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected a key without signing secret to be refused, got %v", err)
+	}
+	if err := s.SetSigningKey("too short"); err == nil {
+		t.Error("expected a short signing key to be refused")
+	}
+}
+
+func TestSQLiteStore_PurgeTenant(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	_, acmeSecret, err := s.Create(NewKey{Name: "acme-agent", Scopes: []string{ScopeIngest}, Tenant: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, defaultSecret, err := s.Create(NewKey{Name: "agent", Scopes: []string{ScopeIngest}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := s.Authenticate(acmeSecret); err != nil || key.Tenant != "acme" {
+		t.Fatalf("expected a key of tenant acme, got %+v, %v", key, err)
+	}
+
+	if err := s.PurgeTenant("acme"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(acmeSecret); !errors.Is(err, ErrInactive) {
+		t.Errorf("expected keys of a purged tenant to be revoked, got %v", err)
+	}
+	if _, err := s.Authenticate(defaultSecret); err != nil {
+		t.Errorf("expected keys of other tenants to stay valid, got %v", err)
+	}
+	if err := s.PurgeTenant(""); err == nil {
+		t.Error("expected the default tenant not to be purgeable")
+	}
+}
+
+func TestNewSQLiteStore_MigratesTenantColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE, scopes TEXT NOT NULL, service TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL, expires_at TEXT, revoked_at TEXT, last_used_at TEXT
+	);
+	INSERT INTO api_keys(name, prefix, key_hash, scopes, created_at) VALUES ('old', 'lsk_old', 'hash', 'read', '2025-01-01T00:00:00Z');`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed on an old schema: %v", err)
+	}
+	defer s.Close()
+	key, err := s.Get(1)
+	if err != nil || key.Name != "old" || key.Tenant != "" {
+		t.Errorf("expected the old key in the default tenant, got %+v, %v", key, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
 	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
 	"github.com/rypi-dev/logger-server/internal/utils/utils"
 )
@@ -22,6 +23,18 @@ func AuditEvent(logger LoggerInterface, r *http.Request, level log_levels.LogLev
 		"user_agent": r.UserAgent(),
 	}
 
+	// Nom de la clé API authentifiée (ScopedApiKeyMiddleware), jamais sa valeur
+	if key := apikey.FromContext(r.Context()); key != nil {
+		ctx["api_key"] = key.Name
+		if key.Tenant != "" {
+			ctx["tenant"] = key.Tenant
+		}
+	}
+	// Identité du certificat client vérifié (mTLS), y compris quand il est refusé
+	if id := utils.GetClientCertIdentity(r); id != "" {
+		ctx["client_cert"] = id
+	}
+
 	for k, v := range extra {
 		ctx[k] = v
 	}
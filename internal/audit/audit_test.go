@@ -1,11 +1,15 @@
 package audit_test
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/rypi-dev/logger-server/internal/apikey"
 	"github.com/rypi-dev/logger-server/internal/audit"
 	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
 )
@@ -78,6 +82,43 @@ func TestAuditEvent(t *testing.T) {
 	}
 }
 
+func TestAuditEvent_APIKeyName(t *testing.T) {
+	mock := &mockLogger{}
+
+	req := httptest.NewRequest("POST", "/log", nil)
+	audit.AuditEvent(mock, req, log_levels.Info, "msg", 201, nil)
+	if _, ok := mock.wroteEntry.Context["api_key"]; ok {
+		t.Error("expected no api_key without an authenticated key")
+	}
+
+	req = req.WithContext(apikey.WithKey(req.Context(), &apikey.Key{Name: "fluent-bit-web"}))
+	audit.AuditEvent(mock, req, log_levels.Info, "msg", 201, nil)
+	if mock.wroteEntry.Context["api_key"] != "fluent-bit-web" {
+		t.Errorf("expected api_key fluent-bit-web, got %v", mock.wroteEntry.Context["api_key"])
+	}
+	if _, ok := mock.wroteEntry.Context["tenant"]; ok {
+		t.Error("expected no tenant for a key of the default tenant")
+	}
+
+	req = req.WithContext(apikey.WithKey(req.Context(), &apikey.Key{Name: "acme-agent", Tenant: "acme"}))
+	audit.AuditEvent(mock, req, log_levels.Info, "msg", 201, nil)
+	if mock.wroteEntry.Context["tenant"] != "acme" {
+		t.Errorf("expected tenant acme, got %v", mock.wroteEntry.Context["tenant"])
+	}
+}
+
+func TestAuditEvent_ClientCert(t *testing.T) {
+	mock := &mockLogger{}
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "billing-agent"}}
+	req := httptest.NewRequest("POST", "/log", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+	audit.AuditEvent(mock, req, log_levels.Info, "msg", 401, nil)
+	if mock.wroteEntry.Context["client_cert"] != "billing-agent" {
+		t.Errorf("expected client_cert billing-agent, got %v", mock.wroteEntry.Context["client_cert"])
+	}
+}
+
 func TestAuditEvent_LoggerNil(t *testing.T) {
 	req := httptest.NewRequest("GET", "/", nil)
 	defer func() {
@@ -0,0 +1,232 @@
+package elastic
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/handler/handler"
+	"github.com/rypi-dev/logger-server/internal/quota/quota"
+)
+
+// MaxRequestBodySize borne un corps bulk (les clients envoient des lots de plusieurs Mo)
+const MaxRequestBodySize = 16 << 20
+
+// DefaultVersion est la version annoncée aux clients; Beats refuse un cluster plus ancien que lui
+const DefaultVersion = "8.17.0"
+
+const clusterName = "logger-server"
+
+var itemsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "elastic_bulk_items_total",
+	Help: "Total number of Elasticsearch bulk items received, by result",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(itemsTotal)
+}
+
+type Config struct {
+	Mapping Mapping
+	Version string
+}
+
+// API expose un sous-ensemble compatible Elasticsearch : _bulk et les endpoints
+// d'information interrogés au démarrage par Beats, Logstash et Vector.
+type API struct {
+	cfg    Config
+	ingest internal.IngestFunc
+}
+
+func NewAPI(cfg Config, ingest internal.IngestFunc) *API {
+	if cfg.Version == "" {
+		cfg.Version = DefaultVersion
+	}
+	return &API{cfg: cfg, ingest: ingest}
+}
+
+// Register ajoute les routes Elasticsearch au routeur
+func (a *API) Register(r *mux.Router) {
+	r.HandleFunc("/", a.product(a.handleInfo)).Methods("GET", "HEAD")
+	r.HandleFunc("/_license", a.product(a.handleLicense)).Methods("GET")
+	r.HandleFunc("/_xpack", a.product(a.handleXPack)).Methods("GET")
+	r.HandleFunc("/_cluster/health", a.product(a.handleHealth)).Methods("GET")
+	r.HandleFunc("/_bulk", a.product(a.handleBulk)).Methods("POST", "PUT")
+	r.HandleFunc("/{index:[^_/][^/]*}/_bulk", a.product(a.handleBulk)).Methods("POST", "PUT")
+}
+
+// product ajoute l'en-tête exigé par les clients officiels (>= 7.14) pour reconnaître Elasticsearch
+func (a *API) product(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		next(w, r)
+	}
+}
+
+func (a *API) handleInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":         clusterName,
+		"cluster_name": clusterName,
+		"cluster_uuid": "logger-server",
+		"version": map[string]interface{}{
+			"number":                              a.cfg.Version,
+			"build_flavor":                        "default",
+			"build_type":                          "docker",
+			"lucene_version":                      "9.12.0",
+			"minimum_wire_compatibility_version":  "7.17.0",
+			"minimum_index_compatibility_version": "7.0.0",
+		},
+		"tagline": "You Know, for Search",
+	})
+}
+
+func (a *API) handleLicense(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"license": map[string]interface{}{"status": "active", "type": "basic", "uid": "logger-server"},
+	})
+}
+
+func (a *API) handleXPack(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"build":    map[string]interface{}{},
+		"features": map[string]interface{}{},
+		"license":  map[string]interface{}{"status": "active", "type": "basic", "mode": "basic", "uid": "logger-server"},
+	})
+}
+
+func (a *API) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"cluster_name":    clusterName,
+		"status":          "green",
+		"timed_out":       false,
+		"number_of_nodes": 1,
+	})
+}
+
+// bulkError reprend le format d'erreur d'Elasticsearch ({"type": ..., "reason": ...})
+type bulkError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+type bulkItemResult struct {
+	Index   string     `json:"_index"`
+	ID      string     `json:"_id,omitempty"`
+	Version int        `json:"_version,omitempty"`
+	Result  string     `json:"result,omitempty"`
+	Status  int        `json:"status"`
+	Error   *bulkError `json:"error,omitempty"`
+}
+
+func (a *API) handleBulk(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+	defer r.Body.Close()
+
+	items, err := ParseBulk(r.Body, mux.Vars(r)["index"])
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "content_too_long_exception", "request body too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "illegal_argument_exception", err.Error())
+		return
+	}
+
+	results := make([]map[string]bulkItemResult, 0, len(items))
+	hasErrors := false
+	storageDown := false
+	ingest := apikey.Bind(r.Context(), a.ingest)
+
+	for _, item := range items {
+		res := a.processItem(ingest, item, &storageDown)
+		if res.Error != nil {
+			hasErrors = true
+		}
+		results = append(results, map[string]bulkItemResult{item.Action: res})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"took":   time.Since(start).Milliseconds(),
+		"errors": hasErrors,
+		"items":  results,
+	})
+}
+
+// processItem ingère un item. Après un échec de stockage (storageDown), les items suivants
+// ne sont pas tentés et sont renvoyés en 429, que tous les clients ES réessaient item par
+// item; un item au-delà d'un quota est renvoyé en 429 de la même façon.
+func (a *API) processItem(ingest internal.IngestFunc, item Item, storageDown *bool) bulkItemResult {
+	res := bulkItemResult{Index: item.Index, ID: item.ID}
+
+	reject := func(status int, errType, reason, metric string) bulkItemResult {
+		itemsTotal.WithLabelValues(metric).Inc()
+		res.Status = status
+		res.Error = &bulkError{Type: errType, Reason: reason}
+		return res
+	}
+
+	switch {
+	case item.Action == ActionUpdate || item.Action == ActionDelete:
+		return reject(http.StatusBadRequest, "illegal_argument_exception",
+			item.Action+" is not supported, logs are append-only", "rejected")
+	case item.Index == "":
+		return reject(http.StatusBadRequest, "action_request_validation_exception", "index is missing", "rejected")
+	case *storageDown:
+		return reject(http.StatusTooManyRequests, "es_rejected_execution_exception", "storage unavailable", "write_error")
+	}
+
+	// _id fourni par le client : un renvoi du même document n'est pas stocké deux fois
+	entry := a.cfg.Mapping.ToLogEntry(item.Document)
+	entry.ID = item.ID
+
+	err := ingest(entry)
+	switch {
+	case err == nil:
+	case errors.Is(err, handler.ErrWriteFailed):
+		*storageDown = true
+		return reject(http.StatusTooManyRequests, "es_rejected_execution_exception", "failed to write log", "write_error")
+	case errors.Is(err, quota.ErrExceeded):
+		return reject(http.StatusTooManyRequests, "es_rejected_execution_exception", err.Error(), "quota_exceeded")
+	default:
+		return reject(http.StatusBadRequest, "document_parsing_exception", err.Error(), "rejected")
+	}
+
+	itemsTotal.WithLabelValues("ok").Inc()
+	if res.ID == "" {
+		res.ID = newID()
+	}
+	res.Version = 1
+	res.Result = "created"
+	res.Status = http.StatusCreated
+	return res
+}
+
+func newID() string {
+	b := make([]byte, 10)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, errType, reason string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error":  bulkError{Type: errType, Reason: reason},
+		"status": status,
+	})
+}
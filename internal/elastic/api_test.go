@@ -0,0 +1,225 @@
+package elastic_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/elastic"
+	"github.com/rypi-dev/logger-server/internal/handler"
+	"github.com/rypi-dev/logger-server/internal/quota"
+)
+
+type collector struct {
+	entries []*internal.LogEntry
+	err     error
+	failAt  int
+}
+
+func (c *collector) ingest(entry *internal.LogEntry) error {
+	if c.err != nil && len(c.entries) >= c.failAt {
+		return c.err
+	}
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+type bulkResponse struct {
+	Errors bool                                    `json:"errors"`
+	Items  []map[string]map[string]json.RawMessage `json:"items"`
+}
+
+func serve(c *collector, method, path, body string) *httptest.ResponseRecorder {
+	r := mux.NewRouter()
+	elastic.NewAPI(elastic.Config{Mapping: elastic.DefaultMapping()}, c.ingest).Register(r)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func itemStatus(t *testing.T, item map[string]map[string]json.RawMessage) int {
+	t.Helper()
+	for _, res := range item {
+		var status int
+		if err := json.Unmarshal(res["status"], &status); err != nil {
+			t.Fatalf("invalid item status: %v", err)
+		}
+		return status
+	}
+	t.Fatal("empty bulk item")
+	return 0
+}
+
+func TestBulk_Success(t *testing.T) {
+	c := &collector{}
+	body := `{"index":{}}
+{"message":"one","log":{"level":"info"}}
+{"create":{"_id":"abc"}}
+{"message":"two","log":{"level":"error"}}
+`
+
+	w := serve(c, "POST", "/filebeat-8/_bulk", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("X-Elastic-Product") != "Elasticsearch" {
+		t.Error("expected X-Elastic-Product header")
+	}
+
+	var resp bulkResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Errors || len(resp.Items) != 2 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	for _, item := range resp.Items {
+		if s := itemStatus(t, item); s != http.StatusCreated {
+			t.Errorf("expected 201, got %d", s)
+		}
+	}
+	if string(resp.Items[1]["create"]["_id"]) != `"abc"` {
+		t.Errorf("expected client id to be echoed, got %s", resp.Items[1]["create"]["_id"])
+	}
+	if len(c.entries) != 2 || c.entries[1].Level != "ERROR" {
+		t.Errorf("unexpected stored entries %+v", c.entries)
+	}
+	if c.entries[0].ID != "" || c.entries[1].ID != "abc" {
+		t.Errorf("expected _id to become the entry ID, got %q and %q", c.entries[0].ID, c.entries[1].ID)
+	}
+}
+
+func TestBulk_PerItemErrors(t *testing.T) {
+	c := &collector{}
+	body := `{"index":{"_index":"logs"}}
+{"message":"kept"}
+{"index":{"_index":"logs"}}
+{"level":"info"}
+{"delete":{"_index":"logs","_id":"1"}}
+{"index":{}}
+{"message":"no index"}
+`
+
+	w := serve(c, "POST", "/_bulk", body)
+	var resp bulkResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Errors {
+		t.Error("expected errors to be true")
+	}
+
+	want := []int{http.StatusCreated, http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest}
+	for i, item := range resp.Items {
+		if s := itemStatus(t, item); s != want[i] {
+			t.Errorf("item %d: expected %d, got %d", i, want[i], s)
+		}
+	}
+	if len(c.entries) != 1 {
+		t.Errorf("expected 1 stored entry, got %d", len(c.entries))
+	}
+}
+
+func TestBulk_WriteFailureIsRetryable(t *testing.T) {
+	c := &collector{err: fmt.Errorf("%w: disk full", handler.ErrWriteFailed), failAt: 1}
+	body := `{"index":{"_index":"logs"}}
+{"message":"stored"}
+{"index":{"_index":"logs"}}
+{"message":"fails"}
+{"index":{"_index":"logs"}}
+{"message":"not attempted"}
+`
+
+	w := serve(c, "POST", "/_bulk", body)
+	var resp bulkResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{http.StatusCreated, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	for i, item := range resp.Items {
+		if s := itemStatus(t, item); s != want[i] {
+			t.Errorf("item %d: expected %d, got %d", i, want[i], s)
+		}
+	}
+}
+
+func TestBulk_QuotaExceededIsRetryable(t *testing.T) {
+	c := &collector{err: fmt.Errorf("%w: tenant/payments daily_entries", quota.ErrExceeded), failAt: 1}
+	body := `{"index":{"_index":"logs"}}
+{"message":"stored"}
+{"index":{"_index":"logs"}}
+{"message":"over quota"}
+{"index":{"_index":"logs"}}
+{"message":"also over quota"}
+`
+
+	w := serve(c, "POST", "/_bulk", body)
+	var resp bulkResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	// Chaque item reste tenté : un quota atteint n'est pas une panne du stockage
+	want := []int{http.StatusCreated, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	for i, item := range resp.Items {
+		if s := itemStatus(t, item); s != want[i] {
+			t.Errorf("item %d: expected %d, got %d", i, want[i], s)
+		}
+		if i == 0 {
+			continue
+		}
+		var e struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		}
+		json.Unmarshal(item["index"]["error"], &e)
+		if e.Type != "es_rejected_execution_exception" || !strings.Contains(e.Reason, "quota exceeded") {
+			t.Errorf("item %d: expected a retryable quota error, got %+v", i, e)
+		}
+	}
+}
+
+func TestBulk_MalformedBody(t *testing.T) {
+	w := serve(&collector{}, "POST", "/_bulk", "{not json}\n")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestClusterInfoEndpoints(t *testing.T) {
+	for _, path := range []string{"/", "/_license", "/_xpack", "/_cluster/health"} {
+		w := serve(&collector{}, "GET", path, "")
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", path, w.Code)
+		}
+		if w.Header().Get("X-Elastic-Product") != "Elasticsearch" {
+			t.Errorf("%s: expected X-Elastic-Product header", path)
+		}
+	}
+
+	w := serve(&collector{}, "GET", "/", "")
+	var info struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Version.Number != elastic.DefaultVersion {
+		t.Errorf("expected version %s, got %s", elastic.DefaultVersion, info.Version.Number)
+	}
+}
@@ -0,0 +1,246 @@
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+)
+
+// MaxLineSize borne une ligne NDJSON (action ou document)
+const MaxLineSize = 1 << 20
+
+var ErrInvalidBulk = errors.New("invalid bulk request")
+
+// Actions bulk acceptées; delete et update n'ont pas de sens pour un journal en ajout seul
+const (
+	ActionIndex  = "index"
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Mapping décrit où trouver les champs de LogEntry dans un document. Chaque liste est
+// essayée dans l'ordre; les noms pointés (log.level) descendent dans les objets imbriqués.
+type Mapping struct {
+	LevelFields     []string
+	MessageFields   []string
+	TimestampFields []string
+	ServiceFields   []string
+	HostFields      []string
+	// ContextFields restreint les champs restants copiés dans le contexte (tous si vide)
+	ContextFields []string
+}
+
+// DefaultMapping couvre ECS (Beats, Logstash ecs_compatibility) et les champs usuels de Vector
+func DefaultMapping() Mapping {
+	return Mapping{
+		LevelFields:     []string{"log.level", "level", "severity"},
+		MessageFields:   []string{"message", "msg", "log"},
+		TimestampFields: []string{"@timestamp", "timestamp"},
+		ServiceFields:   []string{"service.name", "service"},
+		HostFields:      []string{"host.name", "host.hostname", "hostname"},
+	}
+}
+
+// Item est une opération du corps bulk : une ligne d'action et son document éventuel
+type Item struct {
+	Action   string
+	Index    string
+	ID       string
+	Document map[string]interface{}
+}
+
+type actionMeta struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// ParseBulk lit le corps NDJSON. defaultIndex est l'index de l'URL (/{index}/_bulk).
+// Une erreur n'est retournée que si le flux est illisible; les actions non supportées
+// sont renvoyées telles quelles pour être rejetées item par item.
+func ParseBulk(r io.Reader, defaultIndex string) ([]Item, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MaxLineSize)
+
+	var items []Item
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var action map[string]actionMeta
+		if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+			return nil, fmt.Errorf("%w: malformed action line %q", ErrInvalidBulk, truncate(line))
+		}
+
+		var item Item
+		for name, meta := range action {
+			item = Item{Action: name, Index: meta.Index, ID: meta.ID}
+		}
+		if item.Index == "" {
+			item.Index = defaultIndex
+		}
+
+		switch item.Action {
+		case ActionIndex, ActionCreate, ActionUpdate:
+			if !scanner.Scan() {
+				return nil, fmt.Errorf("%w: missing document after %s action", ErrInvalidBulk, item.Action)
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &item.Document); err != nil {
+				return nil, fmt.Errorf("%w: malformed document: %v", ErrInvalidBulk, err)
+			}
+		case ActionDelete:
+		default:
+			return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidBulk, item.Action)
+		}
+
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBulk, err)
+	}
+	return items, nil
+}
+
+func truncate(b []byte) string {
+	if len(b) > 100 {
+		return string(b[:100]) + "..."
+	}
+	return string(b)
+}
+
+// ToLogEntry applique le mapping à un document. Les champs consommés sont retirés du
+// document; le reste (filtré par ContextFields) devient le contexte.
+func (m Mapping) ToLogEntry(doc map[string]interface{}) *internal.LogEntry {
+	entry := &internal.LogEntry{Level: internal.DefaultLogLevel}
+
+	if v, ok := takeString(doc, m.LevelFields); ok {
+		if level := normalizeLevel(v); level != "" {
+			entry.Level = level
+		}
+	}
+	if v, ok := takeString(doc, m.MessageFields); ok {
+		entry.Message = strings.TrimRight(v, "\n")
+	}
+	if v, ok := take(doc, m.TimestampFields); ok {
+		entry.Timestamp = parseTimestamp(v)
+	}
+	if v, ok := takeString(doc, m.ServiceFields); ok {
+		entry.Service = v
+	}
+	if v, ok := takeString(doc, m.HostFields); ok {
+		entry.Host = v
+	}
+
+	ctx := make(map[string]interface{})
+	if len(m.ContextFields) > 0 {
+		for _, field := range m.ContextFields {
+			if v, ok := lookup(doc, field); ok {
+				ctx[field] = v
+			}
+		}
+	} else {
+		for k, v := range doc {
+			ctx[k] = v
+		}
+	}
+	if len(ctx) > 0 {
+		entry.Context = ctx
+	}
+	return entry
+}
+
+// lookup résout un chemin pointé, d'abord comme clé littérale puis dans les objets imbriqués
+func lookup(doc map[string]interface{}, path string) (interface{}, bool) {
+	if v, ok := doc[path]; ok {
+		return v, true
+	}
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return nil, false
+	}
+	nested, ok := doc[head].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookup(nested, rest)
+}
+
+// remove supprime un chemin pointé et les objets parents devenus vides
+func remove(doc map[string]interface{}, path string) {
+	if _, ok := doc[path]; ok {
+		delete(doc, path)
+		return
+	}
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return
+	}
+	nested, ok := doc[head].(map[string]interface{})
+	if !ok {
+		return
+	}
+	remove(nested, rest)
+	if len(nested) == 0 {
+		delete(doc, head)
+	}
+}
+
+func take(doc map[string]interface{}, paths []string) (interface{}, bool) {
+	for _, path := range paths {
+		if v, ok := lookup(doc, path); ok {
+			remove(doc, path)
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func takeString(doc map[string]interface{}, paths []string) (string, bool) {
+	for _, path := range paths {
+		v, _ := lookup(doc, path)
+		if s, ok := v.(string); ok {
+			remove(doc, path)
+			return s, true
+		}
+	}
+	return "", false
+}
+
+func normalizeLevel(v string) string {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	switch v {
+	case "WARNING":
+		return string(log_levels.LogLevelWarn)
+	case "ERR":
+		return string(log_levels.LogLevelError)
+	case "CRITICAL":
+		return string(log_levels.LogLevelFatal)
+	}
+	if log_levels.IsValidLogLevel(v) {
+		return v
+	}
+	return ""
+}
+
+// parseTimestamp accepte RFC 3339 et les epoch en millisecondes (format date par défaut d'ES)
+func parseTimestamp(v interface{}) time.Time {
+	switch t := v.(type) {
+	case string:
+		if ts, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return ts.UTC()
+		}
+	case float64:
+		return time.UnixMilli(int64(t)).UTC()
+	}
+	return time.Time{}
+}
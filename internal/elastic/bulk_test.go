@@ -0,0 +1,129 @@
+package elastic_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/elastic"
+)
+
+func TestParseBulk(t *testing.T) {
+	body := `{"index":{"_index":"app-logs","_id":"1"}}
+{"message":"first"}
+
+{"create":{}}
+{"message":"second"}
+{"delete":{"_index":"app-logs","_id":"1"}}
+{"update":{"_id":"2"}}
+{"doc":{"message":"third"}}
+`
+
+	items, err := elastic.ParseBulk(strings.NewReader(body), "default-index")
+	if err != nil {
+		t.Fatalf("ParseBulk returned error: %v", err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(items))
+	}
+	if items[0].Action != elastic.ActionIndex || items[0].Index != "app-logs" || items[0].ID != "1" {
+		t.Errorf("unexpected first item %+v", items[0])
+	}
+	if items[1].Index != "default-index" || items[1].Document["message"] != "second" {
+		t.Errorf("expected URL index as default, got %+v", items[1])
+	}
+	if items[2].Action != elastic.ActionDelete || items[2].Document != nil {
+		t.Errorf("expected delete without document, got %+v", items[2])
+	}
+	if items[3].Action != elastic.ActionUpdate {
+		t.Errorf("expected update item, got %+v", items[3])
+	}
+}
+
+func TestParseBulk_Invalid(t *testing.T) {
+	for _, body := range []string{
+		"not json\n",
+		`{"index":{}}` + "\n",
+		`{"index":{}}` + "\n" + "not json\n",
+		`{"upsert":{}}` + "\n" + `{}` + "\n",
+		`{"index":{},"create":{}}` + "\n",
+	} {
+		if _, err := elastic.ParseBulk(strings.NewReader(body), ""); !errors.Is(err, elastic.ErrInvalidBulk) {
+			t.Errorf("ParseBulk(%q): expected ErrInvalidBulk, got %v", body, err)
+		}
+	}
+}
+
+func TestMapping_ToLogEntry_ECS(t *testing.T) {
+	doc := map[string]interface{}{
+		"@timestamp": "2025-08-06T14:12:00.123Z",
+		"message":    "GET /health 200\n",
+		"log":        map[string]interface{}{"level": "warning"},
+		"service":    map[string]interface{}{"name": "web"},
+		"host":       map[string]interface{}{"name": "node-1", "os": "linux"},
+		"http":       map[string]interface{}{"status": float64(200)},
+	}
+
+	entry := elastic.DefaultMapping().ToLogEntry(doc)
+
+	if entry.Message != "GET /health 200" || entry.Level != "WARN" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Service != "web" || entry.Host != "node-1" {
+		t.Errorf("expected ECS service and host, got %q / %q", entry.Service, entry.Host)
+	}
+	if want := time.Date(2025, 8, 6, 14, 12, 0, 123000000, time.UTC); !entry.Timestamp.Equal(want) {
+		t.Errorf("expected %v, got %v", want, entry.Timestamp)
+	}
+	if _, ok := entry.Context["log"]; ok {
+		t.Error("expected emptied parent objects to be removed from context")
+	}
+	if _, ok := entry.Context["service"]; ok {
+		t.Error("expected consumed service field to be removed from context")
+	}
+	host, _ := entry.Context["host"].(map[string]interface{})
+	if host["os"] != "linux" {
+		t.Errorf("expected remaining host fields in context, got %v", entry.Context["host"])
+	}
+	if err := entry.Validate(); err != nil {
+		t.Errorf("expected valid entry, got %v", err)
+	}
+}
+
+func TestMapping_ToLogEntry_CustomFields(t *testing.T) {
+	m := elastic.Mapping{
+		LevelFields:     []string{"lvl"},
+		MessageFields:   []string{"text"},
+		TimestampFields: []string{"ts"},
+		ContextFields:   []string{"user.id"},
+	}
+	doc := map[string]interface{}{
+		"lvl":   "error",
+		"text":  "payment failed",
+		"ts":    float64(1754489520000),
+		"user":  map[string]interface{}{"id": "u-42", "email": "a@example.com"},
+		"agent": map[string]interface{}{"type": "filebeat"},
+	}
+
+	entry := m.ToLogEntry(doc)
+	if entry.Level != "ERROR" || entry.Message != "payment failed" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if !entry.Timestamp.Equal(time.UnixMilli(1754489520000)) {
+		t.Errorf("expected epoch millis timestamp, got %v", entry.Timestamp)
+	}
+	if len(entry.Context) != 1 || entry.Context["user.id"] != "u-42" {
+		t.Errorf("expected only whitelisted context fields, got %v", entry.Context)
+	}
+}
+
+func TestMapping_ToLogEntry_Defaults(t *testing.T) {
+	entry := elastic.DefaultMapping().ToLogEntry(map[string]interface{}{"message": "hello", "level": "verbose"})
+	if entry.Level != "INFO" {
+		t.Errorf("expected unknown level to default to INFO, got %q", entry.Level)
+	}
+	if !entry.Timestamp.IsZero() || entry.Context != nil {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
@@ -0,0 +1,344 @@
+package forward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+)
+
+// Limites de protection contre les messages malformés ou abusifs
+const (
+	MaxEntriesPerMessage = 10000
+	MaxDecompressedSize  = 16 << 20
+)
+
+var ErrInvalidMessage = errors.New("invalid forward message")
+
+// Champs de record reconnus (Fluent Bit, Fluentd, driver Docker)
+var (
+	levelFields   = []string{"level", "severity", "log_level"}
+	messageFields = []string{"message", "log", "msg"}
+)
+
+// EventTime est l'extension msgpack (type 0) utilisée par Fluentd pour les timestamps
+// à la nanoseconde : secondes puis nanosecondes, en uint32 big-endian.
+type EventTime struct {
+	time.Time
+}
+
+func init() {
+	msgpack.RegisterExt(0, (*EventTime)(nil))
+}
+
+func (t *EventTime) MarshalMsgpack() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	return b, nil
+}
+
+func (t *EventTime) UnmarshalMsgpack(b []byte) error {
+	if len(b) != 8 {
+		return fmt.Errorf("%w: EventTime must be 8 bytes, got %d", ErrInvalidMessage, len(b))
+	}
+	sec := binary.BigEndian.Uint32(b)
+	nsec := binary.BigEndian.Uint32(b[4:])
+	t.Time = time.Unix(int64(sec), int64(nsec)).UTC()
+	return nil
+}
+
+// Event est un événement décodé : tag, horodatage et record
+type Event struct {
+	Tag    string
+	Time   time.Time
+	Record map[string]interface{}
+}
+
+// Message regroupe les événements d'un message forward et ses options
+type Message struct {
+	Events []Event
+	Chunk  string // identifiant à renvoyer dans l'ack si non vide
+}
+
+// DecodeMessage décode un message déjà lu (tableau msgpack) dans l'un des quatre modes :
+// Message, Forward, PackedForward et CompressedPackedForward.
+func DecodeMessage(arr []interface{}) (*Message, error) {
+	if len(arr) < 2 {
+		return nil, fmt.Errorf("%w: expected at least 2 elements, got %d", ErrInvalidMessage, len(arr))
+	}
+
+	tag, ok := asString(arr[0])
+	if !ok {
+		return nil, fmt.Errorf("%w: tag must be a string", ErrInvalidMessage)
+	}
+
+	msg := &Message{}
+
+	switch entries := arr[1].(type) {
+	case []interface{}:
+		// Forward mode : [tag, [[time, record], ...], option?]
+		if len(arr) > 3 {
+			return nil, fmt.Errorf("%w: too many elements for Forward mode", ErrInvalidMessage)
+		}
+		if len(entries) > MaxEntriesPerMessage {
+			return nil, fmt.Errorf("%w: too many entries (%d)", ErrInvalidMessage, len(entries))
+		}
+		option, err := parseOption(arr, 2)
+		if err != nil {
+			return nil, err
+		}
+		msg.Chunk = option.chunk
+		for _, raw := range entries {
+			ev, err := decodeEntry(tag, raw)
+			if err != nil {
+				return nil, err
+			}
+			msg.Events = append(msg.Events, ev)
+		}
+
+	case []byte, string:
+		// PackedForward / CompressedPackedForward : [tag, bin, option?]
+		if len(arr) > 3 {
+			return nil, fmt.Errorf("%w: too many elements for PackedForward mode", ErrInvalidMessage)
+		}
+		option, err := parseOption(arr, 2)
+		if err != nil {
+			return nil, err
+		}
+		msg.Chunk = option.chunk
+
+		packed, _ := asBytes(entries)
+		if option.compressed == "gzip" {
+			if packed, err = gunzip(packed); err != nil {
+				return nil, err
+			}
+		} else if option.compressed != "" && option.compressed != "text" {
+			return nil, fmt.Errorf("%w: unsupported compression %q", ErrInvalidMessage, option.compressed)
+		}
+
+		events, err := decodePacked(tag, packed)
+		if err != nil {
+			return nil, err
+		}
+		msg.Events = events
+
+	default:
+		// Message mode : [tag, time, record, option?]
+		if len(arr) < 3 || len(arr) > 4 {
+			return nil, fmt.Errorf("%w: unexpected element count for Message mode", ErrInvalidMessage)
+		}
+		option, err := parseOption(arr, 3)
+		if err != nil {
+			return nil, err
+		}
+		msg.Chunk = option.chunk
+		ev, err := decodeEntry(tag, []interface{}{arr[1], arr[2]})
+		if err != nil {
+			return nil, err
+		}
+		msg.Events = []Event{ev}
+	}
+
+	return msg, nil
+}
+
+type options struct {
+	chunk      string
+	compressed string
+}
+
+func parseOption(arr []interface{}, idx int) (options, error) {
+	var opt options
+	if len(arr) <= idx || arr[idx] == nil {
+		return opt, nil
+	}
+	m, ok := arr[idx].(map[string]interface{})
+	if !ok {
+		return opt, fmt.Errorf("%w: option must be a map", ErrInvalidMessage)
+	}
+	opt.chunk, _ = asString(m["chunk"])
+	opt.compressed, _ = asString(m["compressed"])
+	return opt, nil
+}
+
+func decodePacked(tag string, packed []byte) ([]Event, error) {
+	dec := msgpack.NewDecoder(bytes.NewReader(packed))
+	dec.UseLooseInterfaceDecoding(true)
+
+	var events []Event
+	for {
+		raw, err := dec.DecodeInterface()
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		if len(events) >= MaxEntriesPerMessage {
+			return nil, fmt.Errorf("%w: too many entries", ErrInvalidMessage)
+		}
+		ev, err := decodeEntry(tag, raw)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+}
+
+func decodeEntry(tag string, raw interface{}) (Event, error) {
+	pair, ok := raw.([]interface{})
+	if !ok || len(pair) != 2 {
+		return Event{}, fmt.Errorf("%w: entry must be [time, record]", ErrInvalidMessage)
+	}
+
+	ts, err := decodeTime(pair[0])
+	if err != nil {
+		return Event{}, err
+	}
+
+	record, ok := pair[1].(map[string]interface{})
+	if !ok {
+		return Event{}, fmt.Errorf("%w: record must be a map", ErrInvalidMessage)
+	}
+
+	return Event{Tag: tag, Time: ts, Record: record}, nil
+}
+
+func decodeTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case *EventTime:
+		return t.Time, nil
+	case int64:
+		return time.Unix(t, 0).UTC(), nil
+	case uint64:
+		return time.Unix(int64(t), 0).UTC(), nil
+	case float64:
+		sec := int64(t)
+		return time.Unix(sec, int64((t-float64(sec))*1e9)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: unsupported time type %T", ErrInvalidMessage, v)
+	}
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	defer zr.Close()
+
+	out, err := io.ReadAll(io.LimitReader(zr, MaxDecompressedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if len(out) > MaxDecompressedSize {
+		return nil, fmt.Errorf("%w: decompressed chunk exceeds %d bytes", ErrInvalidMessage, MaxDecompressedSize)
+	}
+	return out, nil
+}
+
+// ToLogEntry convertit un événement forward en LogEntry. Le niveau, le message et le
+// service sont extraits des champs usuels; le reste du record va dans le contexte.
+func (e Event) ToLogEntry() *internal.LogEntry {
+	entry := &internal.LogEntry{
+		Level:     internal.DefaultLogLevel,
+		Timestamp: e.Time,
+		Service:   e.Tag,
+	}
+
+	consumed := make(map[string]bool)
+	if level, key := firstString(e.Record, levelFields); key != "" {
+		consumed[key] = true
+		if log_levels.IsValidLogLevel(level) {
+			entry.Level = level
+		}
+	}
+	if message, key := firstString(e.Record, messageFields); key != "" {
+		consumed[key] = true
+		entry.Message = strings.TrimRight(message, "\n")
+	}
+	if service, ok := asString(e.Record["service"]); ok && service != "" {
+		consumed["service"] = true
+		entry.Service = service
+	}
+
+	ctx := make(map[string]interface{})
+	if nested, ok := e.Record["context"].(map[string]interface{}); ok {
+		consumed["context"] = true
+		for k, v := range nested {
+			ctx[k] = normalize(v)
+		}
+	}
+	for k, v := range e.Record {
+		if !consumed[k] {
+			ctx[k] = normalize(v)
+		}
+	}
+	ctx["fluent_tag"] = e.Tag
+	entry.Context = ctx
+
+	return entry
+}
+
+func firstString(record map[string]interface{}, keys []string) (string, string) {
+	for _, k := range keys {
+		if s, ok := asString(record[k]); ok {
+			return s, k
+		}
+	}
+	return "", ""
+}
+
+// normalize convertit les bin msgpack en chaînes pour la sérialisation JSON du contexte
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case map[string]interface{}:
+		for k, inner := range t {
+			t[k] = normalize(inner)
+		}
+		return t
+	case []interface{}:
+		for i, inner := range t {
+			t[i] = normalize(inner)
+		}
+		return t
+	case *EventTime:
+		return t.Time
+	default:
+		return v
+	}
+}
+
+func asString(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case []byte:
+		return string(t), true
+	default:
+		return "", false
+	}
+}
+
+func asBytes(v interface{}) ([]byte, bool) {
+	switch t := v.(type) {
+	case []byte:
+		return t, true
+	case string:
+		return []byte(t), true
+	default:
+		return nil, false
+	}
+}
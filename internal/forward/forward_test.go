@@ -0,0 +1,203 @@
+package forward_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/rypi-dev/logger-server/internal/forward"
+)
+
+var eventTime = time.Date(2025, 8, 6, 14, 12, 0, 123456789, time.UTC)
+
+// roundTrip encode puis décode un message comme le ferait le serveur
+func roundTrip(t *testing.T, v interface{}) []interface{} {
+	t.Helper()
+	data, err := msgpack.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.UseLooseInterfaceDecoding(true)
+	raw, err := dec.DecodeInterface()
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	arr, ok := raw.([]interface{})
+	if !ok {
+		t.Fatalf("expected array, got %T", raw)
+	}
+	return arr
+}
+
+func packEntries(t *testing.T, n int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	for i := 0; i < n; i++ {
+		if err := enc.Encode([]interface{}{&forward.EventTime{Time: eventTime}, map[string]interface{}{"message": "packed"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeMessage_MessageMode(t *testing.T) {
+	arr := roundTrip(t, []interface{}{
+		"app.web",
+		&forward.EventTime{Time: eventTime},
+		map[string]interface{}{"level": "error", "message": "boom"},
+		map[string]interface{}{"chunk": "abc"},
+	})
+
+	msg, err := forward.DecodeMessage(arr)
+	if err != nil {
+		t.Fatalf("DecodeMessage returned error: %v", err)
+	}
+	if msg.Chunk != "abc" {
+		t.Errorf("expected chunk abc, got %q", msg.Chunk)
+	}
+	if len(msg.Events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(msg.Events))
+	}
+	if !msg.Events[0].Time.Equal(eventTime) {
+		t.Errorf("expected nanosecond EventTime %v, got %v", eventTime, msg.Events[0].Time)
+	}
+}
+
+func TestDecodeMessage_ForwardMode(t *testing.T) {
+	arr := roundTrip(t, []interface{}{
+		"app.web",
+		[]interface{}{
+			[]interface{}{eventTime.Unix(), map[string]interface{}{"message": "one"}},
+			[]interface{}{eventTime.Unix(), map[string]interface{}{"message": "two"}},
+		},
+	})
+
+	msg, err := forward.DecodeMessage(arr)
+	if err != nil {
+		t.Fatalf("DecodeMessage returned error: %v", err)
+	}
+	if len(msg.Events) != 2 || msg.Chunk != "" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	if msg.Events[1].Record["message"] != "two" {
+		t.Errorf("unexpected record %v", msg.Events[1].Record)
+	}
+}
+
+func TestDecodeMessage_PackedForward(t *testing.T) {
+	arr := roundTrip(t, []interface{}{
+		"app.web",
+		packEntries(t, 3),
+		map[string]interface{}{"size": 3, "chunk": "p1"},
+	})
+
+	msg, err := forward.DecodeMessage(arr)
+	if err != nil {
+		t.Fatalf("DecodeMessage returned error: %v", err)
+	}
+	if len(msg.Events) != 3 || msg.Chunk != "p1" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestDecodeMessage_CompressedPackedForward(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(packEntries(t, 2))
+	zw.Close()
+
+	arr := roundTrip(t, []interface{}{
+		"app.web",
+		gz.Bytes(),
+		map[string]interface{}{"compressed": "gzip", "chunk": "c1"},
+	})
+
+	msg, err := forward.DecodeMessage(arr)
+	if err != nil {
+		t.Fatalf("DecodeMessage returned error: %v", err)
+	}
+	if len(msg.Events) != 2 {
+		t.Errorf("expected 2 events, got %d", len(msg.Events))
+	}
+}
+
+func TestDecodeMessage_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  []interface{}
+	}{
+		{"too short", []interface{}{"tag"}},
+		{"tag not string", []interface{}{42, int64(1), map[string]interface{}{}}},
+		{"record not map", []interface{}{"tag", int64(1), "record"}},
+		{"bad time", []interface{}{"tag", "yesterday", map[string]interface{}{}}},
+		{"entry not pair", []interface{}{"tag", []interface{}{[]interface{}{int64(1)}}}},
+		{"unknown compression", []interface{}{"tag", []byte{0x90}, map[string]interface{}{"compressed": "lz4"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := forward.DecodeMessage(roundTrip(t, tt.msg))
+			if !errors.Is(err, forward.ErrInvalidMessage) {
+				t.Errorf("expected ErrInvalidMessage, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEvent_ToLogEntry(t *testing.T) {
+	ev := forward.Event{
+		Tag:  "docker.web",
+		Time: eventTime,
+		Record: map[string]interface{}{
+			"log":          "GET / 200\n",
+			"severity":     "warn",
+			"container_id": []byte("abc123"),
+			"context":      map[string]interface{}{"user_id": int64(42)},
+		},
+	}
+
+	entry := ev.ToLogEntry()
+	if entry.Message != "GET / 200" {
+		t.Errorf("unexpected message %q", entry.Message)
+	}
+	if entry.Service != "docker.web" {
+		t.Errorf("expected tag as service, got %q", entry.Service)
+	}
+	if err := entry.Validate(); err != nil {
+		t.Fatalf("expected valid entry, got %v", err)
+	}
+	if entry.Level != "WARN" {
+		t.Errorf("expected level WARN, got %q", entry.Level)
+	}
+	if entry.Context["container_id"] != "abc123" {
+		t.Errorf("expected bin field converted to string, got %v", entry.Context["container_id"])
+	}
+	if entry.Context["user_id"] != int64(42) || entry.Context["fluent_tag"] != "docker.web" {
+		t.Errorf("unexpected context %v", entry.Context)
+	}
+	if _, ok := entry.Context["severity"]; ok {
+		t.Error("expected level field not to be duplicated in context")
+	}
+}
+
+func TestEvent_ToLogEntry_Defaults(t *testing.T) {
+	ev := forward.Event{
+		Tag:    "app",
+		Time:   eventTime,
+		Record: map[string]interface{}{"message": "hello", "level": "verbose", "service": "billing"},
+	}
+
+	entry := ev.ToLogEntry()
+	if entry.Level != "INFO" {
+		t.Errorf("expected unknown level to default to INFO, got %q", entry.Level)
+	}
+	if entry.Service != "billing" {
+		t.Errorf("expected record service to win over tag, got %q", entry.Service)
+	}
+}
@@ -0,0 +1,302 @@
+package forward
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/handler/handler"
+	"github.com/rypi-dev/logger-server/internal/idempotency/idempotency"
+)
+
+const DefaultIdleTimeout = 5 * time.Minute
+
+// Config décrit le listener forward. Si SharedKey est défini, le handshake
+// HELO/PING/PONG est exigé avant tout message.
+type Config struct {
+	Addr         string
+	SharedKey    string
+	SelfHostname string
+	TLSCertFile  string
+	TLSKeyFile   string
+	IdleTimeout  time.Duration
+}
+
+type Server struct {
+	cfg    Config
+	ingest internal.IngestFunc
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+var (
+	eventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "forward_events_total",
+		Help: "Total number of events received over the forward protocol, by result",
+	}, []string{"result"})
+	handshakesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "forward_handshakes_total",
+		Help: "Total number of forward handshakes, by result",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(eventsTotal, handshakesTotal)
+}
+
+func NewServer(cfg Config, ingest internal.IngestFunc) *Server {
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	if cfg.SelfHostname == "" {
+		cfg.SelfHostname, _ = os.Hostname()
+	}
+	return &Server{
+		cfg:    cfg,
+		ingest: ingest,
+		conns:  make(map[net.Conn]struct{}),
+	}
+}
+
+// Start ouvre le listener (TCP ou TLS) et accepte les connexions en arrière-plan
+func (s *Server) Start() error {
+	var ln net.Listener
+	var err error
+
+	if s.cfg.TLSCertFile != "" {
+		cert, certErr := tls.LoadX509KeyPair(s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+		if certErr != nil {
+			return fmt.Errorf("forward tls certificate: %w", certErr)
+		}
+		ln, err = tls.Listen("tcp", s.cfg.Addr, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+	} else {
+		ln, err = net.Listen("tcp", s.cfg.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("forward listen: %w", err)
+	}
+
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.serve(ln)
+	return nil
+}
+
+// Addr retourne l'adresse effectivement écoutée
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve(ln net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	dec := msgpack.NewDecoder(bufio.NewReader(conn))
+	dec.UseLooseInterfaceDecoding(true)
+	enc := msgpack.NewEncoder(conn)
+
+	if s.cfg.SharedKey != "" {
+		conn.SetDeadline(time.Now().Add(s.cfg.IdleTimeout))
+		if err := s.handshake(dec, enc); err != nil {
+			handshakesTotal.WithLabelValues("failed").Inc()
+			return
+		}
+		handshakesTotal.WithLabelValues("ok").Inc()
+		conn.SetWriteDeadline(time.Time{})
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.cfg.IdleTimeout))
+
+		raw, err := dec.DecodeInterface()
+		if err != nil {
+			return
+		}
+		arr, ok := raw.([]interface{})
+		if !ok {
+			eventsTotal.WithLabelValues("invalid").Inc()
+			return
+		}
+
+		msg, err := DecodeMessage(arr)
+		if err != nil {
+			// Flux désynchronisé ou client non conforme : on coupe sans ack pour forcer un renvoi
+			eventsTotal.WithLabelValues("invalid").Inc()
+			return
+		}
+
+		if !s.process(msg) {
+			// Échec de stockage : pas d'ack, le client renverra le chunk
+			return
+		}
+
+		if msg.Chunk != "" {
+			conn.SetWriteDeadline(time.Now().Add(s.cfg.IdleTimeout))
+			if err := enc.Encode(map[string]string{"ack": msg.Chunk}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// process ingère les événements d'un message. Les entrées invalides sont ignorées
+// (les renvoyer n'y changerait rien); retourne false si le stockage a échoué.
+// L'identifiant du chunk sert de clé d'idempotence : un chunk renvoyé faute d'ack
+// n'est pas stocké deux fois.
+func (s *Server) process(msg *Message) bool {
+	for i, ev := range msg.Events {
+		entry := ev.ToLogEntry()
+		entry.ID = idempotency.EntryID(msg.Chunk, i)
+
+		err := s.ingest(entry)
+		switch {
+		case err == nil:
+			eventsTotal.WithLabelValues("ok").Inc()
+		case errors.Is(err, handler.ErrWriteFailed):
+			eventsTotal.WithLabelValues("write_error").Inc()
+			return false
+		default:
+			eventsTotal.WithLabelValues("rejected").Inc()
+		}
+	}
+	return true
+}
+
+// handshake applique l'authentification par clé partagée du protocole forward v1 :
+// le serveur envoie HELO (nonce), le client répond PING (digest), le serveur répond PONG.
+func (s *Server) handshake(dec *msgpack.Decoder, enc *msgpack.Encoder) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	helo := []interface{}{"HELO", map[string]interface{}{
+		"nonce":     nonce,
+		"auth":      []byte{},
+		"keepalive": true,
+	}}
+	if err := enc.Encode(helo); err != nil {
+		return err
+	}
+
+	raw, err := dec.DecodeInterface()
+	if err != nil {
+		return err
+	}
+	ping, ok := raw.([]interface{})
+	if !ok || len(ping) < 4 {
+		return fmt.Errorf("%w: malformed PING", ErrInvalidMessage)
+	}
+	kind, _ := asString(ping[0])
+	clientHostname, _ := asString(ping[1])
+	salt, _ := asBytes(ping[2])
+	digest, _ := asString(ping[3])
+	if kind != "PING" {
+		return fmt.Errorf("%w: expected PING, got %q", ErrInvalidMessage, kind)
+	}
+
+	expected := sharedKeyDigest(salt, clientHostname, nonce, s.cfg.SharedKey)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(digest)) != 1 {
+		enc.Encode([]interface{}{"PONG", false, "shared_key mismatch", s.cfg.SelfHostname, ""})
+		return errors.New("forward handshake: shared key mismatch")
+	}
+
+	pong := []interface{}{
+		"PONG",
+		true,
+		"",
+		s.cfg.SelfHostname,
+		sharedKeyDigest(salt, s.cfg.SelfHostname, nonce, s.cfg.SharedKey),
+	}
+	return enc.Encode(pong)
+}
+
+// sharedKeyDigest calcule sha512_hex(salt + hostname + nonce + shared_key)
+func sharedKeyDigest(salt []byte, hostname string, nonce []byte, sharedKey string) string {
+	h := sha512.New()
+	h.Write(salt)
+	h.Write([]byte(hostname))
+	h.Write(nonce)
+	h.Write([]byte(sharedKey))
+	return hex.EncodeToString(h.Sum(nil))
+}
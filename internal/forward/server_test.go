@@ -0,0 +1,215 @@
+package forward_test
+
+import (
+	"bufio"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/forward"
+	"github.com/rypi-dev/logger-server/internal/handler"
+)
+
+type collector struct {
+	mu      sync.Mutex
+	entries []*internal.LogEntry
+	err     error
+}
+
+func (c *collector) ingest(entry *internal.LogEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+func (c *collector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+type client struct {
+	conn net.Conn
+	enc  *msgpack.Encoder
+	dec  *msgpack.Decoder
+}
+
+func dial(t *testing.T, srv *forward.Server) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	dec := msgpack.NewDecoder(bufio.NewReader(conn))
+	dec.UseLooseInterfaceDecoding(true)
+	return &client{conn: conn, enc: msgpack.NewEncoder(conn), dec: dec}
+}
+
+func startServer(t *testing.T, cfg forward.Config, c *collector) *forward.Server {
+	t.Helper()
+	cfg.Addr = "127.0.0.1:0"
+	srv := forward.NewServer(cfg, c.ingest)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func digest(salt []byte, hostname string, nonce []byte, key string) string {
+	sum := sha512.Sum512([]byte(string(salt) + hostname + string(nonce) + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// handshake joue le rôle du client dans l'échange HELO/PING/PONG
+func (c *client) handshake(t *testing.T, key string) []interface{} {
+	t.Helper()
+	raw, err := c.dec.DecodeInterface()
+	if err != nil {
+		t.Fatalf("reading HELO failed: %v", err)
+	}
+	helo := raw.([]interface{})
+	if helo[0] != "HELO" {
+		t.Fatalf("expected HELO, got %v", helo[0])
+	}
+	// Le décodage "loose" retourne les bin msgpack sous forme de string
+	nonce := []byte(helo[1].(map[string]interface{})["nonce"].(string))
+
+	salt := []byte("client-salt")
+	if err := c.enc.Encode([]interface{}{"PING", "agent-1", salt, digest(salt, "agent-1", nonce, key), "", ""}); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err = c.dec.DecodeInterface()
+	if err != nil {
+		t.Fatalf("reading PONG failed: %v", err)
+	}
+	pong := raw.([]interface{})
+	if pong[0] != "PONG" || len(pong) != 5 {
+		t.Fatalf("unexpected PONG %v", pong)
+	}
+	if pong[1] == true && pong[4] != digest(salt, "logger-server", nonce, key) {
+		t.Errorf("unexpected PONG digest")
+	}
+	return pong
+}
+
+func TestServer_AckChunk(t *testing.T) {
+	c := &collector{}
+	srv := startServer(t, forward.Config{}, c)
+	cl := dial(t, srv)
+
+	msg := []interface{}{
+		"app",
+		[]interface{}{
+			[]interface{}{time.Now().Unix(), map[string]interface{}{"message": "one", "level": "info"}},
+			[]interface{}{time.Now().Unix(), map[string]interface{}{"message": "two", "level": "error"}},
+		},
+		map[string]interface{}{"chunk": "chunk-1"},
+	}
+	if err := cl.enc.Encode(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	var ack map[string]interface{}
+	if err := cl.dec.Decode(&ack); err != nil {
+		t.Fatalf("expected ack, got error: %v", err)
+	}
+	if ack["ack"] != "chunk-1" {
+		t.Errorf("unexpected ack %v", ack)
+	}
+	if c.count() != 2 {
+		t.Errorf("expected 2 stored entries, got %d", c.count())
+	}
+}
+
+func TestServer_InvalidEntriesAreAckedButNotStored(t *testing.T) {
+	c := &collector{}
+	srv := startServer(t, forward.Config{}, c)
+	cl := dial(t, srv)
+
+	msg := []interface{}{"app", time.Now().Unix(), map[string]interface{}{"level": "info"}, map[string]interface{}{"chunk": "empty"}}
+	if err := cl.enc.Encode(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	var ack map[string]interface{}
+	if err := cl.dec.Decode(&ack); err != nil {
+		t.Fatalf("expected ack, got error: %v", err)
+	}
+	if c.count() != 0 {
+		t.Errorf("expected invalid entry to be dropped, got %d entries", c.count())
+	}
+}
+
+func TestServer_NoAckOnWriteFailure(t *testing.T) {
+	c := &collector{err: fmt.Errorf("%w: disk full", handler.ErrWriteFailed)}
+	srv := startServer(t, forward.Config{}, c)
+	cl := dial(t, srv)
+
+	msg := []interface{}{"app", time.Now().Unix(), map[string]interface{}{"message": "lost"}, map[string]interface{}{"chunk": "retry-me"}}
+	if err := cl.enc.Encode(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	var ack map[string]interface{}
+	if err := cl.dec.Decode(&ack); err == nil {
+		t.Errorf("expected connection to be closed without ack, got %v", ack)
+	}
+}
+
+func TestServer_SharedKeyHandshake(t *testing.T) {
+	c := &collector{}
+	srv := startServer(t, forward.Config{SharedKey: "s3cret", SelfHostname: "logger-server"}, c)
+	cl := dial(t, srv)
+
+	pong := cl.handshake(t, "s3cret")
+	if pong[1] != true {
+		t.Fatalf("expected successful PONG, got %v", pong)
+	}
+
+	msg := []interface{}{"app", time.Now().Unix(), map[string]interface{}{"message": "authenticated"}, map[string]interface{}{"chunk": "c"}}
+	if err := cl.enc.Encode(msg); err != nil {
+		t.Fatal(err)
+	}
+	var ack map[string]interface{}
+	if err := cl.dec.Decode(&ack); err != nil {
+		t.Fatalf("expected ack after handshake, got %v", err)
+	}
+	if c.count() != 1 {
+		t.Errorf("expected 1 stored entry, got %d", c.count())
+	}
+}
+
+func TestServer_SharedKeyMismatch(t *testing.T) {
+	c := &collector{}
+	srv := startServer(t, forward.Config{SharedKey: "s3cret", SelfHostname: "logger-server"}, c)
+	cl := dial(t, srv)
+
+	pong := cl.handshake(t, "wrong")
+	if pong[1] != false {
+		t.Fatalf("expected failed PONG, got %v", pong)
+	}
+
+	// Le serveur doit fermer la connexion
+	if _, err := cl.dec.DecodeInterface(); err == nil {
+		t.Error("expected connection to be closed after failed handshake")
+	}
+}
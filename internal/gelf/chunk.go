@@ -0,0 +1,136 @@
+package gelf
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Paramètres du découpage UDP définis par la spécification GELF
+const (
+	MaxChunks           = 128
+	chunkHeaderSize     = 12
+	DefaultChunkTimeout = 5 * time.Second
+	DefaultMaxPending   = 1000
+)
+
+var (
+	ErrInvalidChunk   = errors.New("invalid GELF chunk")
+	ErrTooManyPending = errors.New("too many incomplete GELF messages")
+)
+
+// IsChunked indique si un datagramme est un fragment (magic 0x1e 0x0f)
+func IsChunked(packet []byte) bool {
+	return len(packet) >= 2 && packet[0] == 0x1e && packet[1] == 0x0f
+}
+
+type chunkSet struct {
+	chunks   [][]byte
+	received int
+	size     int
+	first    time.Time
+}
+
+// Reassembler reconstitue les messages UDP découpés. Un message dont tous les fragments
+// ne sont pas arrivés dans le délai est abandonné (voir Expire).
+type Reassembler struct {
+	mu         sync.Mutex
+	pending    map[[8]byte]*chunkSet
+	timeout    time.Duration
+	maxPending int
+	maxSize    int
+	now        func() time.Time
+}
+
+func NewReassembler(timeout time.Duration, maxPending, maxSize int) *Reassembler {
+	if timeout <= 0 {
+		timeout = DefaultChunkTimeout
+	}
+	if maxPending <= 0 {
+		maxPending = DefaultMaxPending
+	}
+	return &Reassembler{
+		pending:    make(map[[8]byte]*chunkSet),
+		timeout:    timeout,
+		maxPending: maxPending,
+		maxSize:    maxSize,
+		now:        time.Now,
+	}
+}
+
+// Add enregistre un fragment et retourne le payload complet quand le dernier arrive,
+// nil sinon. Les doublons sont ignorés.
+func (r *Reassembler) Add(packet []byte) ([]byte, error) {
+	if !IsChunked(packet) || len(packet) <= chunkHeaderSize {
+		return nil, ErrInvalidChunk
+	}
+
+	var id [8]byte
+	copy(id[:], packet[2:10])
+	seq, count := int(packet[10]), int(packet[11])
+	if count == 0 || count > MaxChunks || seq >= count {
+		return nil, ErrInvalidChunk
+	}
+	data := packet[chunkHeaderSize:]
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	set, ok := r.pending[id]
+	if !ok {
+		if len(r.pending) >= r.maxPending {
+			return nil, ErrTooManyPending
+		}
+		set = &chunkSet{chunks: make([][]byte, count), first: r.now()}
+		r.pending[id] = set
+	}
+	if len(set.chunks) != count {
+		delete(r.pending, id)
+		return nil, ErrInvalidChunk
+	}
+	if set.chunks[seq] != nil {
+		return nil, nil
+	}
+
+	set.size += len(data)
+	if r.maxSize > 0 && set.size > r.maxSize {
+		delete(r.pending, id)
+		return nil, ErrInvalidChunk
+	}
+	set.chunks[seq] = append([]byte(nil), data...)
+	set.received++
+
+	if set.received < count {
+		return nil, nil
+	}
+
+	delete(r.pending, id)
+	payload := make([]byte, 0, set.size)
+	for _, chunk := range set.chunks {
+		payload = append(payload, chunk...)
+	}
+	return payload, nil
+}
+
+// Expire abandonne les messages incomplets plus vieux que le délai et retourne leur nombre
+func (r *Reassembler) Expire() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	expired := 0
+	for id, set := range r.pending {
+		if now.Sub(set.first) > r.timeout {
+			delete(r.pending, id)
+			expired++
+		}
+	}
+	return expired
+}
+
+// Pending retourne le nombre de messages en cours de reconstitution
+func (r *Reassembler) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
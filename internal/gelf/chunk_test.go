@@ -0,0 +1,90 @@
+package gelf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func chunk(id byte, seq, count int, data string) []byte {
+	packet := []byte{0x1e, 0x0f, id, 0, 0, 0, 0, 0, 0, 0, byte(seq), byte(count)}
+	return append(packet, data...)
+}
+
+func TestReassembler_OutOfOrder(t *testing.T) {
+	r := NewReassembler(time.Second, 10, 1<<20)
+
+	for _, packet := range [][]byte{chunk(1, 2, 3, "c"), chunk(1, 0, 3, "a"), chunk(1, 0, 3, "a")} {
+		payload, err := r.Add(packet)
+		if err != nil || payload != nil {
+			t.Fatalf("expected pending message, got %q, %v", payload, err)
+		}
+	}
+
+	payload, err := r.Add(chunk(1, 1, 3, "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != "abc" {
+		t.Errorf("expected %q, got %q", "abc", payload)
+	}
+	if r.Pending() != 0 {
+		t.Errorf("expected no pending message, got %d", r.Pending())
+	}
+}
+
+func TestReassembler_InvalidChunks(t *testing.T) {
+	r := NewReassembler(time.Second, 10, 4)
+
+	tests := []struct {
+		name   string
+		packet []byte
+	}{
+		{"not chunked", []byte(`{"short_message":"x"}`)},
+		{"header only", chunk(1, 0, 2, "")},
+		{"zero count", chunk(1, 0, 0, "a")},
+		{"too many chunks", chunk(1, 0, MaxChunks+1, "a")},
+		{"seq out of range", chunk(1, 2, 2, "a")},
+		{"too large", chunk(2, 0, 2, "abcde")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := r.Add(tt.packet); !errors.Is(err, ErrInvalidChunk) {
+				t.Errorf("expected ErrInvalidChunk, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReassembler_MaxPending(t *testing.T) {
+	r := NewReassembler(time.Second, 1, 1<<20)
+
+	if _, err := r.Add(chunk(1, 0, 2, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Add(chunk(2, 0, 2, "a")); !errors.Is(err, ErrTooManyPending) {
+		t.Errorf("expected ErrTooManyPending, got %v", err)
+	}
+}
+
+func TestReassembler_Expire(t *testing.T) {
+	now := time.Date(2025, 8, 6, 14, 0, 0, 0, time.UTC)
+	r := NewReassembler(5*time.Second, 10, 1<<20)
+	r.now = func() time.Time { return now }
+
+	r.Add(chunk(1, 0, 2, "a"))
+	if n := r.Expire(); n != 0 {
+		t.Errorf("expected nothing to expire, got %d", n)
+	}
+
+	now = now.Add(6 * time.Second)
+	if n := r.Expire(); n != 1 {
+		t.Errorf("expected 1 expired message, got %d", n)
+	}
+
+	// Le fragment tardif ouvre un nouveau message incomplet
+	if payload, _ := r.Add(chunk(1, 1, 2, "b")); payload != nil {
+		t.Errorf("expected late chunk not to complete the message, got %q", payload)
+	}
+}
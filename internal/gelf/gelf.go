@@ -0,0 +1,155 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/syslog/syslog"
+)
+
+var ErrInvalidMessage = errors.New("invalid GELF message")
+
+// Champs additionnels (sans "_") donnant le service, par ordre de priorité;
+// le driver Docker envoie _container_name
+var serviceExtras = []string{"service", "container_name"}
+
+// Message est un message GELF 1.1 décodé. Les champs additionnels (préfixés par "_")
+// sont conservés dans Extra, sans le préfixe.
+type Message struct {
+	Version      string
+	Host         string
+	ShortMessage string
+	FullMessage  string
+	Timestamp    time.Time
+	Level        int
+	Extra        map[string]interface{}
+}
+
+// Decode décompresse (gzip, zlib ou non compressé) puis décode un payload GELF.
+// maxSize borne la taille décompressée.
+func Decode(payload []byte, maxSize int) (*Message, error) {
+	data, err := decompress(payload, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	msg := &Message{Level: 1, Extra: make(map[string]interface{})}
+	for key, value := range raw {
+		switch key {
+		case "version":
+			msg.Version, _ = value.(string)
+		case "host":
+			msg.Host, _ = value.(string)
+		case "short_message":
+			msg.ShortMessage, _ = value.(string)
+		case "full_message":
+			msg.FullMessage, _ = value.(string)
+		case "timestamp":
+			if n, ok := value.(json.Number); ok {
+				if secs, err := n.Float64(); err == nil {
+					whole := int64(secs)
+					msg.Timestamp = time.Unix(whole, int64((secs-float64(whole))*1e9)).Round(time.Microsecond).UTC()
+				}
+			}
+		case "level":
+			if n, ok := value.(json.Number); ok {
+				if lvl, err := n.Int64(); err == nil {
+					msg.Level = int(lvl)
+				}
+			}
+		case "_id":
+			// Réservé par la spécification
+		default:
+			if strings.HasPrefix(key, "_") {
+				msg.Extra[strings.TrimPrefix(key, "_")] = value
+			}
+		}
+	}
+
+	if strings.TrimSpace(msg.ShortMessage) == "" {
+		return nil, fmt.Errorf("%w: short_message is required", ErrInvalidMessage)
+	}
+	return msg, nil
+}
+
+func decompress(payload []byte, maxSize int) ([]byte, error) {
+	var r io.Reader
+	switch {
+	case len(payload) >= 2 && payload[0] == 0x1f && payload[1] == 0x8b:
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		defer zr.Close()
+		r = zr
+	case len(payload) >= 2 && payload[0] == 0x78 && (uint16(payload[0])<<8|uint16(payload[1]))%31 == 0:
+		zr, err := zlib.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		if len(payload) > maxSize {
+			return nil, fmt.Errorf("%w: message exceeds %d bytes", ErrInvalidMessage, maxSize)
+		}
+		return payload, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("%w: decompressed message exceeds %d bytes", ErrInvalidMessage, maxSize)
+	}
+	return data, nil
+}
+
+// ToLogEntry convertit le message : level est une sévérité syslog, short_message le message,
+// full_message et les champs additionnels vont dans le contexte.
+func (m *Message) ToLogEntry() *internal.LogEntry {
+	entry := &internal.LogEntry{
+		Level:     string(syslog.SeverityToLogLevel(m.Level)),
+		Message:   m.ShortMessage,
+		Host:      m.Host,
+		Timestamp: m.Timestamp,
+	}
+
+	ctx := make(map[string]interface{}, len(m.Extra)+1)
+	for k, v := range m.Extra {
+		ctx[k] = v
+	}
+	for _, key := range serviceExtras {
+		if s, ok := ctx[key].(string); ok && s != "" {
+			entry.Service = s
+			if key == "service" {
+				delete(ctx, key)
+			}
+			break
+		}
+	}
+	if m.FullMessage != "" && m.FullMessage != m.ShortMessage {
+		ctx["full_message"] = m.FullMessage
+	}
+
+	if len(ctx) > 0 {
+		entry.Context = ctx
+	}
+	return entry
+}
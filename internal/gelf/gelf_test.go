@@ -0,0 +1,137 @@
+package gelf_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/gelf"
+)
+
+const dockerMessage = `{"version":"1.1","host":"docker-host","short_message":"GET /health 200","timestamp":1754489520.25,"level":6,"_container_name":"web","_image_name":"nginx:1.27","_id":"ignored"}`
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zlibBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode_Compression(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{"plain", []byte(dockerMessage)},
+		{"gzip", gzipBytes(t, dockerMessage)},
+		{"zlib", zlibBytes(t, dockerMessage)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := gelf.Decode(tt.payload, 1<<20)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if msg.ShortMessage != "GET /health 200" || msg.Host != "docker-host" || msg.Level != 6 {
+				t.Errorf("unexpected message %+v", msg)
+			}
+			want := time.Date(2025, 8, 6, 14, 12, 0, 250000000, time.UTC)
+			if !msg.Timestamp.Equal(want) {
+				t.Errorf("expected timestamp %v, got %v", want, msg.Timestamp)
+			}
+			if msg.Extra["container_name"] != "web" {
+				t.Errorf("expected _container_name in extras, got %v", msg.Extra)
+			}
+			if _, ok := msg.Extra["id"]; ok {
+				t.Error("expected reserved _id to be ignored")
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{"invalid json", []byte("not json")},
+		{"missing short_message", []byte(`{"version":"1.1","host":"h"}`)},
+		{"too large", []byte(`{"short_message":"` + strings.Repeat("x", 200) + `"}`)},
+		{"decompressed too large", gzipBytes(t, `{"short_message":"`+strings.Repeat("x", 200)+`"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gelf.Decode(tt.payload, 128); !errors.Is(err, gelf.ErrInvalidMessage) {
+				t.Errorf("expected ErrInvalidMessage, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMessage_ToLogEntry(t *testing.T) {
+	msg, err := gelf.Decode([]byte(dockerMessage), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := msg.ToLogEntry()
+	if entry.Level != "INFO" || entry.Message != "GET /health 200" || entry.Host != "docker-host" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Service != "web" {
+		t.Errorf("expected service from _container_name, got %q", entry.Service)
+	}
+	if entry.Context["image_name"] != "nginx:1.27" {
+		t.Errorf("expected extras in context, got %v", entry.Context)
+	}
+}
+
+func TestMessage_ToLogEntry_ServiceAndFullMessage(t *testing.T) {
+	msg, err := gelf.Decode([]byte(`{"short_message":"boom","full_message":"boom\nstack trace","level":3,"_service":"billing"}`), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := msg.ToLogEntry()
+	if entry.Level != "ERROR" || entry.Service != "billing" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if _, ok := entry.Context["service"]; ok {
+		t.Error("expected _service to be removed from context")
+	}
+	if entry.Context["full_message"] != "boom\nstack trace" {
+		t.Errorf("expected full_message in context, got %v", entry.Context)
+	}
+}
+
+func TestMessage_ToLogEntry_DefaultLevel(t *testing.T) {
+	msg, err := gelf.Decode([]byte(`{"short_message":"no level"}`), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Level par défaut de la spécification : 1 (alert)
+	if entry := msg.ToLogEntry(); entry.Level != "FATAL" {
+		t.Errorf("expected FATAL, got %s", entry.Level)
+	}
+}
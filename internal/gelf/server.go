@@ -0,0 +1,281 @@
+package gelf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rypi-dev/logger-server/internal"
+)
+
+const (
+	DefaultMaxMessageSize = 1 << 20
+	DefaultIdleTimeout    = 5 * time.Minute
+)
+
+// Config décrit les listeners GELF; une adresse vide désactive le transport.
+type Config struct {
+	UDPAddr        string
+	TCPAddr        string
+	MaxMessageSize int // taille maximale d'un message décompressé
+	ChunkTimeout   time.Duration
+	MaxPending     int // messages UDP incomplets conservés simultanément
+	IdleTimeout    time.Duration
+}
+
+type Server struct {
+	cfg         Config
+	ingest      internal.IngestFunc
+	reassembler *Reassembler
+
+	mu       sync.Mutex
+	udpConn  net.PacketConn
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+var (
+	messagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gelf_messages_total",
+		Help: "Total number of GELF messages received, by transport and result",
+	}, []string{"transport", "result"})
+	chunksDroppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gelf_chunks_dropped_total",
+		Help: "Total number of GELF UDP chunks dropped, by reason",
+	}, []string{"reason"})
+	incompleteMessagesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gelf_incomplete_messages_total",
+		Help: "Total number of chunked GELF messages discarded because not all chunks arrived in time",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(messagesTotal, chunksDroppedTotal, incompleteMessagesTotal)
+}
+
+func NewServer(cfg Config, ingest internal.IngestFunc) *Server {
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if cfg.ChunkTimeout <= 0 {
+		cfg.ChunkTimeout = DefaultChunkTimeout
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	return &Server{
+		cfg:         cfg,
+		ingest:      ingest,
+		reassembler: NewReassembler(cfg.ChunkTimeout, cfg.MaxPending, cfg.MaxMessageSize),
+		conns:       make(map[net.Conn]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start ouvre les listeners configurés et lance leurs boucles de lecture
+func (s *Server) Start() error {
+	if s.cfg.UDPAddr != "" {
+		conn, err := net.ListenPacket("udp", s.cfg.UDPAddr)
+		if err != nil {
+			return fmt.Errorf("gelf udp listen: %w", err)
+		}
+		s.mu.Lock()
+		s.udpConn = conn
+		s.mu.Unlock()
+		s.wg.Add(2)
+		go s.serveUDP(conn)
+		go s.expireLoop()
+	}
+
+	if s.cfg.TCPAddr != "" {
+		ln, err := net.Listen("tcp", s.cfg.TCPAddr)
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("gelf tcp listen: %w", err)
+		}
+		s.mu.Lock()
+		s.listener = ln
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.serveTCP(ln)
+	}
+
+	return nil
+}
+
+// Addrs retourne les adresses effectivement écoutées (utile avec le port 0)
+func (s *Server) Addrs() []net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var addrs []net.Addr
+	if s.udpConn != nil {
+		addrs = append(addrs, s.udpConn.LocalAddr())
+	}
+	if s.listener != nil {
+		addrs = append(addrs, s.listener.Addr())
+	}
+	return addrs
+}
+
+// Close ferme les listeners et connexions ouvertes puis attend la fin des goroutines
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+
+	var firstErr error
+	if s.udpConn != nil {
+		firstErr = s.udpConn.Close()
+	}
+	if s.listener != nil {
+		if err := s.listener.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return firstErr
+}
+
+func (s *Server) serveUDP(conn net.PacketConn) {
+	defer s.wg.Done()
+
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		packet := buf[:n]
+		if !IsChunked(packet) {
+			s.handleMessage("udp", packet)
+			continue
+		}
+
+		payload, err := s.reassembler.Add(packet)
+		switch {
+		case errors.Is(err, ErrTooManyPending):
+			chunksDroppedTotal.WithLabelValues("too_many_pending").Inc()
+		case err != nil:
+			chunksDroppedTotal.WithLabelValues("invalid").Inc()
+		case payload != nil:
+			s.handleMessage("udp", payload)
+		}
+	}
+}
+
+// expireLoop abandonne périodiquement les messages dont des fragments manquent
+func (s *Server) expireLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.ChunkTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if n := s.reassembler.Expire(); n > 0 {
+				incompleteMessagesTotal.Add(float64(n))
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Server) serveTCP(ln net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn lit les messages d'une connexion TCP, séparés par un octet nul (\0)
+func (s *Server) handleConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	reader := bufio.NewReaderSize(conn, s.cfg.MaxMessageSize+1)
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.cfg.IdleTimeout))
+
+		frame, err := reader.ReadSlice(0)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			messagesTotal.WithLabelValues("tcp", "too_large").Inc()
+			return
+		}
+		if err != nil && !(errors.Is(err, io.EOF) && len(frame) > 0) {
+			return
+		}
+
+		if len(frame) > 0 && frame[len(frame)-1] == 0 {
+			frame = frame[:len(frame)-1]
+		}
+		if len(frame) > 0 {
+			s.handleMessage("tcp", frame)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handleMessage(transport string, payload []byte) {
+	msg, err := Decode(payload, s.cfg.MaxMessageSize)
+	if err != nil {
+		messagesTotal.WithLabelValues(transport, "parse_error").Inc()
+		return
+	}
+
+	if err := s.ingest(msg.ToLogEntry()); err != nil {
+		messagesTotal.WithLabelValues(transport, "rejected").Inc()
+		return
+	}
+	messagesTotal.WithLabelValues(transport, "ok").Inc()
+}
@@ -0,0 +1,135 @@
+package gelf_test
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/gelf"
+)
+
+// collector capte les entrées ingérées par le serveur GELF
+type collector struct {
+	mu      sync.Mutex
+	entries []*internal.LogEntry
+}
+
+func (c *collector) ingest(entry *internal.LogEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+func (c *collector) waitFor(t *testing.T, n int) []*internal.LogEntry {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		if len(c.entries) >= n {
+			out := append([]*internal.LogEntry(nil), c.entries...)
+			c.mu.Unlock()
+			return out
+		}
+		c.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d entries", n)
+	return nil
+}
+
+func startServer(t *testing.T, cfg gelf.Config) (*gelf.Server, *collector) {
+	t.Helper()
+	c := &collector{}
+	srv := gelf.NewServer(cfg, c.ingest)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv, c
+}
+
+func TestServer_UDP(t *testing.T) {
+	srv, c := startServer(t, gelf.Config{UDPAddr: "127.0.0.1:0"})
+
+	conn, err := net.Dial("udp", srv.Addrs()[0].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(gzipBytes(t, dockerMessage)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := c.waitFor(t, 1)
+	if entries[0].Message != "GET /health 200" || entries[0].Service != "web" {
+		t.Errorf("unexpected entry %+v", entries[0])
+	}
+}
+
+func TestServer_UDP_Chunked(t *testing.T) {
+	srv, c := startServer(t, gelf.Config{UDPAddr: "127.0.0.1:0"})
+
+	conn, err := net.Dial("udp", srv.Addrs()[0].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	payload := zlibBytes(t, dockerMessage)
+	half := len(payload) / 2
+	parts := [][]byte{payload[:half], payload[half:]}
+	for i := len(parts) - 1; i >= 0; i-- {
+		packet := append([]byte{0x1e, 0x0f, 1, 2, 3, 4, 5, 6, 7, 8, byte(i), byte(len(parts))}, parts[i]...)
+		if _, err := conn.Write(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries := c.waitFor(t, 1)
+	if entries[0].Host != "docker-host" {
+		t.Errorf("unexpected entry %+v", entries[0])
+	}
+}
+
+func TestServer_TCP_NullDelimited(t *testing.T) {
+	srv, c := startServer(t, gelf.Config{TCPAddr: "127.0.0.1:0"})
+
+	conn, err := net.Dial("tcp", srv.Addrs()[0].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stream := `{"short_message":"first","level":4}` + "\x00" + `not json` + "\x00" + `{"short_message":"second","level":3}` + "\x00"
+	if _, err := conn.Write([]byte(stream)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := c.waitFor(t, 2)
+	if entries[0].Message != "first" || entries[0].Level != "WARN" || entries[1].Message != "second" {
+		t.Errorf("unexpected entries %+v, %+v", entries[0], entries[1])
+	}
+}
+
+func TestServer_Close(t *testing.T) {
+	srv := gelf.NewServer(gelf.Config{UDPAddr: "127.0.0.1:0", TCPAddr: "127.0.0.1:0"}, (&collector{}).ingest)
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.Addrs()) != 2 {
+		t.Fatalf("expected 2 listeners, got %v", srv.Addrs())
+	}
+	if err := srv.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Client appelle logger.v1.LogService depuis un service Go, sans code généré
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+// IngestStream envoie des lots et reçoit leurs acquittements dans l'ordre d'envoi
+type IngestStream struct {
+	grpc.ClientStream
+}
+
+func (s *IngestStream) Send(batch *IngestBatch) error {
+	return s.SendMsg(batch)
+}
+
+func (s *IngestStream) Recv() (*IngestAck, error) {
+	ack := &IngestAck{}
+	if err := s.RecvMsg(ack); err != nil {
+		return nil, err
+	}
+	return ack, nil
+}
+
+// EntryStream reçoit les entrées de Query et Tail; Recv retourne io.EOF en fin de flux
+type EntryStream struct {
+	grpc.ClientStream
+}
+
+func (s *EntryStream) Recv() (*LogEntry, error) {
+	entry := &LogEntry{}
+	if err := s.RecvMsg(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (c *Client) Ingest(ctx context.Context, opts ...grpc.CallOption) (*IngestStream, error) {
+	stream, err := c.cc.NewStream(ctx, &serviceDesc.Streams[0], "/logger.v1.LogService/Ingest", withCodec(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return &IngestStream{stream}, nil
+}
+
+func (c *Client) Query(ctx context.Context, req *QueryRequest, opts ...grpc.CallOption) (*EntryStream, error) {
+	return c.serverStream(ctx, &serviceDesc.Streams[1], "/logger.v1.LogService/Query", req, opts)
+}
+
+func (c *Client) Tail(ctx context.Context, req *TailRequest, opts ...grpc.CallOption) (*EntryStream, error) {
+	return c.serverStream(ctx, &serviceDesc.Streams[2], "/logger.v1.LogService/Tail", req, opts)
+}
+
+func (c *Client) serverStream(ctx context.Context, desc *grpc.StreamDesc, method string, req message, opts []grpc.CallOption) (*EntryStream, error) {
+	stream, err := c.cc.NewStream(ctx, desc, method, withCodec(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &EntryStream{stream}, nil
+}
+
+func withCodec(opts []grpc.CallOption) []grpc.CallOption {
+	return append([]grpc.CallOption{grpc.ForceCodec(codec{})}, opts...)
+}
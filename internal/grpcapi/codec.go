@@ -0,0 +1,25 @@
+package grpcapi
+
+import "fmt"
+
+// codec encode les messages du service avec leur encodage protowire. Il porte le nom "proto"
+// pour rester interopérable avec les clients générés depuis proto/logger.proto.
+type codec struct{}
+
+func (codec) Name() string { return "proto" }
+
+func (codec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		return nil, fmt.Errorf("grpcapi: cannot marshal %T", v)
+	}
+	return m.marshal(nil), nil
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(message)
+	if !ok {
+		return fmt.Errorf("grpcapi: cannot unmarshal into %T", v)
+	}
+	return m.unmarshal(data)
+}
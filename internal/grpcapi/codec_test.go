@@ -0,0 +1,60 @@
+package grpcapi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCodec_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   message
+		out  message
+	}{
+		{"batch", &IngestBatch{BatchID: 7, IdempotencyKey: "retry-key", Entries: []*LogEntry{
+			{Level: "INFO", Message: "one", TimestampUnixNano: 1754489520000000000},
+			{Level: "WARN", Message: "two", Service: "api", Host: "web-1", ContextJSON: []byte(`{"a":1}`), ID: "evt-2"},
+		}}, &IngestBatch{}},
+		{"ack", &IngestAck{BatchID: 7, Accepted: 1, Rejected: []*Rejection{{Index: 1, Reason: "message is required"}}}, &IngestAck{}},
+		{"query", &QueryRequest{Level: "ERROR", Page: 2, Limit: -1}, &QueryRequest{}},
+		{"tail", &TailRequest{MinLevel: "WARN", Service: "billing"}, &TailRequest{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := codec{}.Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := (codec{}).Unmarshal(data, tt.out); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.in, tt.out) {
+				t.Errorf("round trip mismatch:\n got %+v\nwant %+v", tt.out, tt.in)
+			}
+		})
+	}
+}
+
+func TestCodec_WireFormat(t *testing.T) {
+	// Encodage attendu d'un protoc standard pour TailRequest{min_level: "WARN"} et
+	// QueryRequest{page: 2}
+	data, _ := codec{}.Marshal(&TailRequest{MinLevel: "WARN"})
+	if want := []byte{0x0a, 0x04, 'W', 'A', 'R', 'N'}; !bytes.Equal(data, want) {
+		t.Errorf("expected % x, got % x", want, data)
+	}
+	data, _ = codec{}.Marshal(&QueryRequest{Page: 2})
+	if want := []byte{0x10, 0x02}; !bytes.Equal(data, want) {
+		t.Errorf("expected % x, got % x", want, data)
+	}
+}
+
+func TestCodec_Errors(t *testing.T) {
+	if _, err := (codec{}).Marshal("not a message"); err == nil {
+		t.Error("expected error for unknown type")
+	}
+	if err := (codec{}).Unmarshal([]byte{0x0a, 0x10, 'x'}, &TailRequest{}); err == nil {
+		t.Error("expected error for truncated message")
+	}
+}
@@ -0,0 +1,82 @@
+package grpcapi
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rypi-dev/logger-server/internal"
+)
+
+const DefaultTailBuffer = 256
+
+var tailDroppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "grpc_tail_dropped_total",
+	Help: "Total number of entries not delivered to a Tail subscriber because it was too slow",
+})
+
+func init() {
+	prometheus.MustRegister(tailDroppedTotal)
+}
+
+// Hub diffuse les entrées ingérées aux abonnés Tail. Publish ne bloque jamais :
+// un abonné dont le tampon est plein perd les entrées suivantes.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*subscription]struct{}
+}
+
+type subscription struct {
+	ch     chan internal.LogEntry
+	filter func(entry *internal.LogEntry) bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*subscription]struct{})}
+}
+
+// Publish a la signature d'un hook d'ingestion (Handler.AddIngestHook)
+func (h *Hub) Publish(entry internal.LogEntry) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subs {
+		if sub.filter != nil && !sub.filter(&entry) {
+			continue
+		}
+		select {
+		case sub.ch <- entry:
+		default:
+			tailDroppedTotal.Inc()
+		}
+	}
+}
+
+// Subscribe retourne le canal des entrées acceptées par filter (nil = toutes) et la fonction
+// de désabonnement
+func (h *Hub) Subscribe(buffer int, filter func(entry *internal.LogEntry) bool) (<-chan internal.LogEntry, func()) {
+	if buffer <= 0 {
+		buffer = DefaultTailBuffer
+	}
+	sub := &subscription{ch: make(chan internal.LogEntry, buffer), filter: filter}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, sub)
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Subscribers retourne le nombre d'abonnés actifs
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
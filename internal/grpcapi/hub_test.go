@@ -0,0 +1,53 @@
+package grpcapi_test
+
+import (
+	"testing"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/grpcapi"
+)
+
+func TestHub_PublishAndFilter(t *testing.T) {
+	hub := grpcapi.NewHub()
+
+	all, unsubscribeAll := hub.Subscribe(10, nil)
+	defer unsubscribeAll()
+	errorsOnly, unsubscribeErrors := hub.Subscribe(10, func(e *internal.LogEntry) bool { return e.Level == "ERROR" })
+	defer unsubscribeErrors()
+
+	hub.Publish(internal.LogEntry{Level: "INFO", Message: "one"})
+	hub.Publish(internal.LogEntry{Level: "ERROR", Message: "two"})
+
+	if len(all) != 2 {
+		t.Errorf("expected 2 entries for unfiltered subscriber, got %d", len(all))
+	}
+	if len(errorsOnly) != 1 || (<-errorsOnly).Message != "two" {
+		t.Error("expected only the ERROR entry for filtered subscriber")
+	}
+}
+
+func TestHub_SlowSubscriberDoesNotBlock(t *testing.T) {
+	hub := grpcapi.NewHub()
+	entries, unsubscribe := hub.Subscribe(1, nil)
+	defer unsubscribe()
+
+	hub.Publish(internal.LogEntry{Message: "kept"})
+	hub.Publish(internal.LogEntry{Message: "dropped"})
+
+	if len(entries) != 1 || (<-entries).Message != "kept" {
+		t.Error("expected the first entry to be kept and the second dropped")
+	}
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	hub := grpcapi.NewHub()
+	_, unsubscribe := hub.Subscribe(1, nil)
+	if hub.Subscribers() != 1 {
+		t.Fatalf("expected 1 subscriber, got %d", hub.Subscribers())
+	}
+	unsubscribe()
+	unsubscribe()
+	if hub.Subscribers() != 0 {
+		t.Errorf("expected no subscriber, got %d", hub.Subscribers())
+	}
+}
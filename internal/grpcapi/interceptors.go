@@ -0,0 +1,159 @@
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+)
+
+// Limiter est le rate limiter partagé avec l'API HTTP (ratelimit.RateLimiter) : même
+// client (IP, clé, tenant ou service), mêmes limites des entrées par niveau
+type Limiter interface {
+	Allow(r *http.Request) (bool, time.Duration)
+	AllowEntry(r *http.Request, level string) error
+}
+
+// StreamAPIKeyInterceptor exige la clé API dans la métadonnée "x-api-key", comme le header
+// X-API-Key de l'API HTTP
+func StreamAPIKeyInterceptor(validKey string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		key := metadataValue(ss.Context(), "x-api-key")
+		if key == "" || key != validKey {
+			return status.Error(codes.Unauthenticated, "Unauthorized")
+		}
+		return handler(srv, ss)
+	}
+}
+
+// StreamScopedAPIKeyInterceptor authentifie la métadonnée "x-api-key" auprès de keys
+// (clés nommées, voir apikey.Keyring). Ingest exige le scope ingest, Query et Tail le
+// scope read; la clé est placée dans le contexte du flux pour apikey.Bind. Une clé d'un
+// tenant inconnu de tenants (nil : aucun tenant) est refusée.
+func StreamScopedAPIKeyInterceptor(keys apikey.Authenticator, tenants apikey.TenantChecker) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		key, err := keys.Authenticate(metadataValue(ss.Context(), "x-api-key"))
+		if err != nil {
+			return status.Error(codes.Unauthenticated, "Unauthorized")
+		}
+		if key.Tenant != "" && (tenants == nil || !tenants.Exists(key.Tenant)) {
+			return status.Error(codes.PermissionDenied, apikey.ErrUnknownTenant.Error())
+		}
+
+		scope := apikey.ScopeRead
+		if strings.HasSuffix(info.FullMethod, "/Ingest") {
+			scope = apikey.ScopeIngest
+		}
+		if !key.Allows(scope) {
+			return status.Errorf(codes.PermissionDenied, "API key lacks the '%s' scope", scope)
+		}
+		return handler(srv, &keyedStream{ServerStream: ss, ctx: apikey.WithKey(ss.Context(), key)})
+	}
+}
+
+type keyedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *keyedStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamRateLimitInterceptor décompte l'ouverture du flux puis chaque message reçu (un lot
+// Ingest vaut une requête HTTP) avec la limite par défaut. Placé après l'authentification,
+// qui fournit la clé des limites par clé, tenant ou service. Un client limité reçoit
+// ResourceExhausted et le délai d'attente dans la métadonnée "retry-after".
+func StreamRateLimitInterceptor(limiter Limiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ls := &limitedStream{
+			ServerStream: ss,
+			limiter:      limiter,
+			req:          callRequest(ss.Context(), info.FullMethod),
+		}
+		if err := ls.allow(); err != nil {
+			return err
+		}
+		return handler(srv, ls)
+	}
+}
+
+type limitedStream struct {
+	grpc.ServerStream
+	limiter Limiter
+	req     *http.Request
+}
+
+func (s *limitedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.allow()
+}
+
+func (s *limitedStream) allow() error {
+	allowed, retryAfter := s.limiter.Allow(s.req)
+	if allowed {
+		return nil
+	}
+
+	seconds := int(retryAfter.Seconds())
+	if seconds < 0 {
+		seconds = 0
+	}
+	s.SetTrailer(metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+	return status.Error(codes.ResourceExhausted, "Too Many Requests")
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// callRequest décrit un appel gRPC comme une requête HTTP pour le Limiter : contexte (clé
+// authentifiée), adresse et certificat du pair. Les métadonnées du client ne sont pas
+// reprises : elles ne choisissent ni le client ni le niveau.
+func callRequest(ctx context.Context, method string) *http.Request {
+	r := &http.Request{
+		Method:     http.MethodPost,
+		URL:        &url.URL{Path: method},
+		Header:     http.Header{},
+		RemoteAddr: "unknown",
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+	return r.WithContext(ctx)
+}
+
+// limitEntries refuse (ratelimit.ErrLimited) les entrées au-delà de la limite de leur
+// niveau, avant de les passer à ingest, comme POST /log/batch
+func limitEntries(limiter Limiter, r *http.Request, ingest internal.IngestFunc) internal.IngestFunc {
+	return func(entry *internal.LogEntry) error {
+		if err := limiter.AllowEntry(r, entry.Level); err != nil {
+			return err
+		}
+		return ingest(entry)
+	}
+}
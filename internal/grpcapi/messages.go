@@ -0,0 +1,317 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/rypi-dev/logger-server/internal"
+)
+
+// Les messages ci-dessous correspondent à proto/logger.proto. Ils sont encodés à la main
+// avec protowire, faute de protoc dans la chaîne de build.
+
+var ErrInvalidMessage = errors.New("invalid protobuf message")
+
+// message est implémenté par tous les types échangés par le service
+type message interface {
+	marshal(b []byte) []byte
+	unmarshal(data []byte) error
+}
+
+type LogEntry struct {
+	Level             string
+	Message           string
+	TimestampUnixNano int64
+	Service           string
+	Host              string
+	ContextJSON       []byte
+	ID                string
+}
+
+type IngestBatch struct {
+	BatchID        uint64
+	Entries        []*LogEntry
+	IdempotencyKey string
+}
+
+type Rejection struct {
+	Index  uint32
+	Reason string
+}
+
+type IngestAck struct {
+	BatchID  uint64
+	Accepted uint32
+	Rejected []*Rejection
+}
+
+type QueryRequest struct {
+	Level string
+	Page  int32
+	Limit int32
+}
+
+type TailRequest struct {
+	MinLevel string
+	Service  string
+}
+
+// FromLogEntry convertit une entrée du modèle en message
+func FromLogEntry(entry *internal.LogEntry) (*LogEntry, error) {
+	msg := &LogEntry{
+		ID:      entry.ID,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Service: entry.Service,
+		Host:    entry.Host,
+	}
+	if !entry.Timestamp.IsZero() {
+		msg.TimestampUnixNano = entry.Timestamp.UnixNano()
+	}
+	if len(entry.Context) > 0 {
+		ctx, err := json.Marshal(entry.Context)
+		if err != nil {
+			return nil, err
+		}
+		msg.ContextJSON = ctx
+	}
+	return msg, nil
+}
+
+// ToLogEntry convertit le message en entrée du modèle; le contexte doit être un objet JSON
+func (m *LogEntry) ToLogEntry() (*internal.LogEntry, error) {
+	entry := &internal.LogEntry{
+		ID:      m.ID,
+		Level:   m.Level,
+		Message: m.Message,
+		Service: m.Service,
+		Host:    m.Host,
+	}
+	if m.TimestampUnixNano != 0 {
+		entry.Timestamp = time.Unix(0, m.TimestampUnixNano).UTC()
+	}
+	if len(m.ContextJSON) > 0 {
+		if err := json.Unmarshal(m.ContextJSON, &entry.Context); err != nil {
+			return nil, fmt.Errorf("invalid context_json: %v", err)
+		}
+	}
+	return entry, nil
+}
+
+func (m *LogEntry) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.Level)
+	b = appendString(b, 2, m.Message)
+	b = appendVarint(b, 3, uint64(m.TimestampUnixNano))
+	b = appendString(b, 4, m.Service)
+	b = appendString(b, 5, m.Host)
+	b = appendBytes(b, 6, m.ContextJSON)
+	b = appendString(b, 7, m.ID)
+	return b
+}
+
+func (m *LogEntry) unmarshal(data []byte) error {
+	return walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			m.Level = string(v)
+		case num == 2 && typ == protowire.BytesType:
+			m.Message = string(v)
+		case num == 3 && typ == protowire.VarintType:
+			n, _ := protowire.ConsumeVarint(v)
+			m.TimestampUnixNano = int64(n)
+		case num == 4 && typ == protowire.BytesType:
+			m.Service = string(v)
+		case num == 5 && typ == protowire.BytesType:
+			m.Host = string(v)
+		case num == 6 && typ == protowire.BytesType:
+			m.ContextJSON = append([]byte(nil), v...)
+		case num == 7 && typ == protowire.BytesType:
+			m.ID = string(v)
+		}
+		return nil
+	})
+}
+
+func (m *IngestBatch) marshal(b []byte) []byte {
+	b = appendVarint(b, 1, m.BatchID)
+	for _, entry := range m.Entries {
+		b = appendMessage(b, 2, entry)
+	}
+	b = appendString(b, 3, m.IdempotencyKey)
+	return b
+}
+
+func (m *IngestBatch) unmarshal(data []byte) error {
+	return walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			m.BatchID, _ = protowire.ConsumeVarint(v)
+		case num == 2 && typ == protowire.BytesType:
+			entry := &LogEntry{}
+			if err := entry.unmarshal(v); err != nil {
+				return err
+			}
+			m.Entries = append(m.Entries, entry)
+		case num == 3 && typ == protowire.BytesType:
+			m.IdempotencyKey = string(v)
+		}
+		return nil
+	})
+}
+
+func (m *Rejection) marshal(b []byte) []byte {
+	b = appendVarint(b, 1, uint64(m.Index))
+	b = appendString(b, 2, m.Reason)
+	return b
+}
+
+func (m *Rejection) unmarshal(data []byte) error {
+	return walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			n, _ := protowire.ConsumeVarint(v)
+			m.Index = uint32(n)
+		case num == 2 && typ == protowire.BytesType:
+			m.Reason = string(v)
+		}
+		return nil
+	})
+}
+
+func (m *IngestAck) marshal(b []byte) []byte {
+	b = appendVarint(b, 1, m.BatchID)
+	b = appendVarint(b, 2, uint64(m.Accepted))
+	for _, rejection := range m.Rejected {
+		b = appendMessage(b, 3, rejection)
+	}
+	return b
+}
+
+func (m *IngestAck) unmarshal(data []byte) error {
+	return walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			m.BatchID, _ = protowire.ConsumeVarint(v)
+		case num == 2 && typ == protowire.VarintType:
+			n, _ := protowire.ConsumeVarint(v)
+			m.Accepted = uint32(n)
+		case num == 3 && typ == protowire.BytesType:
+			rejection := &Rejection{}
+			if err := rejection.unmarshal(v); err != nil {
+				return err
+			}
+			m.Rejected = append(m.Rejected, rejection)
+		}
+		return nil
+	})
+}
+
+func (m *QueryRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.Level)
+	// int32 négatif : extension de signe sur 64 bits, comme protobuf
+	b = appendVarint(b, 2, uint64(int64(m.Page)))
+	b = appendVarint(b, 3, uint64(int64(m.Limit)))
+	return b
+}
+
+func (m *QueryRequest) unmarshal(data []byte) error {
+	return walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			m.Level = string(v)
+		case num == 2 && typ == protowire.VarintType:
+			n, _ := protowire.ConsumeVarint(v)
+			m.Page = int32(n)
+		case num == 3 && typ == protowire.VarintType:
+			n, _ := protowire.ConsumeVarint(v)
+			m.Limit = int32(n)
+		}
+		return nil
+	})
+}
+
+func (m *TailRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.MinLevel)
+	b = appendString(b, 2, m.Service)
+	return b
+}
+
+func (m *TailRequest) unmarshal(data []byte) error {
+	return walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			m.MinLevel = string(v)
+		case num == 2 && typ == protowire.BytesType:
+			m.Service = string(v)
+		}
+		return nil
+	})
+}
+
+// Les valeurs par défaut (zéro, chaîne vide) ne sont pas émises, comme en proto3
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendMessage(b []byte, num protowire.Number, m message) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m.marshal(nil))
+}
+
+// walkFields parcourt les champs d'un message; v est la charge utile pour BytesType,
+// l'encodage brut de la valeur sinon.
+func walkFields(data []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("%w: %v", ErrInvalidMessage, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		var v []byte
+		if typ == protowire.BytesType {
+			b, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return fmt.Errorf("%w: %v", ErrInvalidMessage, protowire.ParseError(m))
+			}
+			v, n = b, m
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return fmt.Errorf("%w: %v", ErrInvalidMessage, protowire.ParseError(n))
+			}
+			v = data[:n]
+		}
+
+		if err := fn(num, typ, v); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package grpcapi_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/grpcapi"
+)
+
+func TestLogEntry_Conversion(t *testing.T) {
+	entry := &internal.LogEntry{
+		ID:        "evt-42",
+		Level:     "ERROR",
+		Message:   "payment failed",
+		Service:   "billing",
+		Host:      "web-1",
+		Timestamp: time.Date(2025, 8, 6, 14, 12, 0, 123, time.UTC),
+		Context:   map[string]interface{}{"order": "A-42"},
+	}
+
+	msg, err := grpcapi.FromLogEntry(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := msg.ToLogEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, entry) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", got, entry)
+	}
+}
+
+func TestLogEntry_ToLogEntry_InvalidContext(t *testing.T) {
+	msg := &grpcapi.LogEntry{Level: "INFO", Message: "x", ContextJSON: []byte("[1,2]")}
+	if _, err := msg.ToLogEntry(); err == nil {
+		t.Error("expected error for non-object context_json")
+	}
+}
+
+func TestLogEntry_ZeroTimestamp(t *testing.T) {
+	msg, err := grpcapi.FromLogEntry(&internal.LogEntry{Level: "INFO", Message: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.TimestampUnixNano != 0 {
+		t.Errorf("expected zero timestamp to stay unset, got %d", msg.TimestampUnixNano)
+	}
+}
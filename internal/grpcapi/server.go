@@ -0,0 +1,329 @@
+package grpcapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/handler/handler"
+	"github.com/rypi-dev/logger-server/internal/idempotency/idempotency"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+)
+
+const (
+	DefaultMaxBatchSize   = 1000
+	DefaultMaxRecvMsgSize = 4 << 20
+	DefaultStopTimeout    = 10 * time.Second
+
+	// Mêmes valeurs par défaut et bornes que GET /log
+	defaultQueryLimit = 50
+	maxQueryLimit     = 100
+)
+
+// Store est la partie lecture du stockage partagée avec l'API REST
+type Store interface {
+	QueryLogs(tenant string, level log_levels.LogLevel, page, limit int) ([]internal.LogEntry, error)
+}
+
+// Config décrit le listener gRPC. Keys, s'il est défini, remplace APIKey; sans l'un ni
+// l'autre l'authentification est désactivée. Tenants valide le tenant des clés de Keys.
+// Limiter nil désactive le rate limit.
+type Config struct {
+	Addr           string
+	APIKey         string
+	Keys           apikey.Authenticator
+	Tenants        apikey.TenantChecker
+	Limiter        Limiter
+	MaxBatchSize   int // entrées maximales par lot Ingest
+	MaxRecvMsgSize int // taille maximale d'un message reçu, en octets
+	TailBuffer     int // entrées en attente par abonné Tail avant perte
+	StopTimeout    time.Duration
+}
+
+type Server struct {
+	cfg    Config
+	ingest internal.IngestFunc
+	store  Store
+	hub    *Hub
+	grpc   *grpc.Server
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+	done     chan struct{}
+}
+
+var ingestEntriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "grpc_ingest_entries_total",
+	Help: "Total number of log entries received through the gRPC Ingest RPC, by result",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(ingestEntriesTotal)
+}
+
+// logService est l'interface attendue par serviceDesc
+type logService interface {
+	ingestStream(stream grpc.ServerStream) error
+	queryStream(stream grpc.ServerStream) error
+	tailStream(stream grpc.ServerStream) error
+}
+
+// serviceDesc décrit logger.v1.LogService (voir proto/logger.proto)
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "logger.v1.LogService",
+	HandlerType: (*logService)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Ingest",
+			Handler:       func(srv any, stream grpc.ServerStream) error { return srv.(logService).ingestStream(stream) },
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Query",
+			Handler:       func(srv any, stream grpc.ServerStream) error { return srv.(logService).queryStream(stream) },
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Tail",
+			Handler:       func(srv any, stream grpc.ServerStream) error { return srv.(logService).tailStream(stream) },
+			ServerStreams: true,
+		},
+	},
+	Metadata: "logger.proto",
+}
+
+// NewServer crée le service; hub alimente Tail et doit être branché sur les hooks
+// d'ingestion (Handler.AddIngestHook(hub.Publish)).
+func NewServer(cfg Config, ingest internal.IngestFunc, store Store, hub *Hub) *Server {
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = DefaultMaxBatchSize
+	}
+	if cfg.MaxRecvMsgSize <= 0 {
+		cfg.MaxRecvMsgSize = DefaultMaxRecvMsgSize
+	}
+	if cfg.StopTimeout <= 0 {
+		cfg.StopTimeout = DefaultStopTimeout
+	}
+
+	// APIKey → RateLimit → service : le rate limit compte le client de la clé authentifiée
+	var interceptors []grpc.StreamServerInterceptor
+	if cfg.Keys != nil {
+		interceptors = append(interceptors, StreamScopedAPIKeyInterceptor(cfg.Keys, cfg.Tenants))
+	} else if cfg.APIKey != "" {
+		interceptors = append(interceptors, StreamAPIKeyInterceptor(cfg.APIKey))
+	}
+	if cfg.Limiter != nil {
+		interceptors = append(interceptors, StreamRateLimitInterceptor(cfg.Limiter))
+	}
+
+	s := &Server{
+		cfg:    cfg,
+		ingest: ingest,
+		store:  store,
+		hub:    hub,
+		done:   make(chan struct{}),
+	}
+	s.grpc = grpc.NewServer(
+		grpc.ForceServerCodec(codec{}),
+		grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize),
+		grpc.ChainStreamInterceptor(interceptors...),
+	)
+	s.grpc.RegisterService(&serviceDesc, s)
+	return s
+}
+
+// Start ouvre le listener et sert les RPC en arrière-plan
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("grpc listen: %w", err)
+	}
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	go s.grpc.Serve(ln)
+	return nil
+}
+
+// Addr retourne l'adresse effectivement écoutée (utile avec le port 0)
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Close termine les flux Tail puis laisse StopTimeout aux RPC en cours avant de les couper
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(s.cfg.StopTimeout):
+		s.grpc.Stop()
+	}
+	return nil
+}
+
+// ingestStream stocke chaque lot puis l'acquitte. Les entrées invalides ou au-delà de la
+// limite de leur niveau sont listées dans l'acquittement; un échec de stockage termine le
+// flux avec Unavailable et le client renvoie les lots non acquittés.
+func (s *Server) ingestStream(stream grpc.ServerStream) error {
+	ingest := apikey.Bind(stream.Context(), s.ingest)
+	if s.cfg.Limiter != nil {
+		ingest = limitEntries(s.cfg.Limiter, callRequest(stream.Context(), "/logger.v1.LogService/Ingest"), ingest)
+	}
+	for {
+		batch := &IngestBatch{}
+		if err := stream.RecvMsg(batch); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if len(batch.Entries) > s.cfg.MaxBatchSize {
+			return status.Errorf(codes.InvalidArgument, "batch exceeds %d entries", s.cfg.MaxBatchSize)
+		}
+
+		ack := &IngestAck{BatchID: batch.BatchID}
+		for i, msg := range batch.Entries {
+			entry, err := msg.ToLogEntry()
+			if err == nil {
+				if entry.ID == "" {
+					entry.ID = idempotency.EntryID(batch.IdempotencyKey, i)
+				}
+				err = ingest(entry)
+			}
+			if errors.Is(err, handler.ErrWriteFailed) {
+				ingestEntriesTotal.WithLabelValues("failed").Inc()
+				return status.Error(codes.Unavailable, "failed to write log")
+			}
+			if err != nil {
+				ingestEntriesTotal.WithLabelValues("rejected").Inc()
+				ack.Rejected = append(ack.Rejected, &Rejection{Index: uint32(i), Reason: err.Error()})
+				continue
+			}
+			ingestEntriesTotal.WithLabelValues("accepted").Inc()
+			ack.Accepted++
+		}
+
+		if err := stream.SendMsg(ack); err != nil {
+			return err
+		}
+	}
+}
+
+// queryStream applique la validation et les bornes de GET /log puis envoie les entrées une à une
+func (s *Server) queryStream(stream grpc.ServerStream) error {
+	req := &QueryRequest{}
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+
+	page := 1
+	if req.Page < 0 {
+		return status.Error(codes.InvalidArgument, "invalid 'page' parameter")
+	} else if req.Page > 0 {
+		page = int(req.Page)
+	}
+
+	limit := defaultQueryLimit
+	if req.Limit < 0 {
+		limit = 1
+	} else if req.Limit > maxQueryLimit {
+		limit = maxQueryLimit
+	} else if req.Limit > 0 {
+		limit = int(req.Limit)
+	}
+
+	if req.Level != "" && !log_levels.IsValidLogLevel(req.Level) {
+		return status.Error(codes.InvalidArgument, "invalid 'level' parameter")
+	}
+
+	logs, err := s.store.QueryLogs(apikey.TenantFromContext(stream.Context()), log_levels.LogLevel(req.Level), page, limit)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to query logs")
+	}
+
+	for i := range logs {
+		msg, err := FromLogEntry(&logs[i])
+		if err != nil {
+			return status.Error(codes.Internal, "failed to encode logs")
+		}
+		if err := stream.SendMsg(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tailStream diffuse les nouvelles entrées jusqu'à l'annulation par le client ou l'arrêt du serveur
+func (s *Server) tailStream(stream grpc.ServerStream) error {
+	req := &TailRequest{}
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+
+	if req.MinLevel != "" && !log_levels.IsValidLogLevel(req.MinLevel) {
+		return status.Error(codes.InvalidArgument, "invalid 'min_level' parameter")
+	}
+	minLevel := log_levels.NormalizeLogLevel(req.MinLevel)
+
+	tenant := apikey.TenantFromContext(stream.Context())
+	entries, unsubscribe := s.hub.Subscribe(s.cfg.TailBuffer, func(entry *internal.LogEntry) bool {
+		if entry.Tenant != tenant {
+			return false
+		}
+		if req.Service != "" && entry.Service != req.Service {
+			return false
+		}
+		return req.MinLevel == "" || !log_levels.LevelLessThan(log_levels.NormalizeLogLevel(entry.Level), minLevel)
+	})
+	defer unsubscribe()
+
+	for {
+		select {
+		case entry := <-entries:
+			msg, err := FromLogEntry(&entry)
+			if err != nil {
+				continue
+			}
+			if err := stream.SendMsg(msg); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return nil
+		}
+	}
+}
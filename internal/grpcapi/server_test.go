@@ -0,0 +1,483 @@
+package grpcapi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/apikey"
+	"github.com/rypi-dev/logger-server/internal/grpcapi"
+	"github.com/rypi-dev/logger-server/internal/handler"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+)
+
+const apiKey = "secret"
+
+// backend simule le Handler : validation, stockage en mémoire et hook Tail
+type backend struct {
+	mu      sync.Mutex
+	entries []*internal.LogEntry
+	err     error
+	hub     *grpcapi.Hub
+
+	queryTenant string
+	queryLevel  log_levels.LogLevel
+	queryPage   int
+	queryLimit  int
+}
+
+func (b *backend) ingest(entry *internal.LogEntry) error {
+	if b.err != nil {
+		return b.err
+	}
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.entries = append(b.entries, entry)
+	b.mu.Unlock()
+	b.hub.Publish(*entry)
+	return nil
+}
+
+func (b *backend) QueryLogs(tenant string, level log_levels.LogLevel, page, limit int) ([]internal.LogEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.queryTenant, b.queryLevel, b.queryPage, b.queryLimit = tenant, level, page, limit
+	return []internal.LogEntry{
+		{Level: "ERROR", Message: "first"},
+		{Level: "ERROR", Message: "second"},
+	}, nil
+}
+
+// limiter autorise les n premiers appels puis bloque, refuse les entrées DEBUG et note
+// la clé authentifiée de chaque appel
+type limiter struct {
+	mu      sync.Mutex
+	n       int
+	clients []string
+}
+
+func (l *limiter) Allow(r *http.Request) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	client := ""
+	if key := apikey.FromContext(r.Context()); key != nil {
+		client = key.Name
+	}
+	l.clients = append(l.clients, client)
+	if l.n <= 0 {
+		return false, 30 * time.Second
+	}
+	l.n--
+	return true, 0
+}
+
+func (l *limiter) AllowEntry(r *http.Request, level string) error {
+	if level == "DEBUG" {
+		return errors.New("rate limit exceeded for DEBUG entries")
+	}
+	return nil
+}
+
+func startServer(t *testing.T, cfg grpcapi.Config) (*grpcapi.Server, *grpcapi.Client, *backend) {
+	t.Helper()
+	b := &backend{hub: grpcapi.NewHub()}
+	cfg.Addr = "127.0.0.1:0"
+	if cfg.APIKey == "" {
+		cfg.APIKey = apiKey
+	}
+
+	srv := grpcapi.NewServer(cfg, b.ingest, b, b.hub)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := grpc.NewClient(srv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return srv, grpcapi.NewClient(conn), b
+}
+
+func authContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return metadata.AppendToOutgoingContext(ctx, "x-api-key", apiKey)
+}
+
+func TestIngest_AcksEachBatch(t *testing.T) {
+	_, client, b := startServer(t, grpcapi.Config{})
+
+	stream, err := client.Ingest(authContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batches := []*grpcapi.IngestBatch{
+		{BatchID: 1, Entries: []*grpcapi.LogEntry{{Level: "INFO", Message: "one"}, {Level: "WARN", Message: "two"}}},
+		{BatchID: 2, Entries: []*grpcapi.LogEntry{{Level: "BOGUS", Message: "three"}, {Level: "INFO", Message: "four"}}},
+	}
+	for _, batch := range batches {
+		if err := stream.Send(batch); err != nil {
+			t.Fatal(err)
+		}
+		ack, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ack.BatchID != batch.BatchID {
+			t.Errorf("expected ack for batch %d, got %d", batch.BatchID, ack.BatchID)
+		}
+		if batch.BatchID == 2 && (ack.Accepted != 1 || len(ack.Rejected) != 1 || ack.Rejected[0].Index != 0) {
+			t.Errorf("unexpected ack %+v", ack)
+		}
+	}
+
+	stream.CloseSend()
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected EOF after CloseSend, got %v", err)
+	}
+	if len(b.entries) != 3 {
+		t.Errorf("expected 3 stored entries, got %d", len(b.entries))
+	}
+}
+
+func TestIngest_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      grpcapi.Config
+		err      error
+		wantCode codes.Code
+	}{
+		{"write failure", grpcapi.Config{}, fmt.Errorf("%w: disk full", handler.ErrWriteFailed), codes.Unavailable},
+		{"batch too large", grpcapi.Config{MaxBatchSize: 1}, nil, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client, b := startServer(t, tt.cfg)
+			b.err = tt.err
+
+			stream, err := client.Ingest(authContext(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream.Send(&grpcapi.IngestBatch{Entries: []*grpcapi.LogEntry{{Level: "INFO", Message: "a"}, {Level: "INFO", Message: "b"}}})
+			if _, err := stream.Recv(); status.Code(err) != tt.wantCode {
+				t.Errorf("expected %v, got %v", tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	_, client, b := startServer(t, grpcapi.Config{})
+
+	stream, err := client.Query(authContext(t), &grpcapi.QueryRequest{Level: "ERROR", Limit: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+	for {
+		entry, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, entry.Message)
+	}
+
+	if len(messages) != 2 || messages[0] != "first" {
+		t.Errorf("unexpected entries %v", messages)
+	}
+	if b.queryLevel != "ERROR" || b.queryPage != 1 || b.queryLimit != 100 {
+		t.Errorf("expected REST defaults and bounds, got level=%s page=%d limit=%d", b.queryLevel, b.queryPage, b.queryLimit)
+	}
+}
+
+func TestQuery_InvalidLevel(t *testing.T) {
+	_, client, _ := startServer(t, grpcapi.Config{})
+
+	stream, err := client.Query(authContext(t), &grpcapi.QueryRequest{Level: "LOUD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestTail(t *testing.T) {
+	_, client, b := startServer(t, grpcapi.Config{})
+
+	stream, err := client.Tail(authContext(t), &grpcapi.TailRequest{MinLevel: "WARN", Service: "billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for b.hub.Subscribers() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	b.ingest(&internal.LogEntry{Level: "INFO", Message: "too low", Service: "billing"})
+	b.ingest(&internal.LogEntry{Level: "ERROR", Message: "other service", Service: "auth"})
+	b.ingest(&internal.LogEntry{Level: "ERROR", Message: "payment failed", Service: "billing"})
+
+	entry, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Message != "payment failed" {
+		t.Errorf("expected only the matching entry, got %+v", entry)
+	}
+}
+
+func TestTail_EndsOnClose(t *testing.T) {
+	srv, client, b := startServer(t, grpcapi.Config{})
+
+	stream, err := client.Tail(authContext(t), &grpcapi.TailRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for b.hub.Subscribers() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	srv.Close()
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected EOF after server close, got %v", err)
+	}
+}
+
+func TestAPIKeyInterceptor(t *testing.T) {
+	_, client, _ := startServer(t, grpcapi.Config{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, md := range []metadata.MD{nil, metadata.Pairs("x-api-key", "wrong")} {
+		stream, err := client.Query(metadata.NewOutgoingContext(ctx, md), &grpcapi.QueryRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected Unauthenticated, got %v", err)
+		}
+	}
+}
+
+// keys associe des clés en clair à leur description, à la place d'un apikey.Keyring
+type keys map[string]*apikey.Key
+
+func (k keys) Authenticate(key string) (*apikey.Key, error) {
+	if found, ok := k[key]; ok {
+		return found, nil
+	}
+	return nil, apikey.ErrInvalidKey
+}
+
+func TestScopedAPIKeyInterceptor(t *testing.T) {
+	_, client, b := startServer(t, grpcapi.Config{Keys: keys{
+		"reader":  {Name: "reader", Scopes: []string{apikey.ScopeRead}},
+		"shipper": {Name: "shipper", Scopes: []string{apikey.ScopeIngest}, Service: "billing"},
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, "x-api-key", key)
+	}
+
+	stream, err := client.Query(withKey(apiKey), &grpcapi.QueryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected the plain API key to be ignored when Keys is set, got %v", err)
+	}
+
+	stream, err = client.Query(withKey("shipper"), &grpcapi.QueryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for an ingest-only key, got %v", err)
+	}
+
+	ingest, err := client.Ingest(withKey("reader"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingest.Send(&grpcapi.IngestBatch{Entries: []*grpcapi.LogEntry{{Level: "INFO", Message: "a"}}})
+	if _, err := ingest.Recv(); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for a read-only key, got %v", err)
+	}
+
+	ingest, err = client.Ingest(withKey("shipper"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingest.Send(&grpcapi.IngestBatch{Entries: []*grpcapi.LogEntry{
+		{Level: "INFO", Message: "bound"},
+		{Level: "INFO", Message: "other", Service: "payments"},
+	}})
+	ack, err := ingest.Recv()
+	if err != nil {
+		t.Fatalf("expected ack, got %v", err)
+	}
+	if ack.Accepted != 1 || len(ack.Rejected) != 1 || ack.Rejected[0].Index != 1 {
+		t.Errorf("expected the foreign service entry to be rejected, got %+v", ack)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.entries) != 1 || b.entries[0].Service != "billing" {
+		t.Errorf("expected the key service on the stored entry, got %+v", b.entries)
+	}
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	// Ouverture du flux + un lot autorisés, le lot suivant est limité
+	_, client, _ := startServer(t, grpcapi.Config{Limiter: &limiter{n: 2}})
+
+	stream, err := client.Ingest(authContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := &grpcapi.IngestBatch{Entries: []*grpcapi.LogEntry{{Level: "INFO", Message: "a"}}}
+
+	stream.Send(batch)
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("expected first batch to be acked, got %v", err)
+	}
+
+	stream.Send(batch)
+	_, err = stream.Recv()
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+	if got := stream.Trailer().Get("retry-after"); len(got) != 1 || got[0] != "30" {
+		t.Errorf("expected retry-after trailer 30, got %v", got)
+	}
+}
+
+func TestRateLimitInterceptor_KeyedAfterAuth(t *testing.T) {
+	l := &limiter{n: 10}
+	_, client, b := startServer(t, grpcapi.Config{
+		Keys:    keys{"shipper": {Name: "shipper", Scopes: []string{apikey.ScopeIngest}}},
+		Limiter: l,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", "shipper")
+	stream, err := client.Ingest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&grpcapi.IngestBatch{Entries: []*grpcapi.LogEntry{
+		{Level: "INFO", Message: "kept"},
+		{Level: "DEBUG", Message: "limited"},
+	}})
+	ack, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("expected ack, got %v", err)
+	}
+	if ack.Accepted != 1 || len(ack.Rejected) != 1 || ack.Rejected[0].Index != 1 {
+		t.Errorf("expected the DEBUG entry to be rejected by its limit, got %+v", ack)
+	}
+	b.mu.Lock()
+	if len(b.entries) != 1 || b.entries[0].Message != "kept" {
+		t.Errorf("expected only the allowed entry to be stored, got %+v", b.entries)
+	}
+	b.mu.Unlock()
+
+	// Le rate limit voit la clé authentifiée : l'authentification passe avant lui
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.clients) != 2 || l.clients[0] != "shipper" || l.clients[1] != "shipper" {
+		t.Errorf("expected the stream and batch to be limited for key shipper, got %v", l.clients)
+	}
+}
+
+type tenants map[string]bool
+
+func (t tenants) Exists(name string) bool { return t[name] }
+
+func TestScopedAPIKeyInterceptor_Tenants(t *testing.T) {
+	_, client, b := startServer(t, grpcapi.Config{
+		Keys: keys{
+			"acme":    {Name: "acme", Scopes: []string{apikey.ScopeRead, apikey.ScopeIngest}, Tenant: "acme"},
+			"deleted": {Name: "deleted", Scopes: []string{apikey.ScopeRead}, Tenant: "deleted"},
+		},
+		Tenants: tenants{"acme": true},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, "x-api-key", key)
+	}
+
+	stream, err := client.Query(withKey("deleted"), &grpcapi.QueryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for a key of an unknown tenant, got %v", err)
+	}
+
+	stream, err = client.Query(withKey("acme"), &grpcapi.QueryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+	b.mu.Lock()
+	if b.queryTenant != "acme" {
+		t.Errorf("expected the query to be scoped to tenant acme, got %q", b.queryTenant)
+	}
+	b.mu.Unlock()
+
+	tail, err := client.Tail(withKey("acme"), &grpcapi.TailRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for b.hub.Subscribers() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	b.ingest(&internal.LogEntry{Level: "ERROR", Message: "default tenant"})
+	b.ingest(&internal.LogEntry{Level: "ERROR", Message: "other tenant", Tenant: "globex"})
+	b.ingest(&internal.LogEntry{Level: "ERROR", Message: "own tenant", Tenant: "acme"})
+
+	entry, err := tail.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Message != "own tenant" {
+		t.Errorf("expected only entries of tenant acme, got %+v", entry)
+	}
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,16 +13,56 @@ import (
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
 	"github.com/rypi-dev/logger-server/internal/audit/audit"
+	"github.com/rypi-dev/logger-server/internal/idempotency/idempotency"
 	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+	"github.com/rypi-dev/logger-server/internal/quota/quota"
+	"github.com/rypi-dev/logger-server/internal/ratelimit/ratelimit"
 	"github.com/rypi-dev/logger-server/internal/utils/utils"
 )
 
 const MaxRequestBodySize = 4096
 
+// Limites de POST /log/batch (taille décompressée du corps et nombre d'entrées)
+const (
+	MaxBatchBodySize = 4 << 20
+	MaxBatchEntries  = 1000
+)
+
+// ErrWriteFailed signale un échec du stockage (par opposition à une entrée invalide)
+var ErrWriteFailed = errors.New("failed to write log")
+
+// IngestHook est appelé pour chaque entrée stockée avec succès (détection d'anomalies, ...)
+type IngestHook func(entry LogEntry)
+
+// Deduplicator réserve les identifiants d'entrées (LogEntry.ID) pour ne stocker qu'une fois
+// les renvois d'un client (voir idempotency.SQLiteStore)
+type Deduplicator interface {
+	Claim(id string) (bool, error)
+	Release(id string) error
+}
+
+// Quota impute les entrées au tenant et à la clé qui les écrivent, et refuse celles qui
+// dépasseraient leurs quotas (voir quota.SQLiteStore)
+type Quota interface {
+	Reserve(entry *LogEntry) (*quota.Reservation, error)
+	Release(r *quota.Reservation)
+}
+
+// EntryLimiter limite les entrées reçues par client selon le niveau déclaré dans le corps,
+// et non dans un en-tête (voir ratelimit.RateLimiter.AllowEntry)
+type EntryLimiter interface {
+	AllowEntry(r *http.Request, level string) error
+}
+
 type Handler struct {
 	logger       LoggerInterface
 	serverLogger *zap.Logger
+	hooks        []IngestHook
+	dedup        Deduplicator
+	quota        Quota
+	limiter      EntryLimiter
 }
 
 func NewHandler(logger LoggerInterface, serverLogger *zap.Logger) *Handler {
@@ -30,24 +72,44 @@ func NewHandler(logger LoggerInterface, serverLogger *zap.Logger) *Handler {
 	}
 }
 
-func (h *Handler) Router() http.Handler {
-	r := mux.NewRouter()
-	rl, err := ratelimit.NewRateLimiterWithLevel(
-		100,
-		1*time.Minute,
-		1000,
-		log_levels.LogLevelInfo,
-		map[log_levels.LogLevel]int{
-			log_levels.LogLevelError: 200,
-			log_levels.LogLevelWarn: 150,
-		},
-	)
-	if err != nil {
-		panic(err)
+// AddIngestHook enregistre un hook appelé après chaque écriture réussie
+func (h *Handler) AddIngestHook(hook IngestHook) {
+	h.hooks = append(h.hooks, hook)
+}
+
+// SetDeduplicator active la déduplication des entrées portant un identifiant
+func (h *Handler) SetDeduplicator(d Deduplicator) {
+	h.dedup = d
+}
+
+// SetQuota active les quotas d'ingestion par tenant et par clé
+func (h *Handler) SetQuota(q Quota) {
+	h.quota = q
+}
+
+// SetEntryLimiter active les limites par niveau des entrées de POST /log et /log/batch
+func (h *Handler) SetEntryLimiter(l EntryLimiter) {
+	h.limiter = l
+}
+
+// limitEntries refuse (ratelimit.ErrLimited) les entrées de r au-delà de la limite de leur
+// niveau, avant de les passer à ingest
+func (h *Handler) limitEntries(r *http.Request, ingest func(entry *LogEntry) error) func(entry *LogEntry) error {
+	if h.limiter == nil {
+		return ingest
+	}
+	return func(entry *LogEntry) error {
+		if err := h.limiter.AllowEntry(r, entry.Level); err != nil {
+			return err
+		}
+		return ingest(entry)
 	}
+}
 
+func (h *Handler) Router() *mux.Router {
+	r := mux.NewRouter()
 	r.Use(
-		middleware.RateLimiterMiddleware(rl),
+		middleware.DecompressBody(middleware.MaxDecompressedBodySize),
 		middleware.EnrichLogContext,
 		middleware.AuditMiddleware(h.logger),
 	)
@@ -55,6 +117,7 @@ func (h *Handler) Router() http.Handler {
 	// Ajout endpoints REST
 	r.HandleFunc("/log", h.handleLogs).Methods("POST")      // support Fluent Bit /log
 	r.HandleFunc("/log", h.handleGetLogs).Methods("GET")   // récupère les logs
+	r.HandleFunc("/log/batch", h.handleBatch).Methods("POST") // tableau JSON d'entrées (SDK client)
 	r.HandleFunc("/log-levels", h.handleGetLogLevels).Methods("GET") // retourne les niveaux
 
 	// Healthcheck
@@ -108,7 +171,8 @@ func (h *Handler) handleGetLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logs, err := h.logger.QueryLogs(levelFilter, page, limit)
+	// Une clé ne lit que les entrées de son tenant, sans paramètre pour en choisir un autre
+	logs, err := h.logger.QueryLogs(apikey.TenantFromContext(r.Context()), levelFilter, page, limit)
 	if err != nil {
 		h.writeError(w, r, ip, http.StatusInternalServerError, "failed to query logs", time.Since(start))
 		return
@@ -147,17 +211,23 @@ func (h *Handler) handleLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := entry.Validate(); err != nil {
-		h.writeError(w, r, ip, http.StatusBadRequest, err.Error(), time.Since(start))
-		return
-	}
-
-	if entry.Timestamp.IsZero() {
-		entry.Timestamp = time.Now()
+	if entry.ID == "" {
+		entry.ID = r.Header.Get(idempotency.HeaderName)
 	}
 
-	if err := h.logger.Write(entry); err != nil {
-		h.writeError(w, r, ip, http.StatusInternalServerError, "failed to write log", time.Since(start))
+	if err := h.limitEntries(r, apikey.Bind(r.Context(), h.Ingest))(&entry); err != nil {
+		if errors.Is(err, ErrWriteFailed) {
+			h.writeError(w, r, ip, http.StatusInternalServerError, "failed to write log", time.Since(start))
+			return
+		}
+		if wait, ok := RetryAfter(err); ok {
+			if wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(wait)))
+			}
+			h.writeError(w, r, ip, http.StatusTooManyRequests, err.Error(), time.Since(start))
+			return
+		}
+		h.writeError(w, r, ip, http.StatusBadRequest, err.Error(), time.Since(start))
 		return
 	}
 
@@ -182,6 +252,164 @@ func (h *Handler) handleLogs(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// BatchRejection décrit une entrée refusée d'un lot; Index est son rang (à partir de 0).
+// Une entrée refusée par une limite de débit ou un quota est Retryable : le client la
+// renvoie après RetryAfter secondes (0 : inconnu).
+type BatchRejection struct {
+	Index      int    `json:"index"`
+	Error      string `json:"error"`
+	Retryable  bool   `json:"retryable,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+}
+
+// RetryAfter retourne le délai après lequel une entrée refusée par une limite de débit
+// (ratelimit.LimitError) ou un quota (quota.ErrExceeded) peut être renvoyée. ok est faux
+// pour les autres erreurs, que le renvoi ne changera pas; le délai est nul s'il est inconnu.
+func RetryAfter(err error) (wait time.Duration, ok bool) {
+	var limited *ratelimit.LimitError
+	if errors.As(err, &limited) {
+		return limited.RetryAfter, true
+	}
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		return max(time.Until(exceeded.Breach.Reset), 0), true
+	}
+	return 0, errors.Is(err, quota.ErrExceeded)
+}
+
+// retryAfterSeconds arrondit un délai de renvoi à la seconde supérieure (en-tête Retry-After)
+func retryAfterSeconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
+
+// BatchResponse est la réponse de POST /log/batch
+type BatchResponse struct {
+	Accepted int              `json:"accepted"`
+	Rejected []BatchRejection `json:"rejected"`
+}
+
+// handleBatch reçoit un tableau JSON d'entrées. Les entrées invalides sont listées dans la
+// réponse sans faire échouer le lot; un échec du stockage fait échouer tout le lot (500),
+// que le client renvoie avec la même clé Idempotency-Key.
+func (h *Handler) handleBatch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ip := utils.GetClientIP(r)
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		h.writeError(w, r, ip, http.StatusUnsupportedMediaType, "Content-Type must be application/json", time.Since(start))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBatchBodySize)
+	defer r.Body.Close()
+
+	var entries []LogEntry
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.writeError(w, r, ip, http.StatusRequestEntityTooLarge, "request body too large", time.Since(start))
+			return
+		}
+		h.writeError(w, r, ip, http.StatusBadRequest, "invalid JSON", time.Since(start))
+		return
+	}
+	if len(entries) == 0 {
+		h.writeError(w, r, ip, http.StatusBadRequest, "batch is empty", time.Since(start))
+		return
+	}
+	if len(entries) > MaxBatchEntries {
+		h.writeError(w, r, ip, http.StatusRequestEntityTooLarge, fmt.Sprintf("batch exceeds %d entries", MaxBatchEntries), time.Since(start))
+		return
+	}
+
+	key := r.Header.Get(idempotency.HeaderName)
+	// Les entrées au-delà de la limite de leur niveau sont refusées une à une
+	ingest := h.limitEntries(r, apikey.Bind(r.Context(), h.Ingest))
+	resp := BatchResponse{Rejected: []BatchRejection{}}
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ID == "" {
+			entry.ID = idempotency.EntryID(key, i)
+		}
+		if err := ingest(entry); err != nil {
+			if errors.Is(err, ErrWriteFailed) {
+				h.writeError(w, r, ip, http.StatusInternalServerError, "failed to write log", time.Since(start))
+				return
+			}
+			rejection := BatchRejection{Index: i, Error: err.Error()}
+			if wait, ok := RetryAfter(err); ok {
+				rejection.Retryable, rejection.RetryAfter = true, retryAfterSeconds(wait)
+			}
+			resp.Rejected = append(resp.Rejected, rejection)
+			continue
+		}
+		resp.Accepted++
+	}
+
+	h.logAudit(ip, r.Method, r.URL.Path, http.StatusOK, time.Since(start))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Ingest valide, horodate et stocke une entrée. C'est le chemin commun à toutes les
+// entrées (HTTP, syslog, ...). Les erreurs de stockage sont enveloppées dans ErrWriteFailed,
+// les autres sont des erreurs de validation ou de quota (quota.ErrExceeded). Une entrée dont
+// l'ID a déjà été stocké est acquittée (nil) sans être réécrite ni imputée au quota.
+func (h *Handler) Ingest(entry *LogEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	// Les identifiants sont réservés par tenant : deux tenants peuvent utiliser le même
+	dedupID := entry.Tenant + "/" + entry.ID
+
+	if entry.ID != "" && h.dedup != nil {
+		claimed, err := h.dedup.Claim(dedupID)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrWriteFailed, err)
+		}
+		if !claimed {
+			// Renvoi d'une entrée déjà stockée : acquittée sans seconde écriture
+			return nil
+		}
+	}
+
+	var reservation *quota.Reservation
+	if h.quota != nil {
+		var err error
+		if reservation, err = h.quota.Reserve(entry); err != nil {
+			if entry.ID != "" && h.dedup != nil {
+				h.dedup.Release(dedupID)
+			}
+			if errors.Is(err, quota.ErrExceeded) {
+				return err
+			}
+			return fmt.Errorf("%w: %v", ErrWriteFailed, err)
+		}
+	}
+
+	if err := h.logger.Write(*entry); err != nil {
+		if entry.ID != "" && h.dedup != nil {
+			h.dedup.Release(dedupID)
+		}
+		if h.quota != nil {
+			h.quota.Release(reservation)
+		}
+		return fmt.Errorf("%w: %v", ErrWriteFailed, err)
+	}
+
+	for _, hook := range h.hooks {
+		hook(*entry)
+	}
+
+	return nil
+}
+
 func (h *Handler) handleGetLogLevels(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 	ip := utils.GetClientIP(r)
@@ -4,24 +4,30 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/rypi-dev/logger-server/internal/apikey"
 	"github.com/rypi-dev/logger-server/internal/handler"
 	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+	"github.com/rypi-dev/logger-server/internal/quota"
+	"github.com/rypi-dev/logger-server/internal/ratelimit"
 	"go.uber.org/zap"
 )
 
 // mockLogger implémente LoggerInterface pour les tests
 type mockLogger struct {
 	logs      []handler.LogEntry
+	tenant    string
 	queryFunc func(level string, page, limit int) ([]handler.LogEntry, error)
 	writeFunc func(entry handler.LogEntry) error
 }
 
-func (m *mockLogger) QueryLogs(level string, page, limit int) ([]handler.LogEntry, error) {
+func (m *mockLogger) QueryLogs(tenant, level string, page, limit int) ([]handler.LogEntry, error) {
+	m.tenant = tenant
 	if m.queryFunc != nil {
 		return m.queryFunc(level, page, limit)
 	}
@@ -223,6 +229,344 @@ func TestHandleLogs_WriteError(t *testing.T) {
 	}
 }
 
+// mockDedup simule un idempotency.SQLiteStore en mémoire
+type mockDedup struct {
+	seen     map[string]bool
+	released []string
+}
+
+func (d *mockDedup) Claim(id string) (bool, error) {
+	if d.seen[id] {
+		return false, nil
+	}
+	d.seen[id] = true
+	return true, nil
+}
+
+func (d *mockDedup) Release(id string) error {
+	delete(d.seen, id)
+	d.released = append(d.released, id)
+	return nil
+}
+
+func TestHandleGetLogs_Tenant(t *testing.T) {
+	mock := &mockLogger{}
+	h := handler.NewHandler(mock, zap.NewNop())
+
+	// Le paramètre tenant est ignoré : seul compte le tenant de la clé authentifiée
+	req := httptest.NewRequest("GET", "/log?tenant=globex", nil)
+	req = req.WithContext(apikey.WithKey(req.Context(), &apikey.Key{Name: "acme-reader", Tenant: "acme"}))
+	w := httptest.NewRecorder()
+	h.Router().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || mock.tenant != "acme" {
+		t.Errorf("expected logs of tenant acme, got %d for tenant %q", w.Code, mock.tenant)
+	}
+}
+
+func TestIngest_DeduplicatesPerTenant(t *testing.T) {
+	mock := &mockLogger{}
+	h := handler.NewHandler(mock, zap.NewNop())
+	h.SetDeduplicator(&mockDedup{seen: map[string]bool{}})
+
+	for _, tenant := range []string{"", "acme", "acme", "globex"} {
+		if err := h.Ingest(&handler.LogEntry{ID: "evt-1", Level: "INFO", Message: "x", Tenant: tenant}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(mock.logs) != 3 {
+		t.Errorf("expected one entry per tenant, got %d", len(mock.logs))
+	}
+}
+
+func TestHandleLogs_Idempotency(t *testing.T) {
+	mock := &mockLogger{}
+	h := handler.NewHandler(mock, zap.NewNop())
+	h.SetDeduplicator(&mockDedup{seen: map[string]bool{}})
+
+	send := func(body, key string) int {
+		req := httptest.NewRequest("POST", "/log", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		h.Router().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Renvois identiques par id dans le corps puis par en-tête
+	for i := 0; i < 2; i++ {
+		if code := send(`{"id":"evt-1","level":"info","message":"retried"}`, ""); code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", code)
+		}
+		if code := send(`{"level":"info","message":"retried"}`, "key-1"); code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", code)
+		}
+	}
+	send(`{"level":"info","message":"no id"}`, "")
+	send(`{"level":"info","message":"no id"}`, "")
+
+	if len(mock.logs) != 4 {
+		t.Errorf("expected 4 stored entries (2 deduplicated, 2 without id), got %d", len(mock.logs))
+	}
+}
+
+func TestIngest_ReleasesIDOnWriteFailure(t *testing.T) {
+	failing := true
+	mock := &mockLogger{}
+	mock.writeFunc = func(entry handler.LogEntry) error {
+		if failing {
+			return errors.New("disk full")
+		}
+		mock.logs = append(mock.logs, entry)
+		return nil
+	}
+	dedup := &mockDedup{seen: map[string]bool{}}
+	h := handler.NewHandler(mock, zap.NewNop())
+	h.SetDeduplicator(dedup)
+
+	if err := h.Ingest(&handler.LogEntry{ID: "evt-1", Level: "INFO", Message: "x"}); !errors.Is(err, handler.ErrWriteFailed) {
+		t.Fatalf("expected ErrWriteFailed, got %v", err)
+	}
+	if len(dedup.released) != 1 {
+		t.Errorf("expected ID to be released after write failure, got %v", dedup.released)
+	}
+
+	failing = false
+	if err := h.Ingest(&handler.LogEntry{ID: "evt-1", Level: "INFO", Message: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.logs) != 1 {
+		t.Errorf("expected retried entry to be stored, got %d", len(mock.logs))
+	}
+}
+
+// mockQuota accepte max entrées puis refuse les suivantes
+type mockQuota struct {
+	max      int
+	reserved int
+	released int
+}
+
+func (q *mockQuota) Reserve(entry *handler.LogEntry) (*quota.Reservation, error) {
+	if q.reserved >= q.max {
+		return nil, fmt.Errorf("%w: key/%s/%s daily_entries", quota.ErrExceeded, entry.Tenant, entry.KeyName)
+	}
+	q.reserved++
+	return &quota.Reservation{}, nil
+}
+
+func (q *mockQuota) Release(r *quota.Reservation) {
+	q.reserved--
+	q.released++
+}
+
+func TestIngest_Quota(t *testing.T) {
+	failing := false
+	mock := &mockLogger{}
+	mock.writeFunc = func(entry handler.LogEntry) error {
+		if failing {
+			return errors.New("disk full")
+		}
+		mock.logs = append(mock.logs, entry)
+		return nil
+	}
+	q := &mockQuota{max: 2}
+	h := handler.NewHandler(mock, zap.NewNop())
+	h.SetDeduplicator(&mockDedup{seen: map[string]bool{}})
+	h.SetQuota(q)
+
+	if err := h.Ingest(&handler.LogEntry{ID: "evt-1", Level: "INFO", Message: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	// Un renvoi dédupliqué n'est pas imputé au quota
+	if err := h.Ingest(&handler.LogEntry{ID: "evt-1", Level: "INFO", Message: "x"}); err != nil || q.reserved != 1 {
+		t.Fatalf("expected the duplicate not to be counted, got %v with %d reserved", err, q.reserved)
+	}
+
+	failing = true
+	if err := h.Ingest(&handler.LogEntry{Level: "INFO", Message: "x"}); !errors.Is(err, handler.ErrWriteFailed) || q.released != 1 {
+		t.Errorf("expected the failed write to be released, got %v with %d released", err, q.released)
+	}
+	failing = false
+
+	if err := h.Ingest(&handler.LogEntry{Level: "INFO", Message: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	err := h.Ingest(&handler.LogEntry{ID: "evt-2", Level: "INFO", Message: "x"})
+	if !errors.Is(err, quota.ErrExceeded) {
+		t.Fatalf("expected quota.ErrExceeded, got %v", err)
+	}
+	if len(mock.logs) != 2 {
+		t.Errorf("expected 2 stored entries, got %d", len(mock.logs))
+	}
+
+	// L'entrée refusée peut être renvoyée avec le même id une fois le quota libéré
+	q.max = 3
+	if err := h.Ingest(&handler.LogEntry{ID: "evt-2", Level: "INFO", Message: "x"}); err != nil || len(mock.logs) != 3 {
+		t.Errorf("expected the rejected entry to be stored on retry, got %v", err)
+	}
+}
+
+func TestHandleLogs_QuotaExceeded(t *testing.T) {
+	h := handler.NewHandler(&mockLogger{}, zap.NewNop())
+	h.SetQuota(&mockQuota{max: 0})
+
+	req := httptest.NewRequest("POST", "/log", bytes.NewReader([]byte(`{"level":"info","message":"over"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.Router().ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", w.Code)
+	}
+}
+
+func TestHandleBatch(t *testing.T) {
+	mock := &mockLogger{}
+	h := handler.NewHandler(mock, zap.NewNop())
+	dedup := &mockDedup{seen: map[string]bool{}}
+	h.SetDeduplicator(dedup)
+
+	body := `[
+		{"level":"INFO","message":"first"},
+		{"level":"INFO","message":""},
+		{"id":"evt-9","level":"ERROR","message":"third"}
+	]`
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/log/batch", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "batch-1")
+		w := httptest.NewRecorder()
+		h.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := send()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp handler.BatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Accepted != 2 || len(resp.Rejected) != 1 || resp.Rejected[0].Index != 1 || resp.Rejected[0].Retryable {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if len(mock.logs) != 2 || mock.logs[0].ID != "batch-1/0" || mock.logs[1].ID != "evt-9" {
+		t.Fatalf("unexpected stored entries: %+v", mock.logs)
+	}
+
+	// Le renvoi du même lot est acquitté sans nouvelle écriture
+	if w := send(); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on retry, got %d", w.Code)
+	}
+	if len(mock.logs) != 2 {
+		t.Errorf("expected retried batch to be deduplicated, got %d entries", len(mock.logs))
+	}
+}
+
+func newEntryLimiter(t *testing.T, limits string) *ratelimit.RateLimiter {
+	t.Helper()
+	entryLimits, err := ratelimit.ParseLevelLimits(limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl, err := ratelimit.NewRateLimiter(ratelimit.Config{MaxRequests: 100, Window: time.Minute, EntryLimits: entryLimits})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rl.Stop)
+	return rl
+}
+
+func TestHandleBatch_EntryLimits(t *testing.T) {
+	mock := &mockLogger{}
+	h := handler.NewHandler(mock, zap.NewNop())
+	h.SetEntryLimiter(newEntryLimiter(t, "ERROR=1,INFO=2"))
+
+	// Le niveau vient des entrées : seules celles au-delà de la limite de leur niveau sont refusées
+	body := `[
+		{"level":"ERROR","message":"first"},
+		{"level":"error","message":"second"},
+		{"level":"INFO","message":"third"},
+		{"level":"DEBUG","message":"fourth"}
+	]`
+	req := httptest.NewRequest("POST", "/log/batch", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Log-Level", "DEBUG")
+	w := httptest.NewRecorder()
+	h.Router().ServeHTTP(w, req)
+
+	var resp handler.BatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || resp.Accepted != 3 || len(resp.Rejected) != 1 || resp.Rejected[0].Index != 1 {
+		t.Fatalf("expected only the second ERROR entry to be rejected, got %d %+v", w.Code, resp)
+	}
+	// Une entrée limitée peut être renvoyée par le client après retry_after secondes
+	if rej := resp.Rejected[0]; rej.Error != "rate limit exceeded for ERROR entries, retry in 1m0s" || !rej.Retryable || rej.RetryAfter != 60 {
+		t.Errorf("unexpected rejection %+v", rej)
+	}
+	if len(mock.logs) != 3 {
+		t.Errorf("expected 3 stored entries, got %d", len(mock.logs))
+	}
+}
+
+func TestHandleLogs_EntryLimited(t *testing.T) {
+	h := handler.NewHandler(&mockLogger{}, zap.NewNop())
+	h.SetEntryLimiter(newEntryLimiter(t, "WARN=1"))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/log", bytes.NewReader([]byte(`{"level":"warn","message":"disk"}`)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.Router().ServeHTTP(w, req)
+		return w
+	}
+	if w := send(); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+	if w := send(); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected 429 with Retry-After 60, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestHandleBatch_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		writeErr   error
+		wantStatus int
+		wantErrMsg string
+	}{
+		{"Invalid JSON", `{"level":"INFO"}`, nil, http.StatusBadRequest, "invalid JSON"},
+		{"Empty batch", `[]`, nil, http.StatusBadRequest, "batch is empty"},
+		{"Write failure", `[{"level":"INFO","message":"x"}]`, errors.New("disk full"), http.StatusInternalServerError, "failed to write log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockLogger{writeFunc: func(entry handler.LogEntry) error { return tt.writeErr }}
+			h := handler.NewHandler(mock, zap.NewNop())
+
+			req := httptest.NewRequest("POST", "/log/batch", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.Router().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if errMsg := decodeErrorResponse(t, w.Body); errMsg != tt.wantErrMsg {
+				t.Errorf("expected error message %q, got %q", tt.wantErrMsg, errMsg)
+			}
+		})
+	}
+}
+
 func TestHandleGetLogLevels(t *testing.T) {
 	mock := &mockLogger{}
 	h := handler.NewHandler(mock, zap.NewNop())
@@ -0,0 +1,134 @@
+package hec
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Valeurs par défaut du suivi des acks
+const (
+	DefaultChannelIdleTimeout = 10 * time.Minute
+	DefaultMaxPendingAcks     = 10000
+	DefaultMaxChannels        = 10000
+)
+
+var (
+	ErrInvalidChannel = errors.New("invalid data channel")
+	ErrAckLimit       = errors.New("too many pending acks")
+)
+
+// Les canaux HEC sont des GUID choisis par le client
+var channelPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ValidChannel vérifie le format d'un identifiant de canal
+func ValidChannel(channel string) bool {
+	return channelPattern.MatchString(channel)
+}
+
+type ackChannel struct {
+	next     uint64
+	pending  map[uint64]struct{}
+	lastSeen time.Time
+}
+
+// AckManager attribue les ackId par canal. L'écriture étant synchrone, un ackId émis
+// correspond à des données déjà stockées : il est rapporté true jusqu'à sa première
+// consultation, comme dans Splunk.
+type AckManager struct {
+	mu          sync.Mutex
+	channels    map[string]*ackChannel
+	idleTimeout time.Duration
+	maxPending  int
+	maxChannels int
+	now         func() time.Time
+}
+
+func NewAckManager(idleTimeout time.Duration, maxPending int) *AckManager {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultChannelIdleTimeout
+	}
+	if maxPending <= 0 {
+		maxPending = DefaultMaxPendingAcks
+	}
+	return &AckManager{
+		channels:    make(map[string]*ackChannel),
+		idleTimeout: idleTimeout,
+		maxPending:  maxPending,
+		maxChannels: DefaultMaxChannels,
+		now:         time.Now,
+	}
+}
+
+// Issue réserve le prochain ackId du canal
+func (m *AckManager) Issue(channel string) (uint64, error) {
+	if !ValidChannel(channel) {
+		return 0, ErrInvalidChannel
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	ch, ok := m.channels[channel]
+	if !ok {
+		if len(m.channels) >= m.maxChannels {
+			m.sweep(now)
+			if len(m.channels) >= m.maxChannels {
+				return 0, ErrAckLimit
+			}
+		}
+		ch = &ackChannel{pending: make(map[uint64]struct{})}
+		m.channels[channel] = ch
+	}
+	if len(ch.pending) >= m.maxPending {
+		return 0, ErrAckLimit
+	}
+
+	id := ch.next
+	ch.next++
+	ch.pending[id] = struct{}{}
+	ch.lastSeen = now
+	return id, nil
+}
+
+// Cancel retire un ackId dont la requête a échoué : il sera rapporté false
+func (m *AckManager) Cancel(channel string, id uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ch, ok := m.channels[channel]; ok {
+		delete(ch.pending, id)
+	}
+}
+
+// Query retourne l'état des ackIds demandés et oublie ceux rapportés true
+func (m *AckManager) Query(channel string, ids []uint64) map[uint64]bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := make(map[uint64]bool, len(ids))
+	ch, ok := m.channels[channel]
+	if ok {
+		ch.lastSeen = m.now()
+	}
+	for _, id := range ids {
+		if !ok {
+			status[id] = false
+			continue
+		}
+		_, pending := ch.pending[id]
+		status[id] = pending
+		delete(ch.pending, id)
+	}
+	return status
+}
+
+// sweep supprime les canaux inactifs depuis plus que idleTimeout
+func (m *AckManager) sweep(now time.Time) {
+	for id, ch := range m.channels {
+		if now.Sub(ch.lastSeen) > m.idleTimeout {
+			delete(m.channels, id)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package hec
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const testChannel = "0aeeac95-ac74-4aa9-b30d-6c4c0ac581ba"
+
+func TestAckManager_IssueAndQuery(t *testing.T) {
+	m := NewAckManager(time.Minute, 10)
+
+	first, err := m.Issue(testChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := m.Issue(testChannel)
+	if first != 0 || second != 1 {
+		t.Errorf("expected sequential ack ids, got %d, %d", first, second)
+	}
+
+	status := m.Query(testChannel, []uint64{0, 1, 7})
+	if !status[0] || !status[1] || status[7] {
+		t.Errorf("unexpected ack status %v", status)
+	}
+
+	// Un ack rapporté true est oublié
+	if m.Query(testChannel, []uint64{0})[0] {
+		t.Error("expected ack to be cleared after being reported")
+	}
+}
+
+func TestAckManager_Cancel(t *testing.T) {
+	m := NewAckManager(time.Minute, 10)
+	id, _ := m.Issue(testChannel)
+	m.Cancel(testChannel, id)
+
+	if m.Query(testChannel, []uint64{id})[id] {
+		t.Error("expected cancelled ack to be reported false")
+	}
+}
+
+func TestAckManager_Limits(t *testing.T) {
+	m := NewAckManager(time.Minute, 2)
+
+	if _, err := m.Issue("not-a-guid"); !errors.Is(err, ErrInvalidChannel) {
+		t.Errorf("expected ErrInvalidChannel, got %v", err)
+	}
+
+	m.Issue(testChannel)
+	m.Issue(testChannel)
+	if _, err := m.Issue(testChannel); !errors.Is(err, ErrAckLimit) {
+		t.Errorf("expected ErrAckLimit, got %v", err)
+	}
+}
+
+func TestAckManager_ExpiresIdleChannels(t *testing.T) {
+	now := time.Date(2025, 8, 6, 14, 0, 0, 0, time.UTC)
+	m := NewAckManager(time.Minute, 10)
+	m.maxChannels = 1
+	m.now = func() time.Time { return now }
+
+	if _, err := m.Issue(testChannel); err != nil {
+		t.Fatal(err)
+	}
+
+	other := "11111111-2222-3333-4444-555555555555"
+	if _, err := m.Issue(other); !errors.Is(err, ErrAckLimit) {
+		t.Errorf("expected channel limit, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := m.Issue(other); err != nil {
+		t.Errorf("expected idle channel to be swept, got %v", err)
+	}
+}
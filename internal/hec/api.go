@@ -0,0 +1,239 @@
+package hec
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/handler/handler"
+	"github.com/rypi-dev/logger-server/internal/idempotency/idempotency"
+	"github.com/rypi-dev/logger-server/internal/quota/quota"
+)
+
+// MaxRequestBodySize borne un lot d'événements
+const MaxRequestBodySize = 4 << 20
+
+var eventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "hec_events_total",
+	Help: "Total number of events received on the Splunk HEC endpoints, by endpoint and result",
+}, []string{"endpoint", "result"})
+
+func init() {
+	prometheus.MustRegister(eventsTotal)
+}
+
+// Config active les acks d'indexation (canaux X-Splunk-Request-Channel)
+type Config struct {
+	EnableAck          bool
+	ChannelIdleTimeout time.Duration
+	MaxPendingAcks     int
+}
+
+// API expose les endpoints Splunk HTTP Event Collector. L'authentification
+// "Authorization: Splunk <token>" est traitée par le middleware de clé API.
+type API struct {
+	ingest internal.IngestFunc
+	acks   *AckManager
+}
+
+func NewAPI(cfg Config, ingest internal.IngestFunc) *API {
+	a := &API{ingest: ingest}
+	if cfg.EnableAck {
+		a.acks = NewAckManager(cfg.ChannelIdleTimeout, cfg.MaxPendingAcks)
+	}
+	return a
+}
+
+// Register ajoute les routes /services/collector/* au routeur
+func (a *API) Register(r *mux.Router) {
+	for _, path := range []string{"/services/collector", "/services/collector/event", "/services/collector/event/1.0"} {
+		r.HandleFunc(path, a.handleEvent).Methods("POST")
+	}
+	for _, path := range []string{"/services/collector/raw", "/services/collector/raw/1.0"} {
+		r.HandleFunc(path, a.handleRaw).Methods("POST")
+	}
+	r.HandleFunc("/services/collector/ack", a.handleAck).Methods("POST")
+	r.HandleFunc("/services/collector/health", a.handleHealth).Methods("GET")
+	r.HandleFunc("/services/collector/health/1.0", a.handleHealth).Methods("GET")
+}
+
+type response struct {
+	Text               string  `json:"text"`
+	Code               int     `json:"code"`
+	AckID              *uint64 `json:"ackId,omitempty"`
+	InvalidEventNumber *int    `json:"invalid-event-number,omitempty"`
+}
+
+func writeCode(w http.ResponseWriter, status, code int) {
+	writeResponse(w, status, response{Text: Text(code), Code: code})
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (a *API) handleEvent(w http.ResponseWriter, r *http.Request) {
+	a.handleIngest(w, r, "event", DecodeEvents)
+}
+
+func (a *API) handleRaw(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	meta := Event{
+		Host:       q.Get("host"),
+		Source:     q.Get("source"),
+		SourceType: q.Get("sourcetype"),
+		Index:      q.Get("index"),
+	}
+	a.handleIngest(w, r, "raw", func(body []byte) ([]Event, error) {
+		return RawEvents(body, meta)
+	})
+}
+
+// handleIngest est commun à /event et /raw : canal, lecture du corps, décodage puis
+// ingestion séquentielle. Comme Splunk, les événements précédant un événement invalide
+// restent stockés et la réponse indique le rang de l'événement fautif.
+func (a *API) handleIngest(w http.ResponseWriter, r *http.Request, endpoint string, decode func([]byte) ([]Event, error)) {
+	channel := requestChannel(r)
+	if channel != "" && !ValidChannel(channel) {
+		writeCode(w, http.StatusBadRequest, CodeInvalidChannel)
+		return
+	}
+	if a.acks != nil && channel == "" {
+		writeCode(w, http.StatusBadRequest, CodeChannelMissing)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeCode(w, http.StatusRequestEntityTooLarge, CodeInvalidFormat)
+			return
+		}
+		writeCode(w, http.StatusBadRequest, CodeInvalidFormat)
+		return
+	}
+
+	events, err := decode(body)
+	if err != nil {
+		var decodeErr *DecodeError
+		switch {
+		case errors.Is(err, ErrNoData):
+			writeCode(w, http.StatusBadRequest, CodeNoData)
+		case errors.As(err, &decodeErr):
+			eventsTotal.WithLabelValues(endpoint, "invalid").Inc()
+			n := decodeErr.EventNumber
+			writeResponse(w, http.StatusBadRequest, response{Text: Text(decodeErr.Code), Code: decodeErr.Code, InvalidEventNumber: &n})
+		default:
+			writeCode(w, http.StatusBadRequest, CodeInvalidFormat)
+		}
+		return
+	}
+
+	var ackID uint64
+	if a.acks != nil {
+		if ackID, err = a.acks.Issue(channel); err != nil {
+			writeCode(w, http.StatusServiceUnavailable, CodeServerBusy)
+			return
+		}
+	}
+
+	key := r.Header.Get(idempotency.HeaderName)
+	ingest := apikey.Bind(r.Context(), a.ingest)
+	for i, ev := range events {
+		entry := ev.ToLogEntry()
+		entry.ID = idempotency.EntryID(key, i)
+
+		err := ingest(entry)
+		if err == nil {
+			eventsTotal.WithLabelValues(endpoint, "ok").Inc()
+			continue
+		}
+
+		if a.acks != nil {
+			a.acks.Cancel(channel, ackID)
+		}
+		if errors.Is(err, handler.ErrWriteFailed) {
+			// 503 "Server is busy" : les clients HEC réessaient
+			eventsTotal.WithLabelValues(endpoint, "write_error").Inc()
+			writeCode(w, http.StatusServiceUnavailable, CodeServerBusy)
+			return
+		}
+		if errors.Is(err, quota.ErrExceeded) {
+			// Les clients HEC ne réessaient pas un 429 : quota atteint, renvoyé comme 503
+			eventsTotal.WithLabelValues(endpoint, "quota_exceeded").Inc()
+			writeCode(w, http.StatusServiceUnavailable, CodeServerBusy)
+			return
+		}
+		eventsTotal.WithLabelValues(endpoint, "rejected").Inc()
+		n := i
+		writeResponse(w, http.StatusBadRequest, response{Text: Text(CodeInvalidFormat), Code: CodeInvalidFormat, InvalidEventNumber: &n})
+		return
+	}
+
+	resp := response{Text: Text(CodeSuccess), Code: CodeSuccess}
+	if a.acks != nil {
+		resp.AckID = &ackID
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
+
+func (a *API) handleAck(w http.ResponseWriter, r *http.Request) {
+	if a.acks == nil {
+		writeCode(w, http.StatusBadRequest, CodeAckDisabled)
+		return
+	}
+
+	channel := requestChannel(r)
+	if channel == "" {
+		writeCode(w, http.StatusBadRequest, CodeChannelMissing)
+		return
+	}
+	if !ValidChannel(channel) {
+		writeCode(w, http.StatusBadRequest, CodeInvalidChannel)
+		return
+	}
+
+	var req struct {
+		Acks []uint64 `json:"acks"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestBodySize)).Decode(&req); err != nil {
+		writeCode(w, http.StatusBadRequest, CodeInvalidFormat)
+		return
+	}
+
+	status := a.acks.Query(channel, req.Acks)
+	acks := make(map[string]bool, len(status))
+	for id, ok := range status {
+		acks[strconv.FormatUint(id, 10)] = ok
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"acks": acks})
+}
+
+func (a *API) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeCode(w, http.StatusOK, CodeHealthy)
+}
+
+// requestChannel lit le canal dans l'en-tête X-Splunk-Request-Channel ou le paramètre channel
+func requestChannel(r *http.Request) string {
+	if ch := strings.TrimSpace(r.Header.Get("X-Splunk-Request-Channel")); ch != "" {
+		return ch
+	}
+	return strings.TrimSpace(r.URL.Query().Get("channel"))
+}
@@ -0,0 +1,171 @@
+package hec_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/handler"
+	"github.com/rypi-dev/logger-server/internal/hec"
+	"github.com/rypi-dev/logger-server/internal/quota"
+)
+
+const channel = "0aeeac95-ac74-4aa9-b30d-6c4c0ac581ba"
+
+type collector struct {
+	entries []*internal.LogEntry
+	err     error
+}
+
+func (c *collector) ingest(entry *internal.LogEntry) error {
+	if c.err != nil {
+		return c.err
+	}
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+type hecResponse struct {
+	Text               string  `json:"text"`
+	Code               int     `json:"code"`
+	AckID              *uint64 `json:"ackId"`
+	InvalidEventNumber *int    `json:"invalid-event-number"`
+}
+
+func newRouter(cfg hec.Config, c *collector) *mux.Router {
+	r := mux.NewRouter()
+	hec.NewAPI(cfg, c.ingest).Register(r)
+	return r
+}
+
+func do(t *testing.T, r http.Handler, method, target, body string, headers map[string]string) (int, hecResponse) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp hecResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid HEC response: %v", err)
+	}
+	return w.Code, resp
+}
+
+func TestEventEndpoint(t *testing.T) {
+	c := &collector{}
+	r := newRouter(hec.Config{}, c)
+
+	status, resp := do(t, r, "POST", "/services/collector/event", `{"event":"one"}{"event":{"message":"two"}}`, nil)
+	if status != http.StatusOK || resp.Code != hec.CodeSuccess || resp.Text != "Success" {
+		t.Fatalf("unexpected response %d %+v", status, resp)
+	}
+	if resp.AckID != nil {
+		t.Error("expected no ackId when acks are disabled")
+	}
+	if len(c.entries) != 2 {
+		t.Errorf("expected 2 stored entries, got %d", len(c.entries))
+	}
+}
+
+func TestRawEndpoint(t *testing.T) {
+	c := &collector{}
+	r := newRouter(hec.Config{}, c)
+
+	status, _ := do(t, r, "POST", "/services/collector/raw?host=appliance&source=vendor", "line one\nline two\n", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(c.entries) != 2 || c.entries[0].Host != "appliance" || c.entries[0].Service != "vendor" {
+		t.Errorf("unexpected entries %+v", c.entries)
+	}
+}
+
+func TestEventEndpoint_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+		wantCode   int
+		wantIndex  int
+	}{
+		{"no data", "", nil, http.StatusBadRequest, hec.CodeNoData, -1},
+		{"event required", `{"event":"ok"}{"time":1}`, nil, http.StatusBadRequest, hec.CodeEventRequired, 1},
+		{"validation failure", `{"event":"ok"}{"event":"` + strings.Repeat("x", internal.MaxMessageLength+1) + `"}`, nil, http.StatusBadRequest, hec.CodeInvalidFormat, 1},
+		{"write failure", `{"event":"ok"}`, fmt.Errorf("%w: disk full", handler.ErrWriteFailed), http.StatusServiceUnavailable, hec.CodeServerBusy, -1},
+		{"quota exceeded", `{"event":"ok"}`, fmt.Errorf("%w: tenant/payments daily_entries", quota.ErrExceeded), http.StatusServiceUnavailable, hec.CodeServerBusy, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &collector{err: tt.err}
+			status, resp := do(t, newRouter(hec.Config{}, c), "POST", "/services/collector/event", tt.body, nil)
+			if status != tt.wantStatus || resp.Code != tt.wantCode {
+				t.Fatalf("expected %d/%d, got %d/%+v", tt.wantStatus, tt.wantCode, status, resp)
+			}
+			if tt.wantIndex >= 0 && (resp.InvalidEventNumber == nil || *resp.InvalidEventNumber != tt.wantIndex) {
+				t.Errorf("expected invalid-event-number %d, got %v", tt.wantIndex, resp.InvalidEventNumber)
+			}
+		})
+	}
+}
+
+func TestAcks(t *testing.T) {
+	c := &collector{}
+	r := newRouter(hec.Config{EnableAck: true}, c)
+
+	status, resp := do(t, r, "POST", "/services/collector/event", `{"event":"one"}`, nil)
+	if status != http.StatusBadRequest || resp.Code != hec.CodeChannelMissing {
+		t.Fatalf("expected channel missing, got %d %+v", status, resp)
+	}
+
+	status, resp = do(t, r, "POST", "/services/collector/event", `{"event":"one"}`, map[string]string{"X-Splunk-Request-Channel": "bogus"})
+	if status != http.StatusBadRequest || resp.Code != hec.CodeInvalidChannel {
+		t.Fatalf("expected invalid channel, got %d %+v", status, resp)
+	}
+
+	status, resp = do(t, r, "POST", "/services/collector/event", `{"event":"one"}`, map[string]string{"X-Splunk-Request-Channel": channel})
+	if status != http.StatusOK || resp.AckID == nil || *resp.AckID != 0 {
+		t.Fatalf("expected ackId 0, got %d %+v", status, resp)
+	}
+
+	req := httptest.NewRequest("POST", "/services/collector/ack?channel="+channel, strings.NewReader(`{"acks":[0,1]}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var ackResp struct {
+		Acks map[string]bool `json:"acks"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&ackResp); err != nil {
+		t.Fatal(err)
+	}
+	if !ackResp.Acks["0"] || ackResp.Acks["1"] {
+		t.Errorf("unexpected ack status %v", ackResp.Acks)
+	}
+}
+
+func TestAckEndpoint_Disabled(t *testing.T) {
+	status, resp := do(t, newRouter(hec.Config{}, &collector{}), "POST", "/services/collector/ack?channel="+channel, `{"acks":[0]}`, nil)
+	if status != http.StatusBadRequest || resp.Code != hec.CodeAckDisabled {
+		t.Errorf("expected ACK disabled, got %d %+v", status, resp)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	status, resp := do(t, newRouter(hec.Config{}, &collector{}), "GET", "/services/collector/health", "", nil)
+	if status != http.StatusOK || resp.Code != hec.CodeHealthy {
+		t.Errorf("unexpected health response %d %+v", status, resp)
+	}
+}
@@ -0,0 +1,266 @@
+package hec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+)
+
+// Codes de statut HEC (https://docs.splunk.com/Documentation/Splunk/latest/Data/TroubleshootHTTPEventCollector)
+const (
+	CodeSuccess        = 0
+	CodeTokenRequired  = 2
+	CodeInvalidToken   = 4
+	CodeNoData         = 5
+	CodeInvalidFormat  = 6
+	CodeInternalError  = 8
+	CodeServerBusy     = 9
+	CodeChannelMissing = 10
+	CodeInvalidChannel = 11
+	CodeEventRequired  = 12
+	CodeEventBlank     = 13
+	CodeAckDisabled    = 14
+	CodeHealthy        = 17
+)
+
+var codeText = map[int]string{
+	CodeSuccess:        "Success",
+	CodeTokenRequired:  "Token is required",
+	CodeInvalidToken:   "Invalid token",
+	CodeNoData:         "No data",
+	CodeInvalidFormat:  "Invalid data format",
+	CodeInternalError:  "Internal server error",
+	CodeServerBusy:     "Server is busy",
+	CodeChannelMissing: "Data channel is missing",
+	CodeInvalidChannel: "Invalid data channel",
+	CodeEventRequired:  "Event field is required",
+	CodeEventBlank:     "Event field cannot be blank",
+	CodeAckDisabled:    "ACK is disabled",
+	CodeHealthy:        "HEC is healthy",
+}
+
+// Text retourne le libellé standard d'un code HEC
+func Text(code int) string {
+	return codeText[code]
+}
+
+// DecodeError signale un événement mal formé; EventNumber est son rang (à partir de 0)
+type DecodeError struct {
+	Code        int
+	EventNumber int
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s (event %d)", Text(e.Code), e.EventNumber)
+}
+
+var ErrNoData = errors.New("no data")
+
+// Champs reconnus dans un événement objet ou dans "fields"
+var (
+	levelKeys   = []string{"level", "severity"}
+	messageKeys = []string{"message", "msg", "log"}
+)
+
+// Event est un événement HEC avec ses métadonnées
+type Event struct {
+	Time       time.Time
+	Host       string
+	Source     string
+	SourceType string
+	Index      string
+	Event      interface{}
+	Fields     map[string]interface{}
+}
+
+type wireEvent struct {
+	Time       json.RawMessage        `json:"time"`
+	Host       string                 `json:"host"`
+	Source     string                 `json:"source"`
+	SourceType string                 `json:"sourcetype"`
+	Index      string                 `json:"index"`
+	Event      json.RawMessage        `json:"event"`
+	Fields     map[string]interface{} `json:"fields"`
+}
+
+// DecodeEvents lit une suite d'objets JSON concaténés, comme l'endpoint /event de Splunk
+func DecodeEvents(body []byte) ([]Event, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, ErrNoData
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var events []Event
+	for i := 0; ; i++ {
+		var w wireEvent
+		err := dec.Decode(&w)
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, &DecodeError{Code: CodeInvalidFormat, EventNumber: i}
+		}
+
+		if len(w.Event) == 0 || string(w.Event) == "null" {
+			return nil, &DecodeError{Code: CodeEventRequired, EventNumber: i}
+		}
+		var payload interface{}
+		evDec := json.NewDecoder(bytes.NewReader(w.Event))
+		evDec.UseNumber()
+		if err := evDec.Decode(&payload); err != nil {
+			return nil, &DecodeError{Code: CodeInvalidFormat, EventNumber: i}
+		}
+		if s, ok := payload.(string); ok && strings.TrimSpace(s) == "" {
+			return nil, &DecodeError{Code: CodeEventBlank, EventNumber: i}
+		}
+
+		ts, err := parseTime(w.Time)
+		if err != nil {
+			return nil, &DecodeError{Code: CodeInvalidFormat, EventNumber: i}
+		}
+
+		events = append(events, Event{
+			Time:       ts,
+			Host:       w.Host,
+			Source:     w.Source,
+			SourceType: w.SourceType,
+			Index:      w.Index,
+			Event:      payload,
+			Fields:     w.Fields,
+		})
+	}
+}
+
+// RawEvents découpe le corps de /raw en un événement par ligne; meta porte les
+// métadonnées passées en paramètres de requête (host, source, sourcetype, index).
+func RawEvents(body []byte, meta Event) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		ev := meta
+		ev.Event = line
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrNoData
+	}
+	return events, nil
+}
+
+// parseTime accepte l'epoch en secondes (avec décimales) sous forme de nombre ou de chaîne
+func parseTime(raw json.RawMessage) (time.Time, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}, nil
+	}
+	s := strings.Trim(string(raw), `"`)
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	whole := int64(secs)
+	return time.Unix(whole, int64((secs-float64(whole))*1e9)).Round(time.Microsecond).UTC(), nil
+}
+
+// ToLogEntry convertit un événement : un événement texte devient le message, un objet fournit
+// message et niveau via ses champs usuels, le reste et "fields" vont dans le contexte.
+// Le service vient de fields.service, sinon de source.
+func (e Event) ToLogEntry() *internal.LogEntry {
+	entry := &internal.LogEntry{
+		Level:     internal.DefaultLogLevel,
+		Timestamp: e.Time,
+		Host:      e.Host,
+		Service:   e.Source,
+	}
+
+	ctx := make(map[string]interface{})
+	fields := make(map[string]interface{}, len(e.Fields))
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+
+	switch ev := e.Event.(type) {
+	case string:
+		entry.Message = strings.TrimRight(ev, "\n")
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(ev))
+		for k, v := range ev {
+			obj[k] = v
+		}
+		if msg, ok := takeString(obj, messageKeys); ok {
+			entry.Message = strings.TrimRight(msg, "\n")
+		} else {
+			raw, _ := json.Marshal(ev)
+			entry.Message = string(raw)
+			obj = nil
+		}
+		if level, ok := takeString(obj, levelKeys); ok {
+			setLevel(entry, level)
+		}
+		for k, v := range obj {
+			ctx[k] = v
+		}
+	default:
+		raw, _ := json.Marshal(ev)
+		entry.Message = string(raw)
+	}
+
+	if level, ok := takeString(fields, levelKeys); ok {
+		setLevel(entry, level)
+	}
+	if service, ok := takeString(fields, []string{"service"}); ok && service != "" {
+		entry.Service = service
+	}
+	for k, v := range fields {
+		ctx[k] = v
+	}
+	if e.SourceType != "" {
+		ctx["sourcetype"] = e.SourceType
+	}
+	if e.Index != "" {
+		ctx["index"] = e.Index
+	}
+
+	if len(ctx) > 0 {
+		entry.Context = ctx
+	}
+	return entry
+}
+
+func setLevel(entry *internal.LogEntry, level string) {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	if level == "WARNING" {
+		level = string(log_levels.LogLevelWarn)
+	}
+	if log_levels.IsValidLogLevel(level) {
+		entry.Level = level
+	}
+}
+
+func takeString(m map[string]interface{}, keys []string) (string, bool) {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok {
+			delete(m, k)
+			return s, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package hec_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/hec"
+)
+
+func TestDecodeEvents_Concatenated(t *testing.T) {
+	body := `{"time": 1754489520.25, "host": "fw-1", "source": "firewall", "event": "connection dropped"}
+{"time": "1754489521", "sourcetype": "_json", "event": {"message": "login", "level": "warn", "user": "alice"}, "fields": {"region": "eu"}}{"event": "no separator"}`
+
+	events, err := hec.DecodeEvents([]byte(body))
+	if err != nil {
+		t.Fatalf("DecodeEvents returned error: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if want := time.Unix(1754489520, 250000000).UTC(); !events[0].Time.Equal(want) {
+		t.Errorf("expected %v, got %v", want, events[0].Time)
+	}
+	if !events[1].Time.Equal(time.Unix(1754489521, 0)) {
+		t.Errorf("expected string epoch to be parsed, got %v", events[1].Time)
+	}
+	if events[1].Fields["region"] != "eu" {
+		t.Errorf("unexpected fields %v", events[1].Fields)
+	}
+}
+
+func TestDecodeEvents_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantIndex int
+	}{
+		{"missing event", `{"event":"ok"}{"host":"h"}`, hec.CodeEventRequired, 1},
+		{"blank event", `{"event":"  "}`, hec.CodeEventBlank, 0},
+		{"malformed JSON", `{"event":"ok"} {"event":`, hec.CodeInvalidFormat, 1},
+		{"bad time", `{"event":"ok","time":"yesterday"}`, hec.CodeInvalidFormat, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := hec.DecodeEvents([]byte(tt.body))
+			var decodeErr *hec.DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("expected DecodeError, got %v", err)
+			}
+			if decodeErr.Code != tt.wantCode || decodeErr.EventNumber != tt.wantIndex {
+				t.Errorf("expected code %d at %d, got %+v", tt.wantCode, tt.wantIndex, decodeErr)
+			}
+		})
+	}
+
+	if _, err := hec.DecodeEvents([]byte("  \n")); !errors.Is(err, hec.ErrNoData) {
+		t.Errorf("expected ErrNoData for empty body, got %v", err)
+	}
+}
+
+func TestRawEvents(t *testing.T) {
+	events, err := hec.RawEvents([]byte("first line\r\n\nsecond line\n"), hec.Event{Host: "appliance", Source: "vendor"})
+	if err != nil {
+		t.Fatalf("RawEvents returned error: %v", err)
+	}
+	if len(events) != 2 || events[0].Event != "first line" || events[1].Host != "appliance" {
+		t.Errorf("unexpected events %+v", events)
+	}
+
+	if _, err := hec.RawEvents([]byte("\n\n"), hec.Event{}); !errors.Is(err, hec.ErrNoData) {
+		t.Errorf("expected ErrNoData, got %v", err)
+	}
+}
+
+func TestEvent_ToLogEntry_Object(t *testing.T) {
+	ev := hec.Event{
+		Host:       "fw-1",
+		Source:     "firewall",
+		SourceType: "_json",
+		Event:      map[string]interface{}{"message": "login failed", "level": "warning", "user": "alice"},
+		Fields:     map[string]interface{}{"service": "auth", "region": "eu"},
+	}
+
+	entry := ev.ToLogEntry()
+	if entry.Message != "login failed" || entry.Level != "WARN" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Service != "auth" || entry.Host != "fw-1" {
+		t.Errorf("expected fields.service and host, got %q / %q", entry.Service, entry.Host)
+	}
+	if entry.Context["user"] != "alice" || entry.Context["region"] != "eu" || entry.Context["sourcetype"] != "_json" {
+		t.Errorf("unexpected context %v", entry.Context)
+	}
+	if _, ok := entry.Context["service"]; ok {
+		t.Error("expected service field not to be duplicated in context")
+	}
+	if err := entry.Validate(); err != nil {
+		t.Errorf("expected valid entry, got %v", err)
+	}
+}
+
+func TestEvent_ToLogEntry_Defaults(t *testing.T) {
+	entry := hec.Event{Source: "appliance", Event: "plain text\n"}.ToLogEntry()
+	if entry.Message != "plain text" || entry.Level != "INFO" || entry.Service != "appliance" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Context != nil {
+		t.Errorf("expected nil context, got %v", entry.Context)
+	}
+
+	// Objet sans champ message : sérialisé tel quel
+	entry = hec.Event{Event: map[string]interface{}{"code": json.Number("42")}}.ToLogEntry()
+	if entry.Message != `{"code":42}` {
+		t.Errorf("expected JSON message, got %q", entry.Message)
+	}
+}
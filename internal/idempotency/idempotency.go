@@ -0,0 +1,134 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HeaderName porte la clé d'idempotence d'une requête HTTP (entrée unique ou lot)
+const HeaderName = "Idempotency-Key"
+
+const DefaultWindow = 24 * time.Hour
+
+var duplicatesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "ingest_duplicates_suppressed_total",
+	Help: "Total number of log entries acknowledged but not stored because their ID was already seen",
+})
+
+func init() {
+	prometheus.MustRegister(duplicatesTotal)
+}
+
+// EntryID dérive l'identifiant de la i-ème entrée d'un lot à partir de la clé du lot.
+// Une clé vide donne un identifiant vide (pas de déduplication).
+func EntryID(key string, index int) string {
+	if key == "" {
+		return ""
+	}
+	return key + "/" + strconv.Itoa(index)
+}
+
+// SQLiteStore mémorise les identifiants déjà stockés pendant la fenêtre de déduplication.
+// La clé primaire de la table garantit qu'un même identifiant n'est réservé qu'une fois.
+type SQLiteStore struct {
+	db     *sql.DB
+	window time.Duration
+	now    func() time.Time
+}
+
+// NewSQLiteStore ouvre (ou crée) la table des identifiants dans la base SQLite donnée.
+func NewSQLiteStore(path string, window time.Duration) (*SQLiteStore, error) {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		id TEXT PRIMARY KEY,
+		seen_at INTEGER NOT NULL
+	) WITHOUT ROWID;
+	`); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_idempotency_keys_seen_at ON idempotency_keys(seen_at);`); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{db: db, window: window, now: time.Now}, nil
+}
+
+// Claim réserve id et retourne true s'il n'a pas été vu dans la fenêtre. Sinon l'entrée est
+// un doublon : elle est comptée et false est retourné.
+func (s *SQLiteStore) Claim(id string) (bool, error) {
+	now := s.now()
+	res, err := s.db.Exec(`
+	INSERT INTO idempotency_keys(id, seen_at) VALUES (?, ?)
+	ON CONFLICT(id) DO UPDATE SET seen_at = excluded.seen_at WHERE seen_at < ?
+	`, id, now.UnixNano(), now.Add(-s.window).UnixNano())
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		duplicatesTotal.Inc()
+		return false, nil
+	}
+	return true, nil
+}
+
+// Release libère un id réservé dont l'écriture a échoué, pour que le renvoi soit stocké
+func (s *SQLiteStore) Release(id string) error {
+	_, err := s.db.Exec(`DELETE FROM idempotency_keys WHERE id = ?`, id)
+	return err
+}
+
+// Purge supprime les identifiants sortis de la fenêtre et retourne leur nombre
+func (s *SQLiteStore) Purge() (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM idempotency_keys WHERE seen_at < ?`, s.now().Add(-s.window).UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Run purge périodiquement la table jusqu'à l'annulation du contexte
+func (s *SQLiteStore) Run(ctx context.Context) {
+	interval := s.window
+	if interval > time.Hour {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Purge()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
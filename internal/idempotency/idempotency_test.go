@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newStore(t *testing.T, window time.Duration) (*SQLiteStore, *time.Time) {
+	t.Helper()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "logs.sqlite"), window)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	now := time.Date(2025, 8, 6, 14, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestEntryID(t *testing.T) {
+	if got := EntryID("batch-1", 3); got != "batch-1/3" {
+		t.Errorf("expected batch-1/3, got %q", got)
+	}
+	if got := EntryID("", 3); got != "" {
+		t.Errorf("expected empty ID without key, got %q", got)
+	}
+}
+
+func TestClaim_Duplicate(t *testing.T) {
+	store, _ := newStore(t, time.Hour)
+	before := testutil.ToFloat64(duplicatesTotal)
+
+	if ok, err := store.Claim("evt-1"); err != nil || !ok {
+		t.Fatalf("expected first claim to succeed, got %v, %v", ok, err)
+	}
+	if ok, err := store.Claim("evt-1"); err != nil || ok {
+		t.Fatalf("expected duplicate, got %v, %v", ok, err)
+	}
+	if ok, _ := store.Claim("evt-2"); !ok {
+		t.Error("expected a different ID to be claimed")
+	}
+
+	if got := testutil.ToFloat64(duplicatesTotal) - before; got != 1 {
+		t.Errorf("expected 1 duplicate counted, got %v", got)
+	}
+}
+
+func TestClaim_AfterWindow(t *testing.T) {
+	store, now := newStore(t, time.Hour)
+
+	store.Claim("evt-1")
+	*now = now.Add(2 * time.Hour)
+
+	if ok, err := store.Claim("evt-1"); err != nil || !ok {
+		t.Errorf("expected ID outside the window to be claimed again, got %v, %v", ok, err)
+	}
+	if ok, _ := store.Claim("evt-1"); ok {
+		t.Error("expected the renewed ID to be a duplicate")
+	}
+}
+
+func TestRelease(t *testing.T) {
+	store, _ := newStore(t, time.Hour)
+
+	store.Claim("evt-1")
+	if err := store.Release("evt-1"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := store.Claim("evt-1"); !ok {
+		t.Error("expected released ID to be claimable")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	store, now := newStore(t, time.Hour)
+
+	store.Claim("old")
+	*now = now.Add(30 * time.Minute)
+	store.Claim("recent")
+	*now = now.Add(45 * time.Minute)
+
+	n, err := store.Purge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 purged ID, got %d", n)
+	}
+	if ok, _ := store.Claim("recent"); ok {
+		t.Error("expected recent ID to be kept")
+	}
+}
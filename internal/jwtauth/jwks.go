@@ -0,0 +1,274 @@
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultRefreshInterval est la durée de validité du cache JWKS
+	DefaultRefreshInterval = time.Hour
+
+	// Délai minimal entre deux rechargements provoqués par un kid inconnu, pour qu'un
+	// client envoyant des jetons forgés ne fasse pas interroger le fournisseur en boucle
+	minUnknownKidRefresh = time.Minute
+
+	maxJWKSSize = 1 << 20
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+var jwksRefreshTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "jwt_jwks_refresh_total",
+	Help: "Total number of JWKS loads, by result",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(jwksRefreshTotal)
+}
+
+// publicKey est une clé du JWKS avec l'algorithme qu'elle accepte
+type publicKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// KeySet charge les clés publiques d'un JWKS depuis une URL (http/https) ou un fichier.
+// Les clés sont rechargées après RefreshInterval, et plus tôt quand un jeton cite un kid
+// inconnu : le fournisseur peut ainsi publier une nouvelle clé et signer avec sans
+// attendre l'expiration du cache. Après un échec de chargement, les clés précédentes
+// restent utilisées.
+type KeySet struct {
+	source  string
+	refresh time.Duration
+	client  *http.Client
+	now     func() time.Time
+
+	loadMu sync.Mutex // un seul chargement à la fois
+
+	mu          sync.RWMutex
+	keys        map[string]publicKey
+	loadedAt    time.Time
+	lastAttempt time.Time
+}
+
+func NewKeySet(source string, refresh time.Duration) *KeySet {
+	if refresh <= 0 {
+		refresh = DefaultRefreshInterval
+	}
+	return &KeySet{
+		source:  source,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		now:     time.Now,
+	}
+}
+
+// Load (re)charge le JWKS; à appeler au démarrage pour détecter une mauvaise configuration
+func (ks *KeySet) Load() error {
+	ks.loadMu.Lock()
+	defer ks.loadMu.Unlock()
+	return ks.load()
+}
+
+func (ks *KeySet) load() error {
+	now := ks.now()
+	ks.mu.Lock()
+	ks.lastAttempt = now
+	ks.mu.Unlock()
+
+	raw, err := ks.fetch()
+	if err == nil {
+		var keys map[string]publicKey
+		if keys, err = parseJWKS(raw); err == nil {
+			ks.mu.Lock()
+			ks.keys = keys
+			ks.loadedAt = now
+			ks.mu.Unlock()
+			jwksRefreshTotal.WithLabelValues("success").Inc()
+			return nil
+		}
+	}
+	jwksRefreshTotal.WithLabelValues("error").Inc()
+	return fmt.Errorf("load JWKS from %s: %w", ks.source, err)
+}
+
+func (ks *KeySet) fetch() ([]byte, error) {
+	if !strings.HasPrefix(ks.source, "http://") && !strings.HasPrefix(ks.source, "https://") {
+		return os.ReadFile(ks.source)
+	}
+
+	resp, err := ks.client.Get(ks.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+// key retourne la clé kid, en rechargeant le JWKS si le cache a expiré ou si kid est inconnu
+func (ks *KeySet) key(kid string) (publicKey, error) {
+	now := ks.now()
+	ks.mu.RLock()
+	k, ok := ks.keys[kid]
+	stale := now.Sub(ks.loadedAt) >= ks.refresh
+	retry := now.Sub(ks.lastAttempt) >= minUnknownKidRefresh
+	ks.mu.RUnlock()
+
+	if (stale || !ok) && retry {
+		ks.loadMu.Lock()
+		// Un autre appel a pu recharger pendant l'attente du verrou
+		ks.mu.RLock()
+		k, ok = ks.keys[kid]
+		reload := (now.Sub(ks.loadedAt) >= ks.refresh || !ok) && now.Sub(ks.lastAttempt) >= minUnknownKidRefresh
+		ks.mu.RUnlock()
+		if reload {
+			// En cas d'échec, les clés en cache restent valables
+			_ = ks.load()
+			ks.mu.RLock()
+			k, ok = ks.keys[kid]
+			ks.mu.RUnlock()
+		}
+		ks.loadMu.Unlock()
+	}
+
+	if !ok {
+		return publicKey{}, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+	}
+	return k, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS retourne les clés de signature supportées; les autres (chiffrement, HMAC,
+// courbes non supportées) et les clés invalides sont ignorées et comptées dans
+// jwt_jwks_refresh_total{result="skipped_key"}, pour qu'une clé mal publiée ne bloque pas
+// les autres. Seul un JWKS sans aucune clé utilisable est une erreur
+func parseJWKS(raw []byte) (map[string]publicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]publicKey)
+	var lastErr error
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			jwksRefreshTotal.WithLabelValues("skipped_key").Inc()
+			continue
+		}
+		pk, err := k.publicKey()
+		if err != nil {
+			lastErr = fmt.Errorf("key %q: %w", k.Kid, err)
+			jwksRefreshTotal.WithLabelValues("skipped_key").Inc()
+			continue
+		}
+		if pk.key == nil || (k.Alg != "" && k.Alg != pk.alg) {
+			jwksRefreshTotal.WithLabelValues("skipped_key").Inc()
+			continue
+		}
+		keys[k.Kid] = pk
+	}
+	if len(keys) == 0 {
+		if lastErr != nil {
+			return nil, fmt.Errorf("no usable signing key in JWKS: %w", lastErr)
+		}
+		return nil, errors.New("no supported signing key in JWKS")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (publicKey, error) {
+	switch {
+	case k.Kty == "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return publicKey{}, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return publicKey{}, err
+		}
+		if n.BitLen() < 2048 || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return publicKey{}, errors.New("RSA key must be at least 2048 bits with a valid exponent")
+		}
+		return publicKey{alg: "RS256", key: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return publicKey{}, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return publicKey{}, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return publicKey{}, errors.New("invalid P-256 coordinates")
+		}
+		// ecdh vérifie que le point est sur la courbe
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return publicKey{}, err
+		}
+		return publicKey{alg: "ES256", key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return publicKey{}, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return publicKey{}, errors.New("invalid Ed25519 key")
+		}
+		return publicKey{alg: "EdDSA", key: ed25519.PublicKey(x)}, nil
+	}
+	return publicKey{}, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
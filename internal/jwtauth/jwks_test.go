@@ -0,0 +1,172 @@
+package jwtauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// jwksServer publie un JWKS modifiable et compte les requêtes
+type jwksServer struct {
+	mu   sync.Mutex
+	kids []string
+	fail bool
+	hits int
+	*httptest.Server
+}
+
+func newJWKSServer(t *testing.T, kids ...string) *jwksServer {
+	s := &jwksServer{kids: kids}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.hits++
+		if s.fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var keys []map[string]string
+		for _, kid := range s.kids {
+			pub, _, _ := ed25519.GenerateKey(rand.Reader)
+			keys = append(keys, map[string]string{"kty": "OKP", "crv": "Ed25519", "kid": kid, "x": base64.RawURLEncoding.EncodeToString(pub)})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(fail bool, kids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail, s.kids = fail, kids
+}
+
+func (s *jwksServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits
+}
+
+func newTestKeySet(t *testing.T, url string) (*KeySet, *time.Time) {
+	t.Helper()
+	now := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	ks := NewKeySet(url, time.Hour)
+	ks.now = func() time.Time { return now }
+	return ks, &now
+}
+
+func TestKeySet_CachesAndRefreshes(t *testing.T) {
+	srv := newJWKSServer(t, "k1")
+	ks, now := newTestKeySet(t, srv.URL)
+
+	if _, err := ks.key("k1"); err != nil {
+		t.Fatalf("expected k1 to be loaded on first use, got %v", err)
+	}
+	*now = now.Add(30 * time.Minute)
+	ks.key("k1")
+	if srv.count() != 1 {
+		t.Errorf("expected the JWKS to be cached, got %d fetches", srv.count())
+	}
+
+	*now = now.Add(31 * time.Minute)
+	ks.key("k1")
+	if srv.count() != 2 {
+		t.Errorf("expected a refresh after the interval, got %d fetches", srv.count())
+	}
+}
+
+func TestKeySet_Rollover(t *testing.T) {
+	srv := newJWKSServer(t, "k1")
+	ks, now := newTestKeySet(t, srv.URL)
+	if err := ks.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Le fournisseur publie k2 et signe avec : un kid inconnu provoque un rechargement,
+	// au plus un par minUnknownKidRefresh
+	srv.set(false, "k1", "k2")
+	if _, err := ks.key("k2"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected a reload to be throttled right after the last one, got %v", err)
+	}
+	*now = now.Add(minUnknownKidRefresh)
+	if _, err := ks.key("k2"); err != nil {
+		t.Fatalf("expected k2 after reload, got %v", err)
+	}
+	if _, err := ks.key("forged"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+	if srv.count() != 2 {
+		t.Errorf("expected unknown kids to be throttled, got %d fetches", srv.count())
+	}
+}
+
+func TestKeySet_KeepsKeysOnFailure(t *testing.T) {
+	srv := newJWKSServer(t, "k1")
+	ks, now := newTestKeySet(t, srv.URL)
+	if err := ks.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.set(true)
+	*now = now.Add(2 * time.Hour)
+	if err := ks.Load(); err == nil {
+		t.Error("expected Load to report the failure")
+	}
+	if _, err := ks.key("k1"); err != nil {
+		t.Errorf("expected cached keys to stay valid when the provider is down, got %v", err)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	raw := `{"keys": [
+		{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		{"kty": "OKP", "crv": "X25519", "kid": "x", "x": "AAAA"},
+		{"kty": "OKP", "crv": "Ed25519", "kid": "ed", "alg": "EdDSA", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+	]}`
+	keys, err := parseJWKS([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys["ed"].alg != "EdDSA" {
+		t.Errorf("expected only the Ed25519 signing key, got %v", keys)
+	}
+
+	for name, raw := range map[string]string{
+		"short RSA":    `{"keys": [{"kty": "RSA", "kid": "r", "n": "AQAB", "e": "AQAB"}]}`,
+		"off-curve EC": `{"keys": [{"kty": "EC", "crv": "P-256", "kid": "e", "x": "AQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", "y": "AQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}]}`,
+		"no keys":      `{"keys": []}`,
+	} {
+		if _, err := parseJWKS([]byte(raw)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseJWKS_SkipsInvalidKey(t *testing.T) {
+	raw := `{"keys": [
+		{"kty": "RSA", "kid": "short", "n": "AQAB", "e": "AQAB"},
+		{"kty": "OKP", "crv": "Ed25519", "kid": "ed", "alg": "EdDSA", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+	]}`
+	skipped := testutil.ToFloat64(jwksRefreshTotal.WithLabelValues("skipped_key"))
+	keys, err := parseJWKS([]byte(raw))
+	if err != nil {
+		t.Fatalf("an invalid key must not reject the whole set: %v", err)
+	}
+	if len(keys) != 1 || keys["ed"].alg != "EdDSA" {
+		t.Errorf("expected the Ed25519 key only, got %v", keys)
+	}
+	if got := testutil.ToFloat64(jwksRefreshTotal.WithLabelValues("skipped_key")) - skipped; got != 1 {
+		t.Errorf("expected 1 skipped key, got %v", got)
+	}
+}
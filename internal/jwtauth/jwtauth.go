@@ -0,0 +1,276 @@
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+)
+
+// DefaultLeeway est la tolérance d'horloge appliquée à exp et nbf
+const DefaultLeeway = time.Minute
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Config décrit les jetons acceptés et la correspondance entre leurs claims et les scopes
+// des clés API (apikey.ScopeIngest, ScopeRead, ScopeAdmin).
+type Config struct {
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+
+	// GroupsClaim désigne la liste des groupes du jeton ("groups" par défaut). Un chemin
+	// pointé est accepté pour les claims imbriqués (realm_access.roles).
+	GroupsClaim string
+	// GroupScopes donne les scopes de chaque groupe; DefaultScopes ceux de tout jeton valide
+	GroupScopes   map[string][]string
+	DefaultScopes []string
+
+	// TenantClaim, s'il est défini, range le jeton dans le tenant nommé par ce claim, comme
+	// une clé API d'un tenant. Un jeton sans ce claim est refusé.
+	TenantClaim string
+	// ServiceClaim, s'il est défini, lie le jeton au service nommé par ce claim, comme le
+	// service d'une clé API (apikey.Bind). Un jeton sans ce claim est refusé.
+	ServiceClaim string
+}
+
+// Verifier valide des JWT signés (RS256, ES256, EdDSA) avec les clés d'un KeySet et les
+// convertit en apikey.Key : le middleware, l'audit et l'ingestion les traitent comme des
+// clés API nommées d'après l'utilisateur.
+type Verifier struct {
+	cfg  Config
+	keys *KeySet
+	now  func() time.Time
+}
+
+func NewVerifier(cfg Config, keys *KeySet) (*Verifier, error) {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("issuer and audience are required")
+	}
+	if cfg.Leeway <= 0 {
+		cfg.Leeway = DefaultLeeway
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if len(cfg.DefaultScopes) > 0 {
+		scopes, err := apikey.ValidateScopes(cfg.DefaultScopes)
+		if err != nil {
+			return nil, err
+		}
+		cfg.DefaultScopes = scopes
+	}
+	groups := make(map[string][]string, len(cfg.GroupScopes))
+	for group, scopes := range cfg.GroupScopes {
+		valid, err := apikey.ValidateScopes(scopes)
+		if err != nil {
+			return nil, fmt.Errorf("group %q: %w", group, err)
+		}
+		groups[group] = valid
+	}
+	cfg.GroupScopes = groups
+	return &Verifier{cfg: cfg, keys: keys, now: time.Now}, nil
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Authenticate valide le jeton et retourne la clé équivalente. Il implémente
+// apikey.Authenticator pour les jetons Bearer.
+func (v *Verifier) Authenticate(token string) (*apikey.Key, error) {
+	claims, err := v.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	return v.key(claims)
+}
+
+func (v *Verifier) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed JWT", ErrInvalidToken)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature encoding", ErrInvalidToken)
+	}
+
+	key, err := v.keys.key(h.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	// L'algorithme vient de la clé : un jeton "none" ou HS256 ne peut pas être accepté
+	if h.Alg != key.alg {
+		return nil, fmt.Errorf("%w: algorithm %q does not match key %q", ErrInvalidToken, h.Alg, h.Kid)
+	}
+	if !verifySignature(key, parts[0]+"."+parts[1], sig) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+func verifySignature(key publicKey, input string, sig []byte) bool {
+	switch k := key.key.(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256([]byte(input))
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	case *ecdsa.PublicKey:
+		// Signature JWS : r || s sur 32 octets chacun (pas de DER)
+		if len(sig) != 64 {
+			return false
+		}
+		digest := sha256.Sum256([]byte(input))
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, []byte(input), sig)
+	}
+	return false
+}
+
+func (v *Verifier) checkClaims(claims map[string]interface{}) error {
+	if iss, _ := claims["iss"].(string); iss != v.cfg.Issuer {
+		return fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if !hasAudience(claims["aud"], v.cfg.Audience) {
+		return errors.New("audience mismatch")
+	}
+
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("missing exp")
+	}
+	if !now.Before(time.Unix(int64(exp), 0).Add(v.cfg.Leeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.cfg.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not yet valid")
+	}
+	return nil
+}
+
+func hasAudience(aud interface{}, want string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == want
+	case []interface{}:
+		for _, item := range a {
+			if s, ok := item.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key convertit les claims : nom d'après preferred_username, email ou sub, scopes d'après
+// les groupes, tenant et service d'après TenantClaim et ServiceClaim
+func (v *Verifier) key(claims map[string]interface{}) (*apikey.Key, error) {
+	name := ""
+	for _, claim := range []string{"preferred_username", "email", "sub"} {
+		if name, _ = claims[claim].(string); name != "" {
+			break
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	seen := make(map[string]bool)
+	var scopes []string
+	add := func(list []string) {
+		for _, s := range list {
+			if !seen[s] {
+				seen[s] = true
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	add(v.cfg.DefaultScopes)
+	for _, group := range stringList(claimValue(claims, v.cfg.GroupsClaim)) {
+		add(v.cfg.GroupScopes[group])
+	}
+
+	key := &apikey.Key{Name: name, Scopes: scopes}
+	if v.cfg.TenantClaim != "" {
+		tenant, _ := claimValue(claims, v.cfg.TenantClaim).(string)
+		if tenant == "" {
+			return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidToken, v.cfg.TenantClaim)
+		}
+		key.Tenant = tenant
+	}
+	if v.cfg.ServiceClaim != "" {
+		service, _ := claimValue(claims, v.cfg.ServiceClaim).(string)
+		if service == "" {
+			return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidToken, v.cfg.ServiceClaim)
+		}
+		key.Service = service
+	}
+	exp := time.Unix(int64(claims["exp"].(float64)), 0).UTC()
+	key.ExpiresAt = &exp
+	return key, nil
+}
+
+// claimValue suit un chemin pointé dans les claims (realm_access.roles)
+func claimValue(claims map[string]interface{}, path string) interface{} {
+	var cur interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// stringList accepte un tableau de chaînes ou une chaîne séparée par des espaces (claim scope)
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case string:
+		return strings.Fields(list)
+	case []interface{}:
+		var out []string
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,247 @@
+package jwtauth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/apikey"
+	"github.com/rypi-dev/logger-server/internal/jwtauth"
+)
+
+const (
+	issuer   = "https://sso.example.com/realms/ops"
+	audience = "logger-server"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// signer signe des jetons de test avec une clé et publie sa JWK
+type signer struct {
+	kid string
+	alg string
+	key crypto.Signer
+}
+
+func (s signer) jwk() map[string]string {
+	switch pub := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": s.kid, "use": "sig", "n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": s.kid, "crv": "P-256", "x": b64(pub.X.FillBytes(make([]byte, 32))), "y": b64(pub.Y.FillBytes(make([]byte, 32)))}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": s.kid, "crv": "Ed25519", "x": b64(pub)}
+	}
+	return nil
+}
+
+func (s signer) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": s.alg, "kid": s.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+
+	var sig []byte
+	var err error
+	switch k := s.key.(type) {
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(input))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(input))
+		r, ss, e := ecdsa.Sign(rand.Reader, k, digest[:])
+		sig, err = append(r.FillBytes(make([]byte, 32)), ss.FillBytes(make([]byte, 32))...), e
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(input))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + b64(sig)
+}
+
+func newSigners(t *testing.T) []signer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	return []signer{
+		{kid: "rsa-1", alg: "RS256", key: rsaKey},
+		{kid: "ec-1", alg: "ES256", key: ecKey},
+		{kid: "ed-1", alg: "EdDSA", key: edKey},
+	}
+}
+
+func writeJWKS(t *testing.T, signers ...signer) string {
+	t.Helper()
+	var keys []map[string]string
+	for _, s := range signers {
+		keys = append(keys, s.jwk())
+	}
+	raw, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":                issuer,
+		"aud":                []string{"account", audience},
+		"sub":                "f3b2c1",
+		"preferred_username": "alice",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"groups":             []string{"oncall"},
+	}
+}
+
+func newVerifier(t *testing.T, cfg jwtauth.Config, signers ...signer) *jwtauth.Verifier {
+	t.Helper()
+	keys := jwtauth.NewKeySet(writeJWKS(t, signers...), 0)
+	if err := keys.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	cfg.Issuer, cfg.Audience = issuer, audience
+	v, err := jwtauth.NewVerifier(cfg, keys)
+	if err != nil {
+		t.Fatalf("NewVerifier failed: %v", err)
+	}
+	return v
+}
+
+func TestVerifier_Algorithms(t *testing.T) {
+	signers := newSigners(t)
+	v := newVerifier(t, jwtauth.Config{GroupScopes: map[string][]string{"oncall": {"read"}}}, signers...)
+
+	for _, s := range signers {
+		key, err := v.Authenticate(s.sign(t, validClaims()))
+		if err != nil {
+			t.Errorf("%s: expected a valid token, got %v", s.alg, err)
+			continue
+		}
+		if key.Name != "alice" || !key.Allows(apikey.ScopeRead) || key.Allows(apikey.ScopeIngest) {
+			t.Errorf("%s: unexpected key %+v", s.alg, key)
+		}
+		if key.ExpiresAt == nil {
+			t.Errorf("%s: expected the token expiry on the key", s.alg)
+		}
+	}
+}
+
+func TestVerifier_RejectsInvalidTokens(t *testing.T) {
+	signers := newSigners(t)
+	good := signers[0]
+	v := newVerifier(t, jwtauth.Config{DefaultScopes: []string{"read"}}, good)
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		c := validClaims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	valid := good.sign(t, validClaims())
+	parts := strings.Split(valid, ".")
+	noneHeader := b64([]byte(`{"alg":"none","kid":"rsa-1"}`))
+
+	tests := map[string]string{
+		"malformed":       "not-a-jwt",
+		"tampered claims": parts[0] + "." + b64([]byte(`{"iss":"`+issuer+`","aud":"`+audience+`","sub":"mallory","exp":9999999999}`)) + "." + parts[2],
+		"alg none":        noneHeader + "." + parts[1] + ".",
+		"unknown kid":     signer{kid: "other", alg: "ES256", key: signers[1].key}.sign(t, validClaims()),
+		"wrong alg":       signer{kid: "rsa-1", alg: "ES256", key: signers[1].key}.sign(t, validClaims()),
+		"wrong issuer":    good.sign(t, with("iss", "https://evil.example.com")),
+		"wrong audience":  good.sign(t, with("aud", "grafana")),
+		"expired":         good.sign(t, with("exp", time.Now().Add(-2*time.Minute).Unix())),
+		"missing exp":     good.sign(t, with("exp", nil)),
+		"not yet valid":   good.sign(t, with("nbf", time.Now().Add(5*time.Minute).Unix())),
+	}
+
+	for name, token := range tests {
+		if _, err := v.Authenticate(token); !errors.Is(err, jwtauth.ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+
+	// Tolérance d'horloge : expiré depuis moins de Leeway
+	if _, err := v.Authenticate(good.sign(t, with("exp", time.Now().Add(-10*time.Second).Unix()))); err != nil {
+		t.Errorf("expected leeway on exp, got %v", err)
+	}
+
+	// Sans preferred_username, email puis sub nomment l'utilisateur
+	key, err := v.Authenticate(good.sign(t, with("preferred_username", nil)))
+	if err != nil || key.Name != "f3b2c1" {
+		t.Errorf("expected sub as name, got %+v, %v", key, err)
+	}
+}
+
+func TestVerifier_ClaimMapping(t *testing.T) {
+	s := newSigners(t)[2]
+	v := newVerifier(t, jwtauth.Config{
+		GroupsClaim:   "realm_access.roles",
+		GroupScopes:   map[string][]string{"logs-admin": {"admin"}, "shipper": {"ingest"}, "dev": {"read", "ingest"}},
+		DefaultScopes: []string{"read"},
+		TenantClaim:   "tenant",
+		ServiceClaim:  "app",
+	}, s)
+
+	claims := validClaims()
+	claims["realm_access"] = map[string]interface{}{"roles": []string{"dev", "unknown"}}
+	claims["tenant"] = "payments"
+	claims["app"] = "billing"
+	key, err := v.Authenticate(s.sign(t, claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(key.Scopes, ",") != "read,ingest" {
+		t.Errorf("expected default and group scopes without duplicates, got %v", key.Scopes)
+	}
+	if key.Tenant != "payments" || key.Service != "billing" {
+		t.Errorf("expected tenant payments and bound service billing, got %q, %q", key.Tenant, key.Service)
+	}
+
+	for _, claim := range []string{"tenant", "app"} {
+		missing := validClaims()
+		for name, value := range claims {
+			missing[name] = value
+		}
+		delete(missing, claim)
+		if _, err := v.Authenticate(s.sign(t, missing)); !errors.Is(err, jwtauth.ErrInvalidToken) {
+			t.Errorf("expected tokens without %s to be rejected, got %v", claim, err)
+		}
+	}
+}
+
+func TestNewVerifier_Validation(t *testing.T) {
+	keys := jwtauth.NewKeySet("unused", 0)
+	if _, err := jwtauth.NewVerifier(jwtauth.Config{Issuer: issuer}, keys); err == nil {
+		t.Error("expected audience to be required")
+	}
+	cfg := jwtauth.Config{Issuer: issuer, Audience: audience, GroupScopes: map[string][]string{"ops": {"root"}}}
+	if _, err := jwtauth.NewVerifier(cfg, keys); !errors.Is(err, apikey.ErrInvalidScope) {
+		t.Errorf("expected ErrInvalidScope, got %v", err)
+	}
+}
@@ -57,22 +57,33 @@ func NewSQLiteLogger(path string, maxRows int, minLevel log_levels.LogLevel, cle
 		level TEXT NOT NULL,
 		message TEXT NOT NULL,
 		timestamp TEXT NOT NULL,
-		context TEXT
+		context TEXT,
+		service TEXT,
+		host TEXT,
+		tenant TEXT NOT NULL DEFAULT ''
 	);`); err != nil {
 		db.Close()
 		return nil, err
 	}
 
+	// Migration des bases créées avant l'ajout des colonnes service/host/tenant : les
+	// entrées existantes appartiennent au tenant par défaut
+	if err := addMissingColumns(db, "logs", map[string]string{"service": "TEXT", "host": "TEXT", "tenant": "TEXT NOT NULL DEFAULT ''"}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	// Index pour accélérer les requêtes filtrées par level + timestamp DESC
 	if _, err = db.Exec(`
 	CREATE INDEX IF NOT EXISTS idx_logs_level_timestamp ON logs(level, timestamp DESC);
+	CREATE INDEX IF NOT EXISTS idx_logs_tenant_id ON logs(tenant, id);
 	`); err != nil {
 		db.Close()
 		return nil, err
 	}
 
 	insertStmt, err := db.Prepare(`
-	INSERT INTO logs(level, message, timestamp, context) VALUES (?, ?, ?, ?);
+	INSERT INTO logs(level, message, timestamp, context, service, host, tenant) VALUES (?, ?, ?, ?, ?, ?, ?);
 	`)
 	if err != nil {
 		db.Close()
@@ -106,6 +117,41 @@ func NewSQLiteLogger(path string, maxRows int, minLevel log_levels.LogLevel, cle
 	return logger, nil
 }
 
+// addMissingColumns ajoute à la table les colonnes absentes (ALTER TABLE ... ADD COLUMN)
+func addMissingColumns(db *sql.DB, table string, columns map[string]string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s);", table))
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	for name, colType := range columns {
+		if existing[name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, name, colType)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", table, name, err)
+		}
+	}
+	return nil
+}
+
 func (l *SQLiteLogger) cleanupLoop() {
 	defer l.wg.Done()
 	ticker := time.NewTicker(l.cleanupInterval)
@@ -136,7 +182,8 @@ func (l *SQLiteLogger) cleanup() error {
 	return err
 }
 
-func (l *SQLiteLogger) QueryLogs(level log_levels.LogLevel, page, limit int) ([]LogEntry, error) {
+// QueryLogs lit une page des entrées d'un tenant ("" : tenant par défaut)
+func (l *SQLiteLogger) QueryLogs(tenant string, level log_levels.LogLevel, page, limit int) ([]LogEntry, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -151,11 +198,11 @@ func (l *SQLiteLogger) QueryLogs(level log_levels.LogLevel, page, limit int) ([]
 
 	offset := (page - 1) * limit
 
-	query := `SELECT level, message, timestamp, context FROM logs`
-	args := []interface{}{}
+	query := `SELECT level, message, timestamp, context, service, host FROM logs WHERE tenant = ?`
+	args := []interface{}{tenant}
 
 	if level != "" {
-		query += " WHERE level = ?"
+		query += " AND level = ?"
 		args = append(args, string(level))
 	}
 
@@ -172,12 +219,16 @@ func (l *SQLiteLogger) QueryLogs(level log_levels.LogLevel, page, limit int) ([]
 	for rows.Next() {
 		var entry LogEntry
 		var ts string
-		var ctxJSON sql.NullString
+		var ctxJSON, service, host sql.NullString
 
-		if err := rows.Scan(&entry.Level, &entry.Message, &ts, &ctxJSON); err != nil {
+		if err := rows.Scan(&entry.Level, &entry.Message, &ts, &ctxJSON, &service, &host); err != nil {
 			return nil, err
 		}
 
+		entry.Service = service.String
+		entry.Host = host.String
+		entry.Tenant = tenant
+
 		entry.Timestamp = utils.SafeParseTimestamp(ts)
 
 		if ctxJSON.Valid && ctxJSON.String != "" {
@@ -220,13 +271,55 @@ func (l *SQLiteLogger) Write(entry LogEntry) error {
 
 	ts := entry.Timestamp.Format(utils.TimestampLayout)
 
-	_, err = l.insertStmt.Exec(string(entryLevel), entry.Message, ts, ctxJSON)
+	_, err = l.insertStmt.Exec(string(entryLevel), entry.Message, ts, ctxJSON, nullIfEmpty(entry.Service), nullIfEmpty(entry.Host), entry.Tenant)
 	if err != nil {
 		l.totalErrors++
 	}
 	return err
 }
 
+// PruneTenant applique la rétention d'un tenant : supprime ses entrées antérieures à before
+// (si non nul) puis les plus anciennes au-delà de maxEntries (si > 0)
+func (l *SQLiteLogger) PruneTenant(tenant string, before time.Time, maxEntries int) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var deleted int64
+	if !before.IsZero() {
+		res, err := l.db.Exec(`DELETE FROM logs WHERE tenant = ? AND CAST(strftime('%s', timestamp) AS INTEGER) < ?`, tenant, before.Unix())
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	if maxEntries > 0 {
+		res, err := l.db.Exec(`DELETE FROM logs WHERE tenant = ? AND id NOT IN (
+			SELECT id FROM logs WHERE tenant = ? ORDER BY id DESC LIMIT ?
+		)`, tenant, tenant, maxEntries)
+		if err != nil {
+			return deleted, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// PurgeTenant supprime toutes les entrées d'un tenant (suppression du tenant)
+func (l *SQLiteLogger) PurgeTenant(tenant string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := l.db.Exec(`DELETE FROM logs WHERE tenant = ?`, tenant)
+	return err
+}
+
+// nullIfEmpty stocke NULL plutôt qu'une chaîne vide pour les champs optionnels
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
 func (l *SQLiteLogger) Close() error {
 	l.cleanupCancel()
 	l.wg.Wait() // Attend que cleanupLoop soit fini
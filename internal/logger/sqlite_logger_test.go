@@ -1,6 +1,7 @@
 package logger_test
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
 	"testing"
@@ -51,7 +52,7 @@ func TestSQLiteLogger_WriteAndQuery_Success(t *testing.T) {
 		t.Errorf("Write failed: %v", err)
 	}
 
-	results, err := l.QueryLogs("INFO", 1, 10)
+	results, err := l.QueryLogs("", "INFO", 1, 10)
 	if err != nil {
 		t.Errorf("QueryLogs failed: %v", err)
 	}
@@ -60,6 +61,74 @@ func TestSQLiteLogger_WriteAndQuery_Success(t *testing.T) {
 	}
 }
 
+func TestSQLiteLogger_WriteAndQuery_ServiceAndHost(t *testing.T) {
+	tmp := t.TempDir()
+	dbPath := filepath.Join(tmp, "logs.db")
+
+	l, err := logger.NewSQLiteLogger(dbPath, 0, "INFO", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	entry := sampleLogEntry("INFO")
+	entry.Service = "billing"
+	entry.Host = "web-1"
+	if err := l.Write(entry); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	results, err := l.QueryLogs("", "INFO", 1, 10)
+	if err != nil {
+		t.Fatalf("QueryLogs failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Service != "billing" || results[0].Host != "web-1" {
+		t.Errorf("expected service and host to round-trip, got %+v", results)
+	}
+}
+
+func TestNewSQLiteLogger_MigratesOldSchema(t *testing.T) {
+	tmp := t.TempDir()
+	dbPath := filepath.Join(tmp, "logs.db")
+
+	// Base créée avant l'ajout des colonnes service/host
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		level TEXT NOT NULL,
+		message TEXT NOT NULL,
+		timestamp TEXT NOT NULL,
+		context TEXT
+	);
+	INSERT INTO logs(level, message, timestamp, context) VALUES ('INFO', 'legacy', '2025-08-06T14:12:00Z', '{}');`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	l, err := logger.NewSQLiteLogger(dbPath, 0, "INFO", 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteLogger failed on old schema: %v", err)
+	}
+	defer l.Close()
+
+	entry := sampleLogEntry("INFO")
+	entry.Service = "billing"
+	if err := l.Write(entry); err != nil {
+		t.Fatalf("Write failed after migration: %v", err)
+	}
+
+	results, err := l.QueryLogs("", "INFO", 1, 10)
+	if err != nil {
+		t.Fatalf("QueryLogs failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected legacy and new rows, got %d", len(results))
+	}
+}
+
 func TestSQLiteLogger_Write_BelowMinLevel(t *testing.T) {
 	tmp := t.TempDir()
 	dbPath := filepath.Join(tmp, "logs.db")
@@ -124,7 +193,7 @@ func TestSQLiteLogger_QueryLogs_InvalidLevel(t *testing.T) {
 	}
 	defer l.Close()
 
-	_, err = l.QueryLogs("BADLEVEL", 1, 10)
+	_, err = l.QueryLogs("", "BADLEVEL", 1, 10)
 	if err == nil {
 		t.Error("expected error for invalid query level")
 	}
@@ -140,7 +209,7 @@ func TestSQLiteLogger_QueryLogs_InvalidPagination(t *testing.T) {
 	}
 	defer l.Close()
 
-	_, err = l.QueryLogs("INFO", 0, -10)
+	_, err = l.QueryLogs("", "INFO", 0, -10)
 	if err == nil {
 		t.Error("expected error for invalid pagination")
 	}
@@ -167,7 +236,7 @@ func TestSQLiteLogger_Cleanup_RemovesOldLogs(t *testing.T) {
 	// Attend que cleanup ait le temps de tourner
 	time.Sleep(500 * time.Millisecond)
 
-	results, err := l.QueryLogs("INFO", 1, 20)
+	results, err := l.QueryLogs("", "INFO", 1, 20)
 	if err != nil {
 		t.Fatalf("QueryLogs failed: %v", err)
 	}
@@ -176,6 +245,109 @@ func TestSQLiteLogger_Cleanup_RemovesOldLogs(t *testing.T) {
 	}
 }
 
+func TestSQLiteLogger_TenantIsolation(t *testing.T) {
+	l, err := logger.NewSQLiteLogger(filepath.Join(t.TempDir(), "logs.db"), 0, "INFO", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for _, tenant := range []string{"", "acme", "acme", "globex"} {
+		entry := sampleLogEntry("INFO")
+		entry.Tenant = tenant
+		if err := l.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for tenant, want := range map[string]int{"": 1, "acme": 2, "globex": 1, "initech": 0} {
+		results, err := l.QueryLogs(tenant, "", 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != want {
+			t.Errorf("tenant %q: expected %d entries, got %d", tenant, want, len(results))
+		}
+		for _, r := range results {
+			if r.Tenant != tenant {
+				t.Errorf("tenant %q: got entry of tenant %q", tenant, r.Tenant)
+			}
+		}
+	}
+
+	if err := l.PurgeTenant("acme"); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := l.QueryLogs("acme", "", 1, 10); len(results) != 0 {
+		t.Errorf("expected purged tenant to be empty, got %d entries", len(results))
+	}
+	if results, _ := l.QueryLogs("globex", "", 1, 10); len(results) != 1 {
+		t.Errorf("expected other tenants to be kept, got %d entries", len(results))
+	}
+}
+
+func TestSQLiteLogger_PruneTenant(t *testing.T) {
+	l, err := logger.NewSQLiteLogger(filepath.Join(t.TempDir(), "logs.db"), 0, "INFO", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	now := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	for i, age := range []time.Duration{72 * time.Hour, 48 * time.Hour, 2 * time.Hour, time.Hour, 0} {
+		entry := sampleLogEntry("INFO")
+		entry.Tenant = "acme"
+		entry.Message = "log " + string(rune(i+'0'))
+		entry.Timestamp = now.Add(-age)
+		l.Write(entry)
+	}
+	other := sampleLogEntry("INFO")
+	other.Tenant = "globex"
+	other.Timestamp = now.Add(-72 * time.Hour)
+	l.Write(other)
+
+	deleted, err := l.PruneTenant("acme", now.Add(-24*time.Hour), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 3 {
+		t.Errorf("expected 3 entries deleted, got %d", deleted)
+	}
+	results, _ := l.QueryLogs("acme", "", 1, 10)
+	if len(results) != 2 || results[0].Message != "log 4" || results[1].Message != "log 3" {
+		t.Errorf("expected the 2 most recent entries to be kept, got %+v", results)
+	}
+	if results, _ := l.QueryLogs("globex", "", 1, 10); len(results) != 1 {
+		t.Errorf("expected other tenants to be untouched, got %d entries", len(results))
+	}
+}
+
+func TestSQLiteLogger_Cleanup_PerTenant(t *testing.T) {
+	l, err := logger.NewSQLiteLogger(filepath.Join(t.TempDir(), "logs.db"), 3, "DEBUG", 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	quiet := sampleLogEntry("INFO")
+	quiet.Tenant = "quiet"
+	l.Write(quiet)
+	for i := 0; i < 10; i++ {
+		noisy := sampleLogEntry("INFO")
+		noisy.Tenant = "noisy"
+		l.Write(noisy)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	if results, _ := l.QueryLogs("noisy", "", 1, 20); len(results) != 3 {
+		t.Errorf("expected noisy tenant to be trimmed to 3 entries, got %d", len(results))
+	}
+	if results, _ := l.QueryLogs("quiet", "", 1, 20); len(results) != 1 {
+		t.Errorf("expected quiet tenant to keep its entry, got %d", len(results))
+	}
+}
+
 func TestSQLiteLogger_Close_IsSafeTwice(t *testing.T) {
 	tmp := t.TempDir()
 	dbPath := filepath.Join(tmp, "logs.db")
@@ -0,0 +1,146 @@
+package loki
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/golang/snappy"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/handler/handler"
+	"github.com/rypi-dev/logger-server/internal/idempotency/idempotency"
+	"github.com/rypi-dev/logger-server/internal/quota/quota"
+)
+
+// Limites des corps de requête, avant et après décompression snappy
+const (
+	MaxRequestBodySize  = 4 << 20
+	MaxDecompressedSize = 16 << 20
+)
+
+var entriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "loki_entries_total",
+	Help: "Total number of entries received on the Loki push API, by result",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(entriesTotal)
+}
+
+// API expose l'endpoint push de Loki pour promtail et Grafana Agent
+type API struct {
+	ingest internal.IngestFunc
+}
+
+func NewAPI(ingest internal.IngestFunc) *API {
+	return &API{ingest: ingest}
+}
+
+// Register ajoute POST /loki/api/v1/push au routeur
+func (a *API) Register(r *mux.Router) {
+	r.HandleFunc("/loki/api/v1/push", a.handlePush).Methods("POST")
+}
+
+func (a *API) handlePush(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	// promtail envoie du protobuf compressé snappy; sans Content-Type, Loki suppose aussi protobuf
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var streams []Stream
+	switch mediaType {
+	case "application/json":
+		streams, err = DecodeJSON(body)
+	case "application/x-protobuf", "":
+		streams, err = decodeSnappyProtobuf(body)
+	default:
+		http.Error(w, "Content-Type must be application/x-protobuf or application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rejected int
+	var firstErr error
+	key := r.Header.Get(idempotency.HeaderName)
+	ingest := apikey.Bind(r.Context(), a.ingest)
+	index := 0
+	for _, stream := range streams {
+		for _, e := range stream.Entries {
+			entry := stream.ToLogEntry(e)
+			entry.ID = idempotency.EntryID(key, index)
+			index++
+
+			err := ingest(entry)
+			switch {
+			case err == nil:
+				entriesTotal.WithLabelValues("ok").Inc()
+			case errors.Is(err, handler.ErrWriteFailed):
+				// 5xx : promtail réessaie le lot
+				entriesTotal.WithLabelValues("write_error").Inc()
+				http.Error(w, "failed to write log", http.StatusInternalServerError)
+				return
+			case errors.Is(err, quota.ErrExceeded):
+				// 429 : promtail réessaie le lot après Retry-After
+				entriesTotal.WithLabelValues("quota_exceeded").Inc()
+				if wait, _ := handler.RetryAfter(err); wait > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				}
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+				return
+			default:
+				entriesTotal.WithLabelValues("rejected").Inc()
+				rejected++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+	}
+
+	// Comme Loki : les entrées valides sont conservées, le client est prévenu des rejets par un 400
+	// (non réessayé, renvoyer les mêmes lignes n'y changerait rien)
+	if rejected > 0 {
+		http.Error(w, fmt.Sprintf("%d entries rejected, first error: %v", rejected, firstErr), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeSnappyProtobuf(body []byte) ([]Stream, error) {
+	size, err := snappy.DecodedLen(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPush, err)
+	}
+	if size > MaxDecompressedSize {
+		return nil, fmt.Errorf("%w: decompressed body exceeds %d bytes", ErrInvalidPush, MaxDecompressedSize)
+	}
+
+	data, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPush, err)
+	}
+	return DecodeProtobuf(data)
+}
@@ -0,0 +1,129 @@
+package loki_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/handler"
+	"github.com/rypi-dev/logger-server/internal/loki"
+	"github.com/rypi-dev/logger-server/internal/quota"
+)
+
+type collector struct {
+	entries []*internal.LogEntry
+	err     error
+}
+
+func (c *collector) ingest(entry *internal.LogEntry) error {
+	if c.err != nil {
+		return c.err
+	}
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+func push(c *collector, contentType string, body []byte) *httptest.ResponseRecorder {
+	r := mux.NewRouter()
+	loki.NewAPI(c.ingest).Register(r)
+
+	req := httptest.NewRequest("POST", "/loki/api/v1/push", bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPush_SnappyProtobuf(t *testing.T) {
+	c := &collector{}
+	body := snappy.Encode(nil, encodePush(`{job="varlogs", level="error"}`,
+		pbEntry{ts: entryTime, line: "segfault"},
+	))
+
+	w := push(c, "application/x-protobuf", body)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(c.entries) != 1 {
+		t.Fatalf("expected 1 stored entry, got %d", len(c.entries))
+	}
+	if e := c.entries[0]; e.Service != "varlogs" || e.Level != "ERROR" || !e.Timestamp.Equal(entryTime) {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+func TestPush_JSON(t *testing.T) {
+	c := &collector{}
+	body := `{"streams":[{"stream":{"app":"web"},"values":[["1754489520000000000","hello"],["1754489521000000000","world"]]}]}`
+
+	w := push(c, "application/json", []byte(body))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(c.entries) != 2 {
+		t.Errorf("expected 2 stored entries, got %d", len(c.entries))
+	}
+}
+
+func TestPush_PartialRejection(t *testing.T) {
+	c := &collector{}
+	body := `{"streams":[{"stream":{"app":"web"},"values":[["1754489520000000000","kept"],["1754489521000000000",""]]}]}`
+
+	w := push(c, "application/json", []byte(body))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	if len(c.entries) != 1 {
+		t.Errorf("expected valid entry to be kept, got %d entries", len(c.entries))
+	}
+}
+
+func TestPush_QuotaExceeded(t *testing.T) {
+	breach := quota.Breach{Dimension: "daily_entries", Used: 100, Limit: 100, Reset: time.Now().Add(90 * time.Second)}
+	body := `{"streams":[{"stream":{"app":"web"},"values":[["1754489520000000000","over"]]}]}`
+
+	w := push(&collector{err: &quota.ExceededError{Breach: breach}}, "application/json", []byte(body))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != "90" {
+		t.Errorf("expected Retry-After 90, got %q", got)
+	}
+}
+
+func TestPush_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		err         error
+		wantStatus  int
+	}{
+		{"unsupported content type", "text/plain", []byte("hello"), nil, http.StatusUnsupportedMediaType},
+		{"not snappy", "application/x-protobuf", []byte("plain protobuf"), nil, http.StatusBadRequest},
+		{"invalid JSON", "application/json", []byte("{"), nil, http.StatusBadRequest},
+		{"write failure", "", snappy.Encode(nil, encodePush(`{app="web"}`, pbEntry{ts: entryTime, line: "x"})),
+			fmt.Errorf("%w: disk full", handler.ErrWriteFailed), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := push(&collector{err: tt.err}, tt.contentType, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
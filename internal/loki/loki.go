@@ -0,0 +1,369 @@
+package loki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+)
+
+var ErrInvalidPush = errors.New("invalid push request")
+
+// Labels reconnus pour les champs de LogEntry, par ordre de priorité
+var (
+	serviceLabels = []string{"service_name", "service", "app", "job"}
+	hostLabels    = []string{"host", "hostname"}
+	levelLabels   = []string{"level", "detected_level", "severity"}
+)
+
+// Entry est une ligne de log d'un stream avec ses métadonnées structurées éventuelles
+type Entry struct {
+	Timestamp          time.Time
+	Line               string
+	StructuredMetadata map[string]string
+}
+
+// Stream regroupe les lignes partageant un même jeu de labels
+type Stream struct {
+	Labels  map[string]string
+	Entries []Entry
+}
+
+// ToLogEntry convertit une ligne en LogEntry : service, host et niveau sont extraits des
+// labels (ou des métadonnées structurées), les autres labels vont dans le contexte.
+func (s Stream) ToLogEntry(e Entry) *internal.LogEntry {
+	entry := &internal.LogEntry{
+		Level:     internal.DefaultLogLevel,
+		Message:   strings.TrimRight(e.Line, "\n"),
+		Timestamp: e.Timestamp,
+	}
+
+	labels := make(map[string]string, len(s.Labels)+len(e.StructuredMetadata))
+	for k, v := range s.Labels {
+		labels[k] = v
+	}
+	for k, v := range e.StructuredMetadata {
+		labels[k] = v
+	}
+
+	if v, key := first(labels, serviceLabels); key != "" {
+		entry.Service = v
+		delete(labels, key)
+	}
+	if v, key := first(labels, hostLabels); key != "" {
+		entry.Host = v
+		delete(labels, key)
+	}
+	if v, key := first(labels, levelLabels); key != "" {
+		delete(labels, key)
+		if level := normalizeLevel(v); level != "" {
+			entry.Level = level
+		}
+	}
+
+	if len(labels) > 0 {
+		entry.Context = make(map[string]interface{}, len(labels))
+		for k, v := range labels {
+			entry.Context[k] = v
+		}
+	}
+	return entry
+}
+
+func first(labels map[string]string, keys []string) (string, string) {
+	for _, k := range keys {
+		if v, ok := labels[k]; ok && v != "" {
+			return v, k
+		}
+	}
+	return "", ""
+}
+
+// normalizeLevel accepte les niveaux log_levels et les variantes courantes de Loki
+func normalizeLevel(v string) string {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	switch v {
+	case "WARNING":
+		return string(log_levels.LogLevelWarn)
+	case "ERR":
+		return string(log_levels.LogLevelError)
+	case "CRITICAL":
+		return string(log_levels.LogLevelFatal)
+	}
+	if log_levels.IsValidLogLevel(v) {
+		return v
+	}
+	return ""
+}
+
+// ParseLabels analyse un sélecteur de labels au format Prometheus : {app="web", env="prod"}
+func ParseLabels(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("%w: labels must be enclosed in braces: %q", ErrInvalidPush, s)
+	}
+	rest := strings.TrimSpace(s[1 : len(s)-1])
+
+	labels := make(map[string]string)
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("%w: expected name=\"value\" in %q", ErrInvalidPush, s)
+		}
+		name := strings.TrimSpace(rest[:eq])
+		if !validLabelName(name) {
+			return nil, fmt.Errorf("%w: invalid label name %q", ErrInvalidPush, name)
+		}
+
+		rest = strings.TrimSpace(rest[eq+1:])
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil || quoted[0] != '"' {
+			return nil, fmt.Errorf("%w: label %q value must be a quoted string", ErrInvalidPush, name)
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("%w: label %q: %v", ErrInvalidPush, name, err)
+		}
+		labels[name] = value
+
+		rest = strings.TrimSpace(rest[len(quoted):])
+		if rest == "" {
+			break
+		}
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("%w: expected ',' between labels in %q", ErrInvalidPush, s)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+	return labels, nil
+}
+
+func validLabelName(name string) bool {
+	for i, r := range name {
+		if r == '_' || r <= unicode.MaxASCII && unicode.IsLetter(r) || i > 0 && r >= '0' && r <= '9' {
+			continue
+		}
+		return false
+	}
+	return name != ""
+}
+
+// jsonPush est l'encodage JSON de l'API push :
+// {"streams": [{"stream": {...}, "values": [["<ns>", "line", {...}?], ...]}]}
+type jsonPush struct {
+	Streams []struct {
+		Stream map[string]string   `json:"stream"`
+		Values [][]json.RawMessage `json:"values"`
+	} `json:"streams"`
+}
+
+// DecodeJSON décode le corps JSON d'une requête push
+func DecodeJSON(body []byte) ([]Stream, error) {
+	var push jsonPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPush, err)
+	}
+
+	streams := make([]Stream, 0, len(push.Streams))
+	for _, js := range push.Streams {
+		stream := Stream{Labels: js.Stream}
+		for _, value := range js.Values {
+			if len(value) < 2 || len(value) > 3 {
+				return nil, fmt.Errorf("%w: values must be [timestamp, line] or [timestamp, line, metadata]", ErrInvalidPush)
+			}
+
+			var tsStr, line string
+			if err := json.Unmarshal(value[0], &tsStr); err != nil {
+				return nil, fmt.Errorf("%w: timestamp must be a string of nanoseconds", ErrInvalidPush)
+			}
+			ns, err := strconv.ParseInt(tsStr, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid timestamp %q", ErrInvalidPush, tsStr)
+			}
+			if err := json.Unmarshal(value[1], &line); err != nil {
+				return nil, fmt.Errorf("%w: line must be a string", ErrInvalidPush)
+			}
+
+			entry := Entry{Timestamp: time.Unix(0, ns).UTC(), Line: line}
+			if len(value) == 3 {
+				if err := json.Unmarshal(value[2], &entry.StructuredMetadata); err != nil {
+					return nil, fmt.Errorf("%w: structured metadata must be an object of strings", ErrInvalidPush)
+				}
+			}
+			stream.Entries = append(stream.Entries, entry)
+		}
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
+// Numéros de champs de logproto.PushRequest et des messages imbriqués
+const (
+	fieldPushStreams = 1
+
+	fieldStreamLabels  = 1
+	fieldStreamEntries = 2
+
+	fieldEntryTimestamp = 1
+	fieldEntryLine      = 2
+	fieldEntryMetadata  = 3
+
+	fieldPairName  = 1
+	fieldPairValue = 2
+
+	fieldTimestampSeconds = 1
+	fieldTimestampNanos   = 2
+)
+
+// DecodeProtobuf décode un logproto.PushRequest (déjà décompressé). Le décodage est fait
+// à la main avec protowire pour ne pas dépendre du module Loki.
+func DecodeProtobuf(data []byte) ([]Stream, error) {
+	var streams []Stream
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num != fieldPushStreams || typ != protowire.BytesType {
+			return nil
+		}
+		stream, err := decodeStream(v)
+		if err != nil {
+			return err
+		}
+		streams = append(streams, stream)
+		return nil
+	})
+	return streams, err
+}
+
+func decodeStream(data []byte) (Stream, error) {
+	var stream Stream
+	var labels string
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case fieldStreamLabels:
+			labels = string(v)
+		case fieldStreamEntries:
+			entry, err := decodeEntry(v)
+			if err != nil {
+				return err
+			}
+			stream.Entries = append(stream.Entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return Stream{}, err
+	}
+
+	if stream.Labels, err = ParseLabels(labels); err != nil {
+		return Stream{}, err
+	}
+	return stream, nil
+}
+
+func decodeEntry(data []byte) (Entry, error) {
+	var entry Entry
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case fieldEntryTimestamp:
+			ts, err := decodeTimestamp(v)
+			if err != nil {
+				return err
+			}
+			entry.Timestamp = ts
+		case fieldEntryLine:
+			entry.Line = string(v)
+		case fieldEntryMetadata:
+			name, value, err := decodePair(v)
+			if err != nil {
+				return err
+			}
+			if entry.StructuredMetadata == nil {
+				entry.StructuredMetadata = make(map[string]string)
+			}
+			entry.StructuredMetadata[name] = value
+		}
+		return nil
+	})
+	return entry, err
+}
+
+func decodeTimestamp(data []byte) (time.Time, error) {
+	var seconds, nanos int64
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.VarintType {
+			return nil
+		}
+		n, _ := protowire.ConsumeVarint(v)
+		switch num {
+		case fieldTimestampSeconds:
+			seconds = int64(n)
+		case fieldTimestampNanos:
+			nanos = int64(int32(n))
+		}
+		return nil
+	})
+	return time.Unix(seconds, nanos).UTC(), err
+}
+
+func decodePair(data []byte) (string, string, error) {
+	var name, value string
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case fieldPairName:
+			name = string(v)
+		case fieldPairValue:
+			value = string(v)
+		}
+		return nil
+	})
+	return name, value, err
+}
+
+// walkFields parcourt les champs d'un message protobuf. Pour les champs BytesType, v est
+// le contenu sans le préfixe de longueur; pour les varints, v contient le varint brut.
+func walkFields(data []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("%w: %v", ErrInvalidPush, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		var v []byte
+		if typ == protowire.BytesType {
+			b, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return fmt.Errorf("%w: %v", ErrInvalidPush, protowire.ParseError(m))
+			}
+			v, n = b, m
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return fmt.Errorf("%w: %v", ErrInvalidPush, protowire.ParseError(n))
+			}
+			v = data[:n]
+		}
+		data = data[n:]
+
+		if err := fn(num, typ, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package loki_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/rypi-dev/logger-server/internal/loki"
+)
+
+var entryTime = time.Date(2025, 8, 6, 14, 12, 0, 123456789, time.UTC)
+
+type pbEntry struct {
+	ts       time.Time
+	line     string
+	metadata [][2]string
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// encodePush construit un logproto.PushRequest avec un seul stream
+func encodePush(labels string, entries ...pbEntry) []byte {
+	var stream []byte
+	stream = protowire.AppendTag(stream, 1, protowire.BytesType)
+	stream = protowire.AppendString(stream, labels)
+
+	for _, e := range entries {
+		var ts []byte
+		ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(e.ts.Unix()))
+		ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(e.ts.Nanosecond()))
+
+		var entry []byte
+		entry = appendMessage(entry, 1, ts)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, e.line)
+		for _, md := range e.metadata {
+			var pair []byte
+			pair = protowire.AppendTag(pair, 1, protowire.BytesType)
+			pair = protowire.AppendString(pair, md[0])
+			pair = protowire.AppendTag(pair, 2, protowire.BytesType)
+			pair = protowire.AppendString(pair, md[1])
+			entry = appendMessage(entry, 3, pair)
+		}
+		stream = appendMessage(stream, 2, entry)
+	}
+
+	return appendMessage(nil, 1, stream)
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := loki.ParseLabels(`{app="web", env="prod", msg="say \"hi\"", empty=""}`)
+	if err != nil {
+		t.Fatalf("ParseLabels returned error: %v", err)
+	}
+	want := map[string]string{"app": "web", "env": "prod", "msg": `say "hi"`, "empty": ""}
+	if len(labels) != len(want) {
+		t.Fatalf("expected %v, got %v", want, labels)
+	}
+	for k, v := range want {
+		if labels[k] != v {
+			t.Errorf("label %s: expected %q, got %q", k, v, labels[k])
+		}
+	}
+
+	if labels, err := loki.ParseLabels("{}"); err != nil || len(labels) != 0 {
+		t.Errorf("expected empty labels, got %v, %v", labels, err)
+	}
+}
+
+func TestParseLabels_Invalid(t *testing.T) {
+	for _, s := range []string{"", `app="web"`, `{app=web}`, `{app="web" env="prod"}`, `{1app="web"}`, `{app="web}`} {
+		if _, err := loki.ParseLabels(s); !errors.Is(err, loki.ErrInvalidPush) {
+			t.Errorf("ParseLabels(%q): expected ErrInvalidPush, got %v", s, err)
+		}
+	}
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	data := encodePush(`{service_name="checkout", env="prod"}`,
+		pbEntry{ts: entryTime, line: "first"},
+		pbEntry{ts: entryTime, line: "second", metadata: [][2]string{{"trace_id", "abc"}}},
+	)
+
+	streams, err := loki.DecodeProtobuf(data)
+	if err != nil {
+		t.Fatalf("DecodeProtobuf returned error: %v", err)
+	}
+	if len(streams) != 1 || len(streams[0].Entries) != 2 {
+		t.Fatalf("unexpected streams %+v", streams)
+	}
+	s := streams[0]
+	if s.Labels["service_name"] != "checkout" || s.Labels["env"] != "prod" {
+		t.Errorf("unexpected labels %v", s.Labels)
+	}
+	if !s.Entries[0].Timestamp.Equal(entryTime) || s.Entries[0].Line != "first" {
+		t.Errorf("unexpected entry %+v", s.Entries[0])
+	}
+	if s.Entries[1].StructuredMetadata["trace_id"] != "abc" {
+		t.Errorf("expected structured metadata, got %v", s.Entries[1].StructuredMetadata)
+	}
+}
+
+func TestDecodeProtobuf_Invalid(t *testing.T) {
+	if _, err := loki.DecodeProtobuf([]byte{0x0a, 0xff}); !errors.Is(err, loki.ErrInvalidPush) {
+		t.Errorf("expected ErrInvalidPush for truncated message, got %v", err)
+	}
+	if _, err := loki.DecodeProtobuf(encodePush(`not labels`)); !errors.Is(err, loki.ErrInvalidPush) {
+		t.Errorf("expected ErrInvalidPush for bad labels, got %v", err)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	body := `{"streams": [{
+		"stream": {"app": "web"},
+		"values": [
+			["1754489520123456789", "GET / 200"],
+			["1754489521000000000", "GET /login 500", {"level": "error"}]
+		]
+	}]}`
+
+	streams, err := loki.DecodeJSON([]byte(body))
+	if err != nil {
+		t.Fatalf("DecodeJSON returned error: %v", err)
+	}
+	if len(streams) != 1 || len(streams[0].Entries) != 2 {
+		t.Fatalf("unexpected streams %+v", streams)
+	}
+	if got := streams[0].Entries[0].Timestamp; !got.Equal(time.Unix(0, 1754489520123456789)) {
+		t.Errorf("unexpected timestamp %v", got)
+	}
+	if streams[0].Entries[1].StructuredMetadata["level"] != "error" {
+		t.Errorf("expected structured metadata, got %v", streams[0].Entries[1].StructuredMetadata)
+	}
+}
+
+func TestDecodeJSON_Invalid(t *testing.T) {
+	for _, body := range []string{
+		`{`,
+		`{"streams":[{"stream":{},"values":[["now","line"]]}]}`,
+		`{"streams":[{"stream":{},"values":[[1754489520,"line"]]}]}`,
+		`{"streams":[{"stream":{},"values":[["1754489520"]]}]}`,
+		`{"streams":[{"stream":{},"values":[["1754489520","line",{"n":1}]]}]}`,
+	} {
+		if _, err := loki.DecodeJSON([]byte(body)); !errors.Is(err, loki.ErrInvalidPush) {
+			t.Errorf("DecodeJSON(%s): expected ErrInvalidPush, got %v", body, err)
+		}
+	}
+}
+
+func TestStream_ToLogEntry(t *testing.T) {
+	s := loki.Stream{Labels: map[string]string{
+		"app":      "web",
+		"hostname": "node-1",
+		"level":    "warning",
+		"env":      "prod",
+	}}
+
+	entry := s.ToLogEntry(loki.Entry{
+		Timestamp:          entryTime,
+		Line:               "disk almost full\n",
+		StructuredMetadata: map[string]string{"trace_id": "abc"},
+	})
+
+	if entry.Service != "web" || entry.Host != "node-1" || entry.Level != "WARN" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Message != "disk almost full" {
+		t.Errorf("unexpected message %q", entry.Message)
+	}
+	if entry.Context["env"] != "prod" || entry.Context["trace_id"] != "abc" {
+		t.Errorf("unexpected context %v", entry.Context)
+	}
+	if _, ok := entry.Context["app"]; ok {
+		t.Error("expected service label not to be duplicated in context")
+	}
+	if err := entry.Validate(); err != nil {
+		t.Errorf("expected valid entry, got %v", err)
+	}
+}
+
+func TestStream_ToLogEntry_Defaults(t *testing.T) {
+	s := loki.Stream{Labels: map[string]string{"level": "verbose"}}
+
+	entry := s.ToLogEntry(loki.Entry{Line: "hello"})
+	if entry.Level != "INFO" {
+		t.Errorf("expected unknown level to default to INFO, got %q", entry.Level)
+	}
+	if entry.Context != nil {
+		t.Errorf("expected nil context, got %v", entry.Context)
+	}
+}
@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"crypto/x509"
+	"errors"
 	"net/http"
 
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
 	"github.com/rypi-dev/logger-server/internal/audit/audit"
 	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
 	"github.com/rypi-dev/logger-server/internal/utils/utils"
@@ -20,6 +23,8 @@ func ApiKeyMiddleware(validKey string, logger audit.LoggerInterface) func(http.H
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if !verifyAPIKey(r, validKey) {
 				audit.AuditEvent(logger, r, log_levels.LogLevelWarn, "Unauthorized access attempt (API key)", http.StatusUnauthorized, nil)
+				// Les navigateurs demandent alors la clé API (mot de passe Basic) pour l'interface web
+				w.Header().Set("WWW-Authenticate", `Basic realm="logger-server", charset="UTF-8"`)
 				utils.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
 				return
 			}
@@ -28,6 +33,101 @@ func ApiKeyMiddleware(validKey string, logger audit.LoggerInterface) func(http.H
 	}
 }
 
+// CertAuthenticator associe un certificat client vérifié à une clé (tlsauth.Mapper)
+type CertAuthenticator interface {
+	AuthenticateCert(cert *x509.Certificate) (*apikey.Key, error)
+}
+
+// Authenticators regroupe les modes d'authentification acceptés. Tokens valide les jetons
+// "Authorization: Bearer" (jwtauth.Verifier), Certs les certificats clients vérifiés par
+// TLS, Signatures les requêtes signées avec une clé; nil désactive le mode. Tenants
+// vérifie que le tenant d'une clé existe encore; nil refuse toute clé d'un tenant.
+type Authenticators struct {
+	Keys       apikey.Authenticator
+	Tokens     apikey.Authenticator
+	Certs      CertAuthenticator
+	Signatures *SignatureVerifier
+	Tenants    apikey.TenantChecker
+}
+
+// authenticate choisit le mode selon la requête : signature, jeton Bearer, puis clé API,
+// puis certificat client quand aucune clé n'est fournie
+func (a Authenticators) authenticate(r *http.Request) (key *apikey.Key, credential string, err error) {
+	if signed(r) {
+		if a.Signatures == nil {
+			return nil, "request signature", errors.New("request signing is not enabled")
+		}
+		key, err = a.Signatures.verifySignature(r)
+		return key, "request signature", err
+	}
+	if token := utils.GetBearerToken(r); token != "" {
+		if a.Tokens == nil {
+			return nil, "bearer token", errors.New("bearer tokens are not enabled")
+		}
+		key, err = a.Tokens.Authenticate(token)
+		return key, "bearer token", err
+	}
+	apiKey := utils.GetAPIKey(r)
+	if apiKey == "" && a.Certs != nil && utils.GetClientCertIdentity(r) != "" {
+		key, err = a.Certs.AuthenticateCert(r.TLS.VerifiedChains[0][0])
+		return key, "client certificate", err
+	}
+	key, err = a.Keys.Authenticate(apiKey)
+	return key, "API key", err
+}
+
+// ScopedApiKeyMiddleware authentifie la clé API auprès du trousseau (clé de démarrage puis
+// clés nommées) et vérifie qu'elle a le scope de la route (apikey.ScopeFor). La clé est
+// ajoutée au contexte : l'audit enregistre son nom et l'ingestion applique sa liaison au
+// service.
+func ScopedApiKeyMiddleware(keys apikey.Authenticator, logger audit.LoggerInterface) func(http.Handler) http.Handler {
+	return ScopedAuthMiddleware(Authenticators{Keys: keys}, logger)
+}
+
+// ScopedAuthMiddleware accepte en plus les jetons Bearer et les certificats clients, qui
+// reçoivent les mêmes scopes qu'une clé. Une clé d'un tenant n'accède qu'aux routes de
+// données (apikey.TenantAllowed).
+func ScopedAuthMiddleware(auth Authenticators, logger audit.LoggerInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, credential, err := auth.authenticate(r)
+			if err != nil {
+				audit.AuditEvent(logger, r, log_levels.LogLevelWarn, "Unauthorized access attempt ("+credential+")", http.StatusUnauthorized, map[string]interface{}{
+					"reason": err.Error(),
+				})
+				if credential == "bearer token" {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="logger-server", error="invalid_token"`)
+				} else {
+					// Les navigateurs demandent alors la clé API (mot de passe Basic) pour l'interface web
+					w.Header().Set("WWW-Authenticate", `Basic realm="logger-server", charset="UTF-8"`)
+				}
+				utils.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+
+			r = r.WithContext(apikey.WithKey(r.Context(), key))
+			if key.Tenant != "" && (auth.Tenants == nil || !auth.Tenants.Exists(key.Tenant)) {
+				audit.AuditEvent(logger, r, log_levels.LogLevelWarn, "Forbidden: "+credential+" of an unknown tenant", http.StatusForbidden, nil)
+				utils.WriteJSONError(w, http.StatusForbidden, apikey.ErrUnknownTenant.Error())
+				return
+			}
+			if !apikey.TenantAllowed(key.Tenant, r.URL.Path) {
+				audit.AuditEvent(logger, r, log_levels.LogLevelWarn, "Forbidden: "+credential+" of a tenant on a global route", http.StatusForbidden, nil)
+				utils.WriteJSONError(w, http.StatusForbidden, "route not available to tenant keys")
+				return
+			}
+			if scope := apikey.ScopeFor(r.Method, r.URL.Path); !key.Allows(scope) {
+				audit.AuditEvent(logger, r, log_levels.LogLevelWarn, "Forbidden: "+credential+" lacks the required scope", http.StatusForbidden, map[string]interface{}{
+					"required_scope": scope,
+				})
+				utils.WriteJSONError(w, http.StatusForbidden, credential+" lacks the '"+scope+"' scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // ApiKeyMiddlewareWithLevel combine clé API + niveau log
 func ApiKeyMiddlewareWithLevel(validKey string, minLevel log_levels.Level, logger audit.LoggerInterface) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
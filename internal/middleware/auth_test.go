@@ -1,10 +1,16 @@
 package middleware
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
 	"github.com/rypi-dev/logger-server/internal/audit/audit"
 	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
 )
@@ -114,9 +120,252 @@ func TestApiKeyMiddleware(t *testing.T) {
 		if !logger.called {
 			t.Fatal("logger should be called on missing key")
 		}
+		if rec.Header().Get("WWW-Authenticate") == "" {
+			t.Error("expected a WWW-Authenticate challenge for browsers")
+		}
+	})
+
+	t.Run("basic auth password on GET", func(t *testing.T) {
+		mw := ApiKeyMiddleware(validKey, &mockLogger{})
+		handlerCalled := false
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlerCalled = true
+		})
+
+		req := httptest.NewRequest("GET", "/ui/", nil)
+		req.SetBasicAuth("", validKey)
+		mw(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		if !handlerCalled {
+			t.Fatal("handler should be called with the API key as Basic password")
+		}
 	})
 }
 
+// staticKeys authentifie un jeu de clés fixe
+type staticKeys map[string]*apikey.Key
+
+func (s staticKeys) Authenticate(key string) (*apikey.Key, error) {
+	if k, ok := s[key]; ok {
+		return k, nil
+	}
+	return nil, apikey.ErrInvalidKey
+}
+
+func TestScopedApiKeyMiddleware(t *testing.T) {
+	keys := staticKeys{
+		"ingest-key": {Name: "agent", Scopes: []string{apikey.ScopeIngest}},
+		"read-key":   {Name: "oncall", Scopes: []string{apikey.ScopeRead}},
+		"admin-key":  {Name: "ops", Scopes: []string{apikey.ScopeAdmin}},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{"ingest key can write", "POST", "/log", "ingest-key", http.StatusOK},
+		{"ingest key cannot read", "GET", "/log", "ingest-key", http.StatusForbidden},
+		{"ingest key passes shipper probes", "GET", "/_cluster/health", "ingest-key", http.StatusOK},
+		{"read key can read", "GET", "/ui/api/search", "read-key", http.StatusOK},
+		{"read key cannot write", "POST", "/log/batch", "read-key", http.StatusForbidden},
+		{"read key cannot manage keys", "GET", "/admin/keys", "read-key", http.StatusForbidden},
+		{"admin key can do everything", "DELETE", "/admin/keys/1", "admin-key", http.StatusOK},
+		{"any key reaches health", "GET", "/health", "read-key", http.StatusOK},
+		{"unknown key", "GET", "/log", "nope", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &mockLogger{}
+			var got *apikey.Key
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = apikey.FromContext(r.Context())
+			})
+
+			req := newRequestWithHeaders(tt.method, tt.path, map[string]string{"X-API-Key": tt.key})
+			rec := httptest.NewRecorder()
+			ScopedApiKeyMiddleware(keys, logger)(handler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d got %d", tt.want, rec.Code)
+			}
+			switch tt.want {
+			case http.StatusOK:
+				if got == nil || got != keys[tt.key] {
+					t.Error("expected the authenticated key in the request context")
+				}
+			case http.StatusForbidden:
+				if logger.entry.Context["api_key"] != keys[tt.key].Name {
+					t.Errorf("expected audit entry for key %q, got %v", keys[tt.key].Name, logger.entry.Context["api_key"])
+				}
+			case http.StatusUnauthorized:
+				if !logger.called || rec.Header().Get("WWW-Authenticate") == "" {
+					t.Error("expected an audit entry and a WWW-Authenticate challenge")
+				}
+			}
+		})
+	}
+}
+
+func TestScopedAuthMiddleware_BearerTokens(t *testing.T) {
+	keys := staticKeys{"read-key": {Name: "oncall", Scopes: []string{apikey.ScopeRead}}}
+	tokens := staticKeys{"alice-token": {Name: "alice", Scopes: []string{apikey.ScopeRead}}}
+
+	tests := []struct {
+		name    string
+		tokens  apikey.Authenticator
+		headers map[string]string
+		want    int
+		user    string
+	}{
+		{"valid token", tokens, map[string]string{"Authorization": "Bearer alice-token"}, http.StatusOK, "alice"},
+		{"invalid token", tokens, map[string]string{"Authorization": "Bearer forged"}, http.StatusUnauthorized, ""},
+		{"tokens disabled", nil, map[string]string{"Authorization": "Bearer alice-token"}, http.StatusUnauthorized, ""},
+		{"API keys still accepted", tokens, map[string]string{"X-API-Key": "read-key"}, http.StatusOK, "oncall"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &mockLogger{}
+			var got *apikey.Key
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = apikey.FromContext(r.Context())
+			})
+
+			rec := httptest.NewRecorder()
+			ScopedAuthMiddleware(Authenticators{Keys: keys, Tokens: tt.tokens}, logger)(handler).ServeHTTP(rec, newRequestWithHeaders("GET", "/log", tt.headers))
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d got %d", tt.want, rec.Code)
+			}
+			if tt.want == http.StatusOK && (got == nil || got.Name != tt.user) {
+				t.Errorf("expected %q in the request context, got %+v", tt.user, got)
+			}
+			if tt.want == http.StatusUnauthorized {
+				if !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer ") {
+					t.Errorf("expected a Bearer challenge, got %q", rec.Header().Get("WWW-Authenticate"))
+				}
+				if logger.entry.Message != "Unauthorized access attempt (bearer token)" {
+					t.Errorf("unexpected audit message %q", logger.entry.Message)
+				}
+			}
+		})
+	}
+
+	// Un jeton sans le scope de la route est refusé comme une clé
+	rec := httptest.NewRecorder()
+	ScopedAuthMiddleware(Authenticators{Keys: keys, Tokens: tokens}, &mockLogger{})(http.NotFoundHandler()).ServeHTTP(rec,
+		newRequestWithHeaders("POST", "/log", map[string]string{"Authorization": "Bearer alice-token"}))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a read-only token on ingestion, got %d", rec.Code)
+	}
+}
+
+// certKeys donne une clé aux certificats dont le Common Name est connu
+type certKeys map[string]*apikey.Key
+
+func (c certKeys) AuthenticateCert(cert *x509.Certificate) (*apikey.Key, error) {
+	if k, ok := c[cert.Subject.CommonName]; ok {
+		return k, nil
+	}
+	return nil, errors.New("unknown certificate")
+}
+
+func withClientCert(r *http.Request, cn string) *http.Request {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return r
+}
+
+func TestScopedAuthMiddleware_ClientCertificates(t *testing.T) {
+	auth := Authenticators{
+		Keys:  staticKeys{"read-key": {Name: "oncall", Scopes: []string{apikey.ScopeRead}}},
+		Certs: certKeys{"billing-agent": {Name: "billing-agent", Scopes: []string{apikey.ScopeIngest}}},
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+		user string
+	}{
+		{"known certificate", withClientCert(newRequestWithHeaders("POST", "/log", nil), "billing-agent"), http.StatusOK, "billing-agent"},
+		{"certificate scopes apply", withClientCert(newRequestWithHeaders("GET", "/log", nil), "billing-agent"), http.StatusForbidden, ""},
+		{"unknown certificate", withClientCert(newRequestWithHeaders("POST", "/log", nil), "laptop"), http.StatusUnauthorized, ""},
+		{"API key takes precedence", withClientCert(newRequestWithHeaders("GET", "/log", map[string]string{"X-API-Key": "read-key"}), "billing-agent"), http.StatusOK, "oncall"},
+		{"no certificate", newRequestWithHeaders("POST", "/log", nil), http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &mockLogger{}
+			var got *apikey.Key
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = apikey.FromContext(r.Context())
+			})
+
+			rec := httptest.NewRecorder()
+			ScopedAuthMiddleware(auth, logger)(handler).ServeHTTP(rec, tt.req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d got %d", tt.want, rec.Code)
+			}
+			if tt.want == http.StatusOK && (got == nil || got.Name != tt.user) {
+				t.Errorf("expected %q in the request context, got %+v", tt.user, got)
+			}
+			if tt.name == "unknown certificate" && logger.entry.Context["client_cert"] != "laptop" {
+				t.Errorf("expected the certificate identity in the audit entry, got %v", logger.entry.Context["client_cert"])
+			}
+		})
+	}
+}
+
+type knownTenants map[string]bool
+
+func (k knownTenants) Exists(name string) bool { return k[name] }
+
+func TestScopedAuthMiddleware_Tenants(t *testing.T) {
+	keys := staticKeys{
+		"acme-key":    {Name: "acme-ops", Scopes: []string{apikey.ScopeAdmin}, Tenant: "acme"},
+		"deleted-key": {Name: "old", Scopes: []string{apikey.ScopeRead}, Tenant: "deleted"},
+	}
+
+	tests := []struct {
+		name    string
+		tenants apikey.TenantChecker
+		method  string
+		path    string
+		key     string
+		want    int
+	}{
+		{"tenant key reads its logs", knownTenants{"acme": true}, "GET", "/log", "acme-key", http.StatusOK},
+		{"tenant key writes its logs", knownTenants{"acme": true}, "POST", "/log/batch", "acme-key", http.StatusOK},
+		{"tenant admin cannot manage keys", knownTenants{"acme": true}, "GET", "/admin/keys", "acme-key", http.StatusForbidden},
+		{"tenant key cannot read anomalies", knownTenants{"acme": true}, "GET", "/anomalies", "acme-key", http.StatusForbidden},
+		{"deleted tenant", knownTenants{"acme": true}, "GET", "/log", "deleted-key", http.StatusForbidden},
+		{"tenants disabled", nil, "GET", "/log", "acme-key", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &mockLogger{}
+			auth := Authenticators{Keys: keys, Tenants: tt.tenants}
+			req := newRequestWithHeaders(tt.method, tt.path, map[string]string{"X-API-Key": tt.key})
+			rec := httptest.NewRecorder()
+			ScopedAuthMiddleware(auth, logger)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d got %d", tt.want, rec.Code)
+			}
+			if tt.want == http.StatusForbidden && !logger.called {
+				t.Error("expected the refusal to be audited")
+			}
+		})
+	}
+}
+
 func TestApiKeyMiddlewareWithLevel(t *testing.T) {
 	const validKey = "secret123"
 	minLevel := log_levels.LogLevelWarn
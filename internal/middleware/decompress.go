@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// MaxDecompressedBodySize borne tout corps décompressé, avant la limite propre à chaque route
+const MaxDecompressedBodySize = 16 << 20
+
+// En-tête du format snappy "framed"; sans lui, le corps est un bloc snappy unique
+var snappyStreamHeader = []byte("\xff\x06\x00\x00sNaPpY")
+
+var errUnsupportedEncoding = errors.New("unsupported Content-Encoding")
+
+// anonymousClient étiquette les requêtes sans clé API authentifiée
+const anonymousClient = "anonymous"
+
+var (
+	compressedBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_request_compressed_bytes_total",
+		Help: "Total number of compressed request body bytes read, by encoding and API key name",
+	}, []string{"encoding", "client"})
+	decompressedBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_request_decompressed_bytes_total",
+		Help: "Total number of request body bytes after decompression, by encoding and API key name",
+	}, []string{"encoding", "client"})
+)
+
+func init() {
+	prometheus.MustRegister(compressedBytesTotal, decompressedBytesTotal)
+}
+
+// DecompressBody décode les corps envoyés avec Content-Encoding gzip, deflate, zstd ou snappy.
+// Les routes voient un corps en clair : leur http.MaxBytesReader porte donc sur la taille
+// décompressée, et maxSize borne en plus toute requête (protection contre les zip bombs).
+// Le ratio de compression par encodage et par client se calcule à partir des deux compteurs
+// d'octets. Le client est le nom de la clé API authentifiée (ou "anonymous"), en nombre borné
+// contrairement aux adresses IP : l'authentification doit précéder ce middleware.
+func DecompressBody(maxSize int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			compressed := &countingReader{r: r.Body}
+			body, err := newDecompressor(encoding, compressed, maxSize)
+			if err != nil {
+				if errors.Is(err, errUnsupportedEncoding) {
+					utils.WriteJSONError(w, http.StatusUnsupportedMediaType, err.Error())
+					return
+				}
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					utils.WriteJSONError(w, http.StatusRequestEntityTooLarge, "request body too large")
+					return
+				}
+				utils.WriteJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s body", encoding))
+				return
+			}
+			defer body.Close()
+
+			decompressed := &countingReader{r: http.MaxBytesReader(w, body, maxSize)}
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{decompressed, r.Body}
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+
+			next.ServeHTTP(w, r)
+
+			client := anonymousClient
+			if key := apikey.FromContext(r.Context()); key != nil {
+				client = key.Name
+			}
+			compressedBytesTotal.WithLabelValues(encoding, client).Add(float64(compressed.n))
+			decompressedBytesTotal.WithLabelValues(encoding, client).Add(float64(decompressed.n))
+		})
+	}
+}
+
+func newDecompressor(encoding string, body io.Reader, maxSize int64) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		// "deflate" désigne du zlib (RFC 9110), mais certains clients envoient du deflate brut
+		br := bufio.NewReader(body)
+		header, err := br.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case "zstd":
+		dec, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(maxSize)), zstd.WithDecoderMaxWindow(uint64(maxSize)))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdReader{dec: dec, limit: maxSize}, nil
+	case "snappy":
+		br := bufio.NewReader(body)
+		if header, err := br.Peek(len(snappyStreamHeader)); err == nil && bytes.Equal(header, snappyStreamHeader) {
+			return io.NopCloser(snappy.NewReader(br)), nil
+		}
+		return decodeSnappyBlock(br, maxSize)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedEncoding, encoding)
+	}
+}
+
+// decodeSnappyBlock décode un bloc snappy unique (Prometheus remote write, ...). La taille
+// décodée figure en tête du bloc : elle est vérifiée avant d'allouer.
+func decodeSnappyBlock(r io.Reader, maxSize int64) (io.ReadCloser, error) {
+	compressed, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(compressed)) > maxSize {
+		return nil, &http.MaxBytesError{Limit: maxSize}
+	}
+
+	n, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, err
+	}
+	if int64(n) > maxSize {
+		return nil, &http.MaxBytesError{Limit: maxSize}
+	}
+
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// zstdReader signale un dépassement des limites mémoire du décodeur comme une limite de taille
+type zstdReader struct {
+	dec   *zstd.Decoder
+	limit int64
+}
+
+func (z *zstdReader) Read(p []byte) (int, error) {
+	n, err := z.dec.Read(p)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		return n, &http.MaxBytesError{Limit: z.limit}
+	}
+	return n, err
+}
+
+func (z *zstdReader) Close() error {
+	z.dec.Close()
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
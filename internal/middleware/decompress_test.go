@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+)
+
+const payload = `{"level":"INFO","message":"compressed hello"}`
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "zstd":
+		w, _ = zstd.NewWriter(&buf)
+	case "snappy":
+		w = snappy.NewBufferedWriter(&buf)
+	case "snappy-block":
+		return snappy.Encode(nil, data)
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// echoBody renvoie le corps lu par la route, ou 413 si la limite est atteinte
+func echoBody(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if encoding := r.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+			t.Error("expected Content-Encoding to be removed")
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	})
+}
+
+func doCompressed(t *testing.T, maxSize int64, encoding string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	return doCompressedAs(t, nil, maxSize, encoding, body)
+}
+
+// doCompressedAs envoie la requête comme authentifiée par key (nil : anonyme)
+func doCompressedAs(t *testing.T, key *apikey.Key, maxSize int64, encoding string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/log", bytes.NewReader(body))
+	if key != nil {
+		req = req.WithContext(apikey.WithKey(req.Context(), key))
+	}
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	rr := httptest.NewRecorder()
+	DecompressBody(maxSize)(echoBody(t)).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestDecompressBody_Encodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		compress string
+	}{
+		{"gzip", "gzip", "gzip"},
+		{"x-gzip", "x-gzip", "gzip"},
+		{"deflate zlib", "deflate", "deflate"},
+		{"deflate raw", "deflate", "raw-deflate"},
+		{"zstd", "zstd", "zstd"},
+		{"snappy framed", "snappy", "snappy"},
+		{"snappy block", "snappy", "snappy-block"},
+		{"uppercase", "GZIP", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doCompressed(t, MaxDecompressedBodySize, tt.header, compress(t, tt.compress, []byte(payload)))
+			if rr.Code != http.StatusOK || rr.Body.String() != payload {
+				t.Errorf("expected decompressed payload, got %d %q", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestDecompressBody_Passthrough(t *testing.T) {
+	for _, encoding := range []string{"", "identity"} {
+		rr := doCompressed(t, MaxDecompressedBodySize, encoding, []byte(payload))
+		if rr.Body.String() != payload {
+			t.Errorf("encoding %q: expected body unchanged, got %q", encoding, rr.Body.String())
+		}
+	}
+}
+
+func TestDecompressBody_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		encoding   string
+		body       []byte
+		wantStatus int
+	}{
+		{"unsupported", "br", []byte(payload), http.StatusUnsupportedMediaType},
+		{"invalid gzip", "gzip", []byte("not gzip"), http.StatusBadRequest},
+		{"invalid snappy block", "snappy", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := doCompressed(t, MaxDecompressedBodySize, tt.encoding, tt.body); rr.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestDecompressBody_LimitAppliesToDecompressedSize(t *testing.T) {
+	// 1 Mo de zéros se compresse en quelques Ko : la limite doit porter sur le flux décompressé
+	bomb := []byte(strings.Repeat("\x00", 1<<20))
+
+	for _, encoding := range []string{"gzip", "deflate", "zstd", "snappy", "snappy-block"} {
+		t.Run(encoding, func(t *testing.T) {
+			body := compress(t, encoding, bomb)
+			if len(body) > 64<<10 {
+				t.Fatalf("test payload not compressed enough: %d bytes", len(body))
+			}
+			header := encoding
+			if encoding == "snappy-block" {
+				header = "snappy"
+			}
+			if rr := doCompressed(t, 64<<10, header, body); rr.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("expected 413, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestDecompressBody_Metrics(t *testing.T) {
+	body := compress(t, "gzip", []byte(strings.Repeat(payload, 10)))
+
+	// Une série par nom de clé, les requêtes non authentifiées partageant "anonymous"
+	for _, tt := range []struct {
+		key    *apikey.Key
+		client string
+	}{
+		{nil, "anonymous"},
+		{&apikey.Key{Name: "fluent-bit-web"}, "fluent-bit-web"},
+	} {
+		compressedBefore := testutil.ToFloat64(compressedBytesTotal.WithLabelValues("gzip", tt.client))
+		decompressedBefore := testutil.ToFloat64(decompressedBytesTotal.WithLabelValues("gzip", tt.client))
+
+		doCompressedAs(t, tt.key, MaxDecompressedBodySize, "gzip", body)
+
+		if got := testutil.ToFloat64(compressedBytesTotal.WithLabelValues("gzip", tt.client)) - compressedBefore; got != float64(len(body)) {
+			t.Errorf("%s: expected %d compressed bytes, got %v", tt.client, len(body), got)
+		}
+		if got := testutil.ToFloat64(decompressedBytesTotal.WithLabelValues("gzip", tt.client)) - decompressedBefore; got != float64(10*len(payload)) {
+			t.Errorf("%s: expected %d decompressed bytes, got %v", tt.client, 10*len(payload), got)
+		}
+	}
+}
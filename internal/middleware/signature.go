@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+)
+
+const (
+	// DefaultSignatureSkew est l'écart toléré entre l'horodatage signé et l'horloge du serveur
+	DefaultSignatureSkew = 5 * time.Minute
+
+	// MaxSignedBodySize borne le corps lu pour vérifier l'empreinte (corps compressé)
+	MaxSignedBodySize = 16 << 20
+
+	// Nonces mémorisés au plus; au-delà, les requêtes signées sont refusées plutôt que
+	// d'oublier des nonces encore rejouables
+	maxNonces = 1 << 20
+
+	maxNonceLength = 128
+
+	// Fréquence de purge des nonces expirés
+	noncePurgeInterval = time.Minute
+)
+
+var (
+	errSignatureHeaders = errors.New("missing signature headers")
+	errSignatureExpired = errors.New("signature timestamp outside the allowed clock skew")
+	errReplayedNonce    = errors.New("nonce already used")
+	errNonceCacheFull   = errors.New("too many signed requests in the clock skew window")
+)
+
+// SignatureVerifier authentifie les requêtes signées (voir apikey.StringToSign) : la
+// signature doit être valide pour une clé active, l'horodatage à moins de skew de
+// l'horloge du serveur, et le nonce jamais vu pour cette clé pendant cette fenêtre.
+type SignatureVerifier struct {
+	keys   apikey.SignatureAuthenticator
+	skew   time.Duration
+	now    func() time.Time
+	nonces *nonceCache
+}
+
+func NewSignatureVerifier(keys apikey.SignatureAuthenticator, skew time.Duration) *SignatureVerifier {
+	if skew <= 0 {
+		skew = DefaultSignatureSkew
+	}
+	return &SignatureVerifier{
+		keys:   keys,
+		skew:   skew,
+		now:    time.Now,
+		nonces: newNonceCache(maxNonces),
+	}
+}
+
+// signed indique si la requête porte une signature
+func signed(r *http.Request) bool {
+	return r.Header.Get(apikey.HeaderSignature) != ""
+}
+
+// verifySignature vérifie la signature de la requête et retourne sa clé. Le corps est lu
+// puis remis en place pour les handlers.
+func (v *SignatureVerifier) verifySignature(r *http.Request) (*apikey.Key, error) {
+	prefix := r.Header.Get(apikey.HeaderSignatureKey)
+	timestamp := r.Header.Get(apikey.HeaderSignatureTimestamp)
+	nonce := r.Header.Get(apikey.HeaderSignatureNonce)
+	signature := r.Header.Get(apikey.HeaderSignature)
+	if prefix == "" || timestamp == "" || nonce == "" || len(nonce) > maxNonceLength {
+		return nil, errSignatureHeaders
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature timestamp: %w", err)
+	}
+	signedAt := time.Unix(sec, 0)
+	now := v.now()
+	if signedAt.Before(now.Add(-v.skew)) || signedAt.After(now.Add(v.skew)) {
+		return nil, errSignatureExpired
+	}
+
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err = io.ReadAll(io.LimitReader(r.Body, MaxSignedBodySize+1))
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > MaxSignedBodySize {
+			return nil, errors.New("request body too large to verify")
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	key, err := v.keys.AuthenticateSignature(prefix, apikey.StringToSign(r.Method, r.URL.RequestURI(), timestamp, nonce, body), signature)
+	if err != nil {
+		return nil, err
+	}
+	// Le nonce n'est retenu qu'une fois la signature vérifiée : un client sans la clé ne
+	// peut pas remplir le cache. Il reste rejouable tant que l'horodatage est accepté.
+	if err := v.nonces.add(prefix+"\n"+nonce, signedAt.Add(v.skew), now); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// nonceCache mémorise les nonces jusqu'à la fin de leur fenêtre de validité
+type nonceCache struct {
+	mu       sync.Mutex
+	max      int
+	expires  map[string]time.Time
+	purgedAt time.Time
+}
+
+func newNonceCache(max int) *nonceCache {
+	return &nonceCache{max: max, expires: make(map[string]time.Time)}
+}
+
+// add retient le nonce jusqu'à expiresAt, ou échoue si le nonce est déjà connu ou si le
+// cache est plein
+func (c *nonceCache) add(nonce string, expiresAt, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if exp, ok := c.expires[nonce]; ok && now.Before(exp) {
+		return errReplayedNonce
+	}
+	if len(c.expires) >= c.max || now.Sub(c.purgedAt) >= noncePurgeInterval {
+		for n, exp := range c.expires {
+			if !now.Before(exp) {
+				delete(c.expires, n)
+			}
+		}
+		c.purgedAt = now
+		if len(c.expires) >= c.max {
+			return errNonceCacheFull
+		}
+	}
+	c.expires[nonce] = expiresAt
+	return nil
+}
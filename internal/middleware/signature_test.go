@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+)
+
+const testSigningKey = "lsk_agent-secret"
+
+// signingKeys accepte les signatures faites avec testSigningKey, de préfixe "lsk_agent"
+type signingKeys struct{ key *apikey.Key }
+
+func (s signingKeys) AuthenticateSignature(prefix, stringToSign, signature string) (*apikey.Key, error) {
+	if prefix != "lsk_agent" || !apikey.VerifySignature(apikey.SigningSecret(testSigningKey), stringToSign, signature) {
+		return nil, apikey.ErrInvalidKey
+	}
+	return s.key, nil
+}
+
+func signedRequest(method, target, body string, at time.Time, nonce string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	ts := strconv.FormatInt(at.Unix(), 10)
+	r.Header.Set(apikey.HeaderSignatureKey, "lsk_agent")
+	r.Header.Set(apikey.HeaderSignatureTimestamp, ts)
+	r.Header.Set(apikey.HeaderSignatureNonce, nonce)
+	r.Header.Set(apikey.HeaderSignature, apikey.Sign(apikey.SigningSecret(testSigningKey),
+		apikey.StringToSign(method, r.URL.RequestURI(), ts, nonce, []byte(body))))
+	return r
+}
+
+func newTestVerifier(now time.Time) *SignatureVerifier {
+	v := NewSignatureVerifier(signingKeys{&apikey.Key{Name: "agent", Scopes: []string{apikey.ScopeIngest}}}, time.Minute)
+	v.now = func() time.Time { return now }
+	return v
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	now := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		req     func() *http.Request
+		wantErr error
+	}{
+		{"valid", func() *http.Request { return signedRequest("POST", "/log/batch?x=1", `[{"message":"a"}]`, now, "n1") }, nil},
+		{"clock skew tolerated", func() *http.Request { return signedRequest("POST", "/log", "{}", now.Add(50*time.Second), "n1") }, nil},
+		{"too old", func() *http.Request { return signedRequest("POST", "/log", "{}", now.Add(-2*time.Minute), "n1") }, errSignatureExpired},
+		{"in the future", func() *http.Request { return signedRequest("POST", "/log", "{}", now.Add(2*time.Minute), "n1") }, errSignatureExpired},
+		{"body changed", func() *http.Request {
+			r := signedRequest("POST", "/log", "{}", now, "n1")
+			r.Body = io.NopCloser(strings.NewReader(`{"level":"ERROR"}`))
+			return r
+		}, apikey.ErrInvalidKey},
+		{"path changed", func() *http.Request {
+			r := signedRequest("POST", "/log", "{}", now, "n1")
+			r.URL.Path = "/admin/keys"
+			return r
+		}, apikey.ErrInvalidKey},
+		{"missing nonce", func() *http.Request {
+			r := signedRequest("POST", "/log", "{}", now, "n1")
+			r.Header.Del(apikey.HeaderSignatureNonce)
+			return r
+		}, errSignatureHeaders},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.req()
+			key, err := newTestVerifier(now).verifySignature(r)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || key.Name != "agent" {
+				t.Fatalf("expected agent key, got %+v, %v", key, err)
+			}
+			// Le corps reste lisible par le handler
+			if body, _ := io.ReadAll(r.Body); len(body) == 0 {
+				t.Error("expected request body to be restored")
+			}
+		})
+	}
+}
+
+func TestSignatureVerifier_Replay(t *testing.T) {
+	now := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	v := newTestVerifier(now)
+
+	if _, err := v.verifySignature(signedRequest("POST", "/log", "{}", now, "n1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.verifySignature(signedRequest("POST", "/log", "{}", now, "n1")); !errors.Is(err, errReplayedNonce) {
+		t.Errorf("expected replay to be rejected, got %v", err)
+	}
+	if _, err := v.verifySignature(signedRequest("POST", "/log", "{}", now, "n2")); err != nil {
+		t.Errorf("expected a new nonce to be accepted, got %v", err)
+	}
+
+	// Une signature invalide ne consomme pas le nonce
+	forged := signedRequest("POST", "/log", "{}", now, "n3")
+	forged.Header.Set(apikey.HeaderSignature, strings.Repeat("0", 64))
+	if _, err := v.verifySignature(forged); !errors.Is(err, apikey.ErrInvalidKey) {
+		t.Errorf("expected forged signature to be rejected, got %v", err)
+	}
+	if _, err := v.verifySignature(signedRequest("POST", "/log", "{}", now, "n3")); err != nil {
+		t.Errorf("expected nonce of a forged request to stay usable, got %v", err)
+	}
+}
+
+func TestNonceCache(t *testing.T) {
+	now := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	c := newNonceCache(2)
+
+	if err := c.add("a", now.Add(time.Minute), now); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.add("b", now.Add(2*time.Minute), now); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.add("c", now.Add(time.Minute), now); !errors.Is(err, errNonceCacheFull) {
+		t.Errorf("expected full cache to refuse new nonces, got %v", err)
+	}
+
+	// "a" expiré : sa place est libérée et il peut être réutilisé
+	later := now.Add(time.Minute)
+	if err := c.add("a", later.Add(time.Minute), later); err != nil {
+		t.Errorf("expected expired nonce to be accepted again, got %v", err)
+	}
+	if err := c.add("b", later.Add(time.Minute), later); !errors.Is(err, errReplayedNonce) {
+		t.Errorf("expected live nonce to be refused, got %v", err)
+	}
+}
+
+func TestScopedAuthMiddleware_SignedRequests(t *testing.T) {
+	now := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	auth := Authenticators{Keys: staticKeys{}, Signatures: newTestVerifier(now)}
+
+	var got *apikey.Key
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = apikey.FromContext(r.Context())
+	})
+
+	rec := httptest.NewRecorder()
+	ScopedAuthMiddleware(auth, &mockLogger{})(handler).ServeHTTP(rec, signedRequest("POST", "/log", "{}", now, "n1"))
+	if rec.Code != http.StatusOK || got == nil || got.Name != "agent" {
+		t.Fatalf("expected signed request to be accepted as agent, got %d, %+v", rec.Code, got)
+	}
+
+	logger := &mockLogger{}
+	rec = httptest.NewRecorder()
+	ScopedAuthMiddleware(auth, logger)(handler).ServeHTTP(rec, signedRequest("POST", "/log", "{}", now, "n1"))
+	if rec.Code != http.StatusUnauthorized || logger.entry.Message != "Unauthorized access attempt (request signature)" {
+		t.Errorf("expected replay to be audited and refused, got %d %q", rec.Code, logger.entry.Message)
+	}
+
+	rec = httptest.NewRecorder()
+	ScopedAuthMiddleware(Authenticators{Keys: staticKeys{}}, &mockLogger{})(handler).ServeHTTP(rec, signedRequest("POST", "/log", "{}", now, "n2"))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 when signing is disabled, got %d", rec.Code)
+	}
+}
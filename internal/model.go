@@ -14,29 +14,49 @@ import (
 //
 // example:
 // {
+//   "id": "evt-7f3a",
 //   "level": "INFO",
 //   "message": "User logged in",
 //   "timestamp": "2025-08-06T14:12:00Z",
+//   "service": "billing",
+//   "host": "web-1",
 //   "context": {"user_id": 42}
 // }
 type LogEntry struct {
+	ID        string                 `json:"id,omitempty" example:"evt-7f3a"`               // Identifiant client pour l'idempotence
 	Level     string                 `json:"level" example:"INFO"`                           // Niveau de log
 	Message   string                 `json:"message" example:"User logged in"`               // Message de log
+	Service   string                 `json:"service,omitempty" example:"billing"`            // Service émetteur
+	Host      string                 `json:"host,omitempty" example:"web-1"`                 // Machine émettrice
 	Timestamp time.Time              `json:"timestamp" example:"2025-08-06T14:12:00Z"`       // Timestamp RFC3339
 	Context   map[string]interface{} `json:"context,omitempty" example:"{\"user_id\": 42}"` // Données additionnelles
+
+	// Tenant propriétaire de l'entrée, fixé par le serveur d'après la clé (apikey.Bind) et
+	// jamais lu dans le corps des requêtes; vide pour le tenant par défaut
+	Tenant string `json:"-"`
+	// Nom de la clé API ayant écrit l'entrée (apikey.Bind), pour les quotas; vide pour les
+	// entrées non authentifiées (syslog, ...)
+	KeyName string `json:"-"`
 }
 
 type ctxKey string
 
+// IngestFunc fait passer une entrée par le chemin commun de validation et de stockage.
+// Les entrées réseau (syslog, ...) reçoivent Handler.Ingest sous cette forme.
+type IngestFunc func(entry *LogEntry) error
+
+// LoggerInterface stocke les entrées. QueryLogs ne lit que les entrées du tenant donné
+// ("" : tenant par défaut) : il n'existe pas de lecture tous tenants confondus.
 type LoggerInterface interface {
 	Write(entry LogEntry) error
-	QueryLogs(level log_levels.LogLevel, page, limit int) ([]LogEntry, error)
+	QueryLogs(tenant string, level log_levels.LogLevel, page, limit int) ([]LogEntry, error)
 }
 
 const (
 	MaxMessageLength 	= 1024
 	MaxContextSizeBytes = 2048
 	MaxContextKeys   	= 10
+	MaxIDLength      	= 128
 	DefaultLogLevel  	= "INFO"
 	ctxKeyTraceID   ctxKey = "traceID"
 	ctxKeyUserAgent ctxKey = "userAgent"
@@ -48,6 +68,7 @@ var (
     ErrMessageTooLong  = errors.New("message too long")
     ErrContextTooLarge = errors.New("context too large")
     ErrLevelRequired   = errors.New("level is required")
+    ErrIDTooLong       = errors.New("id too long")
 )
 
 // Validate vérifie que l'entrée de log respecte les contraintes de format.
@@ -58,6 +79,9 @@ func (e *LogEntry) Validate() error {
 	if len(e.Message) > MaxMessageLength {
 		return ErrMessageTooLong
 	}
+	if len(e.ID) > MaxIDLength {
+		return ErrIDTooLong
+	}
 	if e.Context != nil {
 		if len(e.Context) > MaxContextKeys {
 			return ErrContextTooLarge
@@ -53,6 +53,15 @@ func TestLogEntry_Validate(t *testing.T) {
 		}
 	})
 
+	t.Run("id too long", func(t *testing.T) {
+		e := baseEntry()
+		e.ID = strings.Repeat("x", internal.MaxIDLength+1)
+		err := e.Validate()
+		if !errors.Is(err, internal.ErrIDTooLong) {
+			t.Errorf("expected ErrIDTooLong, got %v", err)
+		}
+	})
+
 	t.Run("context too many keys", func(t *testing.T) {
 		e := baseEntry()
 		e.Context = make(map[string]interface{})
@@ -0,0 +1,65 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	SpecPath = "/openapi.json"
+	DocsPath = "/docs"
+)
+
+// Version de Swagger UI chargée par la page de documentation
+const swaggerUIVersion = "5.17.14"
+
+// La page charge Swagger UI depuis un CDN : le serveur n'embarque que la spécification.
+// Les requêtes "Try it out" portent la clé saisie dans "Authorize" (X-API-Key).
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>logger-server API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "` + SpecPath + `", dom_id: "#swagger-ui", persistAuthorization: true });
+  </script>
+</body>
+</html>
+`
+
+// API sert la spécification OpenAPI et sa page Swagger UI
+type API struct {
+	spec []byte
+}
+
+// NewAPI encode le document une fois pour toutes
+func NewAPI(doc *Document) (*API, error) {
+	spec, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &API{spec: spec}, nil
+}
+
+// Register ajoute GET /openapi.json et GET /docs au routeur
+func (a *API) Register(r *mux.Router) {
+	r.HandleFunc(SpecPath, a.handleSpec).Methods("GET")
+	r.HandleFunc(DocsPath, a.handleDocs).Methods("GET")
+}
+
+func (a *API) handleSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(a.spec)
+}
+
+func (a *API) handleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(docsPage))
+}
@@ -0,0 +1,44 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/openapi"
+)
+
+func TestAPI_ServesSpecAndDocs(t *testing.T) {
+	api, err := openapi.NewAPI(openapi.Spec())
+	if err != nil {
+		t.Fatalf("NewAPI failed: %v", err)
+	}
+	r := mux.NewRouter()
+	api.Register(r)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected JSON spec, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	var doc map[string]interface{}
+	if err := json.NewDecoder(rr.Body).Decode(&doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc["openapi"] != openapi.Version {
+		t.Errorf("expected openapi %s, got %v", openapi.Version, doc["openapi"])
+	}
+	if _, ok := doc["paths"].(map[string]interface{})["/log/batch"]; !ok {
+		t.Error("expected /log/batch in the served spec")
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/docs", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `url: "/openapi.json"`) {
+		t.Errorf("expected Swagger UI page pointing at the spec, got %d", rr.Code)
+	}
+}
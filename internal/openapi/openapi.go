@@ -0,0 +1,243 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version de la spécification produite
+const Version = "3.0.3"
+
+// Document est un document OpenAPI 3, limité aux champs utilisés par ce serveur
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem associe une méthode en minuscules ("get", "post", ...) à son opération
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema  *Schema     `json:"schema,omitempty"`
+	Example interface{} `json:"example,omitempty"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // bool ou *Schema
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MaxProperties        *int               `json:"maxProperties,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Example              interface{}        `json:"example,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	Parameters      map[string]*Parameter      `json:"parameters,omitempty"`
+	Responses       map[string]*Response       `json:"responses,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// SecurityRequirement associe un schéma de sécurité à ses scopes
+type SecurityRequirement map[string][]string
+
+// Operation retourne l'opération d'une route, ou nil si elle n'est pas décrite
+func (d *Document) Operation(path, method string) *Operation {
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+// Add décrit une route; les méthodes sont celles passées à mux (.Methods("GET", ...))
+func (d *Document) Add(path string, op *Operation, methods ...string) {
+	if d.Paths == nil {
+		d.Paths = make(map[string]PathItem)
+	}
+	item := d.Paths[path]
+	if item == nil {
+		item = make(PathItem)
+		d.Paths[path] = item
+	}
+	for i, method := range methods {
+		o := op
+		if i > 0 {
+			// Une opération par méthode : les operationId doivent être uniques
+			copied := *op
+			copied.OperationID = op.OperationID + method[:1] + strings.ToLower(method[1:])
+			o = &copied
+		}
+		item[strings.ToLower(method)] = o
+	}
+}
+
+// Schema retourne une référence au schéma du type de v, généré à partir de ses tags
+// json et example et enregistré dans les composants sous le nom du type
+func (d *Document) Schema(v interface{}) *Schema {
+	return d.schemaFor(reflect.TypeOf(v))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (d *Document) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := d.Components.Schemas[t.Name()]; !ok {
+			if d.Components.Schemas == nil {
+				d.Components.Schemas = make(map[string]*Schema)
+			}
+			// Réservé avant la génération pour les types récursifs
+			d.Components.Schemas[t.Name()] = &Schema{}
+			*d.Components.Schemas[t.Name()] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Struct:
+		return d.structSchema(t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaFor(t.Elem())}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &Schema{Type: "object", AdditionalProperties: true}
+		}
+		return &Schema{Type: "object", AdditionalProperties: d.schemaFor(t.Elem())}
+	}
+	// interface{} : toute valeur JSON
+	return &Schema{}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Champs d'une struct embarquée sans nom JSON : promus comme par encoding/json
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded := d.structSchema(f.Type)
+			for prop, ps := range embedded.Properties {
+				s.Properties[prop] = ps
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		prop := d.schemaFor(f.Type)
+		if example, ok := f.Tag.Lookup("example"); ok {
+			prop.Example = parseExample(example, f.Type)
+		}
+		s.Properties[name] = prop
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// parseExample convertit la valeur d'un tag example dans le type JSON du champ
+func parseExample(raw string, t reflect.Type) interface{} {
+	if t.Kind() == reflect.String || t == timeType {
+		return raw
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return raw
+	}
+	return v
+}
@@ -0,0 +1,95 @@
+package openapi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/openapi"
+)
+
+type base struct {
+	ID int64 `json:"id"`
+}
+
+type sample struct {
+	base
+	Name     string                 `json:"name" example:"web-1"`
+	Count    int                    `json:"count,omitempty" example:"3"`
+	At       time.Time              `json:"at"`
+	Tags     []string               `json:"tags,omitempty"`
+	Labels   map[string]int         `json:"labels,omitempty"`
+	Extra    map[string]interface{} `json:"extra,omitempty"`
+	Child    *child                 `json:"child,omitempty"`
+	Ignored  string                 `json:"-"`
+	internal string
+}
+
+type child struct {
+	Parent *child `json:"parent,omitempty"`
+}
+
+func TestDocument_Schema(t *testing.T) {
+	var d openapi.Document
+
+	ref := d.Schema(sample{})
+	if ref.Ref != "#/components/schemas/sample" {
+		t.Fatalf("expected reference to the sample component, got %+v", ref)
+	}
+
+	s := d.Components.Schemas["sample"]
+	if s == nil || s.Type != "object" {
+		t.Fatalf("expected object schema, got %+v", s)
+	}
+	if len(s.Properties) != 8 {
+		t.Errorf("expected 8 properties (embedded id promoted, ignored fields skipped), got %d", len(s.Properties))
+	}
+	if got := s.Properties["id"]; got == nil || got.Type != "integer" || got.Format != "int64" {
+		t.Errorf("unexpected id schema: %+v", got)
+	}
+	if got := s.Properties["at"]; got.Type != "string" || got.Format != "date-time" {
+		t.Errorf("unexpected time schema: %+v", got)
+	}
+	if got := s.Properties["tags"]; got.Type != "array" || got.Items.Type != "string" {
+		t.Errorf("unexpected slice schema: %+v", got)
+	}
+	if got := s.Properties["labels"].AdditionalProperties.(*openapi.Schema); got.Type != "integer" {
+		t.Errorf("unexpected map value schema: %+v", got)
+	}
+	if got := s.Properties["extra"].AdditionalProperties; got != true {
+		t.Errorf("expected free-form object, got %v", got)
+	}
+	if got := s.Properties["child"].Ref; got != "#/components/schemas/child" {
+		t.Errorf("expected reference to child, got %q", got)
+	}
+	if d.Components.Schemas["child"].Properties["parent"].Ref != "#/components/schemas/child" {
+		t.Errorf("expected recursive reference")
+	}
+
+	if s.Properties["name"].Example != "web-1" || s.Properties["count"].Example != float64(3) {
+		t.Errorf("unexpected examples: %v, %v", s.Properties["name"].Example, s.Properties["count"].Example)
+	}
+
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	if !required["id"] || !required["name"] || !required["at"] || required["count"] || len(required) != 3 {
+		t.Errorf("unexpected required fields: %v", s.Required)
+	}
+}
+
+func TestDocument_Add(t *testing.T) {
+	var d openapi.Document
+	d.Add("/_bulk", &openapi.Operation{OperationID: "bulk"}, "POST", "PUT")
+
+	post, put := d.Operation("/_bulk", "POST"), d.Operation("/_bulk", "put")
+	if post == nil || put == nil {
+		t.Fatal("expected an operation per method")
+	}
+	if post.OperationID != "bulk" || put.OperationID != "bulkPut" {
+		t.Errorf("expected unique operation IDs, got %q and %q", post.OperationID, put.OperationID)
+	}
+	if d.Operation("/_bulk", "GET") != nil || d.Operation("/missing", "POST") != nil {
+		t.Error("expected nil for undocumented routes")
+	}
+}
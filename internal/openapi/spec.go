@@ -0,0 +1,809 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/anomaly/anomaly"
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/handler/handler"
+	"github.com/rypi-dev/logger-server/internal/idempotency/idempotency"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+	"github.com/rypi-dev/logger-server/internal/quota/quota"
+	"github.com/rypi-dev/logger-server/internal/ratelimit/ratelimit"
+	"github.com/rypi-dev/logger-server/internal/tenant/tenant"
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+	"github.com/rypi-dev/logger-server/internal/webui/webui"
+)
+
+// APIVersion est la version de l'API décrite (info.version)
+const APIVersion = "1.0.0"
+
+const (
+	tagLogs      = "logs"
+	tagIngestion = "compatible ingestion"
+	tagAnomalies = "anomalies"
+	tagWebUI     = "web ui"
+	tagMeta      = "meta"
+	tagQuotas    = "quotas"
+	tagRateLimit = "rate limiting"
+	tagAdmin     = "admin"
+)
+
+const (
+	mediaJSON = "application/json"
+	mediaText = "text/plain"
+	mediaHTML = "text/html"
+)
+
+// Spec décrit toutes les routes HTTP du serveur. Une route ajoutée à un Register doit
+// l'être ici aussi : TestSpecCoversRoutes échoue sinon.
+func Spec() *Document {
+	d := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title: "logger-server",
+			Description: "Centralized log ingestion and query API. Every route requires an API key or an SSO bearer token: " +
+				"writes and shipper probes need the ingest scope, other reads the read scope, /admin/ the admin scope. " +
+				"Keys of a tenant only read and write that tenant's entries and cannot reach /admin/, /anomalies or /metrics.",
+			Version: APIVersion,
+		},
+		Tags: []Tag{
+			{Name: tagLogs, Description: "Native ingestion and query API"},
+			{Name: tagIngestion, Description: "Endpoints compatible with existing log shippers"},
+			{Name: tagAnomalies, Description: "Volume anomalies (LOGGER_ANOMALY_DETECTION=true)"},
+			{Name: tagWebUI, Description: "Embedded search UI"},
+			{Name: tagMeta, Description: "Health, metrics and this document"},
+			{Name: tagQuotas, Description: "Daily and monthly ingestion quotas per tenant or API key. " +
+				"Writes past a quota are rejected with 429; past the soft threshold responses carry `" + quota.HeaderWarning + "`."},
+			{Name: tagRateLimit, Description: "Per-client request limits. Limited responses carry `" + ratelimit.HeaderLimit + "`, `" +
+				ratelimit.HeaderRemaining + "` and `" + ratelimit.HeaderReset + "`; requests past the limit are rejected with 429."},
+			{Name: tagAdmin, Description: "API key and tenant management (admin scope)"},
+		},
+		Security: []SecurityRequirement{{"ApiKeyAuth": {}}, {"BasicAuth": {}}, {"BearerAuth": {}}, {"SignatureAuth": {}}},
+	}
+	d.addComponents()
+	d.addLogRoutes()
+	d.addIngestionRoutes()
+	d.addAnomalyRoutes()
+	d.addWebUIRoutes()
+	d.addMetaRoutes()
+	d.addAdminRoutes()
+	return d
+}
+
+func (d *Document) addComponents() {
+	d.Components.SecuritySchemes = map[string]*SecurityScheme{
+		"ApiKeyAuth": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+		"BasicAuth": {Type: "http", Scheme: "basic",
+			Description: "The API key as the password, any user name. Accepted on GET and HEAD only."},
+		"SplunkAuth": {Type: "apiKey", In: "header", Name: "Authorization",
+			Description: "`Splunk <API key>`, as sent by Splunk HEC clients."},
+		"BearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT",
+			Description: "SSO access token (when LOGGER_JWT_JWKS is set); scopes come from the token groups."},
+		"SignatureAuth": {Type: "apiKey", In: "header", Name: apikey.HeaderSignature,
+			Description: "HMAC-SHA256 request signature made with a generated key, which is not sent. " +
+				"Also requires the `" + apikey.HeaderSignatureKey + "` (key prefix), `" + apikey.HeaderSignatureTimestamp +
+				"` (Unix seconds) and `" + apikey.HeaderSignatureNonce + "` headers; see the README for the signed string."},
+	}
+
+	d.Components.Parameters = map[string]*Parameter{
+		"IdempotencyKey": {Name: idempotency.HeaderName, In: "header",
+			Description: "Identifies the request so that resent entries are stored once " +
+				"(when LOGGER_IDEMPOTENCY_WINDOW is set). Entry n of a batch gets the ID `<key>:<n>`.",
+			Schema: &Schema{Type: "string"}},
+		"Page":  {Name: "page", In: "query", Schema: &Schema{Type: "integer", Minimum: intPtr(1), Default: 1}},
+		"Limit": {Name: "limit", In: "query", Schema: &Schema{Type: "integer", Minimum: intPtr(1), Maximum: intPtr(100), Default: 50}},
+	}
+
+	errorBody := map[string]MediaType{mediaJSON: {Schema: d.Schema(utils.ErrorResponse{})}}
+	d.Components.Responses = map[string]*Response{
+		"BadRequest":           {Description: "Invalid parameter or body", Content: errorBody},
+		"PayloadTooLarge":      {Description: "Body (after decompression) or batch too large", Content: errorBody},
+		"UnsupportedMediaType": {Description: "Unsupported Content-Type or Content-Encoding", Content: errorBody},
+		"InternalError":        {Description: "Storage failure; the request can be retried", Content: errorBody},
+		"Unauthorized": {
+			Description: "Missing or invalid API key",
+			Headers: map[string]*Header{
+				"WWW-Authenticate": {Description: "Basic challenge for browsers", Schema: &Schema{Type: "string"}},
+			},
+			Content: errorBody,
+		},
+		"Forbidden": {Description: "The API key or bearer token lacks the scope required by the route", Content: errorBody},
+		"NotFound":  {Description: "Unknown resource", Content: errorBody},
+		"TooManyRequests": {
+			Description: "Rate limit exceeded (text) or, on writes, ingestion quota exceeded (JSON)",
+			Headers: map[string]*Header{
+				"Retry-After":             {Description: "Seconds to wait before retrying", Schema: &Schema{Type: "integer"}},
+				ratelimit.HeaderLimit:     {Description: "Requests allowed per window", Schema: &Schema{Type: "integer"}},
+				ratelimit.HeaderRemaining: {Description: "Requests left in the window", Schema: &Schema{Type: "integer"}},
+				ratelimit.HeaderReset:     {Description: "Seconds until the full limit is available again", Schema: &Schema{Type: "integer"}},
+			},
+			Content: map[string]MediaType{
+				mediaText: {Schema: &Schema{Type: "string"}},
+				mediaJSON: {Schema: d.Schema(utils.ErrorResponse{})},
+			},
+		},
+	}
+
+	// Contraintes appliquées par LogEntry.Validate
+	d.Schema(internal.LogEntry{})
+	s := d.Components.Schemas["LogEntry"]
+	s.Required = []string{"level", "message"}
+	s.Properties["id"].MaxLength = intPtr(internal.MaxIDLength)
+	s.Properties["id"].Description = "Client-supplied ID used for deduplication"
+	s.Properties["level"].Enum = levelEnum()
+	s.Properties["level"].Description = "Case-insensitive on input"
+	s.Properties["message"].MaxLength = intPtr(internal.MaxMessageLength)
+	s.Properties["timestamp"].Description = "RFC3339; defaults to the time of receipt"
+	s.Properties["context"].MaxProperties = intPtr(internal.MaxContextKeys)
+	s.Properties["context"].Description = fmt.Sprintf("At most %d bytes once encoded", internal.MaxContextSizeBytes)
+}
+
+func (d *Document) addLogRoutes() {
+	d.Add("/log", d.op(&Operation{
+		Tags:        []string{tagLogs},
+		Summary:     "Ingest a log entry",
+		OperationID: "ingestLog",
+		Parameters:  []*Parameter{paramRef("IdempotencyKey")},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{mediaJSON: {Schema: d.Schema(internal.LogEntry{})}}},
+		Responses: map[string]*Response{
+			"201": jsonResponse("Entry stored", &Schema{Type: "object", Properties: map[string]*Schema{
+				"status":  {Type: "string", Example: "ok"},
+				"message": {Type: "string", Example: "log received"},
+			}}),
+			"400": responseRef("BadRequest"),
+			"413": responseRef("PayloadTooLarge"),
+			"415": responseRef("UnsupportedMediaType"),
+			"500": responseRef("InternalError"),
+		},
+	}), "POST")
+
+	d.Add("/log", d.op(&Operation{
+		Tags:        []string{tagLogs},
+		Summary:     "Query stored log entries of the caller's tenant, newest first",
+		OperationID: "queryLogs",
+		Parameters: []*Parameter{
+			paramRef("Page"),
+			paramRef("Limit"),
+			{Name: "level", In: "query", Description: "Exact level", Schema: &Schema{Type: "string", Enum: levelEnum()}},
+		},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Matching entries", &Schema{Type: "array", Items: d.Schema(internal.LogEntry{})}),
+			"400": responseRef("BadRequest"),
+			"500": responseRef("InternalError"),
+		},
+	}), "GET")
+
+	d.Add("/log/batch", d.op(&Operation{
+		Tags:        []string{tagLogs},
+		Summary:     "Ingest a batch of log entries",
+		Description: "Invalid entries are listed in `rejected` without failing the batch. A storage failure fails the whole batch with 500; resend it with the same Idempotency-Key.",
+		OperationID: "ingestBatch",
+		Parameters:  []*Parameter{paramRef("IdempotencyKey")},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{mediaJSON: {Schema: &Schema{
+			Type: "array", Items: d.Schema(internal.LogEntry{}), MaxItems: intPtr(handler.MaxBatchEntries),
+		}}}},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Batch processed", d.Schema(handler.BatchResponse{})),
+			"400": responseRef("BadRequest"),
+			"413": responseRef("PayloadTooLarge"),
+			"415": responseRef("UnsupportedMediaType"),
+			"500": responseRef("InternalError"),
+		},
+	}), "POST")
+
+	d.Add("/log-levels", d.op(&Operation{
+		Tags:        []string{tagLogs},
+		Summary:     "List the accepted log levels",
+		OperationID: "listLogLevels",
+		Responses: map[string]*Response{
+			"200": jsonResponse("Levels from lowest to highest", &Schema{Type: "array", Items: &Schema{Type: "string", Enum: levelEnum()}}),
+		},
+	}), "GET")
+}
+
+func (d *Document) addIngestionRoutes() {
+	d.Add("/v1/logs", d.op(&Operation{
+		Tags:        []string{tagIngestion},
+		Summary:     "OpenTelemetry OTLP/HTTP logs export",
+		OperationID: "otlpExport",
+		Parameters:  []*Parameter{paramRef("IdempotencyKey")},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/x-protobuf": {Schema: &Schema{Type: "string", Format: "binary", Description: "ExportLogsServiceRequest"}},
+			mediaJSON:                {Schema: &Schema{Type: "object", Description: "ExportLogsServiceRequest (protobuf JSON mapping)"}},
+		}},
+		Responses: map[string]*Response{
+			"200": {Description: "ExportLogsServiceResponse; rejected records are reported in partial_success",
+				Content: map[string]MediaType{"application/x-protobuf": {}, mediaJSON: {Schema: &Schema{Type: "object"}}}},
+			"400": {Description: "Undecodable request (google.rpc.Status)"},
+			"413": responseRef("PayloadTooLarge"),
+			"415": responseRef("UnsupportedMediaType"),
+			"500": {Description: "Storage failure (google.rpc.Status); the exporter retries"},
+		},
+	}), "POST")
+
+	d.Add("/loki/api/v1/push", d.op(&Operation{
+		Tags:        []string{tagIngestion},
+		Summary:     "Loki push API (promtail, Grafana Agent)",
+		OperationID: "lokiPush",
+		Parameters:  []*Parameter{paramRef("IdempotencyKey")},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/x-protobuf": {Schema: &Schema{Type: "string", Format: "binary", Description: "Snappy-compressed PushRequest"}},
+			mediaJSON:                {Schema: &Schema{Type: "object", Description: `{"streams": [{"stream": {...}, "values": [["<ns>", "<line>"]]}]}`}},
+		}},
+		Responses: map[string]*Response{
+			"204": {Description: "All entries stored"},
+			"400": textResponse("Undecodable body, or some entries rejected (the valid ones are stored)"),
+			"413": textResponse("Body too large"),
+			"415": textResponse("Unsupported Content-Type"),
+			"500": textResponse("Storage failure; promtail retries"),
+		},
+	}), "POST")
+
+	hecResponse := &Schema{Type: "object", Properties: map[string]*Schema{
+		"text":                 {Type: "string", Example: "Success"},
+		"code":                 {Type: "integer", Example: 0},
+		"ackId":                {Type: "integer", Format: "int64"},
+		"invalid-event-number": {Type: "integer"},
+	}}
+	hecSecurity := []SecurityRequirement{{"SplunkAuth": {}}, {"ApiKeyAuth": {}}}
+	hecChannel := &Parameter{Name: "X-Splunk-Request-Channel", In: "header",
+		Description: "Required when indexer acknowledgement is enabled (LOGGER_HEC_ACK=true)", Schema: &Schema{Type: "string", Format: "uuid"}}
+	hecIngest := func(summary, id, media string) *Operation {
+		return d.op(&Operation{
+			Tags:        []string{tagIngestion},
+			Summary:     summary,
+			OperationID: id,
+			Parameters:  []*Parameter{hecChannel, paramRef("IdempotencyKey")},
+			RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{media: {Schema: &Schema{Type: "string"}}}},
+			Responses: map[string]*Response{
+				"200": jsonResponse("Events stored", hecResponse),
+				"400": jsonResponse("Invalid events (Splunk error code)", hecResponse),
+				"413": jsonResponse("Body too large", hecResponse),
+				"503": jsonResponse("Storage failure (server busy)", hecResponse),
+			},
+			Security: hecSecurity,
+		})
+	}
+	for _, path := range []string{"/services/collector", "/services/collector/event", "/services/collector/event/1.0"} {
+		d.Add(path, hecIngest("Splunk HEC events (concatenated JSON objects)", operationID("hecEvent", path), mediaJSON), "POST")
+	}
+	for _, path := range []string{"/services/collector/raw", "/services/collector/raw/1.0"} {
+		d.Add(path, hecIngest("Splunk HEC raw lines (one entry per line)", operationID("hecRaw", path), mediaText), "POST")
+	}
+	d.Add("/services/collector/ack", d.op(&Operation{
+		Tags:        []string{tagIngestion},
+		Summary:     "Splunk HEC indexer acknowledgement status",
+		OperationID: "hecAck",
+		Parameters:  []*Parameter{hecChannel},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{mediaJSON: {Schema: &Schema{
+			Type: "object", Properties: map[string]*Schema{"acks": {Type: "array", Items: &Schema{Type: "integer", Format: "int64"}}},
+		}}}},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Status of each ack ID", &Schema{Type: "object", Properties: map[string]*Schema{
+				"acks": {Type: "object", AdditionalProperties: &Schema{Type: "boolean"}},
+			}}),
+			"400": jsonResponse("Acks disabled, or invalid channel or body", hecResponse),
+		},
+		Security: hecSecurity,
+	}), "POST")
+	for _, path := range []string{"/services/collector/health", "/services/collector/health/1.0"} {
+		d.Add(path, d.op(&Operation{
+			Tags:        []string{tagIngestion},
+			Summary:     "Splunk HEC health check",
+			OperationID: operationID("hecHealth", path),
+			Responses:   map[string]*Response{"200": jsonResponse("Healthy", hecResponse)},
+			Security:    hecSecurity,
+		}), "GET")
+	}
+
+	esInfo := func(path, summary, id string, methods ...string) {
+		d.Add(path, d.op(&Operation{
+			Tags:        []string{tagIngestion},
+			Summary:     summary,
+			Description: "Only when LOGGER_ELASTIC_COMPAT=true.",
+			OperationID: id,
+			Responses:   map[string]*Response{"200": jsonResponse("Elasticsearch-compatible response", &Schema{Type: "object"})},
+		}), methods...)
+	}
+	esInfo("/", "Elasticsearch cluster information", "esInfo", "GET", "HEAD")
+	esInfo("/_license", "Elasticsearch license", "esLicense", "GET")
+	esInfo("/_xpack", "Elasticsearch X-Pack features", "esXPack", "GET")
+	esInfo("/_cluster/health", "Elasticsearch cluster health", "esClusterHealth", "GET")
+
+	bulk := func(id string, params ...*Parameter) *Operation {
+		return d.op(&Operation{
+			Tags:        []string{tagIngestion},
+			Summary:     "Elasticsearch bulk ingestion (Beats, Logstash, Vector)",
+			Description: "Only when LOGGER_ELASTIC_COMPAT=true. Documents are mapped to entries with the configured field lists.",
+			OperationID: id,
+			Parameters:  params,
+			RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+				"application/x-ndjson": {Schema: &Schema{Type: "string", Description: "index/create action and document pairs"}},
+			}},
+			Responses: map[string]*Response{
+				"200": jsonResponse("Per-item results (`errors` is true when an item failed)", &Schema{Type: "object", Properties: map[string]*Schema{
+					"took":   {Type: "integer"},
+					"errors": {Type: "boolean"},
+					"items":  {Type: "array", Items: &Schema{Type: "object"}},
+				}}),
+				"400": jsonResponse("Invalid NDJSON", &Schema{Type: "object"}),
+				"413": jsonResponse("Body too large", &Schema{Type: "object"}),
+			},
+		})
+	}
+	d.Add("/_bulk", bulk("esBulk"), "POST", "PUT")
+	d.Add("/{index}/_bulk", bulk("esIndexBulk", &Parameter{Name: "index", In: "path", Required: true,
+		Description: "Ignored; the entry service is taken from the document", Schema: &Schema{Type: "string"}}), "POST", "PUT")
+}
+
+func (d *Document) addAnomalyRoutes() {
+	d.Add("/anomalies", d.op(&Operation{
+		Tags:        []string{tagAnomalies},
+		Summary:     "List detected anomalies, newest first",
+		Description: "Only when LOGGER_ANOMALY_DETECTION=true.",
+		OperationID: "listAnomalies",
+		Parameters: []*Parameter{
+			paramRef("Page"),
+			paramRef("Limit"),
+			{Name: "service", In: "query", Schema: &Schema{Type: "string"}},
+			{Name: "metric", In: "query", Schema: &Schema{Type: "string", Enum: []string{anomaly.MetricVolume, anomaly.MetricErrorRate}}},
+			{Name: "since", In: "query", Schema: &Schema{Type: "string", Format: "date-time"}},
+		},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Anomalies", &Schema{Type: "array", Items: d.Schema(anomaly.Event{})}),
+			"400": responseRef("BadRequest"),
+			"500": responseRef("InternalError"),
+		},
+	}), "GET")
+}
+
+func (d *Document) addWebUIRoutes() {
+	filters := []*Parameter{
+		{Name: "level", In: "query", Description: "Comma-separated levels", Schema: &Schema{Type: "string", Example: "ERROR,WARN"}},
+		{Name: "service", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "q", In: "query", Description: "Case-insensitive message substring", Schema: &Schema{Type: "string"}},
+		{Name: "ctx.{path}", In: "query", Description: "Context value filter, e.g. `ctx.user_id=42` or `ctx.request.method=POST`",
+			Schema: &Schema{Type: "string"}},
+	}
+	timeParam := func(name, desc string, required bool) *Parameter {
+		return &Parameter{Name: name, In: "query", Description: desc, Required: required, Schema: &Schema{Type: "string", Format: "date-time"}}
+	}
+
+	d.Add("/ui/api/search", d.op(&Operation{
+		Tags:        []string{tagWebUI},
+		Summary:     "Search stored entries, newest first",
+		OperationID: "uiSearch",
+		Parameters: append(filters,
+			timeParam("from", "Inclusive lower bound", false),
+			timeParam("to", "Exclusive upper bound", false),
+			&Parameter{Name: "after", In: "query", Description: "Only rows newer than this row_id (live tail)", Schema: &Schema{Type: "integer", Format: "int64"}},
+			&Parameter{Name: "before", In: "query", Description: "Only rows older than this row_id (next page)", Schema: &Schema{Type: "integer", Format: "int64"}},
+			&Parameter{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Minimum: intPtr(1), Maximum: intPtr(webui.MaxSearchLimit), Default: webui.DefaultSearchLimit}},
+		),
+		Responses: map[string]*Response{
+			"200": jsonResponse("Matching entries", &Schema{Type: "array", Items: d.Schema(webui.Record{})}),
+			"400": responseRef("BadRequest"),
+			"500": responseRef("InternalError"),
+		},
+	}), "GET")
+
+	d.Add("/ui/api/histogram", d.op(&Operation{
+		Tags:        []string{tagWebUI},
+		Summary:     "Count matching entries per interval and level",
+		OperationID: "uiHistogram",
+		Parameters: append(filters,
+			timeParam("from", "Start of the first bucket", true),
+			timeParam("to", "End of the last bucket", true),
+			&Parameter{Name: "buckets", In: "query", Schema: &Schema{Type: "integer", Minimum: intPtr(1), Maximum: intPtr(webui.MaxBuckets), Default: webui.DefaultBuckets}},
+		),
+		Responses: map[string]*Response{
+			"200": jsonResponse("Buckets, including empty ones", &Schema{Type: "object", Properties: map[string]*Schema{
+				"interval_seconds": {Type: "integer"},
+				"buckets":          {Type: "array", Items: d.Schema(webui.Bucket{})},
+			}}),
+			"400": responseRef("BadRequest"),
+			"500": responseRef("InternalError"),
+		},
+	}), "GET")
+
+	d.Add("/ui", d.op(&Operation{
+		Tags:        []string{tagWebUI},
+		Summary:     "Redirect to /ui/",
+		OperationID: "uiRedirect",
+		Responses:   map[string]*Response{"301": {Description: "Redirect to /ui/"}},
+	}), "GET")
+
+	d.Add("/ui/", d.op(&Operation{
+		Tags:        []string{tagWebUI},
+		Summary:     "Web UI page and its static assets (/ui/app.js, /ui/style.css)",
+		OperationID: "uiPage",
+		Responses: map[string]*Response{
+			"200": {Description: "Search page", Content: map[string]MediaType{mediaHTML: {Schema: &Schema{Type: "string"}}}},
+			"404": {Description: "Unknown asset"},
+		},
+	}), "GET")
+}
+
+func (d *Document) addMetaRoutes() {
+	d.Add("/health", d.op(&Operation{
+		Tags:        []string{tagMeta},
+		Summary:     "Health check",
+		OperationID: "health",
+		Responses:   map[string]*Response{"200": textResponse("OK")},
+	}), "GET")
+
+	d.Add("/metrics", d.op(&Operation{
+		Tags:        []string{tagMeta},
+		Summary:     "Prometheus metrics",
+		OperationID: "metrics",
+		Responses:   map[string]*Response{"200": textResponse("Prometheus text exposition format")},
+	}), "GET")
+
+	d.Add(SpecPath, d.op(&Operation{
+		Tags:        []string{tagMeta},
+		Summary:     "This OpenAPI document",
+		OperationID: "openapiSpec",
+		Responses:   map[string]*Response{"200": jsonResponse("OpenAPI 3 document", &Schema{Type: "object"})},
+	}), "GET")
+
+	d.Add(DocsPath, d.op(&Operation{
+		Tags:        []string{tagMeta},
+		Summary:     "Swagger UI for this document",
+		OperationID: "openapiDocs",
+		Responses: map[string]*Response{
+			"200": {Description: "Swagger UI page", Content: map[string]MediaType{mediaHTML: {Schema: &Schema{Type: "string"}}}},
+		},
+	}), "GET")
+}
+
+func (d *Document) addAdminRoutes() {
+	keyID := &Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}}
+
+	d.Add("/admin/keys", d.op(&Operation{
+		Tags:        []string{tagAdmin},
+		Summary:     "List API keys, revoked and expired ones included",
+		OperationID: "listAPIKeys",
+		Responses: map[string]*Response{
+			"200": jsonResponse("Keys, newest first (secrets are never returned)", &Schema{Type: "array", Items: d.Schema(apikey.Key{})}),
+			"500": responseRef("InternalError"),
+		},
+	}), "GET")
+
+	d.Add("/admin/keys", d.op(&Operation{
+		Tags:        []string{tagAdmin},
+		Summary:     "Create an API key",
+		Description: "The secret is returned in `key` by this response only; the server keeps its SHA-256 hash.",
+		OperationID: "createAPIKey",
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{mediaJSON: {Schema: d.Schema(apikey.CreateRequest{})}}},
+		Responses: map[string]*Response{
+			"201": jsonResponse("Key created", d.Schema(apikey.CreatedKey{})),
+			"400": responseRef("BadRequest"),
+			"500": responseRef("InternalError"),
+		},
+	}), "POST")
+
+	d.Add("/admin/keys/{id}", d.op(&Operation{
+		Tags:        []string{tagAdmin},
+		Summary:     "Revoke an API key immediately",
+		OperationID: "revokeAPIKey",
+		Parameters:  []*Parameter{keyID},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Revoked key", d.Schema(apikey.Key{})),
+			"404": responseRef("NotFound"),
+			"500": responseRef("InternalError"),
+		},
+	}), "DELETE")
+
+	d.Add("/admin/keys/{id}/rotate", d.op(&Operation{
+		Tags:    []string{tagAdmin},
+		Summary: "Replace an API key, keeping the old one valid for a grace period",
+		Description: fmt.Sprintf("The new key has the same name, scopes, service and tenant. The old key expires after `grace` (default %s).",
+			apikey.DefaultRotationGrace),
+		OperationID: "rotateAPIKey",
+		Parameters:  []*Parameter{keyID},
+		RequestBody: &RequestBody{Content: map[string]MediaType{mediaJSON: {Schema: d.Schema(apikey.RotateRequest{})}}},
+		Responses: map[string]*Response{
+			"201": jsonResponse("New key", d.Schema(apikey.CreatedKey{})),
+			"400": responseRef("BadRequest"),
+			"404": responseRef("NotFound"),
+			"409": {Description: "The key is already revoked or expired", Content: map[string]MediaType{mediaJSON: {Schema: d.Schema(utils.ErrorResponse{})}}},
+			"500": responseRef("InternalError"),
+		},
+	}), "POST")
+
+	d.addTenantRoutes()
+	d.addQuotaRoutes()
+	d.addRateLimitRoutes()
+}
+
+func (d *Document) addTenantRoutes() {
+	name := &Parameter{Name: "name", In: "path", Required: true, Schema: &Schema{Type: "string", Example: "payments"}}
+	conflict := &Response{Description: "A tenant with this name already exists", Content: map[string]MediaType{mediaJSON: {Schema: d.Schema(utils.ErrorResponse{})}}}
+
+	d.Add("/admin/tenants", d.op(&Operation{
+		Tags:        []string{tagAdmin},
+		Summary:     "List tenants",
+		OperationID: "listTenants",
+		Responses: map[string]*Response{
+			"200": jsonResponse("Tenants by name", &Schema{Type: "array", Items: d.Schema(tenant.Tenant{})}),
+		},
+	}), "GET")
+
+	d.Add("/admin/tenants", d.op(&Operation{
+		Tags:        []string{tagAdmin},
+		Summary:     "Create a tenant",
+		Description: "Keys created with this `tenant` then only read and write its entries. Zero settings mean no limit.",
+		OperationID: "createTenant",
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{mediaJSON: {Schema: d.Schema(tenant.CreateRequest{})}}},
+		Responses: map[string]*Response{
+			"201": jsonResponse("Tenant created", d.Schema(tenant.Tenant{})),
+			"400": responseRef("BadRequest"),
+			"409": conflict,
+			"500": responseRef("InternalError"),
+		},
+	}), "POST")
+
+	d.Add("/admin/tenants/{name}", d.op(&Operation{
+		Tags:        []string{tagAdmin},
+		Summary:     "Get a tenant",
+		OperationID: "getTenant",
+		Parameters:  []*Parameter{name},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Tenant", d.Schema(tenant.Tenant{})),
+			"404": responseRef("NotFound"),
+		},
+	}), "GET")
+
+	d.Add("/admin/tenants/{name}", d.op(&Operation{
+		Tags:        []string{tagAdmin},
+		Summary:     "Replace the retention settings of a tenant",
+		OperationID: "updateTenant",
+		Parameters:  []*Parameter{name},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{mediaJSON: {Schema: d.Schema(tenant.Settings{})}}},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Updated tenant", d.Schema(tenant.Tenant{})),
+			"400": responseRef("BadRequest"),
+			"404": responseRef("NotFound"),
+			"500": responseRef("InternalError"),
+		},
+	}), "PUT")
+
+	d.Add("/admin/tenants/{name}", d.op(&Operation{
+		Tags:        []string{tagAdmin},
+		Summary:     "Delete a tenant, revoking its keys and deleting its entries",
+		OperationID: "deleteTenant",
+		Parameters:  []*Parameter{name},
+		Responses: map[string]*Response{
+			"204": {Description: "Tenant deleted"},
+			"404": responseRef("NotFound"),
+			"500": responseRef("InternalError"),
+		},
+	}), "DELETE")
+}
+
+func (d *Document) addQuotaRoutes() {
+	subject := []*Parameter{
+		{Name: "kind", In: "path", Required: true, Schema: &Schema{Type: "string", Enum: []string{quota.KindTenant, quota.KindKey}}},
+		{Name: "name", In: "path", Required: true, Description: "Tenant name, or name of an API key of the default tenant " +
+			"(rotated keys keep their quota)", Schema: &Schema{Type: "string", Example: "payments"}},
+	}
+	keySubject := []*Parameter{
+		{Name: "kind", In: "path", Required: true, Schema: &Schema{Type: "string", Enum: []string{quota.KindKey}}},
+		{Name: "tenant", In: "path", Required: true, Description: "Tenant of the API key",
+			Schema: &Schema{Type: "string", Example: "payments"}},
+		{Name: "name", In: "path", Required: true, Description: "API key name (rotated keys keep their quota)",
+			Schema: &Schema{Type: "string", Example: "fluent-bit-web"}},
+	}
+
+	d.Add("/usage", d.op(&Operation{
+		Tags:        []string{tagQuotas},
+		Summary:     "Current usage and quotas of the caller's tenant and key",
+		OperationID: "getUsage",
+		Responses: map[string]*Response{
+			"200": jsonResponse("Usage of the tenant (if any) then of the key", &Schema{Type: "array", Items: d.Schema(quota.Usage{})}),
+			"500": responseRef("InternalError"),
+		},
+	}), "GET")
+
+	d.Add("/admin/quotas", d.op(&Operation{
+		Tags:        []string{tagAdmin, tagQuotas},
+		Summary:     "List quotas with the current usage of their subject",
+		OperationID: "listQuotas",
+		Responses: map[string]*Response{
+			"200": jsonResponse("Quotas by subject", &Schema{Type: "array", Items: d.Schema(quota.Usage{})}),
+			"500": responseRef("InternalError"),
+		},
+	}), "GET")
+
+	d.Add("/admin/quotas/{kind}/{name}", d.op(&Operation{
+		Tags:    []string{tagAdmin, tagQuotas},
+		Summary: "Set the quotas of a tenant or API key",
+		Description: fmt.Sprintf("Zero disables a quota. Writes are reported from `soft_percent` (default %d) of a quota. "+
+			"Days and months are UTC.", quota.DefaultSoftPercent),
+		OperationID: "setQuota",
+		Parameters:  subject,
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{mediaJSON: {Schema: d.Schema(quota.Limits{})}}},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Quotas and current usage", d.Schema(quota.Usage{})),
+			"400": responseRef("BadRequest"),
+			"500": responseRef("InternalError"),
+		},
+	}), "PUT")
+
+	d.Add("/admin/quotas/{kind}/{name}", d.op(&Operation{
+		Tags:        []string{tagAdmin, tagQuotas},
+		Summary:     "Remove the quotas of a tenant or API key; its usage is still counted",
+		OperationID: "deleteQuota",
+		Parameters:  subject,
+		Responses: map[string]*Response{
+			"204": {Description: "Quotas removed"},
+			"404": responseRef("NotFound"),
+			"500": responseRef("InternalError"),
+		},
+	}), "DELETE")
+
+	d.Add("/admin/quotas/{kind}/{tenant}/{name}", d.op(&Operation{
+		Tags:    []string{tagAdmin, tagQuotas},
+		Summary: "Set the quotas of an API key of a tenant",
+		Description: fmt.Sprintf("Zero disables a quota. Writes are reported from `soft_percent` (default %d) of a quota. "+
+			"Days and months are UTC.", quota.DefaultSoftPercent),
+		OperationID: "setKeyQuota",
+		Parameters:  keySubject,
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{mediaJSON: {Schema: d.Schema(quota.Limits{})}}},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Quotas and current usage", d.Schema(quota.Usage{})),
+			"400": responseRef("BadRequest"),
+			"500": responseRef("InternalError"),
+		},
+	}), "PUT")
+
+	d.Add("/admin/quotas/{kind}/{tenant}/{name}", d.op(&Operation{
+		Tags:        []string{tagAdmin, tagQuotas},
+		Summary:     "Remove the quotas of an API key of a tenant; its usage is still counted",
+		OperationID: "deleteKeyQuota",
+		Parameters:  keySubject,
+		Responses: map[string]*Response{
+			"204": {Description: "Quotas removed"},
+			"404": responseRef("NotFound"),
+			"500": responseRef("InternalError"),
+		},
+	}), "DELETE")
+
+	d.Add("/admin/usage", d.op(&Operation{
+		Tags:        []string{tagAdmin, tagQuotas},
+		Summary:     "Usage of every tenant and API key over a day or a month, for chargeback",
+		OperationID: "usageReport",
+		Parameters: []*Parameter{
+			{Name: "period", In: "query", Description: "`YYYY-MM` or `YYYY-MM-DD` (UTC); defaults to the current month",
+				Schema: &Schema{Type: "string", Example: "2025-09"}},
+		},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Usage by subject", &Schema{Type: "array", Items: d.Schema(quota.PeriodUsage{})}),
+			"400": responseRef("BadRequest"),
+			"500": responseRef("InternalError"),
+		},
+	}), "GET")
+}
+
+func (d *Document) addRateLimitRoutes() {
+	client := []*Parameter{
+		{Name: "client", In: "path", Required: true, Description: "Rate limiting key: IP address, `cert:<identity>`, `key:<tenant>/<name>`, " +
+			"`tenant:<name>` or `service:<tenant>/<service>` depending on LOGGER_RATE_LIMIT_KEY",
+			Schema: &Schema{Type: "string", Example: "key:payments/fluent-bit-web"}},
+	}
+
+	d.Add("/admin/ratelimit/clients", d.op(&Operation{
+		Tags:        []string{tagAdmin, tagRateLimit},
+		Summary:     "List tracked clients with their remaining requests for each rule",
+		OperationID: "listRateLimitClients",
+		Responses: map[string]*Response{
+			"200": jsonResponse("Client states by client and rule", &Schema{Type: "array", Items: d.Schema(ratelimit.ClientStatus{})}),
+		},
+	}), "GET")
+
+	d.Add("/admin/ratelimit/clients/{client}", d.op(&Operation{
+		Tags:        []string{tagAdmin, tagRateLimit},
+		Summary:     "Get the usage of one client for each rule that counts it",
+		OperationID: "getRateLimitClient",
+		Parameters:  client,
+		Responses: map[string]*Response{
+			"200": jsonResponse("Client states by rule", &Schema{Type: "array", Items: d.Schema(ratelimit.ClientStatus{})}),
+			"404": responseRef("NotFound"),
+		},
+	}), "GET")
+
+	d.Add("/admin/ratelimit/clients/{client}", d.op(&Operation{
+		Tags:        []string{tagAdmin, tagRateLimit},
+		Summary:     "Reset the counters of a client, giving it its full limit back",
+		OperationID: "resetRateLimitClient",
+		Parameters:  client,
+		Responses: map[string]*Response{
+			"204": {Description: "Counters reset"},
+			"404": responseRef("NotFound"),
+		},
+	}), "DELETE")
+
+	d.Add("/admin/ratelimit/overrides", d.op(&Operation{
+		Tags:        []string{tagAdmin, tagRateLimit},
+		Summary:     "List temporary limits",
+		OperationID: "listRateLimitOverrides",
+		Responses: map[string]*Response{
+			"200": jsonResponse("Temporary limits by client", &Schema{Type: "array", Items: d.Schema(ratelimit.Override{})}),
+		},
+	}), "GET")
+
+	d.Add("/admin/ratelimit/overrides/{client}", d.op(&Operation{
+		Tags:    []string{tagAdmin, tagRateLimit},
+		Summary: "Raise or lower the request limit of a client for a while",
+		Description: fmt.Sprintf("Replaces the default, per-level and route limits of the client until `ttl` (at most %s) expires. "+
+			"Entry limits are unchanged. Nothing is persisted: overrides are lost on restart.", ratelimit.MaxOverrideTTL),
+		OperationID: "setRateLimitOverride",
+		Parameters:  client,
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{mediaJSON: {Schema: d.Schema(ratelimit.OverrideRequest{})}}},
+		Responses: map[string]*Response{
+			"200": jsonResponse("Temporary limit", d.Schema(ratelimit.Override{})),
+			"400": responseRef("BadRequest"),
+		},
+	}), "PUT")
+
+	d.Add("/admin/ratelimit/overrides/{client}", d.op(&Operation{
+		Tags:        []string{tagAdmin, tagRateLimit},
+		Summary:     "Remove the temporary limit of a client",
+		OperationID: "deleteRateLimitOverride",
+		Parameters:  client,
+		Responses: map[string]*Response{
+			"204": {Description: "Temporary limit removed"},
+			"404": responseRef("NotFound"),
+		},
+	}), "DELETE")
+}
+
+// op ajoute les réponses des middlewares communs à toutes les routes
+func (d *Document) op(o *Operation) *Operation {
+	o.Responses["401"] = responseRef("Unauthorized")
+	o.Responses["403"] = responseRef("Forbidden")
+	o.Responses["429"] = responseRef("TooManyRequests")
+	return o
+}
+
+func jsonResponse(desc string, schema *Schema) *Response {
+	return &Response{Description: desc, Content: map[string]MediaType{mediaJSON: {Schema: schema}}}
+}
+
+func textResponse(desc string) *Response {
+	return &Response{Description: desc, Content: map[string]MediaType{mediaText: {Schema: &Schema{Type: "string"}}}}
+}
+
+func responseRef(name string) *Response {
+	return &Response{Ref: "#/components/responses/" + name}
+}
+
+func paramRef(name string) *Parameter {
+	return &Parameter{Ref: "#/components/parameters/" + name}
+}
+
+// operationID dérive un identifiant unique des alias HEC (/services/collector/event/1.0, ...)
+func operationID(base, path string) string {
+	switch {
+	case path == "/services/collector":
+		return base + "Default"
+	case strings.HasSuffix(path, "/1.0"):
+		return base + "V1"
+	}
+	return base
+}
+
+func levelEnum() []string {
+	var levels []string
+	for _, level := range log_levels.AllLogLevels() {
+		levels = append(levels, string(level))
+	}
+	return levels
+}
+
+func intPtr(v int) *int {
+	return &v
+}
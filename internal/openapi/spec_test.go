@@ -0,0 +1,187 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/rypi-dev/logger-server/internal/anomaly"
+	"github.com/rypi-dev/logger-server/internal/apikey"
+	"github.com/rypi-dev/logger-server/internal/elastic"
+	"github.com/rypi-dev/logger-server/internal/handler"
+	"github.com/rypi-dev/logger-server/internal/hec"
+	"github.com/rypi-dev/logger-server/internal/loki"
+	"github.com/rypi-dev/logger-server/internal/openapi"
+	"github.com/rypi-dev/logger-server/internal/otlp"
+	"github.com/rypi-dev/logger-server/internal/quota"
+	"github.com/rypi-dev/logger-server/internal/ratelimit"
+	"github.com/rypi-dev/logger-server/internal/tenant"
+	"github.com/rypi-dev/logger-server/internal/webui"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Write(entry handler.LogEntry) error { return nil }
+
+func (nopLogger) QueryLogs(tenant, level string, page, limit int) ([]handler.LogEntry, error) {
+	return nil, nil
+}
+
+// fullRouter enregistre toutes les routes HTTP comme cmd/main.go, options comprises
+func fullRouter(t *testing.T) *mux.Router {
+	t.Helper()
+	h := handler.NewHandler(nopLogger{}, zap.NewNop())
+	r := h.Router()
+	r.Handle("/metrics", promhttp.Handler())
+	otlp.NewAPI(h.Ingest).Register(r)
+	loki.NewAPI(h.Ingest).Register(r)
+	hec.NewAPI(hec.Config{EnableAck: true}, h.Ingest).Register(r)
+	elastic.NewAPI(elastic.Config{Mapping: elastic.DefaultMapping()}, h.Ingest).Register(r)
+	webui.NewAPI(nil).Register(r)
+	anomaly.NewAPI(nil).Register(r)
+	apikey.NewAPI(nil).Register(r)
+	tenant.NewAPI(nil).Register(r)
+	quota.NewAPI(nil).Register(r)
+	ratelimit.NewAPI(nil).Register(r)
+
+	api, err := openapi.NewAPI(openapi.Spec())
+	if err != nil {
+		t.Fatalf("NewAPI failed: %v", err)
+	}
+	api.Register(r)
+	return r
+}
+
+// Les variables mux {name:regexp} s'écrivent {name} en OpenAPI
+var muxVarPattern = regexp.MustCompile(`\{([^:}]+):[^}]*\}`)
+
+// routes liste les routes enregistrées sous la forme "METHOD /path"
+func routes(t *testing.T, r *mux.Router) map[string]bool {
+	t.Helper()
+	found := make(map[string]bool)
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Route sans restriction de méthode (/metrics) : documentée en GET
+			methods = []string{"GET"}
+		}
+		for _, method := range methods {
+			found[method+" "+muxVarPattern.ReplaceAllString(tpl, "{$1}")] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	doc := openapi.Spec()
+	registered := routes(t, fullRouter(t))
+
+	var missing []string
+	for route := range registered {
+		method, path, _ := strings.Cut(route, " ")
+		if doc.Operation(path, method) == nil {
+			missing = append(missing, route)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("routes missing from the OpenAPI spec: %v", missing)
+	}
+
+	var stale []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			route := strings.ToUpper(method) + " " + path
+			if !registered[route] {
+				stale = append(stale, route)
+			}
+		}
+	}
+	sort.Strings(stale)
+	if len(stale) > 0 {
+		t.Errorf("spec operations without a registered route: %v", stale)
+	}
+}
+
+func TestSpecIsConsistent(t *testing.T) {
+	doc := openapi.Spec()
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		t.Fatal(err)
+	}
+
+	// Toutes les références pointent vers un composant existant
+	refs := regexp.MustCompile(`"\$ref":"#/components/(\w+)/(\w+)"`).FindAllStringSubmatch(string(raw), -1)
+	if len(refs) == 0 {
+		t.Fatal("expected $ref entries in the spec")
+	}
+	components := generic["components"].(map[string]interface{})
+	for _, ref := range refs {
+		kind, _ := components[ref[1]].(map[string]interface{})
+		if _, ok := kind[ref[2]]; !ok {
+			t.Errorf("unresolved reference #/components/%s/%s", ref[1], ref[2])
+		}
+	}
+
+	ids := make(map[string]string)
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			route := strings.ToUpper(method) + " " + path
+			if op.OperationID == "" {
+				t.Errorf("%s has no operationId", route)
+			}
+			if other, ok := ids[op.OperationID]; ok {
+				t.Errorf("operationId %q used by %s and %s", op.OperationID, other, route)
+			}
+			ids[op.OperationID] = route
+			if op.Responses["401"] == nil {
+				t.Errorf("%s does not document the 401 response", route)
+			}
+		}
+	}
+}
+
+func TestSpec_LogEntrySchema(t *testing.T) {
+	doc := openapi.Spec()
+
+	entry := doc.Components.Schemas["LogEntry"]
+	if entry == nil {
+		t.Fatal("expected LogEntry schema")
+	}
+	if strings.Join(entry.Required, ",") != "level,message" {
+		t.Errorf("expected level and message to be required, got %v", entry.Required)
+	}
+	if got := entry.Properties["message"].Example; got != "User logged in" {
+		t.Errorf("expected example from struct tag, got %v", got)
+	}
+	if got := entry.Properties["context"].Example; got.(map[string]interface{})["user_id"] != float64(42) {
+		t.Errorf("expected JSON object example, got %v", got)
+	}
+	if len(entry.Properties["level"].Enum) != 6 {
+		t.Errorf("expected level enum, got %v", entry.Properties["level"].Enum)
+	}
+
+	errSchema := doc.Components.Schemas["ErrorResponse"]
+	if errSchema == nil || errSchema.Properties["error"] == nil {
+		t.Errorf("expected ErrorResponse schema with an error property, got %+v", errSchema)
+	}
+}
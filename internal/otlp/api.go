@@ -0,0 +1,211 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/handler/handler"
+	"github.com/rypi-dev/logger-server/internal/idempotency/idempotency"
+)
+
+// MaxRequestBodySize borne une requête d'export (les SDK envoient des lots)
+const MaxRequestBodySize = 4 << 20
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+var recordsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "otlp_log_records_total",
+	Help: "Total number of OTLP log records received, by result",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(recordsTotal)
+}
+
+// API reçoit les logs OTLP/HTTP et les fait passer par le chemin d'ingestion commun
+type API struct {
+	ingest internal.IngestFunc
+}
+
+func NewAPI(ingest internal.IngestFunc) *API {
+	return &API{ingest: ingest}
+}
+
+// Register ajoute POST /v1/logs au routeur
+func (a *API) Register(r *mux.Router) {
+	r.HandleFunc("/v1/logs", a.handleExport).Methods("POST")
+}
+
+func (a *API) handleExport(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != contentTypeProtobuf && mediaType != contentTypeJSON) {
+		writeStatus(w, contentTypeJSON, http.StatusUnsupportedMediaType, codes.InvalidArgument,
+			"Content-Type must be application/x-protobuf or application/json")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeStatus(w, mediaType, http.StatusRequestEntityTooLarge, codes.InvalidArgument, "request body too large")
+			return
+		}
+		writeStatus(w, mediaType, http.StatusBadRequest, codes.InvalidArgument, "invalid body")
+		return
+	}
+
+	req := &collogspb.ExportLogsServiceRequest{}
+	if mediaType == contentTypeProtobuf {
+		err = proto.Unmarshal(body, req)
+	} else {
+		err = unmarshalJSON(body, req)
+	}
+	if err != nil {
+		writeStatus(w, mediaType, http.StatusBadRequest, codes.InvalidArgument, fmt.Sprintf("invalid OTLP payload: %v", err))
+		return
+	}
+
+	var rejected int64
+	var firstErr string
+	key := r.Header.Get(idempotency.HeaderName)
+	ingest := apikey.Bind(r.Context(), a.ingest)
+	for i, entry := range ToLogEntries(req) {
+		if entry.ID == "" {
+			entry.ID = idempotency.EntryID(key, i)
+		}
+		err := ingest(entry)
+		switch {
+		case err == nil:
+			recordsTotal.WithLabelValues("ok").Inc()
+		case errors.Is(err, handler.ErrWriteFailed):
+			// 503 est réessayable côté exporteur, contrairement à 500
+			recordsTotal.WithLabelValues("write_error").Inc()
+			writeStatus(w, mediaType, http.StatusServiceUnavailable, codes.Unavailable, "failed to write log")
+			return
+		default:
+			recordsTotal.WithLabelValues("rejected").Inc()
+			rejected++
+			if firstErr == "" {
+				firstErr = err.Error()
+			}
+		}
+	}
+
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if rejected > 0 {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: rejected,
+			ErrorMessage:       firstErr,
+		}
+	}
+	writeMessage(w, mediaType, http.StatusOK, resp)
+}
+
+// writeStatus répond avec un google.rpc.Status, comme l'exige la spec OTLP/HTTP pour les erreurs
+func writeStatus(w http.ResponseWriter, mediaType string, httpStatus int, code codes.Code, msg string) {
+	writeMessage(w, mediaType, httpStatus, &statuspb.Status{Code: int32(code), Message: msg})
+}
+
+func writeMessage(w http.ResponseWriter, mediaType string, httpStatus int, msg proto.Message) {
+	var (
+		data []byte
+		err  error
+	)
+	if mediaType == contentTypeProtobuf {
+		data, err = proto.Marshal(msg)
+	} else {
+		data, err = protojson.Marshal(msg)
+	}
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(httpStatus)
+	_, _ = w.Write(data)
+}
+
+// unmarshalJSON décode l'encodage JSON d'OTLP. Il diffère du mapping protobuf standard
+// sur un point : traceId et spanId sont en hexadécimal et non en base64.
+func unmarshalJSON(body []byte, req *collogspb.ExportLogsServiceRequest) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	for _, rl := range jsonArray(raw, "resourceLogs", "resource_logs") {
+		for _, sl := range jsonArray(rl, "scopeLogs", "scope_logs") {
+			for _, record := range jsonArray(sl, "logRecords", "log_records") {
+				for _, key := range []string{"traceId", "trace_id", "spanId", "span_id"} {
+					if err := hexToBase64(record, key); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(normalized, req)
+}
+
+func jsonArray(v interface{}, keys ...string) []interface{} {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for _, key := range keys {
+		if arr, ok := obj[key].([]interface{}); ok {
+			return arr
+		}
+	}
+	return nil
+}
+
+func hexToBase64(v interface{}, key string) error {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	s, ok := obj[key].(string)
+	if !ok || s == "" {
+		return nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("%s must be hex-encoded", key)
+	}
+	obj[key] = base64.StdEncoding.EncodeToString(b)
+	return nil
+}
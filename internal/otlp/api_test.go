@@ -0,0 +1,179 @@
+package otlp_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/handler"
+	"github.com/rypi-dev/logger-server/internal/otlp"
+)
+
+type collector struct {
+	entries []*internal.LogEntry
+	err     error
+}
+
+func (c *collector) ingest(entry *internal.LogEntry) error {
+	if c.err != nil {
+		return c.err
+	}
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+func newRouter(c *collector) *mux.Router {
+	r := mux.NewRouter()
+	otlp.NewAPI(c.ingest).Register(r)
+	return r
+}
+
+func post(r http.Handler, contentType string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/v1/logs", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestExport_Protobuf(t *testing.T) {
+	c := &collector{}
+	body, err := proto.Marshal(sampleRequest(
+		&logspb.LogRecord{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO, Body: str("first")},
+		&logspb.LogRecord{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_WARN, Body: str("second")},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := post(newRouter(c), "application/x-protobuf", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-protobuf" {
+		t.Errorf("expected protobuf response, got %q", ct)
+	}
+
+	var resp collogspb.ExportLogsServiceResponse
+	if err := proto.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.GetPartialSuccess() != nil {
+		t.Errorf("expected no partial success, got %v", resp.GetPartialSuccess())
+	}
+	if len(c.entries) != 2 || c.entries[1].Level != "WARN" {
+		t.Errorf("unexpected stored entries %+v", c.entries)
+	}
+}
+
+func TestExport_JSONWithHexIDs(t *testing.T) {
+	c := &collector{}
+	body := `{
+		"resourceLogs": [{
+			"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "checkout"}}]},
+			"scopeLogs": [{
+				"logRecords": [{
+					"timeUnixNano": "1754489520000000000",
+					"severityNumber": 17,
+					"body": {"stringValue": "payment declined"},
+					"traceId": "5b8efff798038103d269b633813fc60c",
+					"spanId": "eee19b7ec3c1b174"
+				}]
+			}]
+		}]
+	}`
+
+	w := post(newRouter(c), "application/json", []byte(body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(c.entries) != 1 {
+		t.Fatalf("expected 1 stored entry, got %d", len(c.entries))
+	}
+	entry := c.entries[0]
+	if entry.Level != "ERROR" || entry.Service != "checkout" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Context["trace_id"] != "5b8efff798038103d269b633813fc60c" {
+		t.Errorf("expected hex trace id preserved, got %v", entry.Context["trace_id"])
+	}
+}
+
+func TestExport_PartialSuccess(t *testing.T) {
+	c := &collector{}
+	body, _ := protojson.Marshal(sampleRequest(
+		&logspb.LogRecord{Body: str("kept")},
+		&logspb.LogRecord{Body: str("")},
+		&logspb.LogRecord{Body: str(strings.Repeat("x", internal.MaxMessageLength+1))},
+	))
+
+	w := post(newRouter(c), "application/json", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var resp collogspb.ExportLogsServiceResponse
+	if err := protojson.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.GetPartialSuccess().GetRejectedLogRecords() != 2 {
+		t.Errorf("expected 2 rejected records, got %v", resp.GetPartialSuccess())
+	}
+	if resp.GetPartialSuccess().GetErrorMessage() == "" {
+		t.Error("expected an error message in partial success")
+	}
+	if len(c.entries) != 1 {
+		t.Errorf("expected 1 stored entry, got %d", len(c.entries))
+	}
+}
+
+func TestExport_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		err         error
+		wantStatus  int
+	}{
+		{"unsupported content type", "text/plain", []byte("hello"), nil, http.StatusUnsupportedMediaType},
+		{"invalid protobuf", "application/x-protobuf", []byte{0xff, 0xff}, nil, http.StatusBadRequest},
+		{"invalid JSON", "application/json", []byte("{"), nil, http.StatusBadRequest},
+		{"invalid hex trace id", "application/json", []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"zz"}]}]}]}`), nil, http.StatusBadRequest},
+		{"write failure", "application/json", []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"stringValue":"x"}}]}]}]}`), fmt.Errorf("%w: disk full", handler.ErrWriteFailed), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(newRouter(&collector{err: tt.err}), tt.contentType, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d", tt.wantStatus, w.Code)
+			}
+
+			var status statuspb.Status
+			if strings.HasPrefix(w.Header().Get("Content-Type"), "application/x-protobuf") {
+				if err := proto.Unmarshal(w.Body.Bytes(), &status); err != nil {
+					t.Fatalf("expected protobuf Status body: %v", err)
+				}
+			} else if err := protojson.Unmarshal(w.Body.Bytes(), &status); err != nil {
+				t.Fatalf("expected JSON Status body: %v", err)
+			}
+			if status.GetMessage() == "" {
+				t.Error("expected a status message")
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+)
+
+// Attributs de ressource promus en champs de LogEntry (conventions sémantiques OTel)
+const (
+	AttrServiceName = "service.name"
+	AttrHostName    = "host.name"
+	AttrRecordUID   = "log.record.uid"
+)
+
+// Clés de corps structuré reconnues comme message
+var messageKeys = []string{"message", "msg"}
+
+// Alias de SeverityText courants qui ne sont pas des niveaux log_levels
+var severityTextAliases = map[string]log_levels.LogLevel{
+	"WARNING":  log_levels.LogLevelWarn,
+	"ERR":      log_levels.LogLevelError,
+	"CRITICAL": log_levels.LogLevelFatal,
+	"PANIC":    log_levels.LogLevelFatal,
+}
+
+// SeverityToLogLevel convertit la sévérité OTLP en niveau log_levels. SeverityNumber est
+// prioritaire (plages de 4 : TRACE 1-4, DEBUG 5-8, INFO 9-12, WARN 13-16, ERROR 17-20,
+// FATAL 21-24); à défaut on interprète SeverityText, sinon INFO.
+func SeverityToLogLevel(number logspb.SeverityNumber, text string) log_levels.LogLevel {
+	switch {
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_FATAL:
+		return log_levels.LogLevelFatal
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return log_levels.LogLevelError
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return log_levels.LogLevelWarn
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return log_levels.LogLevelInfo
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG:
+		return log_levels.LogLevelDebug
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_TRACE:
+		return log_levels.LogLevelTrace
+	}
+
+	text = strings.ToUpper(strings.TrimSpace(text))
+	if log_levels.IsValidLogLevel(text) {
+		return log_levels.NormalizeLogLevel(text)
+	}
+	if level, ok := severityTextAliases[text]; ok {
+		return level
+	}
+	return log_levels.LogLevel(internal.DefaultLogLevel)
+}
+
+// ToLogEntries aplatit une requête d'export en entrées, dans l'ordre des LogRecords
+func ToLogEntries(req *collogspb.ExportLogsServiceRequest) []*internal.LogEntry {
+	var entries []*internal.LogEntry
+	for _, rl := range req.GetResourceLogs() {
+		resource := attributesToMap(rl.GetResource().GetAttributes())
+		service, _ := resource[AttrServiceName].(string)
+		host, _ := resource[AttrHostName].(string)
+
+		for _, sl := range rl.GetScopeLogs() {
+			for _, record := range sl.GetLogRecords() {
+				entry := RecordToLogEntry(record)
+				entry.Service = service
+				entry.Host = host
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// RecordToLogEntry convertit un LogRecord : le corps devient le message, les attributs et
+// les identifiants de trace/span vont dans le contexte.
+func RecordToLogEntry(record *logspb.LogRecord) *internal.LogEntry {
+	entry := &internal.LogEntry{
+		Level:   string(SeverityToLogLevel(record.GetSeverityNumber(), record.GetSeverityText())),
+		Context: attributesToMap(record.GetAttributes()),
+	}
+
+	switch {
+	case record.GetTimeUnixNano() != 0:
+		entry.Timestamp = time.Unix(0, int64(record.GetTimeUnixNano())).UTC()
+	case record.GetObservedTimeUnixNano() != 0:
+		entry.Timestamp = time.Unix(0, int64(record.GetObservedTimeUnixNano())).UTC()
+	}
+
+	body := anyValue(record.GetBody())
+	switch b := body.(type) {
+	case string:
+		entry.Message = b
+	case map[string]interface{}:
+		// Corps structuré : le champ message éventuel devient le message, le reste le contexte
+		for _, key := range messageKeys {
+			if msg, ok := b[key].(string); ok {
+				entry.Message = msg
+				delete(b, key)
+				break
+			}
+		}
+		for k, v := range b {
+			entry.Context[k] = v
+		}
+		if entry.Message == "" {
+			raw, _ := json.Marshal(b)
+			entry.Message = string(raw)
+		}
+	case nil:
+	default:
+		raw, _ := json.Marshal(b)
+		entry.Message = string(raw)
+	}
+
+	// Attribut sémantique OpenTelemetry identifiant l'enregistrement : sert à l'idempotence
+	if uid, ok := entry.Context[AttrRecordUID].(string); ok {
+		entry.ID = uid
+		delete(entry.Context, AttrRecordUID)
+	}
+
+	if traceID := record.GetTraceId(); len(traceID) > 0 {
+		entry.Context["trace_id"] = hex.EncodeToString(traceID)
+	}
+	if spanID := record.GetSpanId(); len(spanID) > 0 {
+		entry.Context["span_id"] = hex.EncodeToString(spanID)
+	}
+
+	if len(entry.Context) == 0 {
+		entry.Context = nil
+	}
+	return entry
+}
+
+func attributesToMap(attrs []*commonpb.KeyValue) map[string]interface{} {
+	m := make(map[string]interface{}, len(attrs))
+	for _, kv := range attrs {
+		m[kv.GetKey()] = anyValue(kv.GetValue())
+	}
+	return m
+}
+
+// anyValue convertit un AnyValue OTLP en valeur sérialisable en JSON
+func anyValue(v *commonpb.AnyValue) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return val.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return val.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return val.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(val.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := val.ArrayValue.GetValues()
+		out := make([]interface{}, len(values))
+		for i, item := range values {
+			out[i] = anyValue(item)
+		}
+		return out
+	case *commonpb.AnyValue_KvlistValue:
+		return attributesToMap(val.KvlistValue.GetValues())
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,158 @@
+package otlp_test
+
+import (
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/rypi-dev/logger-server/internal/otlp"
+)
+
+var recordTime = time.Date(2025, 8, 6, 14, 12, 0, 123456789, time.UTC)
+
+func str(v string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+}
+
+func kv(key string, v *commonpb.AnyValue) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: v}
+}
+
+// sampleRequest construit une requête d'export avec une ressource et les records donnés
+func sampleRequest(records ...*logspb.LogRecord) *collogspb.ExportLogsServiceRequest {
+	return &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				kv("service.name", str("checkout")),
+				kv("host.name", str("web-1")),
+			}},
+			ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}},
+		}},
+	}
+}
+
+func TestSeverityToLogLevel(t *testing.T) {
+	tests := []struct {
+		number logspb.SeverityNumber
+		text   string
+		want   string
+	}{
+		{logspb.SeverityNumber_SEVERITY_NUMBER_TRACE2, "", "TRACE"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "", "DEBUG"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_INFO4, "", "INFO"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_WARN, "ERROR", "WARN"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_ERROR3, "", "ERROR"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4, "", "FATAL"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "warning", "WARN"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "error", "ERROR"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "", "INFO"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "notice", "INFO"},
+	}
+
+	for _, tt := range tests {
+		if got := otlp.SeverityToLogLevel(tt.number, tt.text); string(got) != tt.want {
+			t.Errorf("SeverityToLogLevel(%v, %q) = %s, want %s", tt.number, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestToLogEntries(t *testing.T) {
+	req := sampleRequest(&logspb.LogRecord{
+		TimeUnixNano:   uint64(recordTime.UnixNano()),
+		SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+		Body:           str("payment declined"),
+		Attributes:     []*commonpb.KeyValue{kv("order_id", &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 42}})},
+		TraceId:        []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+		SpanId:         []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+	})
+
+	entries := otlp.ToLogEntries(req)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	entry := entries[0]
+
+	if entry.Message != "payment declined" || entry.Level != "ERROR" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Service != "checkout" || entry.Host != "web-1" {
+		t.Errorf("expected resource attributes as fields, got service=%q host=%q", entry.Service, entry.Host)
+	}
+	if !entry.Timestamp.Equal(recordTime) {
+		t.Errorf("expected timestamp %v, got %v", recordTime, entry.Timestamp)
+	}
+	if entry.Context["trace_id"] != "5b8efff798038103d269b633813fc60c" || entry.Context["span_id"] != "eee19b7ec3c1b174" {
+		t.Errorf("unexpected trace context %v", entry.Context)
+	}
+	if entry.Context["order_id"] != int64(42) {
+		t.Errorf("expected attribute in context, got %v", entry.Context)
+	}
+	if err := entry.Validate(); err != nil {
+		t.Errorf("expected valid entry, got %v", err)
+	}
+}
+
+func TestRecordToLogEntry_StructuredBody(t *testing.T) {
+	record := &logspb.LogRecord{
+		ObservedTimeUnixNano: uint64(recordTime.UnixNano()),
+		SeverityText:         "INFO",
+		Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+			Values: []*commonpb.KeyValue{
+				kv("msg", str("user signed in")),
+				kv("user", str("alice")),
+			},
+		}}},
+	}
+
+	entry := otlp.RecordToLogEntry(record)
+	if entry.Message != "user signed in" {
+		t.Errorf("expected message from body, got %q", entry.Message)
+	}
+	if entry.Context["user"] != "alice" {
+		t.Errorf("expected body fields in context, got %v", entry.Context)
+	}
+	if _, ok := entry.Context["msg"]; ok {
+		t.Error("expected message key not to be duplicated in context")
+	}
+	if !entry.Timestamp.Equal(recordTime) {
+		t.Errorf("expected observed time as fallback, got %v", entry.Timestamp)
+	}
+}
+
+func TestRecordToLogEntry_NonStringBody(t *testing.T) {
+	record := &logspb.LogRecord{
+		Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+			Values: []*commonpb.AnyValue{str("a"), {Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}},
+		}}},
+	}
+
+	entry := otlp.RecordToLogEntry(record)
+	if entry.Message != `["a",true]` {
+		t.Errorf("expected JSON-encoded body, got %q", entry.Message)
+	}
+	if entry.Context != nil {
+		t.Errorf("expected nil context, got %v", entry.Context)
+	}
+	if !entry.Timestamp.IsZero() {
+		t.Errorf("expected zero timestamp to be filled at ingestion, got %v", entry.Timestamp)
+	}
+}
+
+func TestRecordToLogEntry_RecordUID(t *testing.T) {
+	record := &logspb.LogRecord{
+		Body:       str("retried"),
+		Attributes: []*commonpb.KeyValue{kv("log.record.uid", str("01J4Z3K8")), kv("user", str("bob"))},
+	}
+
+	entry := otlp.RecordToLogEntry(record)
+	if entry.ID != "01J4Z3K8" {
+		t.Errorf("expected log.record.uid as ID, got %q", entry.ID)
+	}
+	if _, ok := entry.Context["log.record.uid"]; ok || entry.Context["user"] != "bob" {
+		t.Errorf("expected uid to be moved out of the context, got %v", entry.Context)
+	}
+}
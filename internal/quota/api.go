@@ -0,0 +1,183 @@
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// Store est implémenté par SQLiteStore
+type Store interface {
+	Check(tenant, keyName string) (*Breach, []Breach, error)
+	Usage(subject Subject) (*Usage, error)
+	List() ([]Usage, error)
+	SetLimits(subject Subject, l Limits) (*Usage, error)
+	DeleteLimits(subject Subject) error
+	Report(period string) ([]PeriodUsage, error)
+}
+
+// API expose les quotas (scope admin sous /admin/) et la consommation de l'appelant (GET /usage)
+type API struct {
+	store Store
+	now   func() time.Time
+}
+
+func NewAPI(store Store) *API {
+	return &API{store: store, now: time.Now}
+}
+
+// Register ajoute les routes /admin/quotas, /admin/usage et /usage au routeur. Une clé est
+// nommée par son tenant (/admin/quotas/key/payments/agent), sauf une clé du tenant par
+// défaut (/admin/quotas/key/agent).
+func (a *API) Register(r *mux.Router) {
+	r.HandleFunc("/admin/quotas", a.handleList).Methods("GET")
+	for _, path := range []string{"/admin/quotas/{kind:tenant|key}/{name}", "/admin/quotas/{kind:key}/{tenant}/{name}"} {
+		r.HandleFunc(path, a.handleSet).Methods("PUT")
+		r.HandleFunc(path, a.handleDelete).Methods("DELETE")
+	}
+	r.HandleFunc("/admin/usage", a.handleReport).Methods("GET")
+	r.HandleFunc("/usage", a.handleUsage).Methods("GET")
+}
+
+func (a *API) handleList(w http.ResponseWriter, r *http.Request) {
+	list, err := a.store.List()
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "failed to list quotas")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (a *API) handleSet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subject, err := NewSubject(vars["kind"], vars["tenant"], vars["name"])
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var limits Limits
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&limits); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	u, err := a.store.SetLimits(subject, limits)
+	if err != nil {
+		writeStoreError(w, err, "failed to set quota")
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (a *API) handleDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subject, err := NewSubject(vars["kind"], vars["tenant"], vars["name"])
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := a.store.DeleteLimits(subject); err != nil {
+		writeStoreError(w, err, "failed to delete quota")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReport retourne la consommation de tous les tenants et clés sur une période
+// (?period=2025-09 ou 2025-09-01, le mois en cours par défaut), pour la refacturation
+func (a *API) handleReport(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		_, period = periods(a.now())
+	}
+
+	report, err := a.store.Report(period)
+	if err != nil {
+		writeStoreError(w, err, "failed to read usage")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleUsage retourne la consommation courante du tenant et de la clé de l'appelant
+func (a *API) handleUsage(w http.ResponseWriter, r *http.Request) {
+	var tenant, keyName string
+	if key := apikey.FromContext(r.Context()); key != nil {
+		tenant, keyName = key.Tenant, key.Name
+	}
+
+	out := []Usage{}
+	for _, subject := range Subjects(tenant, keyName) {
+		u, err := a.store.Usage(subject)
+		if err != nil {
+			utils.WriteJSONError(w, http.StatusInternalServerError, "failed to read usage")
+			return
+		}
+		out = append(out, *u)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// Middleware refuse (429) les écritures d'un tenant ou d'une clé ayant atteint un quota, et
+// signale les seuils d'avertissement franchis dans l'en-tête X-Quota-Warning. Il se place
+// après l'authentification, qui fournit la clé; une entrée qui dépasse le quota en cours de
+// requête est refusée individuellement par Reserve.
+func Middleware(store Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := apikey.FromContext(r.Context())
+			if key == nil || r.Method == http.MethodGet || r.Method == http.MethodHead ||
+				apikey.ScopeFor(r.Method, r.URL.Path) != apikey.ScopeIngest {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Une erreur de la base n'arrête pas l'ingestion : Reserve la signalera
+			hard, soft, err := store.Check(key.Tenant, key.Name)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, b := range soft {
+				w.Header().Add(HeaderWarning, b.String())
+			}
+			if hard != nil {
+				retry := int(time.Until(hard.Reset).Seconds()) + 1
+				if retry < 1 {
+					retry = 1
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(retry))
+				utils.WriteJSONError(w, http.StatusTooManyRequests, fmt.Sprintf("%v: %s", ErrExceeded, hard))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeStoreError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		utils.WriteJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInvalidLimits), errors.Is(err, ErrInvalidSubject), errors.Is(err, ErrInvalidPeriod):
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+	default:
+		utils.WriteJSONError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,136 @@
+package quota
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/apikey"
+)
+
+func do(h http.Handler, method, path, body string, key *apikey.Key) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if key != nil {
+		req = req.WithContext(apikey.WithKey(req.Context(), key))
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPI(t *testing.T) {
+	s, _, now := newTestStore(t)
+	a := NewAPI(s)
+	a.now = func() time.Time { return *now }
+	r := mux.NewRouter()
+	a.Register(r)
+
+	w := do(r, "PUT", "/admin/quotas/tenant/payments", `{"daily_entries":100,"monthly_bytes":1000000}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var set Usage
+	json.NewDecoder(w.Body).Decode(&set)
+	if set.Subject != "tenant/payments" || set.Limits == nil || set.Limits.DailyEntries != 100 {
+		t.Errorf("unexpected quota: %+v", set)
+	}
+
+	for _, tt := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{"PUT", "/admin/quotas/key/agent", `not json`, http.StatusBadRequest},
+		{"PUT", "/admin/quotas/key/agent", `{"soft_percent":150}`, http.StatusBadRequest},
+		{"PUT", "/admin/quotas/service/web", `{}`, http.StatusNotFound},
+		{"DELETE", "/admin/quotas/key/agent", ``, http.StatusNotFound},
+		{"PUT", "/admin/quotas/tenant/payments/agent", `{}`, http.StatusNotFound},
+		{"GET", "/admin/usage?period=last-month", ``, http.StatusBadRequest},
+	} {
+		if w := do(r, tt.method, tt.path, tt.body, nil); w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, w.Code)
+		}
+	}
+
+	// Une clé est nommée par son tenant; sans tenant, c'est une clé du tenant par défaut
+	w = do(r, "PUT", "/admin/quotas/key/payments/agent", `{"daily_entries":10}`, nil)
+	json.NewDecoder(w.Body).Decode(&set)
+	if w.Code != http.StatusOK || set.Subject != "key/payments/agent" {
+		t.Errorf("expected the quota of key agent of tenant payments, got %d %+v", w.Code, set)
+	}
+	if w := do(r, "DELETE", "/admin/quotas/key/agent", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected no quota for key agent of the default tenant, got %d", w.Code)
+	}
+	if w := do(r, "DELETE", "/admin/quotas/key/payments/agent", "", nil); w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+
+	s.Reserve(entry("payments", "agent"))
+
+	w = do(r, "GET", "/admin/quotas", "", nil)
+	var list []Usage
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list) != 1 || list[0].Daily.Entries != 1 {
+		t.Errorf("expected the quota with its usage, got %+v", list)
+	}
+
+	w = do(r, "GET", "/admin/usage", "", nil)
+	var report []PeriodUsage
+	json.NewDecoder(w.Body).Decode(&report)
+	if w.Code != http.StatusOK || len(report) != 2 || report[0].Period != "2025-09" {
+		t.Errorf("expected the current month report, got %d %+v", w.Code, report)
+	}
+
+	w = do(r, "GET", "/usage", "", &apikey.Key{Name: "agent", Tenant: "payments"})
+	var own []Usage
+	json.NewDecoder(w.Body).Decode(&own)
+	if len(own) != 2 || own[0].Subject != "tenant/payments" || own[0].Limits == nil || own[1].Subject != "key/payments/agent" {
+		t.Errorf("expected the caller's tenant and key usage, got %+v", own)
+	}
+	if w := do(r, "GET", "/usage", "", nil); w.Body.String() != "[]\n" {
+		t.Errorf("expected no usage without a key, got %s", w.Body.String())
+	}
+
+	if w := do(r, "DELETE", "/admin/quotas/tenant/payments", "", nil); w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	s, _, _ := newTestStore(t)
+	s.SetLimits("key//limited", Limits{DailyEntries: 2, SoftPercent: 50})
+	h := Middleware(s)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	key := &apikey.Key{Name: "limited", Scopes: []string{apikey.ScopeIngest}}
+
+	if w := do(h, "POST", "/log", "", key); w.Code != http.StatusCreated || w.Header().Get(HeaderWarning) != "" {
+		t.Errorf("expected the write to pass without warning, got %d %q", w.Code, w.Header().Get(HeaderWarning))
+	}
+
+	s.Reserve(entry("", "limited"))
+	w := do(h, "POST", "/log/batch", "", key)
+	if w.Code != http.StatusCreated || w.Header().Get(HeaderWarning) != "key//limited daily_entries 1/2" {
+		t.Errorf("expected a soft warning, got %d %q", w.Code, w.Header().Get(HeaderWarning))
+	}
+
+	s.Reserve(entry("", "limited"))
+	w = do(h, "POST", "/loki/api/v1/push", "", key)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 429 with Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Les lectures et les routes d'administration ne sont pas concernées
+	for _, tt := range []struct{ method, path string }{{"GET", "/log"}, {"GET", "/usage"}, {"POST", "/admin/keys"}} {
+		if w := do(h, tt.method, tt.path, "", key); w.Code != http.StatusCreated {
+			t.Errorf("%s %s: expected the request to pass, got %d", tt.method, tt.path, w.Code)
+		}
+	}
+	if w := do(h, "POST", "/log", "", nil); w.Code != http.StatusCreated {
+		t.Errorf("expected unauthenticated requests to pass, got %d", w.Code)
+	}
+}
@@ -0,0 +1,207 @@
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+var (
+	ErrExceeded       = errors.New("quota exceeded")
+	ErrInvalidLimits  = errors.New("invalid quota limits")
+	ErrInvalidSubject = errors.New("invalid quota subject")
+	ErrInvalidPeriod  = errors.New("invalid period")
+	ErrNotFound       = errors.New("quota not found")
+)
+
+// Sujets d'un quota : un tenant ou une clé API d'un tenant (par nom : une clé tournée garde
+// son quota)
+const (
+	KindTenant = "tenant"
+	KindKey    = "key"
+)
+
+// Fenêtres des quotas, en UTC
+const (
+	Daily   = "daily"
+	Monthly = "monthly"
+)
+
+// DefaultSoftPercent est le seuil d'avertissement, en pourcentage du quota, si Limits ne
+// le précise pas
+const DefaultSoftPercent = 80
+
+// HeaderWarning signale les seuils d'avertissement atteints dans la réponse à une écriture
+const HeaderWarning = "X-Quota-Warning"
+
+// Formats des périodes de comptage (jour et mois UTC)
+const (
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+)
+
+// Subject identifie le tenant ou la clé dont la consommation est comptée : "tenant/payments",
+// "key/payments/fluent-bit-web". Deux tenants peuvent avoir une clé du même nom; une clé du
+// tenant par défaut a un tenant vide ("key//bootstrap").
+type Subject string
+
+// NewSubject retourne le sujet d'un tenant (tenant vide) ou d'une clé du tenant donné
+func NewSubject(kind, tenant, name string) (Subject, error) {
+	if (kind != KindTenant && kind != KindKey) || (kind == KindTenant && tenant != "") ||
+		name == "" || strings.Contains(name, "/") || strings.Contains(tenant, "/") {
+		return "", fmt.Errorf("%w: %s/%s/%s", ErrInvalidSubject, kind, tenant, name)
+	}
+	if kind == KindTenant {
+		return Subject(KindTenant + "/" + name), nil
+	}
+	return keySubject(tenant, name), nil
+}
+
+func keySubject(tenant, name string) Subject {
+	return Subject(KindKey + "/" + tenant + "/" + name)
+}
+
+// Tenant retourne le tenant du sujet ("" : tenant par défaut)
+func (s Subject) Tenant() string {
+	kind, rest, _ := strings.Cut(string(s), "/")
+	if kind == KindTenant {
+		return rest
+	}
+	tenant, _, _ := strings.Cut(rest, "/")
+	return tenant
+}
+
+// Subjects retourne les sujets auxquels est imputée une écriture : le tenant (hors tenant
+// par défaut) et la clé. Une entrée non authentifiée (syslog, ...) n'est pas comptée.
+func Subjects(tenant, keyName string) []Subject {
+	var out []Subject
+	if tenant != "" {
+		out = append(out, Subject(KindTenant+"/"+tenant))
+	}
+	if keyName != "" {
+		out = append(out, keySubject(tenant, keyName))
+	}
+	return out
+}
+
+// Limits sont les quotas d'un sujet; zéro désactive la limite. Au-delà d'un quota les
+// écritures sont refusées (429), à partir de SoftPercent elles sont signalées.
+type Limits struct {
+	DailyEntries   int64 `json:"daily_entries,omitempty" example:"1000000"`
+	DailyBytes     int64 `json:"daily_bytes,omitempty" example:"1073741824"`
+	MonthlyEntries int64 `json:"monthly_entries,omitempty" example:"20000000"`
+	MonthlyBytes   int64 `json:"monthly_bytes,omitempty" example:"21474836480"`
+	SoftPercent    int   `json:"soft_percent,omitempty" example:"80"`
+}
+
+// Validate vérifie les quotas
+func (l Limits) Validate() error {
+	if l.DailyEntries < 0 || l.DailyBytes < 0 || l.MonthlyEntries < 0 || l.MonthlyBytes < 0 {
+		return fmt.Errorf("%w: quotas must not be negative", ErrInvalidLimits)
+	}
+	if l.SoftPercent < 0 || l.SoftPercent > 100 {
+		return fmt.Errorf("%w: soft_percent must be between 0 and 100", ErrInvalidLimits)
+	}
+	return nil
+}
+
+func (l Limits) softPercent() int64 {
+	if l.SoftPercent == 0 {
+		return DefaultSoftPercent
+	}
+	return int64(l.SoftPercent)
+}
+
+// Counter est la consommation d'un sujet sur une période
+type Counter struct {
+	Entries int64 `json:"entries" example:"125000"`
+	Bytes   int64 `json:"bytes" example:"48000000"`
+}
+
+// Breach décrit un quota atteint (ou un seuil d'avertissement) par un sujet
+type Breach struct {
+	Subject   Subject
+	Dimension string // daily_entries, daily_bytes, monthly_entries ou monthly_bytes
+	Used      int64
+	Limit     int64
+	Reset     time.Time
+}
+
+func (b Breach) String() string {
+	return fmt.Sprintf("%s %s %d/%d", b.Subject, b.Dimension, b.Used, b.Limit)
+}
+
+// ExceededError est retournée par Reserve pour une entrée qui dépasserait un quota
+// (ErrExceeded); l'entrée peut être renvoyée après Breach.Reset
+type ExceededError struct {
+	Breach Breach
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%v: %s %s (%d/%d)", ErrExceeded, e.Breach.Subject, e.Breach.Dimension, e.Breach.Used, e.Breach.Limit)
+}
+
+func (e *ExceededError) Unwrap() error {
+	return ErrExceeded
+}
+
+type dimension struct {
+	name   string
+	period string
+	used   int64
+	add    int64
+	limit  int64
+	reset  time.Time
+}
+
+// dimensions associe la consommation du jour et du mois aux quotas, pour une écriture de
+// entries entrées et bytes octets (0, 0 : état courant)
+func (l Limits) dimensions(now time.Time, daily, monthly Counter, entries, bytes int64) []dimension {
+	day, month := periods(now)
+	dayReset, monthReset := resets(now)
+	return []dimension{
+		{"daily_entries", day, daily.Entries, entries, l.DailyEntries, dayReset},
+		{"daily_bytes", day, daily.Bytes, bytes, l.DailyBytes, dayReset},
+		{"monthly_entries", month, monthly.Entries, entries, l.MonthlyEntries, monthReset},
+		{"monthly_bytes", month, monthly.Bytes, bytes, l.MonthlyBytes, monthReset},
+	}
+}
+
+// periods retourne les périodes de comptage de l'instant donné
+func periods(now time.Time) (day, month string) {
+	now = now.UTC()
+	return now.Format(dayLayout), now.Format(monthLayout)
+}
+
+// resets retourne les débuts du jour et du mois suivants, où les compteurs repartent de zéro
+func resets(now time.Time) (day, month time.Time) {
+	now = now.UTC()
+	y, m, d := now.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC), time.Date(y, m+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ValidatePeriod vérifie une période de comptage : un jour (2025-09-01) ou un mois (2025-09)
+func ValidatePeriod(period string) error {
+	if _, err := time.Parse(dayLayout, period); err == nil {
+		return nil
+	}
+	if _, err := time.Parse(monthLayout, period); err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %q (expected YYYY-MM-DD or YYYY-MM)", ErrInvalidPeriod, period)
+}
+
+// Size est la taille imputée à une entrée : ses champs texte et son contexte encodé
+func Size(entry *internal.LogEntry) int64 {
+	n := len(entry.ID) + len(entry.Level) + len(entry.Message) + len(entry.Service) + len(entry.Host)
+	if len(entry.Context) > 0 {
+		if ctx, err := utils.MarshalContext(entry.Context); err == nil {
+			n += len(ctx)
+		}
+	}
+	return int64(n)
+}
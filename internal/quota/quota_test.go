@@ -0,0 +1,98 @@
+package quota
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+)
+
+func TestSubjects(t *testing.T) {
+	got := Subjects("payments", "fluent-bit")
+	if len(got) != 2 || got[0] != "tenant/payments" || got[1] != "key/payments/fluent-bit" {
+		t.Errorf("unexpected subjects: %v", got)
+	}
+	if got := Subjects("", "bootstrap"); len(got) != 1 || got[0] != "key//bootstrap" {
+		t.Errorf("expected only the key for the default tenant, got %v", got)
+	}
+	if got := Subjects("", ""); len(got) != 0 {
+		t.Errorf("expected unauthenticated entries not to be counted, got %v", got)
+	}
+
+	if s, err := NewSubject(KindTenant, "", "payments"); err != nil || s != "tenant/payments" {
+		t.Errorf("unexpected subject %q, %v", s, err)
+	}
+	// Deux tenants peuvent avoir une clé du même nom
+	if s, err := NewSubject(KindKey, "payments", "agent"); err != nil || s != "key/payments/agent" {
+		t.Errorf("unexpected subject %q, %v", s, err)
+	}
+	if s, err := NewSubject(KindKey, "", "agent"); err != nil || s != "key//agent" {
+		t.Errorf("unexpected subject %q, %v", s, err)
+	}
+	for _, kind := range []string{"service", ""} {
+		if _, err := NewSubject(kind, "", "payments"); !errors.Is(err, ErrInvalidSubject) {
+			t.Errorf("expected ErrInvalidSubject for kind %q, got %v", kind, err)
+		}
+	}
+	for _, bad := range [][2]string{{"", ""}, {"payments", "a/b"}, {"a/b", "agent"}} {
+		if _, err := NewSubject(KindKey, bad[0], bad[1]); !errors.Is(err, ErrInvalidSubject) {
+			t.Errorf("expected ErrInvalidSubject for %v, got %v", bad, err)
+		}
+	}
+	if _, err := NewSubject(KindTenant, "payments", "agent"); !errors.Is(err, ErrInvalidSubject) {
+		t.Errorf("expected ErrInvalidSubject for a tenant of a tenant, got %v", err)
+	}
+
+	for subject, tenant := range map[Subject]string{"tenant/payments": "payments", "key/payments/agent": "payments", "key//agent": ""} {
+		if got := subject.Tenant(); got != tenant {
+			t.Errorf("expected tenant %q for %s, got %q", tenant, subject, got)
+		}
+	}
+}
+
+func TestLimits_Validate(t *testing.T) {
+	if err := (Limits{DailyEntries: 10, MonthlyBytes: 1 << 30, SoftPercent: 90}).Validate(); err != nil {
+		t.Errorf("expected valid limits, got %v", err)
+	}
+	for _, bad := range []Limits{{DailyBytes: -1}, {SoftPercent: 101}, {SoftPercent: -5}} {
+		if err := bad.Validate(); !errors.Is(err, ErrInvalidLimits) {
+			t.Errorf("expected ErrInvalidLimits for %+v, got %v", bad, err)
+		}
+	}
+}
+
+func TestValidatePeriod(t *testing.T) {
+	for _, p := range []string{"2025-09", "2025-09-01"} {
+		if err := ValidatePeriod(p); err != nil {
+			t.Errorf("expected %q to be valid, got %v", p, err)
+		}
+	}
+	for _, p := range []string{"", "2025", "2025-13", "09-2025", "2025-09-01T00:00:00Z"} {
+		if err := ValidatePeriod(p); !errors.Is(err, ErrInvalidPeriod) {
+			t.Errorf("expected ErrInvalidPeriod for %q, got %v", p, err)
+		}
+	}
+}
+
+func TestResets(t *testing.T) {
+	day, month := resets(time.Date(2025, 12, 31, 23, 0, 0, 0, time.FixedZone("CET", 3600)))
+	if !day.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected day reset %v", day)
+	}
+	// 23h CET est encore le 31 décembre en UTC
+	if !month.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected month reset %v", month)
+	}
+}
+
+func TestSize(t *testing.T) {
+	entry := &internal.LogEntry{Level: "INFO", Message: "hello", Service: "web"}
+	if n := Size(entry); n != 12 {
+		t.Errorf("expected 12 bytes, got %d", n)
+	}
+	entry.Context = map[string]interface{}{"a": 1}
+	if n := Size(entry); n != int64(12+len(`{"a":1}`)) {
+		t.Errorf("expected the encoded context to be counted, got %d", n)
+	}
+}
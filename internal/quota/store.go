@@ -0,0 +1,534 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rypi-dev/logger-server/internal"
+)
+
+// DefaultFlushInterval est la fréquence d'écriture des compteurs en base : un arrêt brutal
+// perd au plus la consommation de cet intervalle
+const DefaultFlushInterval = 10 * time.Second
+
+var (
+	usedEntries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quota_used_entries",
+		Help: "Log entries written by a tenant or API key in the current day or month",
+	}, []string{"subject", "window"})
+	usedBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quota_used_bytes",
+		Help: "Bytes written by a tenant or API key in the current day or month",
+	}, []string{"subject", "window"})
+	rejectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "quota_rejected_entries_total",
+		Help: "Total number of log entries rejected because a quota was exceeded",
+	}, []string{"subject"})
+	warningsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "quota_soft_limit_reached_total",
+		Help: "Total number of soft quota thresholds reached",
+	}, []string{"subject", "dimension"})
+)
+
+func init() {
+	prometheus.MustRegister(usedEntries, usedBytes, rejectedTotal, warningsTotal)
+}
+
+type usageKey struct {
+	subject Subject
+	period  string
+}
+
+type warnKey struct {
+	subject   Subject
+	dimension string
+	period    string
+}
+
+// SQLiteStore conserve les quotas (table quota_limits) et la consommation par jour et par
+// mois (table quota_usage, gardée pour la refacturation). Les compteurs des périodes en
+// cours sont tenus en mémoire et écrits en base par Flush.
+type SQLiteStore struct {
+	db  *sql.DB
+	now func() time.Time
+
+	mu     sync.Mutex
+	limits map[Subject]Limits
+	usage  map[usageKey]*Counter
+	dirty  map[usageKey]bool
+	warned map[warnKey]bool
+}
+
+// NewSQLiteStore ouvre (ou crée) les tables des quotas dans la base SQLite donnée.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS quota_limits (
+		subject TEXT PRIMARY KEY,
+		daily_entries INTEGER NOT NULL DEFAULT 0,
+		daily_bytes INTEGER NOT NULL DEFAULT 0,
+		monthly_entries INTEGER NOT NULL DEFAULT 0,
+		monthly_bytes INTEGER NOT NULL DEFAULT 0,
+		soft_percent INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE IF NOT EXISTS quota_usage (
+		subject TEXT NOT NULL,
+		period TEXT NOT NULL,
+		entries INTEGER NOT NULL DEFAULT 0,
+		bytes INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (subject, period)
+	) WITHOUT ROWID;
+	`); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &SQLiteStore{
+		db:     db,
+		now:    time.Now,
+		limits: make(map[Subject]Limits),
+		usage:  make(map[usageKey]*Counter),
+		dirty:  make(map[usageKey]bool),
+		warned: make(map[warnKey]bool),
+	}
+	if err := s.loadLimits(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLiteStore) loadLimits() error {
+	rows, err := s.db.Query(`SELECT subject, daily_entries, daily_bytes, monthly_entries, monthly_bytes, soft_percent FROM quota_limits`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subject Subject
+		var l Limits
+		if err := rows.Scan(&subject, &l.DailyEntries, &l.DailyBytes, &l.MonthlyEntries, &l.MonthlyBytes, &l.SoftPercent); err != nil {
+			return err
+		}
+		s.limits[subject] = l
+	}
+	return rows.Err()
+}
+
+// counter retourne le compteur d'une période, chargé depuis la base au premier accès
+// (appelé avec s.mu verrouillé)
+func (s *SQLiteStore) counter(subject Subject, period string) (*Counter, error) {
+	key := usageKey{subject, period}
+	if c, ok := s.usage[key]; ok {
+		return c, nil
+	}
+	c := &Counter{}
+	err := s.db.QueryRow(`SELECT entries, bytes FROM quota_usage WHERE subject = ? AND period = ?`, subject, period).
+		Scan(&c.Entries, &c.Bytes)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	s.usage[key] = c
+	return c, nil
+}
+
+// counters retourne les compteurs du jour et du mois d'un sujet (s.mu verrouillé)
+func (s *SQLiteStore) counters(subject Subject, now time.Time) (daily, monthly *Counter, err error) {
+	day, month := periods(now)
+	if daily, err = s.counter(subject, day); err != nil {
+		return nil, nil, err
+	}
+	if monthly, err = s.counter(subject, month); err != nil {
+		return nil, nil, err
+	}
+	return daily, monthly, nil
+}
+
+// Reservation est l'imputation d'une entrée par Reserve. Elle garde les périodes débitées :
+// Release les recrédite même si le jour ou le mois a changé entre-temps.
+type Reservation struct {
+	subjects   []Subject
+	day, month string
+	size       int64
+}
+
+// Reserve impute une entrée aux quotas de son tenant et de sa clé. Si l'un d'eux serait
+// dépassé, rien n'est imputé et l'erreur enveloppe ErrExceeded; les autres erreurs viennent
+// de la base. Une entrée non authentifiée n'est pas comptée (réservation nil).
+func (s *SQLiteStore) Reserve(entry *internal.LogEntry) (*Reservation, error) {
+	subjects := Subjects(entry.Tenant, entry.KeyName)
+	if len(subjects) == 0 {
+		return nil, nil
+	}
+	size := Size(entry)
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type reserved struct {
+		subject        Subject
+		daily, monthly *Counter
+	}
+	var all []reserved
+	for _, subject := range subjects {
+		daily, monthly, err := s.counters(subject, now)
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := s.limits[subject]; ok {
+			for _, d := range l.dimensions(now, *daily, *monthly, 1, size) {
+				if d.limit > 0 && d.used+d.add > d.limit {
+					rejectedTotal.WithLabelValues(string(subject)).Inc()
+					return nil, &ExceededError{Breach{Subject: subject, Dimension: d.name, Used: d.used, Limit: d.limit, Reset: d.reset}}
+				}
+			}
+		}
+		all = append(all, reserved{subject, daily, monthly})
+	}
+
+	day, month := periods(now)
+	for _, r := range all {
+		r.daily.Entries++
+		r.daily.Bytes += size
+		r.monthly.Entries++
+		r.monthly.Bytes += size
+		s.dirty[usageKey{r.subject, day}] = true
+		s.dirty[usageKey{r.subject, month}] = true
+		if l, ok := s.limits[r.subject]; ok {
+			s.warn(r.subject, l, now, *r.daily, *r.monthly)
+		}
+	}
+	return &Reservation{subjects: subjects, day: day, month: month, size: size}, nil
+}
+
+// warn signale une fois par période chaque seuil d'avertissement franchi (s.mu verrouillé)
+func (s *SQLiteStore) warn(subject Subject, l Limits, now time.Time, daily, monthly Counter) {
+	for _, d := range l.dimensions(now, daily, monthly, 0, 0) {
+		if d.limit <= 0 || d.used*100 < d.limit*l.softPercent() {
+			continue
+		}
+		key := warnKey{subject, d.name, d.period}
+		if s.warned[key] {
+			continue
+		}
+		s.warned[key] = true
+		warningsTotal.WithLabelValues(string(subject), d.name).Inc()
+		log.Printf("quota warning: %s reached %d%% of its %s quota (%d/%d)", subject, d.used*100/d.limit, d.name, d.used, d.limit)
+	}
+}
+
+// Release annule une réservation dont l'écriture a échoué, sur les périodes qu'elle a
+// débitées
+func (s *SQLiteStore) Release(r *Reservation) {
+	if r == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subject := range r.subjects {
+		for _, period := range []string{r.day, r.month} {
+			// Le compteur d'une période terminée a pu être écrit et oublié par Flush
+			c, err := s.counter(subject, period)
+			if err != nil {
+				log.Printf("quota: failed to release %s %s: %v", subject, period, err)
+				continue
+			}
+			if c.Entries > 0 {
+				c.Entries--
+				c.Bytes -= r.size
+				if c.Bytes < 0 {
+					c.Bytes = 0
+				}
+				s.dirty[usageKey{subject, period}] = true
+			}
+		}
+	}
+}
+
+// Check retourne le premier quota atteint par le tenant ou la clé, et les seuils
+// d'avertissement franchis
+func (s *SQLiteStore) Check(tenant, keyName string) (*Breach, []Breach, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var soft []Breach
+	for _, subject := range Subjects(tenant, keyName) {
+		l, ok := s.limits[subject]
+		if !ok {
+			continue
+		}
+		daily, monthly, err := s.counters(subject, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, d := range l.dimensions(now, *daily, *monthly, 0, 0) {
+			if d.limit <= 0 {
+				continue
+			}
+			b := Breach{Subject: subject, Dimension: d.name, Used: d.used, Limit: d.limit, Reset: d.reset}
+			if d.used >= d.limit {
+				return &b, soft, nil
+			}
+			if d.used*100 >= d.limit*l.softPercent() {
+				soft = append(soft, b)
+			}
+		}
+	}
+	return nil, soft, nil
+}
+
+// Usage est la consommation courante d'un sujet et ses quotas éventuels
+type Usage struct {
+	Subject Subject `json:"subject" example:"tenant/payments"`
+	Day     string  `json:"day" example:"2025-09-01"`
+	Daily   Counter `json:"daily"`
+	Month   string  `json:"month" example:"2025-09"`
+	Monthly Counter `json:"monthly"`
+	Limits  *Limits `json:"limits,omitempty"`
+}
+
+// Usage retourne la consommation courante d'un sujet
+func (s *SQLiteStore) Usage(subject Subject) (*Usage, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usageOf(subject, now)
+}
+
+func (s *SQLiteStore) usageOf(subject Subject, now time.Time) (*Usage, error) {
+	daily, monthly, err := s.counters(subject, now)
+	if err != nil {
+		return nil, err
+	}
+	day, month := periods(now)
+	u := &Usage{Subject: subject, Day: day, Daily: *daily, Month: month, Monthly: *monthly}
+	if l, ok := s.limits[subject]; ok {
+		u.Limits = &l
+	}
+	return u, nil
+}
+
+// List retourne les sujets ayant des quotas, avec leur consommation courante, par sujet
+func (s *SQLiteStore) List() ([]Usage, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Usage, 0, len(s.limits))
+	for subject := range s.limits {
+		u, err := s.usageOf(subject, now)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Subject < out[j].Subject })
+	return out, nil
+}
+
+// SetLimits crée ou remplace les quotas d'un sujet
+func (s *SQLiteStore) SetLimits(subject Subject, l Limits) (*Usage, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec(`
+	INSERT INTO quota_limits(subject, daily_entries, daily_bytes, monthly_entries, monthly_bytes, soft_percent)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(subject) DO UPDATE SET daily_entries = excluded.daily_entries, daily_bytes = excluded.daily_bytes,
+		monthly_entries = excluded.monthly_entries, monthly_bytes = excluded.monthly_bytes, soft_percent = excluded.soft_percent
+	`, subject, l.DailyEntries, l.DailyBytes, l.MonthlyEntries, l.MonthlyBytes, l.SoftPercent); err != nil {
+		return nil, err
+	}
+	s.limits[subject] = l
+	return s.usageOf(subject, now)
+}
+
+// DeleteLimits supprime les quotas d'un sujet; sa consommation reste comptée
+func (s *SQLiteStore) DeleteLimits(subject Subject) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.limits[subject]; !ok {
+		return ErrNotFound
+	}
+	if _, err := s.db.Exec(`DELETE FROM quota_limits WHERE subject = ?`, subject); err != nil {
+		return err
+	}
+	delete(s.limits, subject)
+	return nil
+}
+
+// PurgeTenant supprime les quotas d'un tenant supprimé et de ses clés (tenant.Purger). Sa
+// consommation passée est gardée pour la refacturation.
+func (s *SQLiteStore) PurgeTenant(name string) error {
+	subject, err := NewSubject(KindTenant, "", name)
+	if err != nil {
+		return err
+	}
+	subjects := []Subject{subject}
+	s.mu.Lock()
+	for subject := range s.limits {
+		if subject.Tenant() == name && strings.HasPrefix(string(subject), KindKey+"/") {
+			subjects = append(subjects, subject)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, subject := range subjects {
+		if err := s.DeleteLimits(subject); err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// PeriodUsage est la consommation d'un sujet sur un jour ou un mois. Tenant regroupe un
+// tenant et ses clés pour la refacturation (vide pour le tenant par défaut).
+type PeriodUsage struct {
+	Subject Subject `json:"subject" example:"key/payments/fluent-bit-web"`
+	Tenant  string  `json:"tenant,omitempty" example:"payments"`
+	Period  string  `json:"period" example:"2025-09"`
+	Counter
+}
+
+// Report retourne la consommation de tous les sujets sur un jour (2025-09-01) ou un mois
+// (2025-09), par sujet, avec le tenant de chacun
+func (s *SQLiteStore) Report(period string) ([]PeriodUsage, error) {
+	if err := ValidatePeriod(period); err != nil {
+		return nil, err
+	}
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT subject, entries, bytes FROM quota_usage WHERE period = ? ORDER BY subject`, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []PeriodUsage{}
+	for rows.Next() {
+		u := PeriodUsage{Period: period}
+		if err := rows.Scan(&u.Subject, &u.Entries, &u.Bytes); err != nil {
+			return nil, err
+		}
+		u.Tenant = u.Subject.Tenant()
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// Flush écrit en base les compteurs modifiés, oublie ceux des périodes terminées et met
+// à jour les métriques
+func (s *SQLiteStore) Flush() error {
+	day, month := periods(s.now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.dirty) > 0 {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		for key := range s.dirty {
+			c := s.usage[key]
+			if _, err := tx.Exec(`
+			INSERT INTO quota_usage(subject, period, entries, bytes) VALUES (?, ?, ?, ?)
+			ON CONFLICT(subject, period) DO UPDATE SET entries = excluded.entries, bytes = excluded.bytes
+			`, key.subject, key.period, c.Entries, c.Bytes); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		s.dirty = make(map[usageKey]bool)
+	}
+
+	// Les sujets sans écriture sur la période en cours repartent de zéro
+	for key := range s.usage {
+		if key.period != day && key.period != month {
+			window := windowOf(key.period)
+			usedEntries.WithLabelValues(string(key.subject), window).Set(0)
+			usedBytes.WithLabelValues(string(key.subject), window).Set(0)
+			delete(s.usage, key)
+		}
+	}
+	for key, c := range s.usage {
+		window := windowOf(key.period)
+		usedEntries.WithLabelValues(string(key.subject), window).Set(float64(c.Entries))
+		usedBytes.WithLabelValues(string(key.subject), window).Set(float64(c.Bytes))
+	}
+	for key := range s.warned {
+		if key.period != day && key.period != month {
+			delete(s.warned, key)
+		}
+	}
+	return nil
+}
+
+func windowOf(period string) string {
+	if len(period) == len(monthLayout) {
+		return Monthly
+	}
+	return Daily
+}
+
+// Run écrit périodiquement les compteurs jusqu'à l'annulation du contexte
+func (s *SQLiteStore) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Printf("quota flush error: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close écrit les derniers compteurs avant de fermer la base
+func (s *SQLiteStore) Close() error {
+	flushErr := s.Flush()
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
@@ -0,0 +1,278 @@
+package quota
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/rypi-dev/logger-server/internal"
+)
+
+func newTestStore(t *testing.T) (*SQLiteStore, string, *time.Time) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "logs.sqlite")
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	now := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, path, &now
+}
+
+// entry retourne une entrée de 10 octets (niveau et message)
+func entry(tenant, key string) *internal.LogEntry {
+	return &internal.LogEntry{Level: "INFO", Message: "hello!", Tenant: tenant, KeyName: key}
+}
+
+func TestSQLiteStore_Reserve(t *testing.T) {
+	s, _, _ := newTestStore(t)
+	if _, err := s.SetLimits("tenant/payments", Limits{DailyEntries: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	var last *Reservation
+	for i := 0; i < 3; i++ {
+		r, err := s.Reserve(entry("payments", "agent"))
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+		last = r
+	}
+	before := testutil.ToFloat64(rejectedTotal.WithLabelValues("tenant/payments"))
+	_, err := s.Reserve(entry("payments", "agent"))
+	if !errors.Is(err, ErrExceeded) {
+		t.Fatalf("expected ErrExceeded, got %v", err)
+	}
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) || exceeded.Breach.Dimension != "daily_entries" || !exceeded.Breach.Reset.Equal(time.Date(2025, 9, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the breach with its reset time, got %v", err)
+	}
+	if got := testutil.ToFloat64(rejectedTotal.WithLabelValues("tenant/payments")) - before; got != 1 {
+		t.Errorf("expected 1 rejection counted, got %v", got)
+	}
+
+	// Une entrée refusée n'est imputée à aucun sujet, pas même à la clé sans quota
+	u, err := s.Usage("key/payments/agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Daily != (Counter{Entries: 3, Bytes: 30}) || u.Monthly != u.Daily || u.Limits != nil {
+		t.Errorf("unexpected key usage: %+v", u)
+	}
+
+	s.Release(last)
+	if _, err := s.Reserve(entry("payments", "other-agent")); err != nil {
+		t.Errorf("expected the released entry to free the quota, got %v", err)
+	}
+
+	// Les entrées non authentifiées ne sont pas comptées
+	if r, err := s.Reserve(entry("", "")); err != nil || r != nil {
+		t.Fatalf("expected no reservation, got %+v, %v", r, err)
+	}
+	s.Release(nil)
+}
+
+func TestSQLiteStore_ReleaseChargedPeriods(t *testing.T) {
+	s, _, now := newTestStore(t)
+	s.Reserve(entry("payments", "agent"))
+	r, err := s.Reserve(entry("payments", "agent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// L'écriture échoue après minuit, une fois le compteur de la veille écrit et oublié
+	*now = now.Add(24 * time.Hour)
+	s.Reserve(entry("payments", "agent"))
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	s.Release(r)
+
+	yesterday, err := s.Report(now.Add(-24 * time.Hour).Format("2006-01-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	today, err := s.Report(now.Format("2006-01-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(yesterday) != 2 || yesterday[0].Entries != 1 || yesterday[1].Entries != 1 {
+		t.Errorf("expected the entry to be released from the day it was charged to, got %+v", yesterday)
+	}
+	if len(today) != 2 || today[0].Entries != 1 || today[1].Entries != 1 {
+		t.Errorf("expected today's usage to be unchanged, got %+v", today)
+	}
+}
+
+func TestSQLiteStore_ReserveWindows(t *testing.T) {
+	s, _, now := newTestStore(t)
+	if _, err := s.SetLimits("key//windows", Limits{DailyBytes: 25, MonthlyEntries: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Reserve(entry("", "windows"))
+	s.Reserve(entry("", "windows"))
+	if _, err := s.Reserve(entry("", "windows")); !errors.Is(err, ErrExceeded) {
+		t.Fatalf("expected the daily bytes quota to be exceeded, got %v", err)
+	}
+
+	// Le lendemain le quota journalier repart de zéro, pas le mensuel
+	*now = now.Add(24 * time.Hour)
+	if _, err := s.Reserve(entry("", "windows")); err != nil {
+		t.Fatalf("expected a new day to reset the daily quota, got %v", err)
+	}
+	if _, err := s.Reserve(entry("", "windows")); !errors.Is(err, ErrExceeded) {
+		t.Fatalf("expected the monthly entries quota to be exceeded, got %v", err)
+	}
+
+	*now = time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := s.Reserve(entry("", "windows")); err != nil {
+		t.Errorf("expected a new month to reset the monthly quota, got %v", err)
+	}
+}
+
+func TestSQLiteStore_Persistence(t *testing.T) {
+	s, path, now := newTestStore(t)
+	if _, err := s.SetLimits("tenant/persisted", Limits{MonthlyEntries: 100, SoftPercent: 90}); err != nil {
+		t.Fatal(err)
+	}
+	s.Reserve(entry("persisted", ""))
+	s.Reserve(entry("persisted", ""))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	reopened.now = func() time.Time { return *now }
+
+	u, err := reopened.Usage("tenant/persisted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Monthly.Entries != 2 || u.Daily.Entries != 2 || u.Limits == nil || u.Limits.SoftPercent != 90 {
+		t.Errorf("expected usage and limits to survive a restart, got %+v", u)
+	}
+	if u.Day != "2025-09-15" || u.Month != "2025-09" {
+		t.Errorf("unexpected periods %s, %s", u.Day, u.Month)
+	}
+}
+
+func TestSQLiteStore_Check(t *testing.T) {
+	s, _, now := newTestStore(t)
+	if _, err := s.SetLimits("tenant/checked", Limits{DailyEntries: 10, SoftPercent: 50}); err != nil {
+		t.Fatal(err)
+	}
+
+	before := testutil.ToFloat64(warningsTotal.WithLabelValues("tenant/checked", "daily_entries"))
+	for i := 0; i < 5; i++ {
+		s.Reserve(entry("checked", "checker"))
+	}
+	hard, soft, err := s.Check("checked", "checker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hard != nil || len(soft) != 1 || soft[0].Dimension != "daily_entries" || soft[0].Used != 5 {
+		t.Errorf("expected a soft warning on daily entries, got %v, %v", hard, soft)
+	}
+
+	for i := 0; i < 5; i++ {
+		s.Reserve(entry("checked", "checker"))
+	}
+	hard, _, _ = s.Check("checked", "checker")
+	if hard == nil || hard.String() != "tenant/checked daily_entries 10/10" {
+		t.Fatalf("expected the daily quota to be reached, got %v", hard)
+	}
+	if !hard.Reset.Equal(time.Date(2025, 9, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected reset %v", hard.Reset)
+	}
+	if got := testutil.ToFloat64(warningsTotal.WithLabelValues("tenant/checked", "daily_entries")) - before; got != 1 {
+		t.Errorf("expected the soft threshold to be reported once, got %v", got)
+	}
+
+	*now = now.Add(24 * time.Hour)
+	if hard, soft, _ := s.Check("checked", "checker"); hard != nil || len(soft) != 0 {
+		t.Errorf("expected no breach on a new day, got %v, %v", hard, soft)
+	}
+}
+
+func TestSQLiteStore_ReportAndFlush(t *testing.T) {
+	s, _, now := newTestStore(t)
+	s.Reserve(entry("reported", "reporter"))
+	s.Reserve(entry("reported", "reporter"))
+	*now = now.Add(24 * time.Hour)
+	s.Reserve(entry("reported", "reporter"))
+
+	month, err := s.Report("2025-09")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(month) != 2 || month[0].Subject != "key/reported/reporter" || month[0].Tenant != "reported" || month[1].Subject != "tenant/reported" || month[1].Entries != 3 || month[1].Bytes != 30 {
+		t.Errorf("unexpected monthly report: %+v", month)
+	}
+	day, err := s.Report("2025-09-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(day) != 2 || day[0].Entries != 2 {
+		t.Errorf("unexpected daily report: %+v", day)
+	}
+	if _, err := s.Report("september"); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("expected ErrInvalidPeriod, got %v", err)
+	}
+
+	if got := testutil.ToFloat64(usedEntries.WithLabelValues("tenant/reported", Daily)); got != 1 {
+		t.Errorf("expected 1 entry today in metrics, got %v", got)
+	}
+	if got := testutil.ToFloat64(usedBytes.WithLabelValues("tenant/reported", Monthly)); got != 30 {
+		t.Errorf("expected 30 bytes this month in metrics, got %v", got)
+	}
+
+	// Sans écriture le lendemain, la métrique journalière repart de zéro
+	*now = now.Add(24 * time.Hour)
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(usedEntries.WithLabelValues("tenant/reported", Daily)); got != 0 {
+		t.Errorf("expected the daily metric to be reset, got %v", got)
+	}
+}
+
+func TestSQLiteStore_DeleteLimits(t *testing.T) {
+	s, _, _ := newTestStore(t)
+	s.SetLimits("tenant/deleted", Limits{DailyEntries: 1})
+	s.SetLimits("key/deleted/agent", Limits{DailyEntries: 1})
+	s.SetLimits("key//deleted", Limits{DailyEntries: 1})
+	s.Reserve(entry("deleted", ""))
+
+	if err := s.PurgeTenant("deleted"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Reserve(entry("deleted", "")); err != nil {
+		t.Errorf("expected the purged quota to no longer apply, got %v", err)
+	}
+	if err := s.PurgeTenant("deleted"); err != nil {
+		t.Errorf("expected purging a tenant without quota to succeed, got %v", err)
+	}
+	// Les quotas des clés du tenant disparaissent avec lui, pas ceux d'une clé homonyme
+	if err := s.DeleteLimits("key//deleted"); err != nil {
+		t.Errorf("expected the default tenant key quota to be kept, got %v", err)
+	}
+	if err := s.DeleteLimits("key//unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if list, _ := s.List(); len(list) != 0 {
+		t.Errorf("expected no quota left, got %+v", list)
+	}
+	if _, err := s.SetLimits("tenant/deleted", Limits{DailyEntries: -1}); !errors.Is(err, ErrInvalidLimits) {
+		t.Errorf("expected ErrInvalidLimits, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+var (
+	ErrNotFound        = errors.New("rate limit client not found")
+	ErrInvalidOverride = errors.New("invalid rate limit override")
+)
+
+// MaxOverrideTTL est la durée maximale d'une limite temporaire
+const MaxOverrideTTL = 7 * 24 * time.Hour
+
+// Override remplace temporairement la limite des requêtes d'un client (limite par défaut,
+// limites par niveau et routes); les limites des entrées ne changent pas
+type Override struct {
+	Client    string    `json:"client" example:"key:payments/fluent-bit-web"`
+	Limit     int       `json:"limit" example:"1000"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OverrideRequest est le corps de PUT /admin/ratelimit/overrides/{client}
+type OverrideRequest struct {
+	Limit int    `json:"limit" example:"1000"`
+	TTL   string `json:"ttl" example:"1h"`
+}
+
+// ClientStatus est l'état d'un client pour une règle
+type ClientStatus struct {
+	Client       string    `json:"client" example:"key:payments/fluent-bit-web"`
+	Rule         string    `json:"rule,omitempty" example:"POST /log/batch"` // vide : limite par défaut
+	Algorithm    string    `json:"algorithm" example:"sliding_window"`
+	Limit        int       `json:"limit" example:"100"`
+	Remaining    int       `json:"remaining" example:"42"`
+	ResetSeconds int       `json:"reset_seconds" example:"35"`
+	LastSeen     time.Time `json:"last_seen"`
+	Override     *Override `json:"override,omitempty"`
+}
+
+// Clients retourne l'état de tous les clients suivis, par client puis par règle
+func (rl *RateLimiter) Clients() []ClientStatus {
+	return rl.statuses(func(string) bool { return true })
+}
+
+// Client retourne l'état d'un client (clé ByIP, ByAPIKey, ... : "1.2.3.4", "key:payments/agent")
+// pour chaque règle qui le compte. Avec un état partagé, c'est le dernier état vu par
+// cette instance.
+func (rl *RateLimiter) Client(client string) ([]ClientStatus, error) {
+	out := rl.statuses(func(c string) bool { return c == client })
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, client)
+	}
+	return out, nil
+}
+
+func (rl *RateLimiter) statuses(match func(client string) bool) []ClientStatus {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	out := []ClientStatus{}
+	for _, data := range rl.requests {
+		if !match(data.client) {
+			continue
+		}
+		limit := data.policy.rule.Limit
+		o := rl.override(data.client, now)
+		if o != nil && !data.policy.entries {
+			limit = o.Limit
+		}
+		var override *Override
+		if o != nil {
+			copy := *o
+			override = &copy
+		}
+		var remaining int
+		var reset time.Duration
+		if data.shared != nil {
+			limit, remaining, reset = data.shared.limit, data.shared.remaining, data.shared.resetAt(now)
+		} else {
+			remaining, reset = data.policy.alg.status(data, now, limit)
+		}
+		algorithm := data.policy.rule.Algorithm
+		if algorithm == "" {
+			algorithm = FixedWindow
+		}
+		out = append(out, ClientStatus{
+			Client:       data.client,
+			Rule:         data.policy.rule.String(),
+			Algorithm:    algorithm,
+			Limit:        limit,
+			Remaining:    remaining,
+			ResetSeconds: int(math.Ceil(reset.Seconds())),
+			LastSeen:     data.lastSeen,
+			Override:     override,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Client != out[j].Client {
+			return out[i].Client < out[j].Client
+		}
+		return out[i].Rule < out[j].Rule
+	})
+	return out
+}
+
+// Reset efface les compteurs d'un client pour toutes les règles : sa limite est de nouveau
+// entièrement disponible. Sa limite temporaire éventuelle est conservée. Avec un état
+// partagé, les compteurs de toutes les instances sont effacés.
+func (rl *RateLimiter) Reset(client string) error {
+	found := false
+	if rl.shared != nil {
+		n, err := rl.shared.store.Delete(context.Background(), rl.sharedKeys(client)...)
+		if err != nil {
+			return fmt.Errorf("reset shared rate limit state: %w", err)
+		}
+		found = n > 0
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for key, data := range rl.requests {
+		if data.client == client {
+			delete(rl.requests, key)
+			activeClients.Dec()
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrNotFound, client)
+	}
+	return nil
+}
+
+// SetOverride remplace la limite des requêtes d'un client par limit pendant ttl, sans
+// redémarrage. Les compteurs sont conservés : une limite abaissée s'applique aussitôt.
+// Avec un état partagé, la limite s'applique sur toutes les instances; chacune garde aussi
+// les limites posées par elle pour limiter localement si le stockage est injoignable.
+func (rl *RateLimiter) SetOverride(client string, limit int, ttl time.Duration) (*Override, error) {
+	if client == "" {
+		return nil, fmt.Errorf("%w: client is required", ErrInvalidOverride)
+	}
+	if err := utils.ValidateMaxRequests(limit); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOverride, err)
+	}
+	if ttl <= 0 || ttl > MaxOverrideTTL {
+		return nil, fmt.Errorf("%w: ttl must be between 0 and %s", ErrInvalidOverride, MaxOverrideTTL)
+	}
+
+	rl.mu.Lock()
+	o := Override{Client: client, Limit: limit, ExpiresAt: rl.now().Add(ttl)}
+	rl.mu.Unlock()
+
+	if rl.shared != nil {
+		if err := rl.shared.store.SetOverride(context.Background(), o); err != nil {
+			return nil, fmt.Errorf("set shared rate limit override: %w", err)
+		}
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.overrides[client] = &o
+	out := o
+	return &out, nil
+}
+
+// Overrides retourne les limites temporaires en cours, par client (celles de toutes les
+// instances avec un état partagé)
+func (rl *RateLimiter) Overrides() ([]Override, error) {
+	rl.mu.Lock()
+	now := rl.now()
+	out := []Override{}
+	for client := range rl.overrides {
+		if o := rl.override(client, now); o != nil {
+			out = append(out, *o)
+		}
+	}
+	rl.mu.Unlock()
+
+	if rl.shared != nil {
+		shared, err := rl.shared.store.Overrides(context.Background(), now)
+		if err != nil {
+			return nil, fmt.Errorf("list shared rate limit overrides: %w", err)
+		}
+		out = append(out[:0], shared...)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Client < out[j].Client })
+	return out, nil
+}
+
+// DeleteOverride rend au client ses limites configurées, sur toutes les instances avec un
+// état partagé
+func (rl *RateLimiter) DeleteOverride(client string) error {
+	found := false
+	if rl.shared != nil {
+		deleted, err := rl.shared.store.DeleteOverride(context.Background(), client)
+		if err != nil {
+			return fmt.Errorf("delete shared rate limit override: %w", err)
+		}
+		found = deleted
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.override(client, rl.now()) != nil {
+		found = true
+	}
+	delete(rl.overrides, client)
+	if !found {
+		return fmt.Errorf("%w: no override for %s", ErrNotFound, client)
+	}
+	return nil
+}
+
+// override retourne la limite temporaire en cours d'un client (appelé avec lock); une
+// limite expirée est supprimée
+func (rl *RateLimiter) override(client string, now time.Time) *Override {
+	o, ok := rl.overrides[client]
+	if !ok {
+		return nil
+	}
+	if !now.Before(o.ExpiresAt) {
+		delete(rl.overrides, client)
+		return nil
+	}
+	return o
+}
@@ -0,0 +1,128 @@
+package ratelimit_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+	"github.com/rypi-dev/logger-server/internal/ratelimit"
+)
+
+func newAdminLimiter(t *testing.T, c *clock) *ratelimit.RateLimiter {
+	t.Helper()
+	rl, err := ratelimit.NewRateLimiter(ratelimit.Config{
+		MaxRequests: 2,
+		Window:      time.Minute,
+		Algorithm:   ratelimit.FixedWindow,
+		EntryLimits: map[log_levels.LogLevel]int{log_levels.LogLevelError: 3},
+		Clock:       c.Now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rl.Stop)
+	return rl
+}
+
+func TestClients(t *testing.T) {
+	c := newClock()
+	rl := newAdminLimiter(t, c)
+	rl.AllowLevel("key:payments/agent", "")
+	rl.AllowLevel("key:payments/other", "")
+	c.Advance(15 * time.Second)
+	rl.AllowLevel("key:payments/agent", "")
+
+	clients := rl.Clients()
+	if len(clients) != 2 || clients[0].Client != "key:payments/agent" || clients[1].Client != "key:payments/other" {
+		t.Fatalf("unexpected clients: %+v", clients)
+	}
+	agent := clients[0]
+	if agent.Rule != "" || agent.Algorithm != ratelimit.FixedWindow || agent.Limit != 2 || agent.Remaining != 0 ||
+		agent.ResetSeconds != 45 || !agent.LastSeen.Equal(c.Now()) {
+		t.Errorf("unexpected status: %+v", agent)
+	}
+
+	if _, err := rl.Client("key:payments/unknown"); !errors.Is(err, ratelimit.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := rl.Reset("key:payments/agent"); err != nil {
+		t.Fatal(err)
+	}
+	if allowed, _ := rl.AllowLevel("key:payments/agent", ""); !allowed {
+		t.Error("expected a reset client to get its full limit back")
+	}
+	if err := rl.Reset("key:payments/unknown"); !errors.Is(err, ratelimit.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	c := newClock()
+	rl := newAdminLimiter(t, c)
+
+	o, err := rl.SetOverride("key:payments/agent", 4, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Limit != 4 || !o.ExpiresAt.Equal(c.Now().Add(time.Hour)) {
+		t.Errorf("unexpected override: %+v", o)
+	}
+
+	allowed := 0
+	for i := 0; i < 6; i++ {
+		if ok, _ := rl.AllowLevel("key:payments/agent", ""); ok {
+			allowed++
+		}
+	}
+	if allowed != 4 {
+		t.Errorf("expected the raised limit of 4, got %d", allowed)
+	}
+	statuses, err := rl.Client("key:payments/agent")
+	if err != nil || len(statuses) != 1 || statuses[0].Limit != 4 || statuses[0].Override == nil {
+		t.Errorf("expected the override in the client status, got %+v, %v", statuses, err)
+	}
+	if list, err := rl.Overrides(); err != nil || len(list) != 1 || list[0].Client != "key:payments/agent" {
+		t.Errorf("unexpected overrides: %+v", list)
+	}
+
+	// Les limites des entrées ne changent pas
+	req := httptest.NewRequest(http.MethodPost, "/log/batch", nil)
+	req.RemoteAddr = "1.2.3.4:5678"
+	rl.SetOverride("1.2.3.4", 100, time.Hour)
+	rl.AllowEntry(req, "ERROR")
+	statuses, _ = rl.Client("1.2.3.4")
+	if len(statuses) != 1 || statuses[0].Rule != "entries ERROR" || statuses[0].Limit != 3 || statuses[0].Remaining != 2 {
+		t.Errorf("expected the entry limit to be kept, got %+v", statuses)
+	}
+
+	// Une limite temporaire expire avec son ttl
+	if _, err := rl.SetOverride("key:payments/agent", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	c.Advance(time.Minute)
+	rl.AllowLevel("key:payments/agent", "")
+	if ok, _ := rl.AllowLevel("key:payments/agent", ""); !ok {
+		t.Error("expected the override to expire with its ttl")
+	}
+
+	if err := rl.DeleteOverride("key:payments/agent"); !errors.Is(err, ratelimit.ErrNotFound) {
+		t.Errorf("expected the expired override to be gone, got %v", err)
+	}
+	for _, tt := range []struct {
+		client string
+		limit  int
+		ttl    time.Duration
+	}{
+		{"", 10, time.Hour},
+		{"key:payments/agent", 0, time.Hour},
+		{"key:payments/agent", 10, 0},
+		{"key:payments/agent", 10, 30 * 24 * time.Hour},
+	} {
+		if _, err := rl.SetOverride(tt.client, tt.limit, tt.ttl); !errors.Is(err, ratelimit.ErrInvalidOverride) {
+			t.Errorf("expected ErrInvalidOverride for %+v, got %v", tt, err)
+		}
+	}
+}
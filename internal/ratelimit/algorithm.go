@@ -0,0 +1,224 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Algorithmes de limitation disponibles (Config.Algorithm, Rule.Algorithm)
+const (
+	// FixedWindow compte les requêtes d'une fenêtre ouverte à la première requête du client.
+	// Simple et économe, mais un client peut envoyer jusqu'à 2x la limite à cheval sur deux
+	// fenêtres.
+	FixedWindow = "fixed_window"
+	// TokenBucket remplit un seau de Burst jetons au rythme de Limit par Window; chaque
+	// requête consomme un jeton. Les rafales sont permises jusqu'à Burst, le débit moyen
+	// reste Limit par Window.
+	TokenBucket = "token_bucket"
+	// SlidingLog garde l'heure de chaque requête de la dernière Window : limite exacte, au
+	// prix d'une mémoire proportionnelle à Limit par client.
+	SlidingLog = "sliding_log"
+	// SlidingWindow estime le nombre de requêtes de la dernière Window à partir des
+	// compteurs de la fenêtre courante et de la précédente, pondérée par son recouvrement.
+	SlidingWindow = "sliding_window"
+)
+
+var ErrInvalidAlgorithm = errors.New("invalid rate limit algorithm")
+
+// algorithm décide, à partir de l'état d'un client, si une requête de plus est permise
+type algorithm interface {
+	// allow impute une requête au client si limit le permet, sinon retourne le délai avant
+	// qu'une requête soit de nouveau acceptée
+	allow(c *clientData, now time.Time, limit int) (bool, time.Duration)
+	// status retourne les requêtes encore permises et le délai avant que toute la limite
+	// soit de nouveau disponible
+	status(c *clientData, now time.Time, limit int) (remaining int, reset time.Duration)
+	// idle indique que l'état du client n'a plus d'effet et peut être supprimé
+	idle(c *clientData, now time.Time) bool
+}
+
+// newAlgorithm retourne l'algorithme name pour une limite de limit requêtes par window
+func newAlgorithm(name string, limit int, window time.Duration, burst int) (algorithm, error) {
+	switch name {
+	case FixedWindow, "":
+		return fixedWindow{window: window}, nil
+	case TokenBucket:
+		if burst < 0 {
+			return nil, fmt.Errorf("%w: burst must not be negative", ErrInvalidAlgorithm)
+		}
+		if burst == 0 {
+			burst = limit
+		}
+		return tokenBucket{window: window, limit: limit, burst: burst}, nil
+	case SlidingLog:
+		return slidingLog{window: window}, nil
+	case SlidingWindow:
+		return slidingWindow{window: window}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAlgorithm, name)
+	}
+}
+
+// fixedWindow : count requêtes depuis firstSeen
+type fixedWindow struct {
+	window time.Duration
+}
+
+func (a fixedWindow) allow(c *clientData, now time.Time, limit int) (bool, time.Duration) {
+	if c.firstSeen.IsZero() || now.Sub(c.firstSeen) >= a.window {
+		c.firstSeen = now
+		c.count = 0
+	}
+	if c.count >= limit {
+		return false, a.window - now.Sub(c.firstSeen)
+	}
+	c.count++
+	return true, 0
+}
+
+func (a fixedWindow) status(c *clientData, now time.Time, limit int) (int, time.Duration) {
+	if now.Sub(c.firstSeen) >= a.window {
+		return limit, 0
+	}
+	return max(limit-c.count, 0), a.window - now.Sub(c.firstSeen)
+}
+
+func (a fixedWindow) idle(c *clientData, now time.Time) bool {
+	return now.Sub(c.firstSeen) >= a.window
+}
+
+// tokenBucket : tokens jetons à l'instant refilled
+type tokenBucket struct {
+	window time.Duration
+	limit  int
+	burst  int
+}
+
+func (a tokenBucket) allow(c *clientData, now time.Time, limit int) (bool, time.Duration) {
+	// Le débit suit la limite de la requête (limites par niveau), la capacité reste Burst
+	perToken := float64(a.window) / float64(limit)
+	if c.refilled.IsZero() {
+		c.tokens = float64(a.burst)
+	} else if elapsed := now.Sub(c.refilled); elapsed > 0 {
+		c.tokens = math.Min(float64(a.burst), c.tokens+float64(elapsed)/perToken)
+	}
+	c.refilled = now
+
+	if c.tokens < 1 {
+		return false, time.Duration(math.Ceil((1 - c.tokens) * perToken))
+	}
+	c.tokens--
+	return true, 0
+}
+
+func (a tokenBucket) status(c *clientData, now time.Time, limit int) (int, time.Duration) {
+	perToken := float64(a.window) / float64(limit)
+	tokens := math.Min(float64(a.burst), c.tokens+float64(now.Sub(c.refilled))/perToken)
+	return int(tokens), time.Duration(math.Ceil((float64(a.burst) - tokens) * perToken))
+}
+
+func (a tokenBucket) idle(c *clientData, now time.Time) bool {
+	missing := float64(a.burst) - c.tokens
+	return float64(now.Sub(c.refilled)) >= missing*float64(a.window)/float64(a.limit)
+}
+
+// slidingLog : log contient les heures des requêtes de la dernière fenêtre, dans l'ordre
+type slidingLog struct {
+	window time.Duration
+}
+
+func (a slidingLog) allow(c *clientData, now time.Time, limit int) (bool, time.Duration) {
+	a.expire(c, now)
+	if len(c.log) >= limit {
+		// La requête qui doit sortir de la fenêtre pour libérer une place
+		return false, c.log[len(c.log)-limit].Add(a.window).Sub(now)
+	}
+	c.log = append(c.log, now)
+	return true, 0
+}
+
+func (a slidingLog) expire(c *clientData, now time.Time) {
+	cutoff := now.Add(-a.window)
+	i := 0
+	for i < len(c.log) && !c.log[i].After(cutoff) {
+		i++
+	}
+	if i > 0 {
+		c.log = append(c.log[:0], c.log[i:]...)
+	}
+}
+
+func (a slidingLog) status(c *clientData, now time.Time, limit int) (int, time.Duration) {
+	a.expire(c, now)
+	if len(c.log) == 0 {
+		return limit, 0
+	}
+	return max(limit-len(c.log), 0), c.log[len(c.log)-1].Add(a.window).Sub(now)
+}
+
+func (a slidingLog) idle(c *clientData, now time.Time) bool {
+	return len(c.log) == 0 || !c.log[len(c.log)-1].After(now.Add(-a.window))
+}
+
+// slidingWindow : count requêtes dans la fenêtre alignée commencée à firstSeen, previous
+// dans la fenêtre précédente
+type slidingWindow struct {
+	window time.Duration
+}
+
+func (a slidingWindow) allow(c *clientData, now time.Time, limit int) (bool, time.Duration) {
+	a.advance(c, now)
+	elapsed := now.Sub(c.firstSeen)
+	// previous x (window - elapsed) / window + count + 1 > limit, en entiers
+	if time.Duration(c.previous)*(a.window-elapsed)+time.Duration(c.count+1)*a.window > time.Duration(limit)*a.window {
+		return false, a.retryAfter(c, elapsed, limit)
+	}
+	c.count++
+	return true, 0
+}
+
+// advance passe à la fenêtre alignée qui contient now
+func (a slidingWindow) advance(c *clientData, now time.Time) {
+	start := now.Truncate(a.window)
+	if start.Equal(c.firstSeen) {
+		return
+	}
+	if start.Sub(c.firstSeen) == a.window {
+		c.previous = c.count
+	} else {
+		c.previous = 0
+	}
+	c.count = 0
+	c.firstSeen = start
+}
+
+// retryAfter calcule quand le poids décroissant de la fenêtre précédente (ou, si la
+// fenêtre courante est pleine, celui de la fenêtre courante une fois devenue la
+// précédente) laissera passer une requête
+func (a slidingWindow) retryAfter(c *clientData, elapsed time.Duration, limit int) time.Duration {
+	if c.count < limit && c.previous > 0 {
+		return a.window - a.window*time.Duration(limit-1-c.count)/time.Duration(c.previous) - elapsed
+	}
+	return 2*a.window - a.window*time.Duration(limit-1)/time.Duration(c.count) - elapsed
+}
+
+func (a slidingWindow) status(c *clientData, now time.Time, limit int) (int, time.Duration) {
+	a.advance(c, now)
+	elapsed := now.Sub(c.firstSeen)
+	used := time.Duration(c.previous)*(a.window-elapsed) + time.Duration(c.count)*a.window
+	remaining := int((time.Duration(limit)*a.window - used) / a.window)
+	switch {
+	case c.count > 0:
+		return max(remaining, 0), 2*a.window - elapsed
+	case c.previous > 0:
+		return max(remaining, 0), a.window - elapsed
+	default:
+		return limit, 0
+	}
+}
+
+func (a slidingWindow) idle(c *clientData, now time.Time) bool {
+	return now.Sub(c.firstSeen) >= 2*a.window
+}
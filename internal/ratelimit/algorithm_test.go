@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+
+// burst envoie n requêtes à l'instant at et retourne le nombre de requêtes acceptées
+func burst(alg algorithm, c *clientData, at time.Time, n, limit int) int {
+	accepted := 0
+	for i := 0; i < n; i++ {
+		if ok, _ := alg.allow(c, at, limit); ok {
+			accepted++
+		}
+	}
+	return accepted
+}
+
+// Une première requête ouvre la fenêtre, puis le client envoie tout ce qu'il peut juste
+// avant et juste après la fin de cette fenêtre
+func TestAlgorithms_WindowBoundary(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want int // requêtes acceptées en 2 secondes autour de la frontière
+	}{
+		{FixedWindow, 19},
+		{TokenBucket, 10},
+		{SlidingLog, 10},
+		{SlidingWindow, 9},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			alg, err := newAlgorithm(tt.name, 10, time.Minute, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &clientData{}
+			if ok, _ := alg.allow(c, start, 10); !ok {
+				t.Fatal("expected the first request to be allowed")
+			}
+			got := burst(alg, c, start.Add(59*time.Second), 20, 10) + burst(alg, c, start.Add(61*time.Second), 20, 10)
+			if got != tt.want {
+				t.Errorf("expected %d requests across the boundary, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFixedWindow(t *testing.T) {
+	alg := fixedWindow{window: time.Minute}
+	c := &clientData{}
+	if got := burst(alg, c, start, 3, 2); got != 2 {
+		t.Fatalf("expected 2 requests allowed, got %d", got)
+	}
+	if ok, retry := alg.allow(c, start.Add(20*time.Second), 2); ok || retry != 40*time.Second {
+		t.Errorf("expected a 40s retry delay, got %v, %v", ok, retry)
+	}
+	if alg.idle(c, start.Add(59*time.Second)) || !alg.idle(c, start.Add(time.Minute)) {
+		t.Error("expected the client to be idle once its window is over")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	alg, _ := newAlgorithm(TokenBucket, 60, time.Minute, 5)
+	c := &clientData{}
+
+	// Rafale jusqu'à la capacité, puis un jeton par seconde
+	if got := burst(alg, c, start, 10, 60); got != 5 {
+		t.Fatalf("expected a burst of 5, got %d", got)
+	}
+	if ok, retry := alg.allow(c, start.Add(400*time.Millisecond), 60); ok || retry != 600*time.Millisecond {
+		t.Errorf("expected a 600ms retry delay, got %v, %v", ok, retry)
+	}
+	if got := burst(alg, c, start.Add(2*time.Second), 5, 60); got != 2 {
+		t.Errorf("expected 2 refilled tokens, got %d", got)
+	}
+
+	// Une limite par niveau plus haute remplit le seau plus vite, sans l'agrandir
+	if got := burst(alg, c, start.Add(2500*time.Millisecond), 5, 120); got != 1 {
+		t.Errorf("expected 1 token refilled at the higher rate, got %d", got)
+	}
+	if got := burst(alg, c, start.Add(time.Hour), 10, 120); got != 5 {
+		t.Errorf("expected the bucket to stay capped at 5, got %d", got)
+	}
+
+	if alg.idle(c, start.Add(time.Hour+4*time.Second)) || !alg.idle(c, start.Add(time.Hour+5*time.Second)) {
+		t.Error("expected the client to be idle once the bucket is full again")
+	}
+}
+
+func TestSlidingLog(t *testing.T) {
+	alg := slidingLog{window: time.Minute}
+	c := &clientData{}
+	alg.allow(c, start, 3)
+	alg.allow(c, start.Add(10*time.Second), 3)
+	alg.allow(c, start.Add(20*time.Second), 3)
+
+	if ok, retry := alg.allow(c, start.Add(30*time.Second), 3); ok || retry != 30*time.Second {
+		t.Errorf("expected to wait for the first request to leave the window, got %v, %v", ok, retry)
+	}
+	// Une limite plus basse attend la sortie d'une requête plus récente
+	if ok, retry := alg.allow(c, start.Add(30*time.Second), 2); ok || retry != 40*time.Second {
+		t.Errorf("expected a 40s retry delay for a limit of 2, got %v, %v", ok, retry)
+	}
+	if ok, _ := alg.allow(c, start.Add(time.Minute), 3); !ok {
+		t.Error("expected a request once the first one left the window")
+	}
+	if len(c.log) != 3 {
+		t.Errorf("expected expired requests to be dropped, got %d left", len(c.log))
+	}
+	if alg.idle(c, start.Add(time.Minute+59*time.Second)) || !alg.idle(c, start.Add(2*time.Minute)) {
+		t.Error("expected the client to be idle once its last request left the window")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	alg := slidingWindow{window: time.Minute}
+	c := &clientData{}
+	if got := burst(alg, c, start.Add(30*time.Second), 12, 10); got != 10 {
+		t.Fatalf("expected 10 requests in the first window, got %d", got)
+	}
+	// La fenêtre courante est pleine : la suivante commence avec 10 requêtes de poids 1
+	if ok, retry := alg.allow(c, start.Add(40*time.Second), 10); ok || retry != 26*time.Second {
+		t.Errorf("expected a 26s retry delay, got %v, %v", ok, retry)
+	}
+
+	// À 15s de la fenêtre suivante, la précédente pèse encore 10 x 0.75
+	if got := burst(alg, c, start.Add(75*time.Second), 5, 10); got != 2 {
+		t.Errorf("expected 2 requests, got %d", got)
+	}
+	if ok, retry := alg.allow(c, start.Add(75*time.Second), 10); ok || retry != 3*time.Second {
+		t.Errorf("expected a 3s retry delay, got %v, %v", ok, retry)
+	}
+	if ok, _ := alg.allow(c, start.Add(78*time.Second), 10); !ok {
+		t.Error("expected a request after the retry delay")
+	}
+
+	// Deux fenêtres plus tard, plus rien n'est compté
+	if alg.idle(c, start.Add(119*time.Second)) || !alg.idle(c, start.Add(3*time.Minute)) {
+		t.Error("expected the client to be idle two windows later")
+	}
+	if got := burst(alg, c, start.Add(3*time.Minute), 12, 10); got != 10 {
+		t.Errorf("expected a fresh window, got %d", got)
+	}
+}
+
+func TestAlgorithms_Status(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		remaining int
+		reset     time.Duration
+	}{
+		// 4 requêtes à 0s et 30s, sur 10 par minute (le seau a regagné 5 jetons à 30s)
+		{FixedWindow, 6, 30 * time.Second},
+		{TokenBucket, 8, 12 * time.Second},
+		{SlidingLog, 6, 60 * time.Second},
+		{SlidingWindow, 6, 90 * time.Second},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			alg, _ := newAlgorithm(tt.name, 10, time.Minute, 0)
+			c := &clientData{}
+			if remaining, reset := alg.status(c, start, 10); remaining != 10 || reset != 0 {
+				t.Errorf("expected the full limit for a new client, got %d, %v", remaining, reset)
+			}
+			burst(alg, c, start, 2, 10)
+			burst(alg, c, start.Add(30*time.Second), 2, 10)
+			if remaining, reset := alg.status(c, start.Add(30*time.Second), 10); remaining != tt.remaining || reset != tt.reset {
+				t.Errorf("expected %d remaining and a %v reset, got %d, %v", tt.remaining, tt.reset, remaining, reset)
+			}
+		})
+	}
+}
+
+func TestNewAlgorithm(t *testing.T) {
+	if alg, err := newAlgorithm("", 10, time.Minute, 0); err != nil || alg != (fixedWindow{window: time.Minute}) {
+		t.Errorf("expected the fixed window by default, got %v, %v", alg, err)
+	}
+	if _, err := newAlgorithm("leaky_bucket", 10, time.Minute, 0); !errors.Is(err, ErrInvalidAlgorithm) {
+		t.Errorf("expected ErrInvalidAlgorithm, got %v", err)
+	}
+	if _, err := newAlgorithm(TokenBucket, 10, time.Minute, -1); !errors.Is(err, ErrInvalidAlgorithm) {
+		t.Errorf("expected ErrInvalidAlgorithm for a negative burst, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// API expose l'état du rate limiter et ses limites temporaires (scope admin)
+type API struct {
+	limiter *RateLimiter
+}
+
+func NewAPI(limiter *RateLimiter) *API {
+	return &API{limiter: limiter}
+}
+
+// Register ajoute les routes /admin/ratelimit/ au routeur. Un client est désigné par sa clé
+// de limitation ("1.2.3.4", "key:payments/fluent-bit-web", "tenant:payments", ...), encodée dans
+// le chemin si besoin.
+func (a *API) Register(r *mux.Router) {
+	r.HandleFunc("/admin/ratelimit/clients", a.handleList).Methods("GET")
+	r.HandleFunc("/admin/ratelimit/clients/{client:.+}", a.handleGet).Methods("GET")
+	r.HandleFunc("/admin/ratelimit/clients/{client:.+}", a.handleReset).Methods("DELETE")
+	r.HandleFunc("/admin/ratelimit/overrides", a.handleListOverrides).Methods("GET")
+	r.HandleFunc("/admin/ratelimit/overrides/{client:.+}", a.handleSetOverride).Methods("PUT")
+	r.HandleFunc("/admin/ratelimit/overrides/{client:.+}", a.handleDeleteOverride).Methods("DELETE")
+}
+
+func (a *API) handleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.limiter.Clients())
+}
+
+func (a *API) handleGet(w http.ResponseWriter, r *http.Request) {
+	statuses, err := a.limiter.Client(mux.Vars(r)["client"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (a *API) handleReset(w http.ResponseWriter, r *http.Request) {
+	if err := a.limiter.Reset(mux.Vars(r)["client"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleListOverrides(w http.ResponseWriter, r *http.Request) {
+	overrides, err := a.limiter.Overrides()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, overrides)
+}
+
+func (a *API) handleSetOverride(w http.ResponseWriter, r *http.Request) {
+	var req OverrideRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	ttl, err := time.ParseDuration(req.TTL)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "invalid ttl")
+		return
+	}
+
+	o, err := a.limiter.SetOverride(mux.Vars(r)["client"], req.Limit, ttl)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, o)
+}
+
+func (a *API) handleDeleteOverride(w http.ResponseWriter, r *http.Request) {
+	if err := a.limiter.DeleteOverride(mux.Vars(r)["client"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		utils.WriteJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInvalidOverride):
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+	default:
+		utils.WriteJSONError(w, http.StatusInternalServerError, "rate limiter error")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,77 @@
+package ratelimit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/ratelimit"
+)
+
+func TestAPI(t *testing.T) {
+	rl := newAdminLimiter(t, newClock())
+	r := mux.NewRouter()
+	ratelimit.NewAPI(rl).Register(r)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	rl.AllowLevel("service:payments/billing", "")
+	rl.AllowLevel("1.2.3.4", "")
+
+	w := do("GET", "/admin/ratelimit/clients", "")
+	var clients []ratelimit.ClientStatus
+	json.NewDecoder(w.Body).Decode(&clients)
+	if w.Code != http.StatusOK || len(clients) != 2 || clients[0].Client != "1.2.3.4" {
+		t.Fatalf("unexpected clients: %d %+v", w.Code, clients)
+	}
+
+	// Les clés contenant "/" restent utilisables dans le chemin
+	w = do("GET", "/admin/ratelimit/clients/service:payments/billing", "")
+	var one []ratelimit.ClientStatus
+	json.NewDecoder(w.Body).Decode(&one)
+	if w.Code != http.StatusOK || len(one) != 1 || one[0].Remaining != 1 {
+		t.Errorf("unexpected client status: %d %+v", w.Code, one)
+	}
+
+	w = do("PUT", "/admin/ratelimit/overrides/service:payments/billing", `{"limit":50,"ttl":"30m"}`)
+	var o ratelimit.Override
+	json.NewDecoder(w.Body).Decode(&o)
+	if w.Code != http.StatusOK || o.Client != "service:payments/billing" || o.Limit != 50 {
+		t.Errorf("unexpected override: %d %+v", w.Code, o)
+	}
+	w = do("GET", "/admin/ratelimit/overrides", "")
+	var overrides []ratelimit.Override
+	json.NewDecoder(w.Body).Decode(&overrides)
+	if len(overrides) != 1 {
+		t.Errorf("expected 1 override, got %+v", overrides)
+	}
+
+	for _, tt := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{"PUT", "/admin/ratelimit/overrides/key:payments/agent", `not json`, http.StatusBadRequest},
+		{"PUT", "/admin/ratelimit/overrides/key:payments/agent", `{"limit":50,"ttl":"soon"}`, http.StatusBadRequest},
+		{"PUT", "/admin/ratelimit/overrides/key:payments/agent", `{"limit":0,"ttl":"1h"}`, http.StatusBadRequest},
+		{"GET", "/admin/ratelimit/clients/key:payments/unknown", ``, http.StatusNotFound},
+		{"DELETE", "/admin/ratelimit/clients/key:payments/unknown", ``, http.StatusNotFound},
+		{"DELETE", "/admin/ratelimit/overrides/key:payments/unknown", ``, http.StatusNotFound},
+		{"DELETE", "/admin/ratelimit/overrides/service:payments/billing", ``, http.StatusNoContent},
+		{"DELETE", "/admin/ratelimit/clients/1.2.3.4", ``, http.StatusNoContent},
+	} {
+		if w := do(tt.method, tt.path, tt.body); w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, w.Code)
+		}
+	}
+	if clients := rl.Clients(); len(clients) != 1 {
+		t.Errorf("expected the reset client to be gone, got %+v", clients)
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// KeyFunc retourne l'identifiant du client auquel une requête est imputée
+type KeyFunc func(r *http.Request) string
+
+// Clés de limitation disponibles (ParseKey)
+const (
+	KeyIP      = "ip"
+	KeyAPIKey  = "key"
+	KeyTenant  = "tenant"
+	KeyService = "service"
+)
+
+// ParseKey retourne la KeyFunc nommée name (ip par défaut)
+func ParseKey(name string) (KeyFunc, error) {
+	switch name {
+	case KeyIP, "":
+		return ByIP, nil
+	case KeyAPIKey:
+		return ByAPIKey, nil
+	case KeyTenant:
+		return ByTenant, nil
+	case KeyService:
+		return ByService, nil
+	default:
+		return nil, fmt.Errorf("invalid rate limit key %q (expected ip, key, tenant or service)", name)
+	}
+}
+
+// ByIP limite par adresse IP, ou par identité pour un client authentifié par certificat
+// (agents derrière un même NAT ou proxy)
+func ByIP(r *http.Request) string {
+	if id := utils.GetClientCertIdentity(r); id != "" {
+		return "cert:" + id
+	}
+	return utils.GetClientIP(r)
+}
+
+// ByAPIKey limite par clé API (ou jeton), nommée dans son tenant : une clé tournée garde ses
+// compteurs, deux tenants peuvent avoir une clé du même nom. Les requêtes non authentifiées
+// sont limitées par IP.
+func ByAPIKey(r *http.Request) string {
+	if key := apikey.FromContext(r.Context()); key != nil {
+		return "key:" + key.Tenant + "/" + key.Name
+	}
+	return ByIP(r)
+}
+
+// ByTenant limite par tenant : toutes les clés d'un tenant partagent la limite. Les clés du
+// tenant par défaut sont limitées par clé.
+func ByTenant(r *http.Request) string {
+	if key := apikey.FromContext(r.Context()); key != nil && key.Tenant != "" {
+		return "tenant:" + key.Tenant
+	}
+	return ByAPIKey(r)
+}
+
+// ByService limite par service de la clé (Key.Service); les clés sans service sont
+// limitées par clé
+func ByService(r *http.Request) string {
+	if key := apikey.FromContext(r.Context()); key != nil && key.Service != "" {
+		return "service:" + key.Tenant + "/" + key.Service
+	}
+	return ByAPIKey(r)
+}
@@ -0,0 +1,60 @@
+package ratelimit_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rypi-dev/logger-server/internal/apikey"
+	"github.com/rypi-dev/logger-server/internal/ratelimit"
+)
+
+func TestKeyFuncs(t *testing.T) {
+	request := func(key *apikey.Key) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/log", nil)
+		req.RemoteAddr = "1.2.3.4:5678"
+		if key != nil {
+			req = req.WithContext(apikey.WithKey(req.Context(), key))
+		}
+		return req
+	}
+	anonymous := request(nil)
+	agent := request(&apikey.Key{Name: "agent", Tenant: "payments", Service: "billing"})
+	admin := request(&apikey.Key{Name: "bootstrap"})
+
+	for _, tt := range []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{ratelimit.KeyIP, agent, "1.2.3.4"},
+		{ratelimit.KeyAPIKey, agent, "key:payments/agent"},
+		{ratelimit.KeyAPIKey, anonymous, "1.2.3.4"},
+		{ratelimit.KeyTenant, agent, "tenant:payments"},
+		{ratelimit.KeyTenant, admin, "key:/bootstrap"},
+		{ratelimit.KeyService, agent, "service:payments/billing"},
+		{ratelimit.KeyService, admin, "key:/bootstrap"},
+		{ratelimit.KeyService, anonymous, "1.2.3.4"},
+	} {
+		key, err := ratelimit.ParseKey(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := key(tt.req); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := ratelimit.ParseKey("user"); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "collector"}}
+	anonymous.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	if got := ratelimit.ByIP(anonymous); got != "cert:collector" {
+		t.Errorf("expected the certificate identity, got %q", got)
+	}
+}
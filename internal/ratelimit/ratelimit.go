@@ -1,9 +1,11 @@
 package ratelimit
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -13,102 +15,236 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// DefaultMaxClients est le nombre de clients suivis si Config ne le précise pas
+const DefaultMaxClients = 10000
+
+// En-têtes de chaque réponse limitée, pour que le client règle son débit : limite de la
+// fenêtre, requêtes encore permises et secondes avant que toute la limite soit disponible
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+)
+
+var ErrLimited = errors.New("rate limit exceeded")
+
+// LimitError est retournée pour une entrée refusée par AllowEntry
+type LimitError struct {
+	Level      string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v for %s entries, retry in %s", ErrLimited, e.Level, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimited
+}
+
+var (
+	requestsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ratelimiter_requests_total",
+		Help: "Total number of requests processed",
+	})
+	blockedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ratelimiter_blocked_total",
+		Help: "Total number of requests blocked",
+	})
+	activeClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ratelimiter_active_clients",
+		Help: "Current number of active clients",
+	})
+	sharedErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ratelimiter_shared_errors_total",
+		Help: "Total number of shared store errors (requests limited locally)",
+	})
+	sharedAvailable = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ratelimiter_shared_available",
+		Help: "Whether the shared store is used (1) or limits are applied locally (0)",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, blockedTotal, activeClients, sharedErrorsTotal, sharedAvailable)
+}
+
+// Config configure un RateLimiter
+type Config struct {
+	MaxRequests int // limite par défaut, en requêtes par Window
+	Window      time.Duration
+	MaxClients  int    // clients suivis au plus, les moins récents sont évincés
+	Algorithm   string // FixedWindow par défaut
+	Burst       int    // TokenBucket : capacité du seau (MaxRequests par défaut)
+
+	// Client auquel une requête est imputée (ByIP par défaut)
+	Key KeyFunc
+
+	// Seuil minimal de niveau et limites par niveau de la limite par défaut, selon le niveau
+	// déclaré par le client dans l'en-tête LevelHeader (vide : pas de niveau par requête)
+	LevelHeader    string
+	MinLevel       log_levels.LogLevel
+	PerLevelLimits map[log_levels.LogLevel]int
+
+	// Limites par niveau des entrées décodées du corps (AllowEntry), par Window et par
+	// client. Chaque niveau a ses compteurs; un niveau absent n'est pas limité.
+	EntryLimits map[log_levels.LogLevel]int
+
+	// Limites propres à certaines routes, prioritaires sur la limite par défaut
+	Routes []Rule
+
+	// État partagé entre les instances (nil : état en mémoire). Si le stockage est
+	// injoignable, chaque instance limite localement et le réessaie après SharedRetry
+	// (DefaultSharedRetry par défaut).
+	Shared      SharedStore
+	SharedRetry time.Duration
+
+	// Clock remplace time.Now (tests)
+	Clock func() time.Time
+}
+
+// policy est une règle compilée : la limite par défaut (rule.Path vide), une route ou un
+// niveau d'entrées
+type policy struct {
+	rule    Rule
+	alg     algorithm
+	entries bool
+}
+
+// Status est l'état de la limite d'un client après une requête
+type Status struct {
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+}
+
 type RateLimiter struct {
 	mu            sync.RWMutex
 	requests      map[string]*clientData
-	maxRequests   int
 	maxClients    int
-	window        time.Duration
+	now           func() time.Time
 	cleanupTicker *time.Ticker
 	quit          chan struct{}
 	onceStop      sync.Once
 
-	requestsTotal prometheus.Counter
-	blockedTotal  prometheus.Counter
-	activeClients prometheus.Gauge
+	defaults *policy
+	routes   []*policy
+	entries  map[log_levels.LogLevel]*policy
+	key      KeyFunc
+
+	// Limites temporaires par client (API d'administration)
+	overrides map[string]*Override
 
+	shared *shared
+
+	levelHeader    string
 	minLevel       log_levels.LogLevel
 	perLevelLimits map[log_levels.LogLevel]int
 }
 
+// clientData est l'état d'un client pour une règle; les champs utilisés dépendent de
+// l'algorithme
 type clientData struct {
-	count     int
-	firstSeen time.Time
+	policy   *policy
+	client   string
+	lastSeen time.Time
+
+	count     int       // fenêtres fixe et glissante : requêtes de la fenêtre courante
+	firstSeen time.Time // fenêtres fixe et glissante : début de la fenêtre courante
+	previous  int       // fenêtre glissante : requêtes de la fenêtre précédente
+
+	tokens   float64   // seau à jetons
+	refilled time.Time // seau à jetons : dernier remplissage
+
+	log []time.Time // journal glissant
+
+	shared *sharedState // état partagé : dernière réponse du stockage
 }
 
-// NewRateLimiterWithLevel crée un rate limiter avec seuil minimal de niveau et règles par niveau
-func NewRateLimiterWithLevel(maxRequests int, window time.Duration, maxClients int, minLevel log_levels.LogLevel, perLevelLimits map[log_levels.LogLevel]int) (*RateLimiter, error) {
-	if err := utils.ValidateMaxRequests(maxRequests); err != nil {
+// NewRateLimiter crée un rate limiter
+func NewRateLimiter(cfg Config) (*RateLimiter, error) {
+	if err := utils.ValidateMaxRequests(cfg.MaxRequests); err != nil {
 		return nil, err
 	}
-	if err := utils.ValidateWindow(window); err != nil {
+	if err := utils.ValidateWindow(cfg.Window); err != nil {
 		return nil, err
 	}
+	alg, err := newAlgorithm(cfg.Algorithm, cfg.MaxRequests, cfg.Window, cfg.Burst)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxClients <= 0 {
+		cfg.MaxClients = DefaultMaxClients
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	if cfg.Key == nil {
+		cfg.Key = ByIP
+	}
 
 	rl := &RateLimiter{
 		requests:      make(map[string]*clientData),
-		maxRequests:   maxRequests,
-		maxClients:    maxClients,
-		window:        window,
+		overrides:     make(map[string]*Override),
+		maxClients:    cfg.MaxClients,
+		now:           cfg.Clock,
 		cleanupTicker: time.NewTicker(5 * time.Minute),
 		quit:          make(chan struct{}),
-		minLevel:      minLevel,
-		perLevelLimits: perLevelLimits,
+		defaults: &policy{
+			rule: Rule{Algorithm: cfg.Algorithm, Limit: cfg.MaxRequests, Window: cfg.Window, Burst: cfg.Burst},
+			alg:  alg,
+		},
+		key:            cfg.Key,
+		levelHeader:    cfg.LevelHeader,
+		minLevel:       cfg.MinLevel,
+		perLevelLimits: cfg.PerLevelLimits,
+	}
+	for _, rule := range cfg.Routes {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		alg, _ := newAlgorithm(rule.Algorithm, rule.Limit, rule.Window, rule.Burst)
+		rl.routes = append(rl.routes, &policy{rule: rule, alg: alg})
+	}
+	for level, limit := range cfg.EntryLimits {
+		if err := utils.ValidateMaxRequests(limit); err != nil {
+			return nil, fmt.Errorf("%s entries: %v", level, err)
+		}
+		if rl.entries == nil {
+			rl.entries = make(map[log_levels.LogLevel]*policy)
+		}
+		rule := Rule{Path: "entries " + string(level), Algorithm: cfg.Algorithm, Limit: limit, Window: cfg.Window, Burst: cfg.Burst}
+		alg, _ := newAlgorithm(rule.Algorithm, rule.Limit, rule.Window, rule.Burst)
+		rl.entries[level] = &policy{rule: rule, alg: alg, entries: true}
 	}
 
-	rl.initMetrics()
+	if cfg.Shared != nil {
+		rl.shared = newShared(cfg.Shared, cfg.SharedRetry)
+	}
 
 	go rl.cleanupLoop()
 
 	return rl, nil
 }
 
-func (rl *RateLimiter) initMetrics() {
-	rl.requestsTotal = prometheus.NewCounter(prometheus.CounterOpts{
-		Name: "ratelimiter_requests_total",
-		Help: "Total number of requests processed",
-	})
-	rl.blockedTotal = prometheus.NewCounter(prometheus.CounterOpts{
-		Name: "ratelimiter_blocked_total",
-		Help: "Total number of requests blocked",
-	})
-	rl.activeClients = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "ratelimiter_active_clients",
-		Help: "Current number of active clients",
-	})
-
-	// Ignore panic if metrics already registered
-	prometheus.MustRegister(rl.requestsTotal, rl.blockedTotal, rl.activeClients)
-}
-
-// Middleware applique le rate limit selon niveau log dans header "X-Log-Level"
+// Middleware applique le rate limit de la route au client de la requête (Config.Key), selon
+// le niveau déclaré dans l'en-tête Config.LevelHeader s'il est configuré. Pour limiter par
+// clé, tenant ou service, il se place après l'authentification.
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := utils.GetClientIP(r)
-
-		levelStr := r.Header.Get("X-Log-Level")
-		level := log_levels.NormalizeLogLevel(levelStr)
-
-		if log_levels.LevelLessThan(level, rl.minLevel) {
-			// Niveau trop bas, pas de rate limit
-			next.ServeHTTP(w, r)
-			return
+		var level log_levels.LogLevel
+		if rl.levelHeader != "" {
+			level = log_levels.NormalizeLogLevel(r.Header.Get(rl.levelHeader))
 		}
 
-		maxReq := rl.maxRequests
-		if rl.perLevelLimits != nil {
-			if lvlMax, ok := rl.perLevelLimits[level]; ok {
-				maxReq = lvlMax
-			}
+		allowed, retryAfter, status := rl.allowPolicy(rl.policyFor(r.Method, r.URL.Path), rl.key(r), level)
+		if status.Limit > 0 {
+			w.Header().Set(HeaderLimit, strconv.Itoa(status.Limit))
+			w.Header().Set(HeaderRemaining, strconv.Itoa(status.Remaining))
+			w.Header().Set(HeaderReset, strconv.Itoa(int(math.Ceil(status.Reset.Seconds()))))
 		}
-
-		allowed, retryAfter := rl.allow(ip, maxReq)
 		if !allowed {
-			seconds := int(retryAfter.Seconds())
-			if seconds < 0 {
-				seconds = 0
-			}
-			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
 			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
 			return
 		}
@@ -117,60 +253,137 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	})
 }
 
-func (rl *RateLimiter) allow(ip string, maxRequests int) (bool, time.Duration) {
-	now := time.Now()
+// policyFor retourne la règle de la première route correspondante, la limite par défaut sinon
+func (rl *RateLimiter) policyFor(method, path string) *policy {
+	for _, p := range rl.routes {
+		if p.rule.matches(method, path) {
+			return p
+		}
+	}
+	return rl.defaults
+}
 
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+// AllowLevel applique la limite par défaut du middleware (seuil minimal, limites par niveau)
+// à un client identifié par key. Utilisé tel quel par les transports non HTTP (gRPC, ...).
+func (rl *RateLimiter) AllowLevel(key string, level log_levels.LogLevel) (bool, time.Duration) {
+	allowed, retryAfter, _ := rl.allowPolicy(rl.defaults, key, level)
+	return allowed, retryAfter
+}
 
-	// Eviction si trop de clients avant d'ajouter
-	if !rl.exists(ip) && len(rl.requests) >= rl.maxClients {
-		rl.evictOldest()
+// Allow applique la limite par défaut au client de r (Config.Key), sans niveau par requête.
+// Utilisé par les transports non HTTP (gRPC, ...) avec une requête qui décrit l'appel.
+func (rl *RateLimiter) Allow(r *http.Request) (bool, time.Duration) {
+	allowed, retryAfter, _ := rl.allowPolicy(rl.defaults, rl.key(r), "")
+	return allowed, retryAfter
+}
+
+// AllowEntry applique la limite du niveau d'une entrée décodée du corps (Config.EntryLimits)
+// au client de la requête, pour refuser individuellement les entrées d'un lot au-delà de la
+// limite. Retourne une *LimitError (ErrLimited) pour une entrée refusée.
+func (rl *RateLimiter) AllowEntry(r *http.Request, level string) error {
+	normalized := log_levels.NormalizeLogLevel(level)
+	p, ok := rl.entries[normalized]
+	if !ok {
+		return nil
+	}
+	if allowed, retryAfter, _ := rl.allow(p, rl.key(r), p.rule.Limit); !allowed {
+		return &LimitError{Level: string(normalized), RetryAfter: retryAfter}
 	}
+	return nil
+}
 
-	client, exists := rl.requests[ip]
-	if !exists || now.Sub(client.firstSeen) > rl.window {
-		rl.requests[ip] = &clientData{
-			count:     1,
-			firstSeen: now,
+func (rl *RateLimiter) allowPolicy(p *policy, key string, level log_levels.LogLevel) (bool, time.Duration, Status) {
+	// Un niveau absent ou inconnu ne contourne pas la limite
+	if log_levels.IsValidLogLevel(string(level)) && log_levels.LevelLessThan(level, rl.minLevel) {
+		// Niveau trop bas, pas de rate limit
+		return true, 0, Status{}
+	}
+
+	maxReq := p.rule.Limit
+	if p == rl.defaults && rl.perLevelLimits != nil {
+		if lvlMax, ok := rl.perLevelLimits[level]; ok {
+			maxReq = lvlMax
 		}
-		rl.activeClients.Set(float64(len(rl.requests)))
-		rl.requestsTotal.Inc()
-		return true, 0
 	}
 
-	if client.count >= maxRequests {
-		rl.blockedTotal.Inc()
-		return false, rl.window - now.Sub(client.firstSeen)
+	return rl.allow(p, key, maxReq)
+}
+
+func (rl *RateLimiter) allow(p *policy, key string, maxRequests int) (bool, time.Duration, Status) {
+	if rl.shared != nil {
+		if allowed, retryAfter, status, ok := rl.allowShared(p, key, maxRequests); ok {
+			return allowed, retryAfter, status
+		}
 	}
 
-	client.count++
-	rl.requestsTotal.Inc()
-	return true, 0
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	maxRequests = rl.limitFor(p, key, maxRequests, now)
+	client := rl.clientFor(p, key)
+	client.lastSeen = now
+	client.shared = nil
+
+	allowed, retryAfter := p.alg.allow(client, now, maxRequests)
+	status := Status{Limit: maxRequests}
+	status.Remaining, status.Reset = p.alg.status(client, now, maxRequests)
+	if !allowed {
+		blockedTotal.Inc()
+		return false, retryAfter, status
+	}
+	requestsTotal.Inc()
+	return true, 0, status
 }
 
-func (rl *RateLimiter) exists(ip string) bool {
-	_, ok := rl.requests[ip]
-	return ok
+// limitFor applique la limite temporaire éventuelle du client (appelé avec lock)
+func (rl *RateLimiter) limitFor(p *policy, key string, maxRequests int, now time.Time) int {
+	if !p.entries {
+		if o := rl.override(key, now); o != nil {
+			return o.Limit
+		}
+	}
+	return maxRequests
 }
 
-// Evict oldest client (appelé avec lock)
-func (rl *RateLimiter) evictOldest() {
-	if len(rl.requests) <= rl.maxClients {
-		return
+// clientFor retourne l'état d'un client pour une règle, créé au besoin (appelé avec lock)
+func (rl *RateLimiter) clientFor(p *policy, key string) *clientData {
+	client, exists := rl.requests[stateKey(p, rl.defaults, key)]
+	if !exists {
+		// Eviction si trop de clients avant d'ajouter
+		if len(rl.requests) >= rl.maxClients {
+			rl.evictOldest()
+		}
+		client = &clientData{policy: p, client: key}
+		rl.requests[stateKey(p, rl.defaults, key)] = client
+		activeClients.Inc()
+	}
+	return client
+}
+
+// stateKey est la clé de l'état d'un client pour une règle : les compteurs d'une route ou
+// d'un niveau d'entrées sont distincts de ceux de la limite par défaut
+func stateKey(p, defaults *policy, client string) string {
+	if p == defaults {
+		return client
 	}
+	return p.rule.String() + "|" + client
+}
 
-	var oldestIP string
+// Evict oldest client (appelé avec lock) : le moins récemment vu
+func (rl *RateLimiter) evictOldest() {
+	var oldestKey string
 	var oldestTime time.Time
 
-	for ip, data := range rl.requests {
-		if oldestTime.IsZero() || data.firstSeen.Before(oldestTime) {
-			oldestIP = ip
-			oldestTime = data.firstSeen
+	for key, data := range rl.requests {
+		if oldestTime.IsZero() || data.lastSeen.Before(oldestTime) {
+			oldestKey = key
+			oldestTime = data.lastSeen
 		}
 	}
 
-	delete(rl.requests, oldestIP)
+	delete(rl.requests, oldestKey)
+	activeClients.Dec()
 }
 
 // Cleanup loop pour nettoyage périodique
@@ -185,15 +398,16 @@ func (rl *RateLimiter) cleanupLoop() {
 	}
 }
 
-// Cleanup supprime les clients expirés et évince les plus vieux si trop nombreux
+// Cleanup supprime les clients revenus à zéro et évince les plus vieux si trop nombreux
 func (rl *RateLimiter) cleanup() {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	now := time.Now()
-	for ip, data := range rl.requests {
-		if now.Sub(data.firstSeen) > rl.window {
-			delete(rl.requests, ip)
+	now := rl.now()
+	for key, data := range rl.requests {
+		if data.idle(now) {
+			delete(rl.requests, key)
+			activeClients.Dec()
 		}
 	}
 
@@ -201,7 +415,20 @@ func (rl *RateLimiter) cleanup() {
 		rl.evictOldest()
 	}
 
-	rl.activeClients.Set(float64(len(rl.requests)))
+	for client, o := range rl.overrides {
+		if !now.Before(o.ExpiresAt) {
+			delete(rl.overrides, client)
+		}
+	}
+}
+
+// idle indique que l'état n'a plus d'effet : selon la dernière réponse du stockage
+// partagé, ou l'algorithme pour un état local
+func (c *clientData) idle(now time.Time) bool {
+	if c.shared != nil {
+		return c.shared.resetAt(now) == 0
+	}
+	return c.policy.alg.idle(c, now)
 }
 
 // Stop arrête proprement le nettoyage périodique
@@ -212,14 +439,15 @@ func (rl *RateLimiter) Stop() {
 	})
 }
 
-// AllowTest expose allow pour les tests
+// AllowTest expose allow (limite par défaut) pour les tests
 func (rl *RateLimiter) AllowTest(ip string, maxReq int) (bool, time.Duration) {
-    return rl.allow(ip, maxReq)  // `allow` en minuscule
+	allowed, retryAfter, _ := rl.allow(rl.defaults, ip, maxReq)
+	return allowed, retryAfter
 }
 
 // CleanupTest permet de déclencher cleanup manuellement dans les tests
 func (rl *RateLimiter) CleanupTest() {
-	rl.Cleanup()
+	rl.cleanup()
 }
 
 // ClientsSnapshot retourne les clients pour tests
@@ -239,4 +467,4 @@ func (rl *RateLimiter) ClientExists(ip string) bool {
 	defer rl.mu.RUnlock()
 	_, exists := rl.requests[ip]
 	return exists
-}
\ No newline at end of file
+}
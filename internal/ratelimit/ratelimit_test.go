@@ -1,17 +1,36 @@
 package ratelimit_test
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/rypi-dev/logger-server/internal/apikey"
 	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
-	"github.com/rypi-dev/logger-server/internal/middleware/ratelimit"
+	"github.com/rypi-dev/logger-server/internal/ratelimit"
 )
 
-func TestNewRateLimiterWithLevel_ValidConfig(t *testing.T) {
-	rl, err := ratelimit.NewRateLimiterWithLevel(5, time.Second, 10, "INFO", nil)
+// newLevelLimiter crée un rate limiter à fenêtre fixe par IP, avec seuil minimal de niveau
+// et limites par niveau lus dans l'en-tête X-Log-Level
+func newLevelLimiter(maxRequests int, window time.Duration, maxClients int, minLevel log_levels.LogLevel, perLevelLimits map[log_levels.LogLevel]int) (*ratelimit.RateLimiter, error) {
+	return ratelimit.NewRateLimiter(ratelimit.Config{
+		MaxRequests:    maxRequests,
+		Window:         window,
+		MaxClients:     maxClients,
+		Algorithm:      ratelimit.FixedWindow,
+		LevelHeader:    "X-Log-Level",
+		MinLevel:       minLevel,
+		PerLevelLimits: perLevelLimits,
+	})
+}
+
+func TestNewRateLimiter_LevelHeader(t *testing.T) {
+	rl, err := newLevelLimiter(5, time.Second, 10, "INFO", nil)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -19,7 +38,7 @@ func TestNewRateLimiterWithLevel_ValidConfig(t *testing.T) {
 }
 
 func TestMiddleware_AllowRequest(t *testing.T) {
-	rl, _ := ratelimit.NewRateLimiterWithLevel(5, time.Minute, 10, "INFO", nil)
+	rl, _ := newLevelLimiter(5, time.Minute, 10, "INFO", nil)
 	defer rl.Stop()
 
 	handlerCalled := false
@@ -40,7 +59,7 @@ func TestMiddleware_AllowRequest(t *testing.T) {
 }
 
 func TestMiddleware_BlockRequest_TooMany(t *testing.T) {
-	rl, _ := ratelimit.NewRateLimiterWithLevel(2, time.Minute, 10, "INFO", nil)
+	rl, _ := newLevelLimiter(2, time.Minute, 10, "INFO", nil)
 	defer rl.Stop()
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -67,8 +86,33 @@ func TestMiddleware_BlockRequest_TooMany(t *testing.T) {
 	}
 }
 
+func TestMiddleware_ClientCertificate_KeyedByIdentity(t *testing.T) {
+	rl, _ := newLevelLimiter(1, time.Minute, 10, "INFO", nil)
+	defer rl.Stop()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "collector"}}
+
+	// Même certificat depuis deux adresses : un seul quota
+	codes := []int{}
+	for _, addr := range []string{"1.2.3.4:5678", "5.6.7.8:5678"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Log-Level", "INFO")
+		req.RemoteAddr = addr
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		rr := httptest.NewRecorder()
+		rl.Middleware(handler).ServeHTTP(rr, req)
+		codes = append(codes, rr.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("expected [200 429], got %v", codes)
+	}
+}
+
 func TestMiddleware_LevelBelowMin_NotLimited(t *testing.T) {
-	rl, _ := ratelimit.NewRateLimiterWithLevel(1, time.Minute, 10, "INFO", nil)
+	rl, _ := newLevelLimiter(1, time.Minute, 10, "INFO", nil)
 	defer rl.Stop()
 
 	handlerCalled := 0
@@ -90,8 +134,19 @@ func TestMiddleware_LevelBelowMin_NotLimited(t *testing.T) {
 	}
 }
 
+// clock est une horloge de test avancée à la main
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time          { return c.now }
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newClock() *clock {
+	return &clock{now: time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)}
+}
+
 func TestAllow_ResetsAfterWindow(t *testing.T) {
-	rl, _ := ratelimit.NewRateLimiterWithLevel(1, 100*time.Millisecond, 10, "INFO", nil)
+	c := newClock()
+	rl, _ := ratelimit.NewRateLimiter(ratelimit.Config{MaxRequests: 1, Window: 100 * time.Millisecond, Clock: c.Now})
 	defer rl.Stop()
 
 	ip := "1.2.3.4"
@@ -103,13 +158,14 @@ func TestAllow_ResetsAfterWindow(t *testing.T) {
 	}
 
 	// Second call should be blocked
-	allowed, _ = rl.AllowTest(ip, 1)
-	if allowed {
-		t.Error("expected second call to be blocked")
+	c.Advance(30 * time.Millisecond)
+	allowed, retryAfter := rl.AllowTest(ip, 1)
+	if allowed || retryAfter != 70*time.Millisecond {
+		t.Errorf("expected second call to be blocked for 70ms, got %v, %v", allowed, retryAfter)
 	}
 
 	// Wait until window expires
-	time.Sleep(120 * time.Millisecond)
+	c.Advance(70 * time.Millisecond)
 
 	// Should be allowed again
 	allowed, _ = rl.AllowTest(ip, 1)
@@ -122,7 +178,7 @@ func TestMiddleware_PerLevelLimits(t *testing.T) {
 	limits := map[log_levels.LogLevel]int{
 		"ERROR": 1,
 	}
-	rl, _ := ratelimit.NewRateLimiterWithLevel(5, time.Minute, 10, "DEBUG", limits)
+	rl, _ := newLevelLimiter(5, time.Minute, 10, "DEBUG", limits)
 	defer rl.Stop()
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -148,8 +204,28 @@ func TestMiddleware_PerLevelLimits(t *testing.T) {
 	}
 }
 
+func TestAllowLevel(t *testing.T) {
+	rl, _ := newLevelLimiter(1, time.Minute, 10, "INFO", map[log_levels.LogLevel]int{
+		log_levels.LogLevelError: 2,
+	})
+	defer rl.Stop()
+
+	if allowed, _ := rl.AllowLevel("grpc-client", log_levels.LogLevelInfo); !allowed {
+		t.Error("expected first INFO call to be allowed")
+	}
+	if allowed, retryAfter := rl.AllowLevel("grpc-client", log_levels.LogLevelInfo); allowed || retryAfter <= 0 {
+		t.Errorf("expected second INFO call to be blocked with a retry delay, got %v, %v", allowed, retryAfter)
+	}
+	if allowed, _ := rl.AllowLevel("grpc-client", log_levels.LogLevelError); !allowed {
+		t.Error("expected ERROR call to use its own higher limit")
+	}
+	if allowed, _ := rl.AllowLevel("grpc-client", log_levels.LogLevelDebug); !allowed {
+		t.Error("expected levels below the minimum not to be limited")
+	}
+}
+
 func TestEviction_WhenMaxClientsExceeded(t *testing.T) {
-	rl, _ := ratelimit.NewRateLimiterWithLevel(5, time.Minute, 2, "INFO", nil)
+	rl, _ := newLevelLimiter(5, time.Minute, 2, "INFO", nil)
 	defer rl.Stop()
 
 	ips := []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}
@@ -165,13 +241,19 @@ func TestEviction_WhenMaxClientsExceeded(t *testing.T) {
 }
 
 func TestCleanup_RemovesExpiredClients(t *testing.T) {
-	rl, _ := ratelimit.NewRateLimiterWithLevel(5, 50*time.Millisecond, 10, "INFO", nil)
+	c := newClock()
+	rl, _ := ratelimit.NewRateLimiter(ratelimit.Config{MaxRequests: 5, Window: 50 * time.Millisecond, Clock: c.Now})
 	defer rl.Stop()
 
 	ip := "1.2.3.4"
 	rl.AllowTest(ip, 5)
 
-	time.Sleep(100 * time.Millisecond)
+	rl.CleanupTest()
+	if !rl.ClientExists(ip) {
+		t.Fatal("expected an active client to be kept")
+	}
+
+	c.Advance(100 * time.Millisecond)
 
 	rl.CleanupTest()
 
@@ -180,8 +262,183 @@ func TestCleanup_RemovesExpiredClients(t *testing.T) {
 	}
 }
 
+func TestMiddleware_Routes(t *testing.T) {
+	c := newClock()
+	rl, err := ratelimit.NewRateLimiter(ratelimit.Config{
+		MaxRequests: 1,
+		Window:      time.Minute,
+		Algorithm:   ratelimit.SlidingWindow,
+		Routes: []ratelimit.Rule{
+			{Method: "POST", Path: "/log/batch", Algorithm: ratelimit.TokenBucket, Limit: 60, Window: time.Minute, Burst: 3},
+		},
+		Clock: c.Now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Stop()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "1.2.3.4:5678"
+		rr := httptest.NewRecorder()
+		rl.Middleware(handler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// La route a son propre seau de 3 jetons, indépendant de la limite par défaut
+	for i := 0; i < 3; i++ {
+		if rr := do("POST", "/log/batch"); rr.Code != http.StatusOK {
+			t.Fatalf("batch %d: expected 200, got %d", i, rr.Code)
+		}
+	}
+	rr := do("POST", "/log/batch")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected 429 with Retry-After 1, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if rr := do("POST", "/log"); rr.Code != http.StatusOK {
+		t.Errorf("expected the default limit to be untouched, got %d", rr.Code)
+	}
+	if rr := do("GET", "/log/batch"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected other methods to share the default limit, got %d", rr.Code)
+	}
+
+	// Un jeton par seconde
+	c.Advance(time.Second)
+	if rr := do("POST", "/log/batch"); rr.Code != http.StatusOK {
+		t.Errorf("expected a refilled token to be accepted, got %d", rr.Code)
+	}
+}
+
+func TestMiddleware_Headers(t *testing.T) {
+	c := newClock()
+	rl, _ := ratelimit.NewRateLimiter(ratelimit.Config{MaxRequests: 2, Window: time.Minute, Algorithm: ratelimit.SlidingLog, Clock: c.Now})
+	defer rl.Stop()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/log", nil)
+		req.RemoteAddr = "1.2.3.4:5678"
+		rr := httptest.NewRecorder()
+		rl.Middleware(handler).ServeHTTP(rr, req)
+		return rr
+	}
+	headers := func(rr *httptest.ResponseRecorder) []string {
+		return []string{rr.Header().Get(ratelimit.HeaderLimit), rr.Header().Get(ratelimit.HeaderRemaining), rr.Header().Get(ratelimit.HeaderReset)}
+	}
+
+	if got := headers(do()); got[0] != "2" || got[1] != "1" || got[2] != "60" {
+		t.Errorf("unexpected headers after the first request: %v", got)
+	}
+	c.Advance(20 * time.Second)
+	do()
+	rr := do()
+	if got := headers(rr); rr.Code != http.StatusTooManyRequests || got[1] != "0" || got[2] != "60" || rr.Header().Get("Retry-After") != "40" {
+		t.Errorf("expected 429 with no remaining request, got %d %v, Retry-After %q", rr.Code, got, rr.Header().Get("Retry-After"))
+	}
+}
+
+func TestMiddleware_KeyAndLevelHeader(t *testing.T) {
+	rl, _ := ratelimit.NewRateLimiter(ratelimit.Config{
+		MaxRequests: 1,
+		Window:      time.Minute,
+		Key:         ratelimit.ByAPIKey,
+		MinLevel:    log_levels.LogLevelInfo,
+		Clock:       newClock().Now,
+	})
+	defer rl.Stop()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	do := func(name, addr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/log", nil)
+		req.RemoteAddr = addr
+		// Sans Config.LevelHeader, l'en-tête ne contourne plus la limite
+		req.Header.Set("X-Log-Level", "DEBUG")
+		req = req.WithContext(apikey.WithKey(req.Context(), &apikey.Key{Name: name}))
+		rr := httptest.NewRecorder()
+		rl.Middleware(handler).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Une clé garde sa limite quelle que soit son adresse, deux clés derrière un même NAT
+	// ont chacune la leur
+	codes := []int{do("agent", "1.1.1.1:1"), do("agent", "2.2.2.2:2"), do("other", "1.1.1.1:1")}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests || codes[2] != http.StatusOK {
+		t.Errorf("expected [200 429 200], got %v", codes)
+	}
+}
+
+func TestAllowEntry(t *testing.T) {
+	c := newClock()
+	rl, _ := ratelimit.NewRateLimiter(ratelimit.Config{
+		MaxRequests: 1,
+		Window:      time.Minute,
+		Algorithm:   ratelimit.SlidingLog,
+		EntryLimits: map[log_levels.LogLevel]int{log_levels.LogLevelInfo: 2, log_levels.LogLevelError: 3},
+		Clock:       c.Now,
+	})
+	defer rl.Stop()
+
+	req := httptest.NewRequest(http.MethodPost, "/log/batch", nil)
+	req.RemoteAddr = "1.2.3.4:5678"
+
+	var rejected []string
+	for _, level := range []string{"info", "ERROR", "INFO", "INFO", "DEBUG", "DEBUG", "ERROR", "ERROR", "ERROR"} {
+		if err := rl.AllowEntry(req, level); err != nil {
+			if !errors.Is(err, ratelimit.ErrLimited) {
+				t.Fatalf("expected ErrLimited, got %v", err)
+			}
+			rejected = append(rejected, err.Error())
+		}
+	}
+	want := []string{
+		"rate limit exceeded for INFO entries, retry in 1m0s",
+		"rate limit exceeded for ERROR entries, retry in 1m0s",
+	}
+	if len(rejected) != 2 || rejected[0] != want[0] || rejected[1] != want[1] {
+		t.Errorf("expected one INFO and one ERROR entry rejected, got %q", rejected)
+	}
+
+	// Les entrées ont leurs propres compteurs : la requête reste sous sa limite
+	if allowed, _ := rl.AllowLevel("1.2.3.4", ""); !allowed {
+		t.Error("expected entry limits not to consume the request limit")
+	}
+
+	c.Advance(time.Minute)
+	var le *ratelimit.LimitError
+	if err := rl.AllowEntry(req, "INFO"); err != nil {
+		t.Errorf("expected the entry to be allowed in a new window, got %v", err)
+	}
+	rl.AllowEntry(req, "INFO")
+	if err := rl.AllowEntry(req, "INFO"); !errors.As(err, &le) || le.Level != "INFO" || le.RetryAfter != time.Minute {
+		t.Errorf("expected a LimitError with a retry delay, got %v", err)
+	}
+}
+
+func TestNewRateLimiter_InvalidConfig(t *testing.T) {
+	for _, cfg := range []ratelimit.Config{
+		{MaxRequests: 0, Window: time.Minute},
+		{MaxRequests: 10, Window: 0},
+		{MaxRequests: 10, Window: time.Minute, Algorithm: "leaky_bucket"},
+		{MaxRequests: 10, Window: time.Minute, Routes: []ratelimit.Rule{{Path: "/log", Limit: 10}}},
+		{MaxRequests: 10, Window: time.Minute, EntryLimits: map[log_levels.LogLevel]int{log_levels.LogLevelInfo: 0}},
+	} {
+		if rl, err := ratelimit.NewRateLimiter(cfg); err == nil {
+			rl.Stop()
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
+
 func TestStop_IsSafe(t *testing.T) {
-	rl, _ := ratelimit.NewRateLimiterWithLevel(5, time.Second, 10, "INFO", nil)
+	rl, _ := newLevelLimiter(5, time.Second, 10, "INFO", nil)
 	rl.Stop()
 	rl.Stop() // Should be safe to call again
-}
\ No newline at end of file
+}
@@ -0,0 +1,532 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Valeurs par défaut de RedisConfig
+const (
+	DefaultRedisPrefix   = "logger-server:ratelimit:"
+	DefaultRedisTimeout  = 100 * time.Millisecond
+	DefaultRedisPoolSize = 8
+)
+
+// limiterScript applique un algorithme à l'état d'un client dans KEYS[1] (hash, ou sorted
+// set pour sliding_log), comme les algorithmes en mémoire. ARGV : algorithme, limite,
+// fenêtre (ms), capacité du seau, maintenant (ms), identifiant unique de la requête, et
+// client dont la limite temporaire en cours dans le hash KEYS[2] remplace la limite.
+// Retourne {acceptée, délai avant nouvel essai, requêtes restantes, reset (ms), limite}.
+const limiterScript = `
+local key = KEYS[1]
+local alg = ARGV[1]
+local limit = tonumber(ARGV[2])
+local window = tonumber(ARGV[3])
+local burst = tonumber(ARGV[4])
+local now = tonumber(ARGV[5])
+
+if KEYS[2] then
+	local override = redis.call('HGET', KEYS[2], ARGV[7])
+	if override then
+		local sep = string.find(override, ':', 1, true)
+		if tonumber(string.sub(override, sep + 1)) > now then
+			limit = tonumber(string.sub(override, 1, sep - 1))
+		end
+	end
+end
+
+if alg == 'token_bucket' then
+	local per = window / limit
+	local state = redis.call('HMGET', key, 'tokens', 'refilled')
+	local tokens = tonumber(state[1])
+	local refilled = tonumber(state[2])
+	if tokens == nil then
+		tokens = burst
+		refilled = now
+	elseif now > refilled then
+		tokens = math.min(burst, tokens + (now - refilled) / per)
+		refilled = now
+	end
+	local allowed, retry = 0, 0
+	if tokens < 1 then
+		retry = math.ceil((1 - tokens) * per)
+	else
+		tokens = tokens - 1
+		allowed = 1
+	end
+	local reset = math.ceil((burst - tokens) * per)
+	redis.call('HSET', key, 'tokens', tostring(tokens), 'refilled', refilled)
+	redis.call('PEXPIRE', key, reset + 1)
+	return {allowed, retry, math.floor(tokens), reset, limit}
+end
+
+if alg == 'sliding_log' then
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+	local count = redis.call('ZCARD', key)
+	local allowed, retry = 0, 0
+	if count >= limit then
+		local first = redis.call('ZRANGE', key, count - limit, count - limit, 'WITHSCORES')
+		retry = tonumber(first[2]) + window - now
+	else
+		redis.call('ZADD', key, now, ARGV[6])
+		count = count + 1
+		allowed = 1
+	end
+	local last = redis.call('ZRANGE', key, -1, -1, 'WITHSCORES')
+	local reset = tonumber(last[2]) + window - now
+	redis.call('PEXPIRE', key, reset + 1)
+	return {allowed, retry, math.max(limit - count, 0), reset, limit}
+end
+
+if alg == 'sliding_window' then
+	local state = redis.call('HMGET', key, 'start', 'count', 'previous')
+	local last = tonumber(state[1])
+	local count = tonumber(state[2]) or 0
+	local previous = tonumber(state[3]) or 0
+	local start = now - now % window
+	if last ~= nil and last > start then
+		start = last
+	elseif last ~= start then
+		if last ~= nil and start - last == window then
+			previous = count
+		else
+			previous = 0
+		end
+		count = 0
+	end
+	local elapsed = math.max(now - start, 0)
+	local allowed, retry = 0, 0
+	if previous * (window - elapsed) + (count + 1) * window > limit * window then
+		if count < limit and previous > 0 then
+			retry = window - math.floor(window * (limit - 1 - count) / previous) - elapsed
+		else
+			retry = 2 * window - math.floor(window * (limit - 1) / count) - elapsed
+		end
+	else
+		count = count + 1
+		allowed = 1
+	end
+	local used = previous * (window - elapsed) + count * window
+	local remaining = math.max(math.floor((limit * window - used) / window), 0)
+	local reset = 0
+	if count > 0 then
+		reset = 2 * window - elapsed
+	elseif previous > 0 then
+		reset = window - elapsed
+	else
+		remaining = limit
+	end
+	redis.call('HSET', key, 'start', start, 'count', count, 'previous', previous)
+	redis.call('PEXPIRE', key, 2 * window - elapsed)
+	return {allowed, retry, remaining, reset, limit}
+end
+
+local state = redis.call('HMGET', key, 'start', 'count')
+local start = tonumber(state[1])
+local count = tonumber(state[2]) or 0
+if start == nil or now - start >= window then
+	start = now
+	count = 0
+end
+local allowed, retry = 0, 0
+if count >= limit then
+	retry = window - (now - start)
+else
+	count = count + 1
+	allowed = 1
+	redis.call('HSET', key, 'start', start, 'count', count)
+	redis.call('PEXPIRE', key, window - (now - start))
+end
+return {allowed, retry, math.max(limit - count, 0), window - (now - start), limit}
+`
+
+var limiterScriptSHA = func() string {
+	sum := sha1.Sum([]byte(limiterScript))
+	return hex.EncodeToString(sum[:])
+}()
+
+// RedisConfig configure un RedisStore
+type RedisConfig struct {
+	Addr     string // host:port
+	Password string
+	DB       int
+	Prefix   string        // préfixe des clés (DefaultRedisPrefix par défaut)
+	Timeout  time.Duration // connexion et commande (DefaultRedisTimeout par défaut)
+	PoolSize int           // connexions gardées ouvertes (DefaultRedisPoolSize par défaut)
+}
+
+// RedisStore est un SharedStore parlant le protocole Redis (Redis, Valkey, KeyDB, ...) :
+// chaque requête est décidée par un script Lua, atomique entre les instances. L'heure
+// vient de l'instance appelante, les horloges des instances doivent être synchronisées.
+type RedisStore struct {
+	cfg      RedisConfig
+	pool     chan *redisConn
+	instance string
+	seq      atomic.Uint64
+}
+
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// redisError est une réponse d'erreur du serveur : la connexion reste utilisable
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedisStore crée un RedisStore. Les connexions sont ouvertes à la demande : un serveur
+// injoignable au démarrage n'empêche pas de démarrer.
+func NewRedisStore(cfg RedisConfig) (*RedisStore, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("redis address is required")
+	}
+	if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+		return nil, fmt.Errorf("invalid redis address %q: %v", cfg.Addr, err)
+	}
+	if cfg.DB < 0 {
+		return nil, fmt.Errorf("invalid redis database %d", cfg.DB)
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultRedisPrefix
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultRedisTimeout
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = DefaultRedisPoolSize
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &RedisStore{
+		cfg:      cfg,
+		pool:     make(chan *redisConn, cfg.PoolSize),
+		instance: hex.EncodeToString(id),
+	}, nil
+}
+
+// Allow exécute le script de limitation pour l'état key (EVALSHA, puis EVAL si le serveur
+// ne connaît pas encore le script)
+func (s *RedisStore) Allow(ctx context.Context, key string, rule Rule, limit int, override string, now time.Time) (Decision, error) {
+	burst := rule.Burst
+	if burst == 0 {
+		burst = rule.Limit
+	}
+	keys := []string{"1", s.cfg.Prefix + key}
+	if override != "" {
+		keys = []string{"2", s.cfg.Prefix + key, s.overridesKey()}
+	}
+	args := append(keys,
+		rule.Algorithm,
+		strconv.Itoa(limit),
+		strconv.FormatInt(millis(rule.Window), 10),
+		strconv.Itoa(burst),
+		strconv.FormatInt(now.UnixMilli(), 10),
+		s.instance+"-"+strconv.FormatUint(s.seq.Add(1), 10),
+	)
+	if override != "" {
+		args = append(args, override)
+	}
+
+	reply, err := s.do(ctx, append([]string{"EVALSHA", limiterScriptSHA}, args...)...)
+	var rerr redisError
+	if errors.As(err, &rerr) && strings.HasPrefix(string(rerr), "NOSCRIPT") {
+		reply, err = s.do(ctx, append([]string{"EVAL", limiterScript}, args...)...)
+	}
+	if err != nil {
+		return Decision{}, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 5 {
+		return Decision{}, fmt.Errorf("redis: unexpected script reply %v", reply)
+	}
+	var n [5]int64
+	for i, v := range values {
+		if n[i], ok = v.(int64); !ok {
+			return Decision{}, fmt.Errorf("redis: unexpected script reply %v", reply)
+		}
+	}
+	return Decision{
+		Allowed:    n[0] == 1,
+		RetryAfter: time.Duration(n[1]) * time.Millisecond,
+		Remaining:  int(n[2]),
+		Reset:      time.Duration(n[3]) * time.Millisecond,
+		Limit:      int(n[4]),
+	}, nil
+}
+
+// overridesKey est le hash des limites temporaires : "limite:expiration" (ms) par client.
+// Il expire avec la dernière d'entre elles.
+func (s *RedisStore) overridesKey() string {
+	return s.cfg.Prefix + "overrides"
+}
+
+// SetOverride ajoute o au hash des limites temporaires et en repousse l'expiration
+// jusqu'à o.ExpiresAt si besoin
+func (s *RedisStore) SetOverride(ctx context.Context, o Override) error {
+	current, err := s.overrides(ctx)
+	if err != nil {
+		return err
+	}
+	expires := o.ExpiresAt
+	for _, other := range current {
+		if other.ExpiresAt.After(expires) {
+			expires = other.ExpiresAt
+		}
+	}
+
+	value := strconv.Itoa(o.Limit) + ":" + strconv.FormatInt(o.ExpiresAt.UnixMilli(), 10)
+	if _, err := s.do(ctx, "HSET", s.overridesKey(), o.Client, value); err != nil {
+		return err
+	}
+	_, err = s.do(ctx, "PEXPIREAT", s.overridesKey(), strconv.FormatInt(expires.UnixMilli(), 10))
+	return err
+}
+
+// Overrides retourne les limites temporaires en cours à now
+func (s *RedisStore) Overrides(ctx context.Context, now time.Time) ([]Override, error) {
+	all, err := s.overrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := all[:0]
+	for _, o := range all {
+		if now.Before(o.ExpiresAt) {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+
+// DeleteOverride retire la limite temporaire d'un client du hash
+func (s *RedisStore) DeleteOverride(ctx context.Context, client string) (bool, error) {
+	reply, err := s.do(ctx, "HDEL", s.overridesKey(), client)
+	if err != nil {
+		return false, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return false, fmt.Errorf("redis: unexpected HDEL reply %v", reply)
+	}
+	return n > 0, nil
+}
+
+// overrides lit toutes les limites temporaires du hash, expirées comprises
+func (s *RedisStore) overrides(ctx context.Context) ([]Override, error) {
+	reply, err := s.do(ctx, "HGETALL", s.overridesKey())
+	if err != nil {
+		return nil, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values)%2 != 0 {
+		return nil, fmt.Errorf("redis: unexpected HGETALL reply %v", reply)
+	}
+	out := make([]Override, 0, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		client, _ := values[i].(string)
+		value, _ := values[i+1].(string)
+		limit, expires, found := strings.Cut(value, ":")
+		n, err1 := strconv.Atoi(limit)
+		ms, err2 := strconv.ParseInt(expires, 10, 64)
+		if !found || err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("redis: invalid override %q for %s", value, client)
+		}
+		out = append(out, Override{Client: client, Limit: n, ExpiresAt: time.UnixMilli(ms).UTC()})
+	}
+	return out, nil
+}
+
+// Delete supprime des états
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	args := []string{"DEL"}
+	for _, key := range keys {
+		args = append(args, s.cfg.Prefix+key)
+	}
+	reply, err := s.do(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected DEL reply %v", reply)
+	}
+	return int(n), nil
+}
+
+// Ping vérifie que le serveur répond (démarrage, diagnostic)
+func (s *RedisStore) Ping(ctx context.Context) error {
+	_, err := s.do(ctx, "PING")
+	return err
+}
+
+// Close ferme les connexions inutilisées
+func (s *RedisStore) Close() error {
+	for {
+		select {
+		case c := <-s.pool:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do envoie une commande et lit sa réponse. Une connexion en erreur réseau est fermée, les
+// autres retournent au pool.
+func (s *RedisStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	c, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.do(s.deadline(ctx), args...)
+	var rerr redisError
+	if err != nil && !errors.As(err, &rerr) {
+		c.conn.Close()
+		return nil, err
+	}
+	select {
+	case s.pool <- c:
+	default:
+		c.conn.Close()
+	}
+	return reply, err
+}
+
+// deadline est l'échéance d'une commande : Timeout, ou plus tôt si ctx l'impose
+func (s *RedisStore) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(s.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// conn retourne une connexion du pool, ou en ouvre une (AUTH, SELECT)
+func (s *RedisStore) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-s.pool:
+		return c, nil
+	default:
+	}
+
+	dialer := net.Dialer{Deadline: s.deadline(ctx)}
+	conn, err := dialer.DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, rd: bufio.NewReader(conn)}
+	if s.cfg.Password != "" {
+		if _, err := c.do(s.deadline(ctx), "AUTH", s.cfg.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.cfg.DB != 0 {
+		if _, err := c.do(s.deadline(ctx), "SELECT", strconv.Itoa(s.cfg.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) do(deadline time.Time, args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(c.rd)
+}
+
+// readReply lit une réponse RESP : chaîne, entier (int64), nil, tableau ou erreur
+func readReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: invalid reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", body)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("redis: invalid array length %q", body)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		// Une erreur dans le tableau est retournée une fois le tableau lu entièrement
+		values := make([]interface{}, n)
+		var elemErr error
+		for i := range values {
+			values[i], err = readReply(rd)
+			var rerr redisError
+			if errors.As(err, &rerr) {
+				if elemErr == nil {
+					elemErr = err
+				}
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if elemErr != nil {
+			return nil, elemErr
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("redis: invalid reply %q", line)
+	}
+}
+
+// millis arrondit d au milliseconde supérieure (au moins 1)
+func millis(d time.Duration) int64 {
+	return max(int64((d+time.Millisecond-1)/time.Millisecond), 1)
+}
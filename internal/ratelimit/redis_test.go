@@ -0,0 +1,407 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis est un serveur en mémoire parlant le protocole Redis. Il exécute le script de
+// limitation avec les algorithmes en mémoire, dont le script est la traduction.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	scripts  map[string]bool
+	states   map[string]*clientData
+	hashes   map[string]map[string]string
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{
+		ln:       ln,
+		password: password,
+		conns:    make(map[net.Conn]bool),
+		scripts:  make(map[string]bool),
+		states:   make(map[string]*clientData),
+		hashes:   make(map[string]map[string]string),
+	}
+	go f.accept()
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeRedis) Addr() string {
+	return f.ln.Addr().String()
+}
+
+// Close arrête le serveur et coupe les connexions ouvertes
+func (f *fakeRedis) Close() {
+	f.ln.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for conn := range f.conns {
+		conn.Close()
+	}
+}
+
+// Commands retourne les noms des commandes reçues
+func (f *fakeRedis) Commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func (f *fakeRedis) accept() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns[conn] = true
+		f.mu.Unlock()
+		go f.serve(conn)
+	}
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		cmd, err := readReply(rd)
+		if err != nil {
+			return
+		}
+		values, _ := cmd.([]interface{})
+		args := make([]string, len(values))
+		for i, v := range values {
+			args[i], _ = v.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		var reply interface{}
+		name := strings.ToUpper(args[0])
+		switch {
+		case name == "AUTH":
+			authed = len(args) == 2 && args[1] == f.password
+			reply = redisError("WRONGPASS invalid password")
+			if authed {
+				reply = "OK"
+			}
+		case !authed:
+			reply = redisError("NOAUTH Authentication required.")
+		default:
+			reply = f.exec(name, args[1:])
+		}
+		if _, err := conn.Write(encodeReply(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) exec(name string, args []string) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, name)
+
+	switch name {
+	case "PING":
+		return "PONG"
+	case "SELECT":
+		return "OK"
+	case "DEL":
+		n := int64(0)
+		for _, key := range args {
+			if _, ok := f.states[key]; ok {
+				delete(f.states, key)
+				n++
+			}
+			if _, ok := f.hashes[key]; ok {
+				delete(f.hashes, key)
+				n++
+			}
+		}
+		return n
+	case "EVAL":
+		sum := sha1.Sum([]byte(args[0]))
+		f.scripts[hex.EncodeToString(sum[:])] = true
+		return f.limit(args[1:])
+	case "EVALSHA":
+		if !f.scripts[args[0]] {
+			return redisError("NOSCRIPT No matching script. Please use EVAL.")
+		}
+		return f.limit(args[1:])
+	case "HSET":
+		h, ok := f.hashes[args[0]]
+		if !ok {
+			h = make(map[string]string)
+			f.hashes[args[0]] = h
+		}
+		n := int64(0)
+		for i := 1; i+1 < len(args); i += 2 {
+			if _, ok := h[args[i]]; !ok {
+				n++
+			}
+			h[args[i]] = args[i+1]
+		}
+		return n
+	case "HGETALL":
+		out := []interface{}{}
+		for field, value := range f.hashes[args[0]] {
+			out = append(out, field, value)
+		}
+		return out
+	case "HDEL":
+		n := int64(0)
+		for _, field := range args[1:] {
+			if _, ok := f.hashes[args[0]][field]; ok {
+				delete(f.hashes[args[0]], field)
+				n++
+			}
+		}
+		return n
+	case "PEXPIREAT":
+		// Les clés n'expirent pas : les tests lisent l'échéance dans les valeurs
+		if _, ok := f.hashes[args[0]]; ok {
+			return int64(1)
+		}
+		return int64(0)
+	default:
+		return redisError(fmt.Sprintf("ERR unknown command '%s'", name))
+	}
+}
+
+// limit exécute le script de limitation (nombre de clés, KEYS puis ARGV de limiterScript)
+func (f *fakeRedis) limit(args []string) interface{} {
+	numKeys, _ := strconv.Atoi(args[0])
+	keys, argv := args[1:1+numKeys], args[1+numKeys:]
+	key := keys[0]
+	limit, _ := strconv.Atoi(argv[1])
+	window, _ := strconv.ParseInt(argv[2], 10, 64)
+	burst, _ := strconv.Atoi(argv[3])
+	now, _ := strconv.ParseInt(argv[4], 10, 64)
+	if numKeys == 2 {
+		if value, ok := f.hashes[keys[1]][argv[6]]; ok {
+			n, expires, _ := strings.Cut(value, ":")
+			if ms, _ := strconv.ParseInt(expires, 10, 64); ms > now {
+				limit, _ = strconv.Atoi(n)
+			}
+		}
+	}
+
+	alg, err := newAlgorithm(argv[0], limit, time.Duration(window)*time.Millisecond, burst)
+	if err != nil {
+		return redisError("ERR " + err.Error())
+	}
+	c, ok := f.states[key]
+	if !ok {
+		c = &clientData{}
+		f.states[key] = c
+	}
+	at := time.UnixMilli(now)
+	allowed, retry := alg.allow(c, at, limit)
+	remaining, reset := alg.status(c, at, limit)
+	accepted := int64(0)
+	if allowed {
+		accepted = 1
+	}
+	return []interface{}{accepted, ceilMillis(retry), int64(remaining), ceilMillis(reset), int64(limit)}
+}
+
+func ceilMillis(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+func encodeReply(v interface{}) []byte {
+	switch v := v.(type) {
+	case redisError:
+		return []byte("-" + string(v) + "\r\n")
+	case string:
+		return []byte("+" + v + "\r\n")
+	case int64:
+		return []byte(":" + strconv.FormatInt(v, 10) + "\r\n")
+	case []interface{}:
+		out := []byte("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, e := range v {
+			out = append(out, encodeReply(e)...)
+		}
+		return out
+	default:
+		return []byte("$-1\r\n")
+	}
+}
+
+func newTestRedisStore(t *testing.T, cfg RedisConfig) *RedisStore {
+	t.Helper()
+	store, err := NewRedisStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestRedisStore_Allow(t *testing.T) {
+	f := newFakeRedis(t, "secret")
+	store := newTestRedisStore(t, RedisConfig{Addr: f.Addr(), Password: "secret", DB: 2, Prefix: "test:"})
+	rule := Rule{Algorithm: FixedWindow, Limit: 2, Window: time.Minute}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		d, err := store.Allow(ctx, "1.2.3.4", rule, 2, "", start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !d.Allowed || d.Remaining != 1-i || d.Reset != time.Minute {
+			t.Errorf("unexpected decision for request %d: %+v", i+1, d)
+		}
+	}
+	d, err := store.Allow(ctx, "1.2.3.4", rule, 2, "", start.Add(20*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Allowed || d.RetryAfter != 40*time.Second || d.Remaining != 0 {
+		t.Errorf("expected the third request to be refused for 40s, got %+v", d)
+	}
+	if _, ok := f.states["test:1.2.3.4"]; !ok {
+		t.Error("expected the state to be stored under the key prefix")
+	}
+
+	// Le script est envoyé une seule fois, sur une connexion authentifiée réutilisée
+	want := []string{"SELECT", "EVALSHA", "EVAL", "EVALSHA", "EVALSHA"}
+	if got := f.Commands(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("expected commands %v, got %v", want, got)
+	}
+}
+
+// Le serveur de test applique les algorithmes en mémoire : ce test vérifie les arguments
+// et la lecture des réponses, pour chaque algorithme
+func TestRedisStore_Algorithms(t *testing.T) {
+	f := newFakeRedis(t, "")
+	store := newTestRedisStore(t, RedisConfig{Addr: f.Addr()})
+
+	for _, name := range []string{FixedWindow, TokenBucket, SlidingLog, SlidingWindow} {
+		t.Run(name, func(t *testing.T) {
+			rule := Rule{Algorithm: name, Limit: 10, Window: time.Minute}
+			alg, _ := newAlgorithm(name, 10, time.Minute, 0)
+			local := &clientData{}
+			for i, at := range []time.Duration{0, 0, 30 * time.Second, 30 * time.Second, 61 * time.Second} {
+				d, err := store.Allow(context.Background(), name, rule, 10, "", start.Add(at))
+				if err != nil {
+					t.Fatal(err)
+				}
+				allowed, retry := alg.allow(local, start.Add(at), 10)
+				remaining, reset := alg.status(local, start.Add(at), 10)
+				want := Decision{Allowed: allowed, RetryAfter: retry, Remaining: remaining, Reset: reset, Limit: 10}
+				if d != want {
+					t.Errorf("request %d: expected %+v, got %+v", i+1, want, d)
+				}
+			}
+		})
+	}
+}
+
+func TestRedisStore_Delete(t *testing.T) {
+	f := newFakeRedis(t, "")
+	store := newTestRedisStore(t, RedisConfig{Addr: f.Addr()})
+	ctx := context.Background()
+	rule := Rule{Limit: 1, Window: time.Minute}
+
+	store.Allow(ctx, "a", rule, 1, "", start)
+	store.Allow(ctx, "b", rule, 1, "", start)
+	if n, err := store.Delete(ctx, "a", "b", "c"); err != nil || n != 2 {
+		t.Errorf("expected 2 states deleted, got %d, %v", n, err)
+	}
+	if d, err := store.Allow(ctx, "a", rule, 1, "", start); err != nil || !d.Allowed {
+		t.Errorf("expected a fresh state after delete, got %+v, %v", d, err)
+	}
+	if n, err := store.Delete(ctx); err != nil || n != 0 {
+		t.Errorf("expected nothing to delete, got %d, %v", n, err)
+	}
+}
+
+func TestRedisStore_Errors(t *testing.T) {
+	f := newFakeRedis(t, "secret")
+	ctx := context.Background()
+
+	store := newTestRedisStore(t, RedisConfig{Addr: f.Addr(), Password: "wrong"})
+	var rerr redisError
+	if err := store.Ping(ctx); !errors.As(err, &rerr) || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+
+	store = newTestRedisStore(t, RedisConfig{Addr: f.Addr(), Password: "secret"})
+	if err := store.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := store.Ping(ctx); err == nil {
+		t.Error("expected an error once the server is gone")
+	}
+	if _, err := store.Allow(ctx, "a", Rule{Limit: 1, Window: time.Minute}, 1, "", start); err == nil {
+		t.Error("expected an error for an unreachable server")
+	}
+}
+
+func TestNewRedisStore(t *testing.T) {
+	store, err := NewRedisStore(RedisConfig{Addr: "localhost:6379"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.cfg.Prefix != DefaultRedisPrefix || store.cfg.Timeout != DefaultRedisTimeout || cap(store.pool) != DefaultRedisPoolSize {
+		t.Errorf("expected defaults, got %+v", store.cfg)
+	}
+	for _, cfg := range []RedisConfig{{}, {Addr: "localhost"}, {Addr: "localhost:6379", DB: -1}} {
+		if _, err := NewRedisStore(cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	reply, err := readReply(bufio.NewReader(strings.NewReader("*4\r\n+OK\r\n:-3\r\n$5\r\nhe\r\no\r\n$-1\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, _ := reply.([]interface{})
+	if len(values) != 4 || values[0] != "OK" || values[1] != int64(-3) || values[2] != "he\r\no" || values[3] != nil {
+		t.Errorf("unexpected reply %#v", reply)
+	}
+
+	// Une erreur dans un tableau n'empêche pas de lire la réponse suivante
+	rd := bufio.NewReader(strings.NewReader("*2\r\n-ERR boom\r\n:1\r\n+PONG\r\n"))
+	var rerr redisError
+	if _, err := readReply(rd); !errors.As(err, &rerr) {
+		t.Errorf("expected a server error, got %v", err)
+	}
+	if reply, err := readReply(rd); reply != "PONG" || err != nil {
+		t.Errorf("expected the next reply, got %v, %v", reply, err)
+	}
+
+	for _, raw := range []string{"?\r\n", "$abc\r\n", "OK\n", ":1"} {
+		if _, err := readReply(bufio.NewReader(strings.NewReader(raw))); err == nil {
+			t.Errorf("expected an error for %q", raw)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+var ErrInvalidRule = errors.New("invalid rate limit rule")
+
+// Rule limite les requêtes d'une route. La première règle dont la méthode et le préfixe de
+// chemin correspondent s'applique; les autres requêtes suivent la limite par défaut du
+// RateLimiter. Chaque règle a ses propres compteurs par client.
+type Rule struct {
+	Method    string // vide : toutes les méthodes
+	Path      string // préfixe du chemin, ex : /log/batch
+	Algorithm string // FixedWindow par défaut
+	Limit     int    // requêtes par Window
+	Window    time.Duration
+	Burst     int // TokenBucket : capacité du seau (Limit par défaut)
+}
+
+func (r Rule) String() string {
+	if r.Method == "" {
+		return r.Path
+	}
+	return r.Method + " " + r.Path
+}
+
+// Validate vérifie la règle
+func (r Rule) Validate() error {
+	if !strings.HasPrefix(r.Path, "/") {
+		return fmt.Errorf("%w: path %q must start with /", ErrInvalidRule, r.Path)
+	}
+	if err := utils.ValidateMaxRequests(r.Limit); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidRule, r, err)
+	}
+	if err := utils.ValidateWindow(r.Window); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidRule, r, err)
+	}
+	if _, err := newAlgorithm(r.Algorithm, r.Limit, r.Window, r.Burst); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidRule, r, err)
+	}
+	return nil
+}
+
+func (r Rule) matches(method, path string) bool {
+	return (r.Method == "" || r.Method == method) && strings.HasPrefix(path, r.Path)
+}
+
+// ParseRules lit des règles séparées par des virgules, de la forme
+// "[MÉTHODE] /préfixe=algorithme:limite/fenêtre[:burst]", ex :
+// "POST /log/batch=token_bucket:20/1s:100,GET /log=sliding_log:60/1m"
+func ParseRules(raw string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q (expected route=algorithm:limit/window)", ErrInvalidRule, item)
+		}
+
+		var rule Rule
+		fields := strings.Fields(route)
+		switch len(fields) {
+		case 1:
+			rule.Path = fields[0]
+		case 2:
+			rule.Method, rule.Path = strings.ToUpper(fields[0]), fields[1]
+		default:
+			return nil, fmt.Errorf("%w: route %q", ErrInvalidRule, route)
+		}
+
+		parts := strings.Split(strings.TrimSpace(spec), ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("%w: %q (expected algorithm:limit/window[:burst])", ErrInvalidRule, spec)
+		}
+		rule.Algorithm = parts[0]
+		limit, window, ok := strings.Cut(parts[1], "/")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q (expected limit/window)", ErrInvalidRule, parts[1])
+		}
+		var err error
+		if rule.Limit, err = strconv.Atoi(limit); err != nil {
+			return nil, fmt.Errorf("%w: limit %q", ErrInvalidRule, limit)
+		}
+		if rule.Window, err = time.ParseDuration(window); err != nil {
+			return nil, fmt.Errorf("%w: window %q", ErrInvalidRule, window)
+		}
+		if len(parts) == 3 {
+			if rule.Burst, err = strconv.Atoi(parts[2]); err != nil {
+				return nil, fmt.Errorf("%w: burst %q", ErrInvalidRule, parts[2])
+			}
+		}
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ParseLevelLimits lit des limites par niveau séparées par des virgules (Config.EntryLimits,
+// Config.PerLevelLimits), ex : "ERROR=1000,WARN=500,INFO=200"
+func ParseLevelLimits(raw string) (map[log_levels.LogLevel]int, error) {
+	var limits map[log_levels.LogLevel]int
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		level, limit, ok := strings.Cut(item, "=")
+		if !ok || !log_levels.IsValidLogLevel(strings.TrimSpace(level)) {
+			return nil, fmt.Errorf("%w: %q (expected LEVEL=limit)", ErrInvalidRule, item)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil {
+			return nil, fmt.Errorf("%w: limit %q", ErrInvalidRule, limit)
+		}
+		if err := utils.ValidateMaxRequests(n); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRule, item, err)
+		}
+		if limits == nil {
+			limits = make(map[log_levels.LogLevel]int)
+		}
+		limits[log_levels.NormalizeLogLevel(strings.TrimSpace(level))] = n
+	}
+	return limits, nil
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(" post /log/batch=token_bucket:20/1s:100, /loki=sliding_log:60/1m,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rule{
+		{Method: "POST", Path: "/log/batch", Algorithm: TokenBucket, Limit: 20, Window: time.Second, Burst: 100},
+		{Path: "/loki", Algorithm: SlidingLog, Limit: 60, Window: time.Minute},
+	}
+	if len(rules) != len(want) || rules[0] != want[0] || rules[1] != want[1] {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+	if rules, err := ParseRules(""); err != nil || len(rules) != 0 {
+		t.Errorf("expected no rule, got %+v, %v", rules, err)
+	}
+
+	for _, raw := range []string{
+		"/log",
+		"POST /log extra=fixed_window:10/1m",
+		"/log=fixed_window",
+		"/log=fixed_window:10",
+		"/log=fixed_window:ten/1m",
+		"/log=fixed_window:10/forever",
+		"/log=token_bucket:10/1m:many",
+		"/log=token_bucket:10/1m:-1",
+		"/log=leaky_bucket:10/1m",
+		"log=fixed_window:10/1m",
+		"/log=fixed_window:0/1m",
+	} {
+		if _, err := ParseRules(raw); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("expected ErrInvalidRule for %q, got %v", raw, err)
+		}
+	}
+}
+
+func TestRule_Matches(t *testing.T) {
+	rule := Rule{Method: "POST", Path: "/log"}
+	if !rule.matches("POST", "/log/batch") || rule.matches("GET", "/log") || rule.matches("POST", "/loki") {
+		t.Error("expected the rule to match POST requests under /log only")
+	}
+	if !(Rule{Path: "/log"}).matches("GET", "/log") {
+		t.Error("expected a rule without method to match every method")
+	}
+	if s := rule.String(); s != "POST /log" {
+		t.Errorf("unexpected rule name %q", s)
+	}
+}
+
+func TestParseLevelLimits(t *testing.T) {
+	limits, err := ParseLevelLimits("error=1000, WARN = 500,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limits) != 2 || limits["ERROR"] != 1000 || limits["WARN"] != 500 {
+		t.Errorf("unexpected limits: %v", limits)
+	}
+	if limits, err := ParseLevelLimits(""); err != nil || limits != nil {
+		t.Errorf("expected no limit, got %v, %v", limits, err)
+	}
+	for _, raw := range []string{"ERROR", "NOTICE=10", "ERROR=many", "ERROR=0"} {
+		if _, err := ParseLevelLimits(raw); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("expected ErrInvalidRule for %q, got %v", raw, err)
+		}
+	}
+}
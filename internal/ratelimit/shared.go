@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultSharedRetry est le délai avant de réessayer un stockage partagé injoignable
+const DefaultSharedRetry = 5 * time.Second
+
+// SharedStore garde l'état des limites hors du processus, pour que plusieurs instances
+// derrière un répartiteur de charge appliquent ensemble une même limite (RedisStore)
+type SharedStore interface {
+	// Allow impute une requête à l'état key selon rule et la limite limit si elle le
+	// permet, de façon atomique entre les instances. La limite temporaire en cours du
+	// client override remplace limit ("" : pas de limite temporaire).
+	Allow(ctx context.Context, key string, rule Rule, limit int, override string, now time.Time) (Decision, error)
+	// Delete supprime des états et retourne le nombre d'états supprimés
+	Delete(ctx context.Context, keys ...string) (int, error)
+
+	// SetOverride partage une limite temporaire avec les autres instances jusqu'à son
+	// expiration; DeleteOverride indique si le client en avait une
+	SetOverride(ctx context.Context, o Override) error
+	Overrides(ctx context.Context, now time.Time) ([]Override, error)
+	DeleteOverride(ctx context.Context, client string) (bool, error)
+}
+
+// Decision est la réponse d'un SharedStore à une requête
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+	Remaining  int
+	Reset      time.Duration
+	Limit      int // limite appliquée, limite temporaire comprise
+}
+
+// shared suit la disponibilité du stockage partagé : après une erreur, les limites sont
+// appliquées localement par chaque instance pendant retry
+type shared struct {
+	store SharedStore
+	retry time.Duration
+
+	mu        sync.Mutex
+	down      bool
+	downUntil time.Time
+}
+
+func newShared(store SharedStore, retry time.Duration) *shared {
+	if retry <= 0 {
+		retry = DefaultSharedRetry
+	}
+	sharedAvailable.Set(1)
+	return &shared{store: store, retry: retry}
+}
+
+// available indique si le stockage partagé doit être essayé à l'instant now
+func (s *shared) available(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.down || !now.Before(s.downUntil)
+}
+
+// failed passe aux limites locales jusqu'au prochain essai
+func (s *shared) failed(now time.Time, err error) {
+	sharedErrorsTotal.Inc()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.down {
+		log.Printf("rate limit shared store unavailable, limiting locally: %v", err)
+		sharedAvailable.Set(0)
+	}
+	s.down = true
+	s.downUntil = now.Add(s.retry)
+}
+
+// succeeded revient aux limites partagées
+func (s *shared) succeeded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		log.Printf("rate limit shared store available again")
+		sharedAvailable.Set(1)
+	}
+	s.down = false
+}
+
+// sharedState est le dernier état partagé d'un client vu par cette instance (API
+// d'administration, nettoyage)
+type sharedState struct {
+	limit     int
+	remaining int
+	reset     time.Duration
+	at        time.Time
+}
+
+// resetAt retourne le délai restant avant que toute la limite soit disponible à now
+func (s *sharedState) resetAt(now time.Time) time.Duration {
+	return max(s.reset-now.Sub(s.at), 0)
+}
+
+// allowShared applique la règle p via le stockage partagé, avec la limite temporaire
+// partagée du client. ok est faux si le stockage est injoignable : l'appelant applique
+// alors la limite localement.
+func (rl *RateLimiter) allowShared(p *policy, key string, maxRequests int) (allowed bool, retryAfter time.Duration, status Status, ok bool) {
+	rl.mu.Lock()
+	now := rl.now()
+	rl.mu.Unlock()
+
+	if !rl.shared.available(now) {
+		return false, 0, Status{}, false
+	}
+	override := key
+	if p.entries {
+		override = ""
+	}
+	d, err := rl.shared.store.Allow(context.Background(), stateKey(p, rl.defaults, key), p.rule, maxRequests, override, now)
+	if err != nil {
+		rl.shared.failed(now, err)
+		return false, 0, Status{}, false
+	}
+	rl.shared.succeeded()
+
+	rl.mu.Lock()
+	client := rl.clientFor(p, key)
+	client.lastSeen = now
+	client.shared = &sharedState{limit: d.Limit, remaining: d.Remaining, reset: d.Reset, at: now}
+	rl.mu.Unlock()
+
+	status = Status{Limit: d.Limit, Remaining: d.Remaining, Reset: d.Reset}
+	if !d.Allowed {
+		blockedTotal.Inc()
+		return false, d.RetryAfter, status, true
+	}
+	requestsTotal.Inc()
+	return true, 0, status, true
+}
+
+// sharedKeys retourne les clés de tous les états possibles d'un client
+func (rl *RateLimiter) sharedKeys(client string) []string {
+	keys := []string{stateKey(rl.defaults, rl.defaults, client)}
+	for _, p := range rl.routes {
+		keys = append(keys, stateKey(p, rl.defaults, client))
+	}
+	for _, p := range rl.entries {
+		keys = append(keys, stateKey(p, rl.defaults, client))
+	}
+	return keys
+}
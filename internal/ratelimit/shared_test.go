@@ -0,0 +1,262 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// testClock est une horloge réglable partagée par les instances d'un test
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// flakyStore délègue à store tant que err est nil
+type flakyStore struct {
+	store SharedStore
+
+	mu    sync.Mutex
+	err   error
+	calls int
+}
+
+func (s *flakyStore) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *flakyStore) Allow(ctx context.Context, key string, rule Rule, limit int, override string, now time.Time) (Decision, error) {
+	s.mu.Lock()
+	s.calls++
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return Decision{}, err
+	}
+	return s.store.Allow(ctx, key, rule, limit, override, now)
+}
+
+func (s *flakyStore) Delete(ctx context.Context, keys ...string) (int, error) {
+	return s.store.Delete(ctx, keys...)
+}
+
+func (s *flakyStore) SetOverride(ctx context.Context, o Override) error {
+	return s.store.SetOverride(ctx, o)
+}
+
+func (s *flakyStore) Overrides(ctx context.Context, now time.Time) ([]Override, error) {
+	return s.store.Overrides(ctx, now)
+}
+
+func (s *flakyStore) DeleteOverride(ctx context.Context, client string) (bool, error) {
+	return s.store.DeleteOverride(ctx, client)
+}
+
+func newSharedLimiter(t *testing.T, store SharedStore, clock *testClock, cfg Config) *RateLimiter {
+	t.Helper()
+	cfg.Shared = store
+	cfg.Clock = clock.Now
+	rl, err := NewRateLimiter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rl.Stop)
+	return rl
+}
+
+func TestRateLimiter_SharedAcrossInstances(t *testing.T) {
+	f := newFakeRedis(t, "")
+	clock := &testClock{now: start}
+	cfg := Config{MaxRequests: 4, Window: time.Minute, Algorithm: SlidingWindow}
+	replicas := []*RateLimiter{
+		newSharedLimiter(t, newTestRedisStore(t, RedisConfig{Addr: f.Addr()}), clock, cfg),
+		newSharedLimiter(t, newTestRedisStore(t, RedisConfig{Addr: f.Addr()}), clock, cfg),
+	}
+
+	// Les deux instances partagent la limite du client
+	accepted := 0
+	for i := 0; i < 8; i++ {
+		if ok, _ := replicas[i%2].AllowTest("1.2.3.4", 4); ok {
+			accepted++
+		}
+	}
+	if accepted != 4 {
+		t.Errorf("expected 4 requests across both instances, got %d", accepted)
+	}
+	if ok, retry := replicas[0].AllowTest("1.2.3.4", 4); ok || retry <= 0 {
+		t.Errorf("expected a retry delay from the shared state, got %v, %v", ok, retry)
+	}
+
+	// Chaque instance expose le dernier état partagé qu'elle a vu
+	status, err := replicas[1].Client("1.2.3.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 || status[0].Remaining != 0 || status[0].ResetSeconds != 120 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	clock.Advance(2 * time.Minute)
+	replicas[1].CleanupTest()
+	if _, err := replicas[1].Client("1.2.3.4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the client to be cleaned up once its limit is reset, got %v", err)
+	}
+}
+
+func TestRateLimiter_SharedReset(t *testing.T) {
+	f := newFakeRedis(t, "")
+	clock := &testClock{now: start}
+	cfg := Config{
+		MaxRequests: 1,
+		Window:      time.Minute,
+		Routes:      []Rule{{Path: "/loki", Limit: 1, Window: time.Minute}},
+	}
+	a := newSharedLimiter(t, newTestRedisStore(t, RedisConfig{Addr: f.Addr()}), clock, cfg)
+	b := newSharedLimiter(t, newTestRedisStore(t, RedisConfig{Addr: f.Addr()}), clock, cfg)
+
+	a.AllowTest("1.2.3.4", 1)
+	if ok, _ := a.AllowTest("1.2.3.4", 1); ok {
+		t.Fatal("expected the limit to be reached")
+	}
+
+	// Une instance qui n'a jamais vu le client efface les compteurs partagés
+	if err := b.Reset("1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := a.AllowTest("1.2.3.4", 1); !ok {
+		t.Error("expected the limit to be available again on every instance")
+	}
+	if err := b.Reset("5.6.7.8"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if keys := b.sharedKeys("c"); len(keys) != 2 || keys[0] != "c" || keys[1] != "/loki|c" {
+		t.Errorf("unexpected shared keys %v", keys)
+	}
+}
+
+func TestRateLimiter_SharedOverride(t *testing.T) {
+	f := newFakeRedis(t, "")
+	clock := &testClock{now: start}
+	cfg := Config{MaxRequests: 1, Window: time.Hour}
+	a := newSharedLimiter(t, newTestRedisStore(t, RedisConfig{Addr: f.Addr()}), clock, cfg)
+	b := newSharedLimiter(t, newTestRedisStore(t, RedisConfig{Addr: f.Addr()}), clock, cfg)
+
+	// Une limite temporaire posée sur une instance s'applique sur toutes
+	if _, err := a.SetOverride("1.2.3.4", 3, 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	accepted := 0
+	for i := 0; i < 4; i++ {
+		if ok, _ := b.AllowTest("1.2.3.4", 1); ok {
+			accepted++
+		}
+	}
+	if accepted != 3 {
+		t.Errorf("expected the override to apply on the other instance, got %d requests", accepted)
+	}
+	if status, err := b.Client("1.2.3.4"); err != nil || status[0].Limit != 3 {
+		t.Errorf("expected the override limit in the status, got %+v, %v", status, err)
+	}
+	if list, err := b.Overrides(); err != nil || len(list) != 1 || list[0].Client != "1.2.3.4" || list[0].Limit != 3 {
+		t.Errorf("expected the override to be listed by every instance, got %+v, %v", list, err)
+	}
+
+	// Supprimée sur une instance, elle ne s'applique plus nulle part
+	if err := b.DeleteOverride("1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := a.Overrides(); err != nil || len(list) != 0 {
+		t.Errorf("expected no override left, got %+v, %v", list, err)
+	}
+	if err := b.Reset("1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := a.AllowTest("1.2.3.4", 1); !ok {
+		t.Error("expected the configured limit to allow one request")
+	}
+	if ok, _ := a.AllowTest("1.2.3.4", 1); ok {
+		t.Error("expected the configured limit to apply again")
+	}
+
+	// Une limite temporaire expirée n'est plus appliquée ni listée
+	if _, err := b.SetOverride("5.6.7.8", 5, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	a.AllowTest("5.6.7.8", 1)
+	if ok, _ := a.AllowTest("5.6.7.8", 1); ok {
+		t.Error("expected the expired override not to apply")
+	}
+	if list, err := a.Overrides(); err != nil || len(list) != 0 {
+		t.Errorf("expected the expired override not to be listed, got %+v, %v", list, err)
+	}
+}
+
+func TestRateLimiter_SharedFallback(t *testing.T) {
+	f := newFakeRedis(t, "")
+	store := &flakyStore{store: newTestRedisStore(t, RedisConfig{Addr: f.Addr()})}
+	clock := &testClock{now: start}
+	rl := newSharedLimiter(t, store, clock, Config{MaxRequests: 2, Window: time.Minute, SharedRetry: 10 * time.Second})
+
+	rl.AllowTest("1.2.3.4", 2)
+	errorsBefore := testutil.ToFloat64(sharedErrorsTotal)
+
+	// Stockage injoignable : la limite est appliquée localement, sans le réessayer avant
+	// SharedRetry
+	store.fail(errors.New("connection refused"))
+	accepted := 0
+	for i := 0; i < 3; i++ {
+		if ok, _ := rl.AllowTest("1.2.3.4", 2); ok {
+			accepted++
+		}
+	}
+	if accepted != 2 {
+		t.Errorf("expected the local limit to apply, got %d requests", accepted)
+	}
+	if store.calls != 2 {
+		t.Errorf("expected a single failed call to the shared store, got %d calls", store.calls-1)
+	}
+	if got := testutil.ToFloat64(sharedErrorsTotal) - errorsBefore; got != 1 {
+		t.Errorf("expected 1 shared store error, got %v", got)
+	}
+	if got := testutil.ToFloat64(sharedAvailable); got != 0 {
+		t.Errorf("expected the shared store to be reported unavailable, got %v", got)
+	}
+
+	// Après SharedRetry, le stockage rétabli reprend avec son propre état
+	store.fail(nil)
+	clock.Advance(10 * time.Second)
+	if ok, _ := rl.AllowTest("1.2.3.4", 2); !ok {
+		t.Error("expected the shared state to allow a second request")
+	}
+	if ok, _ := rl.AllowTest("1.2.3.4", 2); ok {
+		t.Error("expected the shared limit to apply again")
+	}
+	if got := testutil.ToFloat64(sharedAvailable); got != 1 {
+		t.Errorf("expected the shared store to be reported available, got %v", got)
+	}
+
+	// Un stockage arrêté est une erreur comme une autre
+	f.Close()
+	clock.Advance(time.Minute)
+	if ok, _ := rl.AllowTest("1.2.3.4", 2); !ok {
+		t.Error("expected the request to be limited locally once the server is gone")
+	}
+}
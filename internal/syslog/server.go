@@ -0,0 +1,304 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rypi-dev/logger-server/internal"
+)
+
+const (
+	DefaultMaxMessageSize = 8192
+	DefaultIdleTimeout    = 5 * time.Minute
+)
+
+// Config décrit les listeners à démarrer; une adresse vide désactive le transport.
+type Config struct {
+	UDPAddr        string
+	TCPAddr        string
+	TLSAddr        string
+	TLSCertFile    string
+	TLSKeyFile     string
+	MaxMessageSize int
+	IdleTimeout    time.Duration
+}
+
+type Server struct {
+	cfg    Config
+	ingest internal.IngestFunc
+
+	mu        sync.Mutex
+	udpConn   net.PacketConn
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+var messagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "syslog_messages_total",
+	Help: "Total number of syslog messages received, by transport and result",
+}, []string{"transport", "result"})
+
+func init() {
+	prometheus.MustRegister(messagesTotal)
+}
+
+func NewServer(cfg Config, ingest internal.IngestFunc) *Server {
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	return &Server{
+		cfg:    cfg,
+		ingest: ingest,
+		conns:  make(map[net.Conn]struct{}),
+	}
+}
+
+// Start ouvre les listeners configurés et lance leurs boucles de lecture
+func (s *Server) Start() error {
+	if s.cfg.UDPAddr != "" {
+		conn, err := net.ListenPacket("udp", s.cfg.UDPAddr)
+		if err != nil {
+			return fmt.Errorf("syslog udp listen: %w", err)
+		}
+		s.mu.Lock()
+		s.udpConn = conn
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.serveUDP(conn)
+	}
+
+	if s.cfg.TCPAddr != "" {
+		ln, err := net.Listen("tcp", s.cfg.TCPAddr)
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("syslog tcp listen: %w", err)
+		}
+		s.addListener(ln)
+		s.wg.Add(1)
+		go s.serveStream(ln, "tcp")
+	}
+
+	if s.cfg.TLSAddr != "" {
+		cert, err := tls.LoadX509KeyPair(s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("syslog tls certificate: %w", err)
+		}
+		ln, err := tls.Listen("tcp", s.cfg.TLSAddr, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("syslog tls listen: %w", err)
+		}
+		s.addListener(ln)
+		s.wg.Add(1)
+		go s.serveStream(ln, "tls")
+	}
+
+	return nil
+}
+
+// Addrs retourne les adresses effectivement écoutées (utile avec le port 0)
+func (s *Server) Addrs() []net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var addrs []net.Addr
+	if s.udpConn != nil {
+		addrs = append(addrs, s.udpConn.LocalAddr())
+	}
+	for _, ln := range s.listeners {
+		addrs = append(addrs, ln.Addr())
+	}
+	return addrs
+}
+
+// Close ferme les listeners et connexions ouvertes puis attend la fin des goroutines
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+
+	var firstErr error
+	if s.udpConn != nil {
+		firstErr = s.udpConn.Close()
+	}
+	for _, ln := range s.listeners {
+		if err := ln.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return firstErr
+}
+
+func (s *Server) addListener(ln net.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, ln)
+}
+
+func (s *Server) serveUDP(conn net.PacketConn) {
+	defer s.wg.Done()
+
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		if n > s.cfg.MaxMessageSize {
+			messagesTotal.WithLabelValues("udp", "too_large").Inc()
+			continue
+		}
+		s.handleMessage("udp", buf[:n])
+	}
+}
+
+func (s *Server) serveStream(ln net.Listener, transport string) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.handleConn(conn, transport)
+	}
+}
+
+// handleConn lit les trames d'une connexion TCP/TLS. Les deux framings de la RFC 6587
+// sont acceptés : octet-counting ("LEN SP MSG") et non-transparent (séparé par LF).
+func (s *Server) handleConn(conn net.Conn, transport string) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	reader := bufio.NewReaderSize(conn, s.cfg.MaxMessageSize+16)
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.cfg.IdleTimeout))
+
+		frame, err := s.readFrame(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				messagesTotal.WithLabelValues(transport, "framing_error").Inc()
+			}
+			return
+		}
+		if len(frame) == 0 {
+			continue
+		}
+		s.handleMessage(transport, frame)
+	}
+}
+
+func (s *Server) readFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		size, err := s.readFrameLength(r)
+		if err != nil {
+			return nil, err
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("frame exceeds %d bytes", s.cfg.MaxMessageSize)
+	}
+	if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+		return nil, err
+	}
+	return append([]byte(nil), line...), nil
+}
+
+// maxFrameLengthDigits borne la longueur annoncée d'une trame octet-counting
+const maxFrameLengthDigits = 10
+
+// readFrameLength lit la longueur "LEN SP" d'une trame octet-counting, chiffre par chiffre :
+// un client ne peut ni faire lire une ligne sans fin ni faire allouer plus de MaxMessageSize.
+func (s *Server) readFrameLength(r *bufio.Reader) (int, error) {
+	size := 0
+	for digits := 0; ; digits++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c == ' ' && digits > 0 {
+			return size, nil
+		}
+		if c < '0' || c > '9' || digits == maxFrameLengthDigits {
+			return 0, fmt.Errorf("invalid frame length")
+		}
+		size = size*10 + int(c-'0')
+		if size > s.cfg.MaxMessageSize {
+			return 0, fmt.Errorf("frame length exceeds %d bytes", s.cfg.MaxMessageSize)
+		}
+	}
+}
+
+func (s *Server) handleMessage(transport string, raw []byte) {
+	msg, err := Parse(raw, time.Now())
+	if err != nil {
+		messagesTotal.WithLabelValues(transport, "parse_error").Inc()
+		return
+	}
+
+	if err := s.ingest(msg.ToLogEntry()); err != nil {
+		messagesTotal.WithLabelValues(transport, "rejected").Inc()
+		return
+	}
+	messagesTotal.WithLabelValues(transport, "ok").Inc()
+}
@@ -0,0 +1,168 @@
+package syslog_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/syslog"
+)
+
+// collector capte les entrées ingérées par le serveur syslog
+type collector struct {
+	mu      sync.Mutex
+	entries []*internal.LogEntry
+}
+
+func (c *collector) ingest(entry *internal.LogEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+func (c *collector) waitFor(t *testing.T, n int) []*internal.LogEntry {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		if len(c.entries) >= n {
+			out := append([]*internal.LogEntry(nil), c.entries...)
+			c.mu.Unlock()
+			return out
+		}
+		c.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d entries", n)
+	return nil
+}
+
+func startServer(t *testing.T, cfg syslog.Config) (*syslog.Server, *collector) {
+	t.Helper()
+	c := &collector{}
+	srv := syslog.NewServer(cfg, c.ingest)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv, c
+}
+
+func TestServer_UDP(t *testing.T) {
+	srv, c := startServer(t, syslog.Config{UDPAddr: "127.0.0.1:0"})
+
+	conn, err := net.Dial("udp", srv.Addrs()[0].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("<12>1 2025-08-06T14:12:00Z host app - - - disk almost full")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := c.waitFor(t, 1)
+	if entries[0].Level != "WARN" || entries[0].Message != "disk almost full" {
+		t.Errorf("unexpected entry %+v", entries[0])
+	}
+}
+
+func TestServer_TCP_Framing(t *testing.T) {
+	srv, c := startServer(t, syslog.Config{TCPAddr: "127.0.0.1:0"})
+
+	conn, err := net.Dial("tcp", srv.Addrs()[0].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	octet := "<14>1 - host app - - - counted message"
+	payload := fmt.Sprintf("%d %s", len(octet), octet) +
+		"<13>Aug  6 13:55:02 host cron[1]: newline message\n"
+	if _, err := conn.Write([]byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := c.waitFor(t, 2)
+	if entries[0].Message != "counted message" {
+		t.Errorf("unexpected first message %q", entries[0].Message)
+	}
+	if entries[1].Message != "newline message" || entries[1].Service != "cron" {
+		t.Errorf("unexpected second entry %+v", entries[1])
+	}
+}
+
+func TestServer_TCP_InvalidFrameLength(t *testing.T) {
+	srv, _ := startServer(t, syslog.Config{TCPAddr: "127.0.0.1:0", MaxMessageSize: 1024})
+
+	// Chaque longueur invalide ferme la connexion sans attendre la suite de la trame
+	for _, prefix := range []string{
+		"1" + strings.Repeat("0", 20),
+		"12x ",
+		"2048 ",
+	} {
+		conn, err := net.Dial("tcp", srv.Addrs()[0].String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write([]byte(prefix)); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+			t.Errorf("expected the connection to be closed for %q, got %v", prefix, err)
+		}
+		conn.Close()
+	}
+}
+
+func TestServer_RejectsInvalidEntries(t *testing.T) {
+	srv, c := startServer(t, syslog.Config{TCPAddr: "127.0.0.1:0"})
+
+	conn, err := net.Dial("tcp", srv.Addrs()[0].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Le premier message est vide (rejeté par Validate), le second est correct
+	if _, err := conn.Write([]byte("<14>1 - host app - - -\n<14>1 - host app - - - ok\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := c.waitFor(t, 1)
+	if len(entries) != 1 || entries[0].Message != "ok" {
+		t.Errorf("expected only the valid entry, got %+v", entries)
+	}
+}
+
+func TestServer_CloseIsSafeTwice(t *testing.T) {
+	srv := syslog.NewServer(syslog.Config{UDPAddr: "127.0.0.1:0", TCPAddr: "127.0.0.1:0"}, func(*internal.LogEntry) error { return nil })
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Errorf("second Close returned error: %v", err)
+	}
+}
+
+func TestServer_TLSRequiresCertificate(t *testing.T) {
+	srv := syslog.NewServer(syslog.Config{TLSAddr: "127.0.0.1:0", TLSCertFile: "missing.pem", TLSKeyFile: "missing.key"}, func(*internal.LogEntry) error { return nil })
+	if err := srv.Start(); err == nil {
+		srv.Close()
+		t.Error("expected error when TLS certificate is missing")
+	}
+}
@@ -0,0 +1,291 @@
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+)
+
+const nilValue = "-"
+
+var (
+	ErrInvalidPriority = errors.New("invalid syslog priority")
+	ErrInvalidMessage  = errors.New("invalid syslog message")
+)
+
+// Noms des facilités définis par la RFC 5424 (section 6.2.1)
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// Message est un message syslog décodé (RFC 5424 ou RFC 3164)
+type Message struct {
+	Facility       int
+	Severity       int
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData map[string]map[string]string
+	Content        string
+}
+
+// SeverityToLogLevel associe une sévérité syslog (0..7) à un niveau de log
+func SeverityToLogLevel(severity int) log_levels.LogLevel {
+	switch {
+	case severity <= 2: // emerg, alert, crit
+		return log_levels.LogLevelFatal
+	case severity == 3: // err
+		return log_levels.LogLevelError
+	case severity == 4: // warning
+		return log_levels.LogLevelWarn
+	case severity == 7: // debug
+		return log_levels.LogLevelDebug
+	default: // notice, info
+		return log_levels.LogLevelInfo
+	}
+}
+
+// FacilityName retourne le nom usuel d'une facilité (ou son numéro si inconnu)
+func FacilityName(facility int) string {
+	if facility >= 0 && facility < len(facilityNames) {
+		return facilityNames[facility]
+	}
+	return strconv.Itoa(facility)
+}
+
+// Parse décode un message syslog en détectant automatiquement le format
+func Parse(raw []byte, now time.Time) (*Message, error) {
+	line := strings.TrimRight(string(raw), "\r\n\x00")
+
+	pri, rest, err := parsePriority(line)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{
+		Facility: pri / 8,
+		Severity: pri % 8,
+	}
+
+	// RFC 5424 : la version (1) suit immédiatement le PRI
+	if len(rest) >= 2 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ' {
+		if err := parseRFC5424(msg, rest[2:]); err != nil {
+			return nil, err
+		}
+	} else {
+		parseRFC3164(msg, rest, now)
+	}
+
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = now
+	}
+	return msg, nil
+}
+
+func parsePriority(line string) (int, string, error) {
+	if len(line) < 3 || line[0] != '<' {
+		return 0, "", ErrInvalidPriority
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return 0, "", ErrInvalidPriority
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, "", ErrInvalidPriority
+	}
+	return pri, line[end+1:], nil
+}
+
+// parseRFC5424 décode TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parseRFC5424(msg *Message, s string) error {
+	fields := make([]string, 5)
+	for i := range fields {
+		var ok bool
+		fields[i], s, ok = nextField(s)
+		if !ok {
+			return fmt.Errorf("%w: missing header field", ErrInvalidMessage)
+		}
+	}
+
+	if fields[0] != nilValue {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("%w: bad timestamp %q", ErrInvalidMessage, fields[0])
+		}
+		msg.Timestamp = ts
+	}
+	msg.Hostname = nilToEmpty(fields[1])
+	msg.AppName = nilToEmpty(fields[2])
+	msg.ProcID = nilToEmpty(fields[3])
+	msg.MsgID = nilToEmpty(fields[4])
+
+	sd, rest, err := parseStructuredData(s)
+	if err != nil {
+		return err
+	}
+	msg.StructuredData = sd
+
+	rest = strings.TrimPrefix(rest, " ")
+	rest = strings.TrimPrefix(rest, "\ufeff")
+	msg.Content = rest
+	return nil
+}
+
+// parseStructuredData décode "-" ou une suite de [SD-ID PARAM="VALUE" ...]
+func parseStructuredData(s string) (map[string]map[string]string, string, error) {
+	if strings.HasPrefix(s, nilValue) {
+		return nil, s[1:], nil
+	}
+	if !strings.HasPrefix(s, "[") {
+		return nil, "", fmt.Errorf("%w: bad structured data", ErrInvalidMessage)
+	}
+
+	sd := make(map[string]map[string]string)
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+		end := strings.IndexAny(s, " ]")
+		if end <= 0 {
+			return nil, "", fmt.Errorf("%w: bad SD-ID", ErrInvalidMessage)
+		}
+		id := s[:end]
+		s = s[end:]
+		params := make(map[string]string)
+
+		for {
+			s = strings.TrimLeft(s, " ")
+			if strings.HasPrefix(s, "]") {
+				s = s[1:]
+				break
+			}
+			eq := strings.Index(s, "=\"")
+			if eq <= 0 {
+				return nil, "", fmt.Errorf("%w: bad SD-PARAM", ErrInvalidMessage)
+			}
+			name := s[:eq]
+			value, rest, err := parseParamValue(s[eq+2:])
+			if err != nil {
+				return nil, "", err
+			}
+			params[name] = value
+			s = rest
+		}
+		sd[id] = params
+	}
+	return sd, s, nil
+}
+
+// parseParamValue lit une valeur entre guillemets en gérant les échappements \" \\ \]
+func parseParamValue(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+				i++
+			}
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), s[i+1:], nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("%w: unterminated SD-PARAM value", ErrInvalidMessage)
+}
+
+// parseRFC3164 décode (de façon tolérante) "Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG"
+func parseRFC3164(msg *Message, s string, now time.Time) {
+	s = strings.TrimLeft(s, " ")
+
+	const stampLen = len(time.Stamp)
+	if len(s) >= stampLen {
+		if ts, err := time.ParseInLocation(time.Stamp, s[:stampLen], now.Location()); err == nil {
+			// Le format ne contient pas l'année : on prend l'année courante,
+			// ou la précédente si la date tombe dans le futur (passage du 31/12).
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = ts
+			s = strings.TrimLeft(s[stampLen:], " ")
+
+			if host, rest, ok := nextField(s); ok {
+				msg.Hostname = host
+				s = rest
+			}
+		}
+	}
+
+	// TAG : caractères alphanumériques terminés par ':' ou '[pid]:'
+	if end := strings.IndexAny(s, ":[ "); end > 0 && end <= 48 && s[end] != ' ' {
+		tag := s[:end]
+		rest := s[end:]
+		if rest[0] == '[' {
+			if closing := strings.IndexByte(rest, ']'); closing > 0 {
+				msg.ProcID = rest[1:closing]
+				rest = rest[closing+1:]
+			}
+		}
+		if strings.HasPrefix(rest, ":") {
+			msg.AppName = tag
+			s = strings.TrimPrefix(rest[1:], " ")
+		}
+	}
+
+	msg.Content = s
+}
+
+func nextField(s string) (string, string, bool) {
+	idx := strings.IndexByte(s, ' ')
+	if idx <= 0 {
+		return "", "", false
+	}
+	return s[:idx], s[idx+1:], true
+}
+
+func nilToEmpty(s string) string {
+	if s == nilValue {
+		return ""
+	}
+	return s
+}
+
+// ToLogEntry convertit un message syslog en LogEntry
+func (m *Message) ToLogEntry() *internal.LogEntry {
+	ctx := map[string]interface{}{
+		"facility": FacilityName(m.Facility),
+	}
+	if m.Hostname != "" {
+		ctx["hostname"] = m.Hostname
+	}
+	if m.AppName != "" {
+		ctx["app_name"] = m.AppName
+	}
+	if m.ProcID != "" {
+		ctx["procid"] = m.ProcID
+	}
+	if m.MsgID != "" {
+		ctx["msgid"] = m.MsgID
+	}
+	if len(m.StructuredData) > 0 {
+		ctx["structured_data"] = m.StructuredData
+	}
+
+	return &internal.LogEntry{
+		Level:     string(SeverityToLogLevel(m.Severity)),
+		Message:   m.Content,
+		Service:   m.AppName,
+		Timestamp: m.Timestamp,
+		Context:   ctx,
+	}
+}
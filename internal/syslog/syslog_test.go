@@ -0,0 +1,164 @@
+package syslog_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+	"github.com/rypi-dev/logger-server/internal/syslog"
+)
+
+var fixedNow = time.Date(2025, 8, 6, 14, 0, 0, 0, time.UTC)
+
+func TestParse_RFC5424(t *testing.T) {
+	raw := `<165>1 2025-08-06T14:12:00.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][meta quote="a\"b"] An application event`
+
+	msg, err := syslog.Parse([]byte(raw), fixedNow)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if msg.Facility != 20 || msg.Severity != 5 {
+		t.Errorf("expected facility 20 / severity 5, got %d / %d", msg.Facility, msg.Severity)
+	}
+	if !msg.Timestamp.Equal(time.Date(2025, 8, 6, 14, 12, 0, 3000000, time.UTC)) {
+		t.Errorf("unexpected timestamp %v", msg.Timestamp)
+	}
+	if msg.Hostname != "mymachine.example.com" || msg.AppName != "evntslog" || msg.ProcID != "1234" || msg.MsgID != "ID47" {
+		t.Errorf("unexpected header fields: %+v", msg)
+	}
+	if msg.StructuredData["exampleSDID@32473"]["eventID"] != "1011" {
+		t.Errorf("unexpected structured data: %v", msg.StructuredData)
+	}
+	if msg.StructuredData["meta"]["quote"] != `a"b` {
+		t.Errorf("expected escaped quote to be decoded, got %q", msg.StructuredData["meta"]["quote"])
+	}
+	if msg.Content != "An application event" {
+		t.Errorf("unexpected content %q", msg.Content)
+	}
+}
+
+func TestParse_RFC5424_NilValues(t *testing.T) {
+	msg, err := syslog.Parse([]byte("<14>1 - - - - - -\n"), fixedNow)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if !msg.Timestamp.Equal(fixedNow) {
+		t.Errorf("expected timestamp to default to now, got %v", msg.Timestamp)
+	}
+	if msg.Hostname != "" || msg.AppName != "" || msg.StructuredData != nil || msg.Content != "" {
+		t.Errorf("expected empty fields, got %+v", msg)
+	}
+}
+
+func TestParse_RFC3164(t *testing.T) {
+	msg, err := syslog.Parse([]byte("<34>Aug  6 13:55:02 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8"), fixedNow)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if msg.Severity != 2 || msg.Facility != 4 {
+		t.Errorf("expected facility 4 / severity 2, got %d / %d", msg.Facility, msg.Severity)
+	}
+	if !msg.Timestamp.Equal(time.Date(2025, 8, 6, 13, 55, 2, 0, time.UTC)) {
+		t.Errorf("unexpected timestamp %v", msg.Timestamp)
+	}
+	if msg.Hostname != "mymachine" || msg.AppName != "su" || msg.ProcID != "230" {
+		t.Errorf("unexpected header fields: %+v", msg)
+	}
+	if msg.Content != "'su root' failed for lonvick on /dev/pts/8" {
+		t.Errorf("unexpected content %q", msg.Content)
+	}
+}
+
+func TestParse_RFC3164_PreviousYear(t *testing.T) {
+	newYear := time.Date(2026, 1, 1, 0, 0, 5, 0, time.UTC)
+	msg, err := syslog.Parse([]byte("<13>Dec 31 23:59:59 host app: bye"), newYear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Timestamp.Year() != 2025 {
+		t.Errorf("expected year 2025, got %d", msg.Timestamp.Year())
+	}
+}
+
+func TestParse_RFC3164_NoHeader(t *testing.T) {
+	msg, err := syslog.Parse([]byte("<13>just a message"), fixedNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "just a message" || msg.AppName != "" {
+		t.Errorf("unexpected parse: %+v", msg)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want error
+	}{
+		{"no priority", "hello", syslog.ErrInvalidPriority},
+		{"priority out of range", "<192>1 - - - - - -", syslog.ErrInvalidPriority},
+		{"truncated header", "<14>1 2025-08-06T14:12:00Z host", syslog.ErrInvalidMessage},
+		{"bad timestamp", "<14>1 yesterday host app - - - msg", syslog.ErrInvalidMessage},
+		{"unterminated sd", `<14>1 - host app - - [id k="v msg`, syslog.ErrInvalidMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := syslog.Parse([]byte(tt.raw), fixedNow)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSeverityToLogLevel(t *testing.T) {
+	tests := []struct {
+		severity int
+		want     log_levels.LogLevel
+	}{
+		{0, log_levels.LogLevelFatal},
+		{2, log_levels.LogLevelFatal},
+		{3, log_levels.LogLevelError},
+		{4, log_levels.LogLevelWarn},
+		{5, log_levels.LogLevelInfo},
+		{6, log_levels.LogLevelInfo},
+		{7, log_levels.LogLevelDebug},
+	}
+	for _, tt := range tests {
+		if got := syslog.SeverityToLogLevel(tt.severity); got != tt.want {
+			t.Errorf("SeverityToLogLevel(%d) = %q; want %q", tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestMessage_ToLogEntry(t *testing.T) {
+	msg, err := syslog.Parse([]byte(`<11>1 2025-08-06T14:12:00Z web01 nginx 42 - [req@1 id="7"] upstream timed out`), fixedNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := msg.ToLogEntry()
+	if entry.Level != "ERROR" {
+		t.Errorf("expected level ERROR, got %q", entry.Level)
+	}
+	if entry.Service != "nginx" {
+		t.Errorf("expected service nginx, got %q", entry.Service)
+	}
+	if entry.Message != "upstream timed out" {
+		t.Errorf("unexpected message %q", entry.Message)
+	}
+	if entry.Context["facility"] != "user" || entry.Context["hostname"] != "web01" || entry.Context["procid"] != "42" {
+		t.Errorf("unexpected context %v", entry.Context)
+	}
+	if _, ok := entry.Context["structured_data"]; !ok {
+		t.Error("expected structured_data in context")
+	}
+	if err := entry.Validate(); err != nil {
+		t.Errorf("expected entry to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package tenant
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// Store est implémenté par SQLiteStore
+type Store interface {
+	Create(name string, settings Settings) (*Tenant, error)
+	Get(name string) (*Tenant, error)
+	List() []Tenant
+	Update(name string, settings Settings) (*Tenant, error)
+	Delete(name string) error
+}
+
+// Purger supprime les données d'un tenant : ses clés (apikey.SQLiteStore) et ses entrées
+// (logger.SQLiteLogger)
+type Purger interface {
+	PurgeTenant(name string) error
+}
+
+// CreateRequest est le corps de POST /admin/tenants
+type CreateRequest struct {
+	Name string `json:"name" example:"payments"`
+	Settings
+}
+
+// API expose la gestion des tenants; les routes /admin/ exigent le scope admin d'une clé
+// du tenant par défaut
+type API struct {
+	store   Store
+	purgers []Purger
+}
+
+// NewAPI crée l'API; les purgers sont appelés dans l'ordre à la suppression d'un tenant,
+// les clés en premier pour couper l'ingestion avant d'effacer les entrées
+func NewAPI(store Store, purgers ...Purger) *API {
+	return &API{store: store, purgers: purgers}
+}
+
+// Register ajoute les routes /admin/tenants au routeur
+func (a *API) Register(r *mux.Router) {
+	r.HandleFunc("/admin/tenants", a.handleList).Methods("GET")
+	r.HandleFunc("/admin/tenants", a.handleCreate).Methods("POST")
+	r.HandleFunc("/admin/tenants/{name}", a.handleGet).Methods("GET")
+	r.HandleFunc("/admin/tenants/{name}", a.handleUpdate).Methods("PUT")
+	r.HandleFunc("/admin/tenants/{name}", a.handleDelete).Methods("DELETE")
+}
+
+func (a *API) handleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.store.List())
+}
+
+func (a *API) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	t, err := a.store.Create(req.Name, req.Settings)
+	if err != nil {
+		writeStoreError(w, err, "failed to create tenant")
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (a *API) handleGet(w http.ResponseWriter, r *http.Request) {
+	t, err := a.store.Get(mux.Vars(r)["name"])
+	if err != nil {
+		writeStoreError(w, err, "failed to get tenant")
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (a *API) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	var settings Settings
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&settings); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	t, err := a.store.Update(mux.Vars(r)["name"], settings)
+	if err != nil {
+		writeStoreError(w, err, "failed to update tenant")
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// handleDelete révoque les clés du tenant et efface ses entrées avant de le supprimer :
+// en cas d'échec le tenant reste listé et la suppression peut être relancée
+func (a *API) handleDelete(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if _, err := a.store.Get(name); err != nil {
+		writeStoreError(w, err, "failed to delete tenant")
+		return
+	}
+
+	for _, p := range a.purgers {
+		if err := p.PurgeTenant(name); err != nil {
+			utils.WriteJSONError(w, http.StatusInternalServerError, "failed to purge tenant data")
+			return
+		}
+	}
+	if err := a.store.Delete(name); err != nil {
+		writeStoreError(w, err, "failed to delete tenant")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeStoreError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		utils.WriteJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrExists):
+		utils.WriteJSONError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalidName), errors.Is(err, ErrInvalidSettings):
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+	default:
+		utils.WriteJSONError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,105 @@
+package tenant
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type purger struct {
+	purged []string
+	err    error
+}
+
+func (p *purger) PurgeTenant(name string) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.purged = append(p.purged, name)
+	return nil
+}
+
+func do(r http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+	return w
+}
+
+func TestAPI(t *testing.T) {
+	s, _ := newTestStore(t)
+	keys, logs := &purger{}, &purger{}
+	r := mux.NewRouter()
+	NewAPI(s, keys, logs).Register(r)
+
+	w := do(r, "POST", "/admin/tenants", `{"name":"payments","retention":"720h","max_entries":1000}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created Tenant
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Name != "payments" || created.Retention != "720h" || created.MaxEntries != 1000 {
+		t.Errorf("unexpected tenant: %+v", created)
+	}
+
+	for body, want := range map[string]int{
+		`not json`:            http.StatusBadRequest,
+		`{"name":"Payments"}`: http.StatusBadRequest,
+		`{"name":"billing","retention":"forever"}`: http.StatusBadRequest,
+		`{"name":"payments"}`:                      http.StatusConflict,
+	} {
+		if w := do(r, "POST", "/admin/tenants", body); w.Code != want {
+			t.Errorf("expected %d for %s, got %d", want, body, w.Code)
+		}
+	}
+
+	if w := do(r, "PUT", "/admin/tenants/payments", `{"retention":"24h"}`); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = do(r, "GET", "/admin/tenants/payments", "")
+	var got Tenant
+	json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got.Retention != "24h" || got.MaxEntries != 0 {
+		t.Errorf("expected updated settings, got %d %+v", w.Code, got)
+	}
+	if w := do(r, "PUT", "/admin/tenants/unknown", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+
+	w = do(r, "GET", "/admin/tenants", "")
+	var list []Tenant
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list) != 1 {
+		t.Errorf("expected 1 tenant, got %+v", list)
+	}
+
+	if w := do(r, "DELETE", "/admin/tenants/payments", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if len(keys.purged) != 1 || len(logs.purged) != 1 || s.Exists("payments") {
+		t.Errorf("expected tenant data to be purged, got keys %v logs %v", keys.purged, logs.purged)
+	}
+	if w := do(r, "DELETE", "/admin/tenants/payments", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestAPI_DeleteKeepsTenantOnPurgeFailure(t *testing.T) {
+	s, _ := newTestStore(t)
+	if _, err := s.Create("payments", Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	NewAPI(s, &purger{err: errors.New("disk full")}).Register(r)
+
+	if w := do(r, "DELETE", "/admin/tenants/payments", ""); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+	if !s.Exists("payments") {
+		t.Error("expected the tenant to be kept so the deletion can be retried")
+	}
+}
@@ -0,0 +1,90 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultRetentionInterval est la fréquence d'application des rétentions
+const DefaultRetentionInterval = 10 * time.Minute
+
+var prunedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "tenant_entries_pruned_total",
+	Help: "Total number of log entries deleted by tenant retention settings",
+}, []string{"tenant"})
+
+func init() {
+	prometheus.MustRegister(prunedTotal)
+}
+
+// Pruner supprime les entrées d'un tenant antérieures à before (si non nul) puis les plus
+// anciennes au-delà de maxEntries (si > 0) (logger.SQLiteLogger)
+type Pruner interface {
+	PruneTenant(name string, before time.Time, maxEntries int) (int64, error)
+}
+
+// Lister retourne les tenants dont la rétention est appliquée (SQLiteStore)
+type Lister interface {
+	List() []Tenant
+}
+
+// Retention applique périodiquement la rétention et le nombre maximal d'entrées de chaque
+// tenant. Le tenant par défaut garde le nettoyage global du logger (LOGGER_MAX_ROWS).
+type Retention struct {
+	tenants  Lister
+	pruner   Pruner
+	interval time.Duration
+}
+
+func NewRetention(tenants Lister, pruner Pruner, interval time.Duration) *Retention {
+	if interval <= 0 {
+		interval = DefaultRetentionInterval
+	}
+	return &Retention{tenants: tenants, pruner: pruner, interval: interval}
+}
+
+// Enforce applique les réglages de tous les tenants et retourne le nombre d'entrées
+// supprimées. Un tenant en erreur n'empêche pas de traiter les suivants.
+func (r *Retention) Enforce(now time.Time) (int64, error) {
+	var total int64
+	var firstErr error
+	for _, t := range r.tenants.List() {
+		var before time.Time
+		if d := t.RetentionDuration(); d > 0 {
+			before = now.Add(-d)
+		}
+		if before.IsZero() && t.MaxEntries <= 0 {
+			continue
+		}
+
+		n, err := r.pruner.PruneTenant(t.Name, before, t.MaxEntries)
+		if n > 0 {
+			prunedTotal.WithLabelValues(t.Name).Add(float64(n))
+			total += n
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+	}
+	return total, firstErr
+}
+
+// Run applique les rétentions à intervalle régulier jusqu'à l'annulation du contexte
+func (r *Retention) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if _, err := r.Enforce(now); err != nil {
+				fmt.Printf("tenant retention error: %v\n", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package tenant
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type pruneCall struct {
+	name       string
+	before     time.Time
+	maxEntries int
+}
+
+type pruner struct {
+	calls []pruneCall
+	fail  string
+}
+
+func (p *pruner) PruneTenant(name string, before time.Time, maxEntries int) (int64, error) {
+	p.calls = append(p.calls, pruneCall{name, before, maxEntries})
+	if name == p.fail {
+		return 0, errors.New("locked")
+	}
+	return 2, nil
+}
+
+type tenants []Tenant
+
+func (t tenants) List() []Tenant { return t }
+
+func TestRetention_Enforce(t *testing.T) {
+	now := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	p := &pruner{fail: "broken"}
+	r := NewRetention(tenants{
+		{Name: "broken", Settings: Settings{MaxEntries: 5}},
+		{Name: "payments", Settings: Settings{Retention: "24h", MaxEntries: 100}},
+		{Name: "unlimited"},
+		{Name: "billing", Settings: Settings{MaxEntries: 10}},
+	}, p, 0)
+
+	deleted, err := r.Enforce(now)
+	if err == nil {
+		t.Error("expected the error of the broken tenant to be reported")
+	}
+	if deleted != 4 {
+		t.Errorf("expected 4 entries deleted, got %d", deleted)
+	}
+
+	want := []pruneCall{
+		{"broken", time.Time{}, 5},
+		{"payments", now.Add(-24 * time.Hour), 100},
+		{"billing", time.Time{}, 10},
+	}
+	if len(p.calls) != len(want) {
+		t.Fatalf("expected %d prune calls, got %+v", len(want), p.calls)
+	}
+	for i, c := range want {
+		if got := p.calls[i]; got.name != c.name || !got.before.Equal(c.before) || got.maxEntries != c.maxEntries {
+			t.Errorf("call %d: expected %+v, got %+v", i, c, got)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package tenant
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+var (
+	ErrInvalidName     = errors.New("invalid tenant name")
+	ErrInvalidSettings = errors.New("invalid tenant settings")
+	ErrExists          = errors.New("tenant already exists")
+	ErrNotFound        = errors.New("tenant not found")
+)
+
+// Un nom de tenant apparaît dans les clés, les jetons et les métriques : minuscules,
+// chiffres et tirets
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Tenant isole les logs d'une équipe : ses clés n'écrivent et ne lisent que ses entrées.
+// Le tenant par défaut ("") regroupe les entrées des clés sans tenant et n'est pas géré ici.
+type Tenant struct {
+	Name string `json:"name" example:"payments"`
+	Settings
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Settings sont les réglages modifiables d'un tenant; zéro désactive la limite
+type Settings struct {
+	// Retention est l'âge maximal des entrées (durée Go : "720h")
+	Retention string `json:"retention,omitempty" example:"720h"`
+	// MaxEntries est le nombre maximal d'entrées conservées, les plus anciennes étant
+	// supprimées au-delà
+	MaxEntries int `json:"max_entries,omitempty" example:"1000000"`
+}
+
+// Validate vérifie les réglages et normalise la rétention
+func (s *Settings) Validate() error {
+	s.Retention = strings.TrimSpace(s.Retention)
+	if s.Retention != "" {
+		d, err := time.ParseDuration(s.Retention)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%w: invalid retention %q", ErrInvalidSettings, s.Retention)
+		}
+	}
+	if s.MaxEntries < 0 {
+		return fmt.Errorf("%w: max_entries must not be negative", ErrInvalidSettings)
+	}
+	return nil
+}
+
+// RetentionDuration retourne la rétention, 0 si elle n'est pas limitée
+func (s Settings) RetentionDuration() time.Duration {
+	d, _ := time.ParseDuration(s.Retention)
+	return d
+}
+
+// ValidateName vérifie le nom d'un tenant
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: %q (lowercase letters, digits and dashes, at most 63 characters)", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// SQLiteStore conserve les tenants dans la table tenants. La liste est gardée en mémoire :
+// Exists est appelé à chaque requête authentifiée.
+type SQLiteStore struct {
+	db  *sql.DB
+	now func() time.Time
+
+	mu      sync.RWMutex
+	tenants map[string]Tenant
+}
+
+// NewSQLiteStore ouvre (ou crée) la table des tenants dans la base SQLite donnée.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS tenants (
+		name TEXT PRIMARY KEY,
+		retention TEXT NOT NULL DEFAULT '',
+		max_entries INTEGER NOT NULL DEFAULT 0,
+		created_at TEXT NOT NULL
+	);`); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &SQLiteStore{db: db, now: time.Now, tenants: make(map[string]Tenant)}
+	if err := s.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLiteStore) load() error {
+	rows, err := s.db.Query(`SELECT name, retention, max_entries, created_at FROM tenants`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t Tenant
+		var createdAt string
+		if err := rows.Scan(&t.Name, &t.Retention, &t.MaxEntries, &createdAt); err != nil {
+			return err
+		}
+		t.CreatedAt = utils.SafeParseTimestamp(createdAt)
+		s.tenants[t.Name] = t
+	}
+	return rows.Err()
+}
+
+// Create ajoute un tenant
+func (s *SQLiteStore) Create(name string, settings Settings) (*Tenant, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tenants[name]; ok {
+		return nil, ErrExists
+	}
+	t := Tenant{Name: name, Settings: settings, CreatedAt: s.now().UTC().Truncate(time.Second)}
+	if _, err := s.db.Exec(`INSERT INTO tenants(name, retention, max_entries, created_at) VALUES (?, ?, ?, ?)`,
+		t.Name, t.Retention, t.MaxEntries, t.CreatedAt.Format(utils.TimestampLayout)); err != nil {
+		return nil, err
+	}
+	s.tenants[name] = t
+	return &t, nil
+}
+
+// Update remplace les réglages d'un tenant
+func (s *SQLiteStore) Update(name string, settings Settings) (*Tenant, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tenants[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if _, err := s.db.Exec(`UPDATE tenants SET retention = ?, max_entries = ? WHERE name = ?`,
+		settings.Retention, settings.MaxEntries, name); err != nil {
+		return nil, err
+	}
+	t.Settings = settings
+	s.tenants[name] = t
+	return &t, nil
+}
+
+// Delete supprime un tenant; ses clés et ses entrées sont purgées par l'API au préalable
+func (s *SQLiteStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tenants[name]; !ok {
+		return ErrNotFound
+	}
+	if _, err := s.db.Exec(`DELETE FROM tenants WHERE name = ?`, name); err != nil {
+		return err
+	}
+	delete(s.tenants, name)
+	return nil
+}
+
+func (s *SQLiteStore) Get(name string) (*Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tenants[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
+
+// Exists indique si le tenant existe (apikey.TenantChecker)
+func (s *SQLiteStore) Exists(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.tenants[name]
+	return ok
+}
+
+// List retourne les tenants par ordre alphabétique
+func (s *SQLiteStore) List() []Tenant {
+	s.mu.RLock()
+	out := make([]Tenant, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		out = append(out, t)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,99 @@
+package tenant
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (*SQLiteStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tenants.db")
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	s.now = func() time.Time { return time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC) }
+	return s, path
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"payments", "team-42", "a"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("expected %q to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "-payments", "Payments", "team_42", "a/b", strings.Repeat("a", 64)} {
+		if err := ValidateName(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("expected ErrInvalidName for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestSettings_Validate(t *testing.T) {
+	s := Settings{Retention: " 720h ", MaxEntries: 10}
+	if err := s.Validate(); err != nil || s.Retention != "720h" || s.RetentionDuration() != 720*time.Hour {
+		t.Errorf("expected valid settings, got %+v, %v", s, err)
+	}
+	for _, bad := range []Settings{{Retention: "a month"}, {Retention: "-1h"}, {MaxEntries: -1}} {
+		if err := bad.Validate(); !errors.Is(err, ErrInvalidSettings) {
+			t.Errorf("expected ErrInvalidSettings for %+v, got %v", bad, err)
+		}
+	}
+	if d := (Settings{}).RetentionDuration(); d != 0 {
+		t.Errorf("expected unlimited retention, got %v", d)
+	}
+}
+
+func TestSQLiteStore_CRUD(t *testing.T) {
+	s, path := newTestStore(t)
+
+	created, err := s.Create("payments", Settings{Retention: "24h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Name != "payments" || created.Retention != "24h" || created.CreatedAt.IsZero() {
+		t.Errorf("unexpected tenant: %+v", created)
+	}
+	if _, err := s.Create("payments", Settings{}); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+	if _, err := s.Create("billing", Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Exists("payments") || s.Exists("unknown") {
+		t.Error("unexpected Exists result")
+	}
+
+	updated, err := s.Update("payments", Settings{MaxEntries: 100})
+	if err != nil || updated.Retention != "" || updated.MaxEntries != 100 || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("expected settings to be replaced, got %+v, %v", updated, err)
+	}
+	if _, err := s.Update("unknown", Settings{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// Les tenants survivent à un redémarrage
+	reopened, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	list := reopened.List()
+	if len(list) != 2 || list[0].Name != "billing" || list[1].Name != "payments" || list[1].MaxEntries != 100 {
+		t.Errorf("expected tenants to be reloaded in order, got %+v", list)
+	}
+
+	if err := s.Delete("payments"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("payments"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := s.Delete("payments"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a second delete, got %v", err)
+	}
+}
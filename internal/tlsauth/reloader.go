@@ -0,0 +1,91 @@
+package tlsauth
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Fréquence maximale de vérification des fichiers (un stat par poignée de main sinon)
+const reloadCheckInterval = 10 * time.Second
+
+var certReloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "tls_certificate_reloads_total",
+	Help: "Total number of server certificate reloads after a file change, by result",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(certReloadsTotal)
+}
+
+// CertReloader sert le certificat du serveur et le recharge quand le certificat ou la clé
+// change sur disque (renouvellement cert-manager, certbot, ...), sans redémarrage. Une
+// paire invalide, par exemple pendant l'écriture des deux fichiers, est ignorée : l'ancien
+// certificat reste servi et le chargement est retenté.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	now      func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile, now: time.Now}
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	c.cert, c.modTime, c.checkedAt = &cert, modTime, c.now()
+	return c, nil
+}
+
+// GetCertificate s'utilise comme tls.Config.GetCertificate
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if now.Sub(c.checkedAt) < reloadCheckInterval {
+		return c.cert, nil
+	}
+	c.checkedAt = now
+
+	modTime, err := c.latestModTime()
+	if err != nil || modTime.Equal(c.modTime) {
+		return c.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		certReloadsTotal.WithLabelValues("error").Inc()
+		return c.cert, nil
+	}
+	c.cert, c.modTime = &cert, modTime
+	certReloadsTotal.WithLabelValues("success").Inc()
+	return c.cert, nil
+}
+
+func (c *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,164 @@
+package tlsauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA signe les certificats des tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signe un certificat feuille; tmpl fixe le sujet, les SAN et l'usage
+func (ca *testCA) issue(t *testing.T, serial int64, tmpl *x509.Certificate) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = big.NewInt(serial)
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func serverTemplate() *x509.Certificate {
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serial(t *testing.T, cert *tls.Certificate) int64 {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.SerialNumber.Int64()
+}
+
+func TestCertReloader(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	mtime := time.Now().Add(-time.Hour)
+
+	certPEM, keyPEM := ca.issue(t, 10, serverTemplate())
+	writeFile(t, certFile, certPEM, mtime)
+	writeFile(t, keyFile, keyPEM, mtime)
+
+	r, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	r.checkedAt = now
+
+	get := func() int64 {
+		cert, err := r.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return serial(t, cert)
+	}
+	if got := get(); got != 10 {
+		t.Fatalf("expected initial certificate, got serial %d", got)
+	}
+
+	// Renouvellement : pris en compte seulement après l'intervalle de vérification
+	certPEM, keyPEM = ca.issue(t, 11, serverTemplate())
+	mtime = mtime.Add(time.Minute)
+	writeFile(t, certFile, certPEM, mtime)
+	writeFile(t, keyFile, keyPEM, mtime)
+	if got := get(); got != 10 {
+		t.Errorf("expected cached certificate before the check interval, got serial %d", got)
+	}
+	now = now.Add(reloadCheckInterval)
+	if got := get(); got != 11 {
+		t.Errorf("expected renewed certificate, got serial %d", got)
+	}
+
+	// Certificat écrit sans sa clé : l'ancienne paire reste servie
+	certPEM, _ = ca.issue(t, 12, serverTemplate())
+	mtime = mtime.Add(time.Minute)
+	writeFile(t, certFile, certPEM, mtime)
+	now = now.Add(reloadCheckInterval)
+	if got := get(); got != 11 {
+		t.Errorf("expected previous certificate after an invalid pair, got serial %d", got)
+	}
+}
+
+func TestNewCertReloader_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewCertReloader(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key")); err == nil {
+		t.Error("expected error for missing files")
+	}
+
+	ca := newTestCA(t)
+	certPEM, _ := ca.issue(t, 10, serverTemplate())
+	_, otherKey := ca.issue(t, 11, serverTemplate())
+	writeFile(t, filepath.Join(dir, "tls.crt"), certPEM, time.Now())
+	writeFile(t, filepath.Join(dir, "tls.key"), otherKey, time.Now())
+	if _, err := NewCertReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")); err == nil {
+		t.Error("expected error for mismatched key")
+	}
+}
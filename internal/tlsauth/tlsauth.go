@@ -0,0 +1,109 @@
+package tlsauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// Vérification des certificats clients
+const (
+	ClientAuthOptional = "optional" // vérifié s'il est présenté (défaut avec une CA)
+	ClientAuthRequire  = "require"  // connexion refusée sans certificat valide
+)
+
+var ErrUnknownIdentity = errors.New("client certificate identity has no scope")
+
+// Config décrit le TLS du serveur HTTP. ClientCAFile active la vérification des
+// certificats clients.
+type Config struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	ClientAuth   string
+}
+
+// ServerConfig construit la configuration TLS du serveur, avec rechargement à chaud du
+// certificat
+func ServerConfig(cfg Config) (*tls.Config, error) {
+	reloader, err := NewCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if cfg.ClientCAFile == "" {
+		if cfg.ClientAuth != "" {
+			return nil, errors.New("client certificate verification requires a client CA bundle")
+		}
+		return tlsCfg, nil
+	}
+
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificate found in client CA bundle")
+	}
+	tlsCfg.ClientCAs = pool
+
+	switch cfg.ClientAuth {
+	case "", ClientAuthOptional:
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("invalid client auth mode %q", cfg.ClientAuth)
+	}
+	return tlsCfg, nil
+}
+
+// Mapper donne des scopes aux identités des certificats clients (utils.CertIdentity) : un
+// agent authentifié par certificat est traité comme une clé API nommée d'après son identité.
+type Mapper struct {
+	scopes        map[string][]string
+	defaultScopes []string
+}
+
+// NewMapper valide les scopes par identité et ceux de tout certificat vérifié
+func NewMapper(scopes map[string][]string, defaultScopes []string) (*Mapper, error) {
+	m := &Mapper{scopes: make(map[string][]string, len(scopes))}
+	for id, list := range scopes {
+		valid, err := apikey.ValidateScopes(list)
+		if err != nil {
+			return nil, fmt.Errorf("identity %q: %w", id, err)
+		}
+		m.scopes[id] = valid
+	}
+	if len(defaultScopes) > 0 {
+		valid, err := apikey.ValidateScopes(defaultScopes)
+		if err != nil {
+			return nil, err
+		}
+		m.defaultScopes = valid
+	}
+	return m, nil
+}
+
+// AuthenticateCert retourne la clé équivalente à un certificat déjà vérifié par TLS
+func (m *Mapper) AuthenticateCert(cert *x509.Certificate) (*apikey.Key, error) {
+	id := utils.CertIdentity(cert)
+	scopes := append(append([]string{}, m.defaultScopes...), m.scopes[id]...)
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIdentity, id)
+	}
+	// Scopes déjà validés : ne retire que les doublons
+	scopes, _ = apikey.ValidateScopes(scopes)
+	notAfter := cert.NotAfter
+	return &apikey.Key{Name: id, Scopes: scopes, ExpiresAt: &notAfter}, nil
+}
@@ -0,0 +1,173 @@
+package tlsauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rypi-dev/logger-server/internal/utils"
+)
+
+// tlsFiles écrit le certificat du serveur et le bundle CA clients
+func tlsFiles(t *testing.T, ca *testCA) Config {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := Config{
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	certPEM, keyPEM := ca.issue(t, 2, serverTemplate())
+	writeFile(t, cfg.CertFile, certPEM, time.Now())
+	writeFile(t, cfg.KeyFile, keyPEM, time.Now())
+	writeFile(t, cfg.ClientCAFile, ca.pem, time.Now())
+	return cfg
+}
+
+func TestServerConfig_Modes(t *testing.T) {
+	cfg := tlsFiles(t, newTestCA(t))
+
+	tests := []struct {
+		name   string
+		caFile string
+		mode   string
+		want   tls.ClientAuthType
+		hasErr bool
+	}{
+		{"server only", "", "", tls.NoClientCert, false},
+		{"optional by default", cfg.ClientCAFile, "", tls.VerifyClientCertIfGiven, false},
+		{"optional", cfg.ClientCAFile, ClientAuthOptional, tls.VerifyClientCertIfGiven, false},
+		{"require", cfg.ClientCAFile, ClientAuthRequire, tls.RequireAndVerifyClientCert, false},
+		{"require without CA", "", ClientAuthRequire, 0, true},
+		{"unknown mode", cfg.ClientCAFile, "sometimes", 0, true},
+		{"CA without certificate", cfg.KeyFile, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			c.ClientCAFile, c.ClientAuth = tt.caFile, tt.mode
+			tlsCfg, err := ServerConfig(c)
+			if tt.hasErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tlsCfg.ClientAuth != tt.want || tlsCfg.MinVersion != tls.VersionTLS12 {
+				t.Errorf("unexpected config: client auth %v, min version %x", tlsCfg.ClientAuth, tlsCfg.MinVersion)
+			}
+		})
+	}
+}
+
+func TestServerConfig_Handshake(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := tlsFiles(t, ca)
+	cfg.ClientAuth = ClientAuthRequire
+	tlsCfg, err := ServerConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(utils.GetClientCertIdentity(r)))
+	}))
+	// StartTLS imposerait son propre certificat : le listener TLS est posé à la main
+	srv.Listener = tls.NewListener(srv.Listener, tlsCfg)
+	srv.Start()
+	defer srv.Close()
+	addr := "https://" + srv.Listener.Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+	}
+
+	if _, err := client().Get(addr); err == nil {
+		t.Error("expected handshake failure without a client certificate")
+	}
+
+	certPEM, keyPEM := ca.issue(t, 3, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "collector-1"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client(pair).Get(addr)
+	if err != nil {
+		t.Fatalf("expected handshake with a client certificate, got %v", err)
+	}
+	defer resp.Body.Close()
+	var body [64]byte
+	n, _ := resp.Body.Read(body[:])
+	if got := string(body[:n]); got != "collector-1" {
+		t.Errorf("expected identity collector-1, got %q", got)
+	}
+}
+
+func TestMapper(t *testing.T) {
+	m, err := NewMapper(map[string][]string{
+		"spiffe://prod/collector": {"ingest"},
+		"ops.example.com":         {"read", "admin"},
+	}, []string{"read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	spiffe, _ := url.Parse("spiffe://prod/collector")
+	tests := []struct {
+		name   string
+		cert   *x509.Certificate
+		want   []string
+		wantID string
+	}{
+		{"URI SAN", &x509.Certificate{URIs: []*url.URL{spiffe}, Subject: pkix.Name{CommonName: "ignored"}}, []string{"read", "ingest"}, "spiffe://prod/collector"},
+		{"DNS SAN", &x509.Certificate{DNSNames: []string{"ops.example.com"}}, []string{"read", "admin"}, "ops.example.com"},
+		{"default scopes only", &x509.Certificate{Subject: pkix.Name{CommonName: "someone"}}, []string{"read"}, "someone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cert.NotAfter = notAfter
+			key, err := m.AuthenticateCert(tt.cert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.Name != tt.wantID || !reflect.DeepEqual(key.Scopes, tt.want) {
+				t.Errorf("got %q %v, want %q %v", key.Name, key.Scopes, tt.wantID, tt.want)
+			}
+			if key.ExpiresAt == nil || !key.ExpiresAt.Equal(notAfter) {
+				t.Errorf("expected key to expire with the certificate, got %v", key.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestMapper_UnknownIdentity(t *testing.T) {
+	m, err := NewMapper(map[string][]string{"collector": {"ingest"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.AuthenticateCert(&x509.Certificate{Subject: pkix.Name{CommonName: "intruder"}})
+	if !errors.Is(err, ErrUnknownIdentity) {
+		t.Errorf("expected ErrUnknownIdentity, got %v", err)
+	}
+
+	if _, err := NewMapper(map[string][]string{"collector": {"write"}}, nil); err == nil {
+		t.Error("expected error for an invalid scope")
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"net"
@@ -48,12 +49,15 @@ func UnmarshalContext(jsonStr string) (map[string]interface{}, error) {
 	return ctx, nil
 }
 
-// GenerateCleanupQuery génère la requête SQL de nettoyage
+// GenerateCleanupQuery génère la requête SQL de nettoyage : chaque tenant garde ses
+// dernières entrées, sans que le volume d'un tenant efface celles des autres
 func GenerateCleanupQuery() string {
 	return `
 	DELETE FROM logs
-	WHERE id NOT IN (
-		SELECT id FROM logs ORDER BY id DESC LIMIT ?
+	WHERE id IN (
+		SELECT id FROM (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY tenant ORDER BY id DESC) AS rank FROM logs
+		) WHERE rank > ?
 	)
 	`
 }
@@ -88,6 +92,29 @@ func GetClientIP(r *http.Request) string {
 	return ip
 }
 
+// CertIdentity identifie un certificat client : premier SAN URI (SPIFFE), puis DNS, puis
+// e-mail, à défaut le Common Name du sujet
+func CertIdentity(cert *x509.Certificate) string {
+	switch {
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	}
+	return cert.Subject.CommonName
+}
+
+// GetClientCertIdentity retourne l'identité du certificat client vérifié de la connexion
+// TLS, ou une chaîne vide (pas de TLS, pas de certificat, ou certificat non vérifié)
+func GetClientCertIdentity(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return CertIdentity(r.TLS.VerifiedChains[0][0])
+}
+
 // ValidateWindow vérifie qu'une durée est raisonnable (ex : > 0)
 func ValidateWindow(window time.Duration) error {
 	if window <= 0 {
@@ -104,16 +131,46 @@ func ValidateMaxRequests(max int) error {
 	return nil
 }
 
-// GetAPIKey récupère la clé API dans les headers et la nettoie (trim espaces)
+// GetAPIKey récupère la clé API dans les headers et la nettoie (trim espaces).
+// À défaut de X-API-Key, le jeton HEC "Authorization: Splunk <token>" est accepté, ainsi que
+// le mot de passe Basic des requêtes GET/HEAD (navigateur de l'interface web). Basic est
+// limité à la lecture : le navigateur le renvoie seul, y compris sur des requêtes
+// provoquées par un autre site.
 func GetAPIKey(r *http.Request) string {
-	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(strings.TrimSpace(r.Header.Get("Authorization")), " ")
+	if ok && strings.EqualFold(scheme, "Splunk") {
+		return strings.TrimSpace(token)
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		if _, password, ok := r.BasicAuth(); ok {
+			return strings.TrimSpace(password)
+		}
+	}
+	return ""
+}
+
+// GetBearerToken retourne le jeton de "Authorization: Bearer <jeton>", ou une chaîne vide
+func GetBearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(r.Header.Get("Authorization")), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// ErrorResponse est le corps des erreurs écrites par WriteJSONError
+type ErrorResponse struct {
+	Error string `json:"error" example:"invalid 'limit' parameter"`
 }
 
 // WriteJSONError écrit une erreur en JSON avec status code
 func WriteJSONError(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: message})
 }
 
 // ParseAndValidatePageLimit parse et valide les paramètres page et limit
@@ -2,10 +2,14 @@ package utils_test
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -168,6 +172,88 @@ func TestGetAPIKey(t *testing.T) {
 	}
 }
 
+func TestGetAPIKey_SplunkAuthorization(t *testing.T) {
+	req := httptest.NewRequest("POST", "/services/collector/event", nil)
+	req.Header.Set("Authorization", "Splunk  hec-token ")
+	if key := utils.GetAPIKey(req); key != "hec-token" {
+		t.Errorf("expected 'hec-token', got '%s'", key)
+	}
+
+	req.Header.Set("Authorization", "Bearer hec-token")
+	if key := utils.GetAPIKey(req); key != "" {
+		t.Errorf("expected other schemes to be ignored, got '%s'", key)
+	}
+
+	req.Header.Set("X-API-Key", "mykey")
+	req.Header.Set("Authorization", "Splunk hec-token")
+	if key := utils.GetAPIKey(req); key != "mykey" {
+		t.Errorf("expected X-API-Key to take precedence, got '%s'", key)
+	}
+}
+
+func TestGetAPIKey_BasicAuth(t *testing.T) {
+	req := httptest.NewRequest("GET", "/ui/", nil)
+	req.SetBasicAuth("anyone", "mykey")
+	if key := utils.GetAPIKey(req); key != "mykey" {
+		t.Errorf("expected Basic password on GET, got '%s'", key)
+	}
+
+	req = httptest.NewRequest("POST", "/log", nil)
+	req.SetBasicAuth("anyone", "mykey")
+	if key := utils.GetAPIKey(req); key != "" {
+		t.Errorf("expected Basic auth to be ignored on POST, got '%s'", key)
+	}
+}
+
+func TestGetBearerToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "/log", nil)
+	req.Header.Set("Authorization", "bearer  eyJhbGciOi.x.y ")
+	if token := utils.GetBearerToken(req); token != "eyJhbGciOi.x.y" {
+		t.Errorf("expected the bearer token, got '%s'", token)
+	}
+
+	req.Header.Set("Authorization", "Splunk hec-token")
+	if token := utils.GetBearerToken(req); token != "" {
+		t.Errorf("expected other schemes to be ignored, got '%s'", token)
+	}
+}
+
+func TestCertIdentity(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://prod/ns/web/sa/fluent-bit")
+	tests := []struct {
+		cert *x509.Certificate
+		want string
+	}{
+		{&x509.Certificate{URIs: []*url.URL{spiffe}, DNSNames: []string{"web-1"}}, "spiffe://prod/ns/web/sa/fluent-bit"},
+		{&x509.Certificate{DNSNames: []string{"web-1.example.com"}, Subject: pkix.Name{CommonName: "web"}}, "web-1.example.com"},
+		{&x509.Certificate{EmailAddresses: []string{"ops@example.com"}}, "ops@example.com"},
+		{&x509.Certificate{Subject: pkix.Name{CommonName: "billing-agent"}}, "billing-agent"},
+	}
+	for _, tt := range tests {
+		if got := utils.CertIdentity(tt.cert); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestGetClientCertIdentity(t *testing.T) {
+	req := httptest.NewRequest("POST", "/log", nil)
+	if id := utils.GetClientCertIdentity(req); id != "" {
+		t.Errorf("expected no identity without TLS, got %q", id)
+	}
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "billing-agent"}}
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if id := utils.GetClientCertIdentity(req); id != "" {
+		t.Errorf("expected unverified certificates to be ignored, got %q", id)
+	}
+
+	req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+	if id := utils.GetClientCertIdentity(req); id != "billing-agent" {
+		t.Errorf("expected the verified certificate identity, got %q", id)
+	}
+}
+
 func TestWriteJSONError(t *testing.T) {
 	rr := httptest.NewRecorder()
 	utils.WriteJSONError(rr, http.StatusBadRequest, "bad error")
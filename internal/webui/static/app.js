@@ -0,0 +1,327 @@
+// Interface de recherche de logger-server. L'état de la recherche est gardé dans l'URL
+// (?q=...&level=ERROR,WARN&range=1h&ctx.user_id=42&tail=1) pour pouvoir partager un lien.
+(function () {
+  "use strict";
+
+  const LEVELS = ["TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"];
+  const PAGE_SIZE = 100;
+  const MAX_ROWS = 1000;
+  const TAIL_INTERVAL_MS = 2000;
+
+  const form = document.getElementById("search");
+  const tbody = document.querySelector("#results tbody");
+  const statusEl = document.getElementById("status");
+  const moreBtn = document.getElementById("more");
+  const histogramEl = document.getElementById("histogram");
+  const customRange = form.querySelector(".custom-range");
+
+  let newestID = 0;
+  let oldestID = 0;
+  let tailTimer = null;
+
+  // --- État <-> URL ---
+
+  function readForm() {
+    const data = new FormData(form);
+    const state = {
+      q: data.get("q").trim(),
+      service: data.get("service").trim(),
+      ctx: data.get("ctx").trim(),
+      levels: data.getAll("level"),
+      range: data.get("range"),
+      from: data.get("from"),
+      to: data.get("to"),
+      tail: data.get("tail") === "on",
+    };
+    return state;
+  }
+
+  function fillForm(params) {
+    form.q.value = params.get("q") || "";
+    form.service.value = params.get("service") || "";
+
+    const levels = (params.get("level") || "").split(",");
+    form.querySelectorAll("input[name=level]").forEach((box) => {
+      box.checked = levels.includes(box.value);
+    });
+
+    const ctx = [];
+    params.forEach((value, key) => {
+      if (key.startsWith("ctx.")) ctx.push(key.slice(4) + "=" + value);
+    });
+    form.ctx.value = ctx.join(" ");
+
+    if (params.has("from") || params.has("to")) {
+      form.range.value = "";
+      form.from.value = toLocalInput(params.get("from"));
+      form.to.value = toLocalInput(params.get("to"));
+    } else {
+      form.range.value = params.get("range") || "1h";
+    }
+    form.tail.checked = params.get("tail") === "1";
+    customRange.hidden = form.range.value !== "";
+  }
+
+  // Paramètres de l'URL partageable; une période relative reste relative
+  function stateToParams(state) {
+    const params = new URLSearchParams();
+    if (state.q) params.set("q", state.q);
+    if (state.service) params.set("service", state.service);
+    if (state.levels.length) params.set("level", state.levels.join(","));
+    for (const [path, value] of parseContext(state.ctx)) params.set("ctx." + path, value);
+    if (state.range) {
+      params.set("range", state.range);
+    } else {
+      if (state.from) params.set("from", new Date(state.from).toISOString());
+      if (state.to) params.set("to", new Date(state.to).toISOString());
+    }
+    if (state.tail) params.set("tail", "1");
+    return params;
+  }
+
+  // Paramètres de l'API : la période relative est convertie en from/to absolus
+  function stateToAPIParams(state) {
+    const params = stateToParams(state);
+    params.delete("range");
+    params.delete("tail");
+    const [from, to] = timeBounds(state);
+    if (from) params.set("from", from.toISOString());
+    if (to) params.set("to", to.toISOString());
+    return params;
+  }
+
+  function parseContext(text) {
+    const pairs = [];
+    for (const token of text.split(/\s+/)) {
+      const eq = token.indexOf("=");
+      if (eq > 0) pairs.push([token.slice(0, eq), token.slice(eq + 1)]);
+    }
+    return pairs;
+  }
+
+  function timeBounds(state) {
+    if (state.range) {
+      const to = new Date();
+      return [new Date(to.getTime() - parseDuration(state.range)), null];
+    }
+    return [state.from ? new Date(state.from) : null, state.to ? new Date(state.to) : null];
+  }
+
+  function parseDuration(value) {
+    const m = /^(\d+)([mh])$/.exec(value);
+    if (!m) return 3600 * 1000;
+    return Number(m[1]) * (m[2] === "h" ? 3600 : 60) * 1000;
+  }
+
+  function toLocalInput(iso) {
+    if (!iso) return "";
+    const d = new Date(iso);
+    if (isNaN(d)) return "";
+    const local = new Date(d.getTime() - d.getTimezoneOffset() * 60000);
+    return local.toISOString().slice(0, 19);
+  }
+
+  // --- Appels API ---
+
+  async function fetchJSON(path, params) {
+    const resp = await fetch(path + "?" + params.toString(), { credentials: "same-origin" });
+    const body = await resp.json().catch(() => ({}));
+    if (!resp.ok) throw new Error(body.error || resp.statusText);
+    return body;
+  }
+
+  async function search() {
+    const state = readForm();
+    history.replaceState(null, "", "?" + stateToParams(state).toString());
+    stopTail();
+
+    const params = stateToAPIParams(state);
+    params.set("limit", PAGE_SIZE);
+    statusEl.textContent = "Searching…";
+    try {
+      const [records] = await Promise.all([fetchJSON("api/search", params), drawHistogram(state)]);
+      tbody.replaceChildren();
+      if (records.length) newestID = records[0].row_id;
+      oldestID = records.length ? records[records.length - 1].row_id : 0;
+      records.forEach((rec) => tbody.append(...renderRecord(rec)));
+      moreBtn.hidden = records.length < PAGE_SIZE;
+      statusEl.textContent = records.length ? "" : "No matching entries.";
+    } catch (err) {
+      statusEl.textContent = "Search failed: " + err.message;
+      return;
+    }
+
+    if (state.tail) startTail();
+  }
+
+  async function loadMore() {
+    const params = stateToAPIParams(readForm());
+    params.set("limit", PAGE_SIZE);
+    params.set("before", oldestID);
+    try {
+      const records = await fetchJSON("api/search", params);
+      records.forEach((rec) => tbody.append(...renderRecord(rec)));
+      if (records.length) oldestID = records[records.length - 1].row_id;
+      moreBtn.hidden = records.length < PAGE_SIZE;
+    } catch (err) {
+      statusEl.textContent = "Loading failed: " + err.message;
+    }
+  }
+
+  // --- Live tail : interrogation périodique des entrées plus récentes que newestID ---
+
+  function startTail() {
+    statusEl.textContent = "Live tail: waiting for new entries…";
+    tailTimer = setInterval(pollTail, TAIL_INTERVAL_MS);
+  }
+
+  function stopTail() {
+    if (tailTimer) clearInterval(tailTimer);
+    tailTimer = null;
+  }
+
+  async function pollTail() {
+    const state = readForm();
+    const params = stateToAPIParams(state);
+    params.delete("to");
+    params.set("after", newestID);
+    params.set("limit", 500);
+    try {
+      const records = await fetchJSON("api/search", params);
+      if (!records.length) return;
+      newestID = records[0].row_id;
+      const rows = records.flatMap((rec) => renderRecord(rec, true));
+      tbody.prepend(...rows);
+      while (tbody.rows.length > MAX_ROWS * 2) tbody.lastElementChild.remove();
+      statusEl.textContent = "Live tail: last update " + new Date().toLocaleTimeString();
+    } catch (err) {
+      statusEl.textContent = "Live tail error: " + err.message;
+    }
+  }
+
+  // --- Rendu ---
+
+  function renderRecord(rec, isNew) {
+    const row = document.createElement("tr");
+    row.className = "entry" + (isNew ? " new" : "");
+    row.append(
+      cell(new Date(rec.timestamp).toLocaleString(), "time"),
+      cell(rec.level, "level " + rec.level),
+      cell(rec.service || ""),
+      cell(rec.host || ""),
+      cell(rec.message, "message"),
+    );
+
+    const details = document.createElement("tr");
+    details.className = "details";
+    details.hidden = true;
+    const td = document.createElement("td");
+    td.colSpan = 5;
+    const pre = document.createElement("pre");
+    const shown = Object.assign({}, rec.id ? { id: rec.id } : {}, rec.context || {});
+    pre.textContent = JSON.stringify(shown, null, 2);
+    td.append(pre);
+    details.append(td);
+
+    row.addEventListener("click", () => {
+      details.hidden = !details.hidden;
+    });
+    return [row, details];
+  }
+
+  function cell(text, className) {
+    const td = document.createElement("td");
+    if (className) td.className = className;
+    td.textContent = text;
+    return td;
+  }
+
+  async function drawHistogram(state) {
+    let [from, to] = timeBounds(state);
+    to = to || new Date();
+    if (!from) from = new Date(to.getTime() - 3600 * 1000);
+
+    const params = stateToAPIParams(state);
+    params.set("from", from.toISOString());
+    params.set("to", to.toISOString());
+    params.set("buckets", 60);
+
+    const result = await fetchJSON("api/histogram", params);
+    const buckets = result.buckets;
+    const max = Math.max(1, ...buckets.map((b) => LEVELS.reduce((n, l) => n + (b.counts[l] || 0), 0)));
+
+    const ns = "http://www.w3.org/2000/svg";
+    const svg = document.createElementNS(ns, "svg");
+    svg.setAttribute("viewBox", "0 0 " + buckets.length * 10 + " 100");
+    svg.setAttribute("preserveAspectRatio", "none");
+
+    buckets.forEach((b, i) => {
+      let y = 100;
+      let total = 0;
+      for (const level of LEVELS) {
+        const count = b.counts[level] || 0;
+        if (!count) continue;
+        total += count;
+        const h = (count / max) * 100;
+        y -= h;
+        const rect = document.createElementNS(ns, "rect");
+        rect.setAttribute("x", i * 10 + 1);
+        rect.setAttribute("y", y);
+        rect.setAttribute("width", 8);
+        rect.setAttribute("height", h);
+        rect.setAttribute("class", level);
+        svg.append(rect);
+      }
+
+      // Zone cliquable : restreint la recherche à l'intervalle
+      const hit = document.createElementNS(ns, "rect");
+      hit.setAttribute("x", i * 10);
+      hit.setAttribute("y", 0);
+      hit.setAttribute("width", 10);
+      hit.setAttribute("height", 100);
+      hit.setAttribute("class", "hit");
+      const title = document.createElementNS(ns, "title");
+      title.textContent = new Date(b.start).toLocaleString() + ": " + total + " entries";
+      hit.append(title);
+      hit.addEventListener("click", () => {
+        const start = new Date(b.start);
+        form.range.value = "";
+        form.from.value = toLocalInput(start.toISOString());
+        form.to.value = toLocalInput(new Date(start.getTime() + result.interval_seconds * 1000).toISOString());
+        customRange.hidden = false;
+        search();
+      });
+      svg.append(hit);
+    });
+
+    histogramEl.replaceChildren(svg);
+  }
+
+  // --- Événements ---
+
+  form.addEventListener("submit", (e) => {
+    e.preventDefault();
+    search();
+  });
+  form.range.addEventListener("change", () => {
+    customRange.hidden = form.range.value !== "";
+  });
+  form.tail.addEventListener("change", search);
+  moreBtn.addEventListener("click", loadMore);
+  document.getElementById("copy-link").addEventListener("click", async () => {
+    const url = location.origin + location.pathname + "?" + stateToParams(readForm()).toString();
+    try {
+      await navigator.clipboard.writeText(url);
+      statusEl.textContent = "Link copied.";
+    } catch (err) {
+      prompt("Copy this link:", url);
+    }
+  });
+  window.addEventListener("popstate", () => {
+    fillForm(new URLSearchParams(location.search));
+    search();
+  });
+
+  fillForm(new URLSearchParams(location.search));
+  search();
+})();
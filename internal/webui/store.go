@@ -0,0 +1,187 @@
+package webui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/rypi-dev/logger-server/internal"
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+// Query décrit une recherche dans la table logs (champs vides = pas de filtre, sauf
+// Tenant : une recherche ne porte toujours que sur un tenant, "" étant le tenant par défaut)
+type Query struct {
+	Tenant   string
+	Levels   []string
+	Service  string
+	Text     string            // sous-chaîne du message, insensible à la casse
+	From     time.Time         // inclus
+	To       time.Time         // exclu
+	Context  map[string]string // chemin JSON (ex. "user.id") -> valeur, comparée en texte
+	AfterID  int64             // entrées plus récentes que cette ligne (live tail)
+	BeforeID int64             // entrées plus anciennes que cette ligne (page suivante)
+	Limit    int
+}
+
+// Record est une entrée stockée avec son numéro de ligne, qui sert de curseur
+type Record struct {
+	RowID int64 `json:"row_id"`
+	internal.LogEntry
+}
+
+// Bucket compte par niveau les entrées d'un intervalle de l'histogramme
+type Bucket struct {
+	Start  time.Time      `json:"start"`
+	Counts map[string]int `json:"counts"`
+}
+
+// SQLiteStore lit la table logs écrite par SQLiteLogger
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore ouvre la base des logs; le store ne fait que des lectures
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Les timestamps sont stockés en RFC3339 avec leur fuseau : la comparaison se fait en
+// secondes Unix et non sur le texte
+const tsExpr = "CAST(strftime('%s', timestamp) AS INTEGER)"
+
+// where construit la clause WHERE d'une requête et ses arguments
+func (q Query) where() (string, []interface{}) {
+	conds := []string{"tenant = ?"}
+	args := []interface{}{q.Tenant}
+
+	if len(q.Levels) > 0 {
+		conds = append(conds, "level IN (?"+strings.Repeat(", ?", len(q.Levels)-1)+")")
+		for _, level := range q.Levels {
+			args = append(args, level)
+		}
+	}
+	if q.Service != "" {
+		conds = append(conds, "service = ?")
+		args = append(args, q.Service)
+	}
+	if q.Text != "" {
+		conds = append(conds, "message LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(q.Text)+"%")
+	}
+	if !q.From.IsZero() {
+		conds = append(conds, tsExpr+" >= ?")
+		args = append(args, q.From.Unix())
+	}
+	if !q.To.IsZero() {
+		conds = append(conds, tsExpr+" < ?")
+		args = append(args, q.To.Unix())
+	}
+	for path, value := range q.Context {
+		conds = append(conds, "CAST(json_extract(context, ?) AS TEXT) = ?")
+		args = append(args, "$."+path, value)
+	}
+	if q.AfterID > 0 {
+		conds = append(conds, "id > ?")
+		args = append(args, q.AfterID)
+	}
+	if q.BeforeID > 0 {
+		conds = append(conds, "id < ?")
+		args = append(args, q.BeforeID)
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// Search retourne les entrées correspondantes, des plus récentes aux plus anciennes
+func (s *SQLiteStore) Search(q Query) ([]Record, error) {
+	where, args := q.where()
+	query := "SELECT id, level, message, timestamp, context, service, host FROM logs" + where +
+		" ORDER BY id DESC LIMIT ?"
+	args = append(args, q.Limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var rec Record
+		var ts string
+		var ctxJSON, service, host sql.NullString
+		if err := rows.Scan(&rec.RowID, &rec.Level, &rec.Message, &ts, &ctxJSON, &service, &host); err != nil {
+			return nil, err
+		}
+		rec.Service = service.String
+		rec.Host = host.String
+		rec.Timestamp = utils.SafeParseTimestamp(ts)
+		if ctxJSON.Valid && ctxJSON.String != "" {
+			if ctx, err := utils.UnmarshalContext(ctxJSON.String); err == nil {
+				rec.Context = ctx
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// Histogram compte les entrées de [q.From, q.To) par intervalle et par niveau. Les
+// intervalles vides sont présents, avec des compteurs vides.
+func (s *SQLiteStore) Histogram(q Query, interval time.Duration) ([]Bucket, error) {
+	step := int64(interval / time.Second)
+	if step < 1 {
+		step = 1
+	}
+	from := q.From.Unix()
+
+	where, args := q.where()
+	query := fmt.Sprintf("SELECT (%s - ?) / ? AS bucket, level, COUNT(*) FROM logs%s GROUP BY bucket, level", tsExpr, where)
+	args = append([]interface{}{from, step}, args...)
+
+	n := int((q.To.Unix() - from + step - 1) / step)
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		buckets[i] = Bucket{Start: time.Unix(from+int64(i)*step, 0).UTC(), Counts: map[string]int{}}
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var idx int64
+		var level string
+		var count int
+		if err := rows.Scan(&idx, &level, &count); err != nil {
+			return nil, err
+		}
+		if idx >= 0 && idx < int64(n) {
+			buckets[idx].Counts[level] = count
+		}
+	}
+	return buckets, rows.Err()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
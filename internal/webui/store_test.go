@@ -0,0 +1,159 @@
+package webui_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/rypi-dev/logger-server/internal/webui"
+)
+
+// newTestStore crée une table logs identique à celle de SQLiteLogger
+func newTestStore(t *testing.T) (*webui.SQLiteStore, *sql.DB) {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "logs.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`
+	CREATE TABLE logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		level TEXT NOT NULL,
+		message TEXT NOT NULL,
+		timestamp TEXT NOT NULL,
+		context TEXT,
+		service TEXT,
+		host TEXT,
+		tenant TEXT NOT NULL DEFAULT ''
+	);`); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := webui.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, db
+}
+
+func insertLog(t *testing.T, db *sql.DB, level, message string, ts time.Time, ctx, service string) {
+	t.Helper()
+	if _, err := db.Exec(`INSERT INTO logs(level, message, timestamp, context, service) VALUES (?, ?, ?, ?, ?)`,
+		level, message, ts.Format(time.RFC3339), ctx, service); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLiteStore_SearchTenant(t *testing.T) {
+	s, db := newTestStore(t)
+
+	base := time.Date(2025, 8, 7, 12, 0, 0, 0, time.UTC)
+	insertLog(t, db, "INFO", "default tenant", base, `{}`, "auth")
+	if _, err := db.Exec(`INSERT INTO logs(level, message, timestamp, context, tenant) VALUES ('INFO', 'acme entry', ?, '{}', 'acme')`,
+		base.Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	for tenant, want := range map[string]string{"": "default tenant", "acme": "acme entry"} {
+		records, err := s.Search(webui.Query{Tenant: tenant, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 1 || records[0].Message != want {
+			t.Errorf("tenant %q: expected only %q, got %+v", tenant, want, records)
+		}
+	}
+	buckets, err := s.Histogram(webui.Query{Tenant: "globex", From: base, To: base.Add(time.Hour)}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range buckets {
+		if len(b.Counts) != 0 {
+			t.Errorf("expected no entries for another tenant, got %+v", b)
+		}
+	}
+}
+
+func TestSQLiteStore_Search(t *testing.T) {
+	s, db := newTestStore(t)
+
+	base := time.Date(2025, 8, 7, 12, 0, 0, 0, time.UTC)
+	paris := time.FixedZone("CEST", 2*3600)
+	insertLog(t, db, "INFO", "user logged in", base, `{"user_id":42}`, "auth")
+	insertLog(t, db, "ERROR", "payment 50% failed", base.Add(time.Minute).In(paris), `{"user_id":7,"request":{"method":"POST"}}`, "billing")
+	insertLog(t, db, "WARN", "slow payment", base.Add(2*time.Minute), `{}`, "billing")
+
+	tests := []struct {
+		name  string
+		query webui.Query
+		want  []string
+	}{
+		{"all, newest first", webui.Query{}, []string{"slow payment", "payment 50% failed", "user logged in"}},
+		{"levels", webui.Query{Levels: []string{"ERROR", "WARN"}}, []string{"slow payment", "payment 50% failed"}},
+		{"service", webui.Query{Service: "auth"}, []string{"user logged in"}},
+		{"text is case-insensitive", webui.Query{Text: "PAYMENT"}, []string{"slow payment", "payment 50% failed"}},
+		{"text escapes LIKE wildcards", webui.Query{Text: "50%"}, []string{"payment 50% failed"}},
+		{"time range across time zones", webui.Query{From: base.Add(time.Minute), To: base.Add(2 * time.Minute)}, []string{"payment 50% failed"}},
+		{"context value", webui.Query{Context: map[string]string{"user_id": "42"}}, []string{"user logged in"}},
+		{"nested context path", webui.Query{Context: map[string]string{"request.method": "POST"}}, []string{"payment 50% failed"}},
+		{"after cursor", webui.Query{AfterID: 2}, []string{"slow payment"}},
+		{"before cursor", webui.Query{BeforeID: 2}, []string{"user logged in"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Limit = 10
+			records, err := s.Search(tt.query)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			var got []string
+			for _, rec := range records {
+				got = append(got, rec.Message)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+
+	records, _ := s.Search(webui.Query{Service: "auth", Limit: 10})
+	if rec := records[0]; rec.RowID != 1 || rec.Context["user_id"] != float64(42) || !rec.Timestamp.Equal(base) {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+}
+
+func TestSQLiteStore_Histogram(t *testing.T) {
+	s, db := newTestStore(t)
+
+	base := time.Date(2025, 8, 7, 12, 0, 0, 0, time.UTC)
+	insertLog(t, db, "INFO", "a", base.Add(10*time.Second), "", "")
+	insertLog(t, db, "INFO", "b", base.Add(20*time.Second), "", "")
+	insertLog(t, db, "ERROR", "c", base.Add(70*time.Second), "", "")
+	insertLog(t, db, "ERROR", "out of range", base.Add(10*time.Minute), "", "")
+
+	buckets, err := s.Histogram(webui.Query{From: base, To: base.Add(3 * time.Minute)}, time.Minute)
+	if err != nil {
+		t.Fatalf("Histogram failed: %v", err)
+	}
+	if len(buckets) != 3 {
+		t.Fatalf("expected 3 buckets, got %d", len(buckets))
+	}
+	if buckets[0].Counts["INFO"] != 2 || buckets[1].Counts["ERROR"] != 1 || len(buckets[2].Counts) != 0 {
+		t.Errorf("unexpected buckets: %+v", buckets)
+	}
+	if !buckets[1].Start.Equal(base.Add(time.Minute)) {
+		t.Errorf("expected second bucket to start at %v, got %v", base.Add(time.Minute), buckets[1].Start)
+	}
+}
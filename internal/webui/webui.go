@@ -0,0 +1,215 @@
+package webui
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/apikey/apikey"
+	"github.com/rypi-dev/logger-server/internal/logger/log_levels"
+	"github.com/rypi-dev/logger-server/internal/utils/utils"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+const (
+	DefaultSearchLimit = 100
+	MaxSearchLimit     = 500
+
+	DefaultBuckets = 60
+	MaxBuckets     = 500
+)
+
+// Préfixe des paramètres de filtre sur le contexte : ctx.user_id=42
+const contextParamPrefix = "ctx."
+
+// Chemins JSON acceptés dans les filtres de contexte (segments séparés par des points)
+var contextPathPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// Store est implémenté par SQLiteStore
+type Store interface {
+	Search(q Query) ([]Record, error)
+	Histogram(q Query, interval time.Duration) ([]Bucket, error)
+}
+
+// API sert l'interface web (/ui/) et les recherches qu'elle effectue. Les routes sont
+// derrière la même authentification que le reste de l'API.
+type API struct {
+	store Store
+}
+
+func NewAPI(store Store) *API {
+	return &API{store: store}
+}
+
+// Register ajoute l'interface et ses endpoints au routeur
+func (a *API) Register(r *mux.Router) {
+	r.HandleFunc("/ui/api/search", a.handleSearch).Methods("GET")
+	r.HandleFunc("/ui/api/histogram", a.handleHistogram).Methods("GET")
+
+	assets, _ := fs.Sub(staticFiles, "static")
+	files := http.StripPrefix("/ui/", http.FileServer(http.FS(assets)))
+	r.Handle("/ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently)).Methods("GET")
+	r.PathPrefix("/ui/").Handler(withSecurityHeaders(files)).Methods("GET")
+}
+
+func withSecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'self'; img-src 'self' data:")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleSearch : GET /ui/api/search?level=ERROR,WARN&service=&q=&from=&to=&ctx.<chemin>=&after=&before=&limit=
+func (a *API) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(r)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, err := a.store.Search(q)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "failed to search logs")
+		return
+	}
+	if records == nil {
+		records = []Record{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// handleHistogram : mêmes filtres que la recherche, from et to requis, buckets intervalles
+func (a *API) handleHistogram(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(r)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if q.From.IsZero() || q.To.IsZero() || !q.From.Before(q.To) {
+		utils.WriteJSONError(w, http.StatusBadRequest, "'from' and 'to' are required and 'from' must be before 'to'")
+		return
+	}
+
+	buckets := DefaultBuckets
+	if b := r.URL.Query().Get("buckets"); b != "" {
+		v, err := strconv.Atoi(b)
+		if err != nil || v < 1 || v > MaxBuckets {
+			utils.WriteJSONError(w, http.StatusBadRequest, "invalid 'buckets' parameter")
+			return
+		}
+		buckets = v
+	}
+
+	interval := q.To.Sub(q.From) / time.Duration(buckets)
+	if interval < time.Second {
+		interval = time.Second
+	}
+	interval = interval.Round(time.Second)
+
+	result, err := a.store.Histogram(q, interval)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "failed to compute histogram")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"interval_seconds": int64(interval / time.Second),
+		"buckets":          result,
+	})
+}
+
+func parseQuery(r *http.Request) (Query, error) {
+	values := r.URL.Query()
+	q := Query{Limit: DefaultSearchLimit, Service: values.Get("service"), Text: values.Get("q")}
+	// Le tenant vient de la clé authentifiée, jamais de la requête
+	q.Tenant = apikey.TenantFromContext(r.Context())
+
+	if levels := values.Get("level"); levels != "" {
+		for _, level := range strings.Split(levels, ",") {
+			level = strings.TrimSpace(level)
+			if !log_levels.IsValidLogLevel(level) {
+				return q, errInvalidParam("level")
+			}
+			q.Levels = append(q.Levels, string(log_levels.NormalizeLogLevel(level)))
+		}
+	}
+
+	var err error
+	if q.From, err = parseTime(values.Get("from")); err != nil {
+		return q, errInvalidParam("from")
+	}
+	if q.To, err = parseTime(values.Get("to")); err != nil {
+		return q, errInvalidParam("to")
+	}
+
+	for key, vals := range values {
+		if !strings.HasPrefix(key, contextParamPrefix) || len(vals) == 0 {
+			continue
+		}
+		path := strings.TrimPrefix(key, contextParamPrefix)
+		if !contextPathPattern.MatchString(path) {
+			return q, errInvalidParam(key)
+		}
+		if q.Context == nil {
+			q.Context = make(map[string]string)
+		}
+		q.Context[path] = vals[0]
+	}
+
+	if q.AfterID, err = parseID(values.Get("after")); err != nil {
+		return q, errInvalidParam("after")
+	}
+	if q.BeforeID, err = parseID(values.Get("before")); err != nil {
+		return q, errInvalidParam("before")
+	}
+
+	if l := values.Get("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil || v < 1 {
+			return q, errInvalidParam("limit")
+		}
+		if v > MaxSearchLimit {
+			v = MaxSearchLimit
+		}
+		q.Limit = v
+	}
+
+	return q, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(utils.TimestampLayout, s)
+}
+
+func parseID(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return v, nil
+}
+
+func errInvalidParam(name string) error {
+	return fmt.Errorf("invalid '%s' parameter", name)
+}
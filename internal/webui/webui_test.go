@@ -0,0 +1,206 @@
+package webui_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rypi-dev/logger-server/internal/apikey"
+	"github.com/rypi-dev/logger-server/internal/webui"
+)
+
+// memoryStore enregistre la dernière requête reçue
+type memoryStore struct {
+	records  []webui.Record
+	query    webui.Query
+	interval time.Duration
+}
+
+func (m *memoryStore) Search(q webui.Query) ([]webui.Record, error) {
+	m.query = q
+	return m.records, nil
+}
+
+func (m *memoryStore) Histogram(q webui.Query, interval time.Duration) ([]webui.Bucket, error) {
+	m.query = q
+	m.interval = interval
+	return []webui.Bucket{{Start: q.From, Counts: map[string]int{"INFO": 3}}}, nil
+}
+
+func newRouter(store webui.Store) *mux.Router {
+	r := mux.NewRouter()
+	webui.NewAPI(store).Register(r)
+	return r
+}
+
+func TestHandleSearch(t *testing.T) {
+	store := &memoryStore{records: []webui.Record{{RowID: 7}}}
+	r := newRouter(store)
+
+	req := httptest.NewRequest("GET", "/ui/api/search?level=error,WARN&service=api&q=timeout"+
+		"&from=2025-08-07T10:00:00Z&ctx.user.id=42&before=100&limit=1000", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var got []map[string]interface{}
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0]["row_id"] != float64(7) {
+		t.Errorf("unexpected response: %v", got)
+	}
+
+	q := store.query
+	if len(q.Levels) != 2 || q.Levels[0] != "ERROR" || q.Levels[1] != "WARN" {
+		t.Errorf("unexpected levels: %v", q.Levels)
+	}
+	if q.Service != "api" || q.Text != "timeout" || q.BeforeID != 100 || q.Context["user.id"] != "42" {
+		t.Errorf("unexpected query: %+v", q)
+	}
+	if !q.From.Equal(time.Date(2025, 8, 7, 10, 0, 0, 0, time.UTC)) || !q.To.IsZero() {
+		t.Errorf("unexpected time range: %v - %v", q.From, q.To)
+	}
+	if q.Limit != webui.MaxSearchLimit {
+		t.Errorf("expected limit to be capped at %d, got %d", webui.MaxSearchLimit, q.Limit)
+	}
+}
+
+func TestHandleSearch_Tenant(t *testing.T) {
+	store := &memoryStore{}
+	r := newRouter(store)
+
+	req := httptest.NewRequest("GET", "/ui/api/search?tenant=globex", nil)
+	req = req.WithContext(apikey.WithKey(req.Context(), &apikey.Key{Name: "acme-reader", Tenant: "acme"}))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if store.query.Tenant != "acme" {
+		t.Errorf("expected the search to be scoped to the key tenant, got %q", store.query.Tenant)
+	}
+}
+
+func TestHandleSearch_EmptyResult(t *testing.T) {
+	r := newRouter(&memoryStore{})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/ui/api/search", nil))
+
+	if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != "[]" {
+		t.Errorf("expected 200 with empty array, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestHandleSearch_InvalidParams(t *testing.T) {
+	r := newRouter(&memoryStore{})
+
+	tests := []string{
+		"level=VERBOSE",
+		"from=yesterday",
+		"to=2025-08-07",
+		"after=-1",
+		"before=abc",
+		"limit=0",
+		"ctx.user%20id=1",
+		"ctx.=1",
+	}
+
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest("GET", "/ui/api/search?"+query, nil))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleHistogram(t *testing.T) {
+	store := &memoryStore{}
+	r := newRouter(store)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/ui/api/histogram?from=2025-08-07T10:00:00Z&to=2025-08-07T11:00:00Z&buckets=12&level=INFO", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var got struct {
+		IntervalSeconds int            `json:"interval_seconds"`
+		Buckets         []webui.Bucket `json:"buckets"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.IntervalSeconds != 300 || store.interval != 5*time.Minute {
+		t.Errorf("expected 5 minute interval, got %d s", got.IntervalSeconds)
+	}
+	if len(got.Buckets) != 1 || got.Buckets[0].Counts["INFO"] != 3 {
+		t.Errorf("unexpected buckets: %+v", got.Buckets)
+	}
+	if len(store.query.Levels) != 1 {
+		t.Errorf("expected filters to be forwarded, got %+v", store.query)
+	}
+}
+
+func TestHandleHistogram_InvalidParams(t *testing.T) {
+	r := newRouter(&memoryStore{})
+
+	tests := []string{
+		"",
+		"from=2025-08-07T10:00:00Z",
+		"from=2025-08-07T11:00:00Z&to=2025-08-07T10:00:00Z",
+		"from=2025-08-07T10:00:00Z&to=2025-08-07T11:00:00Z&buckets=0",
+		"from=2025-08-07T10:00:00Z&to=2025-08-07T11:00:00Z&buckets=100000",
+	}
+
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest("GET", "/ui/api/histogram?"+query, nil))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestStaticFiles(t *testing.T) {
+	r := newRouter(&memoryStore{})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/ui", nil))
+	if rr.Code != http.StatusMovedPermanently || rr.Header().Get("Location") != "/ui/" {
+		t.Errorf("expected redirect to /ui/, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/ui/", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "<title>logger-server</title>") {
+		t.Fatalf("expected index page, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Header().Get("Content-Security-Policy"), "default-src 'self'") {
+		t.Errorf("missing Content-Security-Policy header")
+	}
+
+	for _, asset := range []string{"/ui/app.js", "/ui/style.css"} {
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", asset, nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected 200 for %s, got %d", asset, rr.Code)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/ui/missing.js", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown asset, got %d", rr.Code)
+	}
+}
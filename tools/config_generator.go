@@ -20,34 +20,29 @@ const fluentTemplate = `
     Format json
     Tag    incoming.log
 
-[FILTER]
-    Name       lua
-    Match      incoming.*
-    script     lua/inject_header.lua
-    call       add_log_level_header
-
 [FILTER]
     Name       modify
     Match      incoming.*
     Add        stack multi-lang
 
 [OUTPUT]
-    Name   http
-    Match  incoming.*
-    Host   {{.Host}}
-    Port   {{.HostPort}}
-    URI    /log
-    Format json
-    Header Content-Type application/json
-    Header X-Log-Level ${X-Log-Level}
+    Name                 forward
+    Match                incoming.*
+    Host                 {{.Host}}
+    Port                 {{.HostPort}}
+    Require_ack_response True
+{{- if .SharedKey}}
+    Shared_Key           {{.SharedKey}}
+{{- end}}
 `
 
 type Config struct {
-	Flush    int
-	LogLevel string
-	Port     int
-	Host     string
-	HostPort int
+	Flush     int
+	LogLevel  string
+	Port      int
+	Host      string
+	HostPort  int
+	SharedKey string
 }
 
 func main() {
@@ -55,16 +50,18 @@ func main() {
 	logLevel := flag.String("loglevel", "info", "Log level")
 	port := flag.Int("port", 8888, "HTTP input port")
 	host := flag.String("host", "logger-server", "Output host")
-	hostPort := flag.Int("hostport", 8080, "Output host port")
+	hostPort := flag.Int("hostport", 24224, "Output host forward port")
+	sharedKey := flag.String("sharedkey", "", "Forward protocol shared key (optional)")
 	outFile := flag.String("out", "fluent-bit.conf", "Output filename")
 	flag.Parse()
 
 	cfg := Config{
-		Flush:    *flush,
-		LogLevel: *logLevel,
-		Port:     *port,
-		Host:     *host,
-		HostPort: *hostPort,
+		Flush:     *flush,
+		LogLevel:  *logLevel,
+		Port:      *port,
+		Host:      *host,
+		HostPort:  *hostPort,
+		SharedKey: *sharedKey,
 	}
 
 	f, err := os.Create(*outFile)
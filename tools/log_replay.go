@@ -1,44 +1,21 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"net/http"
 	"os"
 	"time"
-)
-
-type LogEntry struct {
-	Level     string                 `json:"level"`
-	Message   string                 `json:"message"`
-	Timestamp string                 `json:"timestamp"`
-	Context   map[string]interface{} `json:"context,omitempty"`
-}
-
-func sendLog(url string, entry LogEntry) error {
-	data, err := json.Marshal(entry)
-	if err != nil {
-		return err
-	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("HTTP error status: %d", resp.StatusCode)
-	}
-
-	return nil
-}
+	"github.com/rypi-dev/logger-server/client"
+)
 
 func main() {
 	filePath := flag.String("file", "", "JSON file containing logs (array)")
-	url := flag.String("url", "http://localhost:8080/log", "Server URL")
+	url := flag.String("url", "http://localhost:8080", "Server URL")
+	apiKey := flag.String("api-key", os.Getenv("LOGGER_API_KEY"), "API key (X-API-Key)")
+	sign := flag.Bool("sign", false, "Sign requests with the API key instead of sending it (lsk_ keys only)")
 	delayMs := flag.Int("delay", 1000, "Delay between logs in ms")
 	flag.Parse()
 
@@ -54,6 +31,17 @@ func main() {
 	}
 	defer file.Close()
 
+	c, err := client.New(client.Config{
+		URL:          *url,
+		APIKey:       *apiKey,
+		SignRequests: *sign,
+		OnError:      func(err error) { fmt.Printf("Error sending logs: %v\n", err) },
+	})
+	if err != nil {
+		fmt.Printf("Error creating client: %v\n", err)
+		os.Exit(1)
+	}
+
 	decoder := json.NewDecoder(file)
 
 	// On attend un tableau JSON : [ {...}, {...}, ... ]
@@ -68,18 +56,26 @@ func main() {
 	}
 
 	for decoder.More() {
-		var entry LogEntry
+		var entry client.Entry
 		if err := decoder.Decode(&entry); err != nil {
 			fmt.Printf("Error decoding log entry: %v\n", err)
 			continue
 		}
 
-		if err := sendLog(*url, entry); err != nil {
-			fmt.Printf("Error sending log: %v\n", err)
+		// Les entrées sont envoyées par lots; LogWait attend si le serveur ne suit pas
+		if err := c.LogWait(context.Background(), entry); err != nil {
+			fmt.Printf("Error queueing log: %v\n", err)
 		}
 
 		time.Sleep(time.Duration(*delayMs) * time.Millisecond)
 	}
 
-	fmt.Println("Replay completed")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := c.Close(ctx); err != nil {
+		fmt.Printf("Error flushing logs: %v\n", err)
+	}
+
+	stats := c.Stats()
+	fmt.Printf("Replay completed: %d sent, %d rejected, %d dropped\n", stats.Sent, stats.Rejected, stats.Dropped)
 }
\ No newline at end of file